@@ -0,0 +1,221 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+// Package backupstore keeps attack backups that are too large for the action state. A backup is compressed, split
+// into chunks and saved by execution ID, the action state only holds a Ref. Checksums of every chunk and of the
+// whole backup are verified when it is loaded.
+package backupstore
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"time"
+
+	"github.com/steadybit/extension-redis/config"
+)
+
+// Backend stores the chunks of backups.
+type Backend interface {
+	// PutChunk saves a chunk, backends that expire data keep it for at least retention
+	PutChunk(ctx context.Context, id string, index int, data []byte, retention time.Duration) error
+	GetChunk(ctx context.Context, id string, index int) ([]byte, error)
+	Delete(ctx context.Context, id string, chunks int) error
+}
+
+// Ref is kept in the action state instead of the backup.
+type Ref struct {
+	Store      string   `json:"store"`      // Backend that holds the chunks
+	ID         string   `json:"id"`         // Execution ID the backup was saved for
+	Chunks     []string `json:"chunks"`     // SHA-256 of every compressed chunk
+	Size       int64    `json:"size"`       // Uncompressed size
+	StoredSize int64    `json:"storedSize"` // Compressed size of all chunks
+	SHA256     string   `json:"sha256"`     // SHA-256 of the uncompressed backup
+}
+
+// retentionAfterAttack is how long a backup is kept after the end of its attack by backends that expire data. It
+// leaves time for the journal to restore after an outage of the extension, backups of attacks that were never stopped
+// are removed eventually.
+const retentionAfterAttack = 7 * 24 * time.Hour
+
+var errChecksum = errors.New("checksum mismatch")
+
+var validID = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// backends creates the backend of every supported store.
+var backends = map[string]func() (Backend, error){
+	config.BackupStoreDisk:  newDiskBackend,
+	config.BackupStoreRedis: newRedisBackend,
+}
+
+// Enabled reports whether STEADYBIT_EXTENSION_BACKUP_STORE is set.
+func Enabled() bool {
+	return config.Config.BackupStore != ""
+}
+
+// Save writes v as JSON to the configured backend. Chunks are written while v is encoded, so the compressed backup
+// is never held in memory as a whole. attackEnd is the planned end of the attack the backup is taken for.
+func Save(ctx context.Context, id string, attackEnd time.Time, v any) (*Ref, error) {
+	if !validID.MatchString(id) {
+		return nil, fmt.Errorf("invalid backup id %q", id)
+	}
+	backend, err := getBackend(config.Config.BackupStore)
+	if err != nil {
+		return nil, err
+	}
+
+	ref := &Ref{Store: config.Config.BackupStore, ID: id}
+	chunks := &chunkWriter{
+		ctx:       ctx,
+		backend:   backend,
+		ref:       ref,
+		size:      config.Config.BackupStoreChunkSizeBytes,
+		retention: time.Until(attackEnd) + retentionAfterAttack,
+	}
+	compressed := gzip.NewWriter(chunks)
+	sum := sha256.New()
+	counter := &countingWriter{}
+	if err := json.NewEncoder(io.MultiWriter(compressed, sum, counter)).Encode(v); err != nil {
+		_ = backend.Delete(ctx, id, len(ref.Chunks))
+		return nil, fmt.Errorf("failed to write backup: %w", err)
+	}
+	if err := compressed.Close(); err != nil {
+		_ = backend.Delete(ctx, id, len(ref.Chunks))
+		return nil, fmt.Errorf("failed to write backup: %w", err)
+	}
+	if err := chunks.flush(); err != nil {
+		_ = backend.Delete(ctx, id, len(ref.Chunks))
+		return nil, fmt.Errorf("failed to write backup: %w", err)
+	}
+	ref.Size = counter.n
+	ref.SHA256 = hex.EncodeToString(sum.Sum(nil))
+	return ref, nil
+}
+
+// Load reads the backup of ref into v. It fails if a chunk is missing or any checksum does not match.
+func Load(ctx context.Context, ref *Ref, v any) error {
+	backend, err := getBackend(ref.Store)
+	if err != nil {
+		return err
+	}
+	chunks := &chunkReader{ctx: ctx, backend: backend, ref: ref}
+	compressed, err := gzip.NewReader(chunks)
+	if err != nil {
+		return fmt.Errorf("failed to read backup %s: %w", ref.ID, err)
+	}
+	sum := sha256.New()
+	counter := &countingWriter{}
+	uncompressed := io.TeeReader(compressed, io.MultiWriter(sum, counter))
+	if err := json.NewDecoder(uncompressed).Decode(v); err != nil {
+		return fmt.Errorf("failed to read backup %s: %w", ref.ID, err)
+	}
+	// The checksum covers the whole backup, including what the decoder did not need to read
+	if _, err := io.Copy(io.Discard, uncompressed); err != nil {
+		return fmt.Errorf("failed to read backup %s: %w", ref.ID, err)
+	}
+	if counter.n != ref.Size || hex.EncodeToString(sum.Sum(nil)) != ref.SHA256 {
+		return fmt.Errorf("backup %s is corrupt: checksum mismatch", ref.ID)
+	}
+	return nil
+}
+
+// Delete removes the chunks of ref from its backend.
+func Delete(ctx context.Context, ref *Ref) error {
+	backend, err := getBackend(ref.Store)
+	if err != nil {
+		return err
+	}
+	return backend.Delete(ctx, ref.ID, len(ref.Chunks))
+}
+
+func getBackend(store string) (Backend, error) {
+	newBackend, ok := backends[store]
+	if !ok {
+		return nil, fmt.Errorf("unsupported backup store %q", store)
+	}
+	return newBackend()
+}
+
+// chunkWriter buffers compressed data and writes it to the backend in chunks of size bytes.
+type chunkWriter struct {
+	ctx       context.Context
+	backend   Backend
+	ref       *Ref
+	size      int
+	retention time.Duration
+	buf       bytes.Buffer
+}
+
+func (w *chunkWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+	for w.buf.Len() >= w.size {
+		if err := w.put(w.buf.Next(w.size)); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func (w *chunkWriter) flush() error {
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	return w.put(w.buf.Next(w.buf.Len()))
+}
+
+func (w *chunkWriter) put(chunk []byte) error {
+	if err := w.backend.PutChunk(w.ctx, w.ref.ID, len(w.ref.Chunks), chunk, w.retention); err != nil {
+		return fmt.Errorf("chunk %d: %w", len(w.ref.Chunks), err)
+	}
+	sum := sha256.Sum256(chunk)
+	w.ref.Chunks = append(w.ref.Chunks, hex.EncodeToString(sum[:]))
+	w.ref.StoredSize += int64(len(chunk))
+	return nil
+}
+
+// chunkReader reads the chunks of a backup in order and verifies each before it is decompressed.
+type chunkReader struct {
+	ctx     context.Context
+	backend Backend
+	ref     *Ref
+	next    int
+	current []byte
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for len(r.current) == 0 {
+		if r.next >= len(r.ref.Chunks) {
+			return 0, io.EOF
+		}
+		chunk, err := r.backend.GetChunk(r.ctx, r.ref.ID, r.next)
+		if err != nil {
+			return 0, fmt.Errorf("chunk %d: %w", r.next, err)
+		}
+		sum := sha256.Sum256(chunk)
+		if hex.EncodeToString(sum[:]) != r.ref.Chunks[r.next] {
+			return 0, fmt.Errorf("chunk %d is corrupt: %w", r.next, errChecksum)
+		}
+		r.current = chunk
+		r.next++
+	}
+	n := copy(p, r.current)
+	r.current = r.current[n:]
+	return n, nil
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
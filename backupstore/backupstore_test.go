@@ -0,0 +1,233 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2024 Steadybit GmbH
+
+package backupstore
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/steadybit/extension-redis/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testBackup struct {
+	Values map[string]string `json:"values"`
+}
+
+func largeBackup(n int) testBackup {
+	backup := testBackup{Values: make(map[string]string, n)}
+	for i := range n {
+		backup.Values[fmt.Sprintf("key:%d", i)] = fmt.Sprintf("value-%d-%s", i, strings.Repeat("x", i%50))
+	}
+	return backup
+}
+
+func useDiskStore(t *testing.T, chunkSize int) string {
+	t.Helper()
+	dir := t.TempDir()
+	orig := config.Config
+	t.Cleanup(func() { config.Config = orig })
+	config.Config.BackupStore = config.BackupStoreDisk
+	config.Config.BackupStoreDir = dir
+	config.Config.BackupStoreChunkSizeBytes = chunkSize
+	return dir
+}
+
+func TestEnabled(t *testing.T) {
+	orig := config.Config.BackupStore
+	defer func() { config.Config.BackupStore = orig }()
+
+	config.Config.BackupStore = ""
+	assert.False(t, Enabled())
+	config.Config.BackupStore = config.BackupStoreDisk
+	assert.True(t, Enabled())
+}
+
+func TestSaveLoadDelete_Disk(t *testing.T) {
+	// Given
+	dir := useDiskStore(t, 1024)
+	backup := largeBackup(2000)
+	ctx := context.Background()
+
+	// When
+	ref, err := Save(ctx, "exec-1", time.Now(), backup)
+	require.NoError(t, err)
+	var loaded testBackup
+	err = Load(ctx, ref, &loaded)
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, backup, loaded)
+	assert.Equal(t, config.BackupStoreDisk, ref.Store)
+	assert.Greater(t, len(ref.Chunks), 1, "the backup is split into chunks")
+	assert.Less(t, ref.StoredSize, ref.Size, "the backup is compressed")
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, files, len(ref.Chunks), "no temporary files left")
+	info, err := os.Stat(filepath.Join(dir, "exec-1.0.chunk"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	// When
+	require.NoError(t, Delete(ctx, ref))
+
+	// Then
+	files, err = os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestLoad_DetectsCorruption(t *testing.T) {
+	tests := []struct {
+		name    string
+		corrupt func(t *testing.T, dir string, ref *Ref)
+		wantErr string
+	}{
+		{
+			name: "changed chunk",
+			corrupt: func(t *testing.T, dir string, ref *Ref) {
+				path := filepath.Join(dir, "exec-1.1.chunk")
+				data, err := os.ReadFile(path)
+				require.NoError(t, err)
+				data[0] ^= 0xff
+				require.NoError(t, os.WriteFile(path, data, 0o600))
+			},
+			wantErr: "chunk 1 is corrupt",
+		},
+		{
+			name: "missing chunk",
+			corrupt: func(t *testing.T, dir string, ref *Ref) {
+				require.NoError(t, os.Remove(filepath.Join(dir, "exec-1.0.chunk")))
+			},
+			wantErr: "chunk 0",
+		},
+		{
+			name: "tampered reference",
+			corrupt: func(t *testing.T, dir string, ref *Ref) {
+				ref.SHA256 = strings.Repeat("0", 64)
+			},
+			wantErr: "checksum mismatch",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// Given
+			dir := useDiskStore(t, 512)
+			ref, err := Save(context.Background(), "exec-1", time.Now(), largeBackup(500))
+			require.NoError(t, err)
+			tc.corrupt(t, dir, ref)
+
+			// When
+			var loaded testBackup
+			err = Load(context.Background(), ref, &loaded)
+
+			// Then
+			assert.ErrorContains(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestSaveLoadDelete_Redis(t *testing.T) {
+	// Given
+	mr := miniredis.RunT(t)
+	orig := config.Config
+	t.Cleanup(func() { config.Config = orig })
+	config.Config.BackupStore = config.BackupStoreRedis
+	config.Config.BackupStoreRedisURL = fmt.Sprintf("redis://%s/3", mr.Addr())
+	config.Config.BackupStoreChunkSizeBytes = 2048
+	t.Cleanup(Close)
+	backup := largeBackup(1000)
+	ctx := context.Background()
+
+	// When
+	ref, err := Save(ctx, "exec-2", time.Now().Add(time.Hour), backup)
+	require.NoError(t, err)
+	var loaded testBackup
+	err = Load(ctx, ref, &loaded)
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, backup, loaded)
+	mr.Select(3)
+	assert.Len(t, mr.Keys(), len(ref.Chunks))
+	assert.True(t, mr.Exists("steadybit:backup:exec-2:0"))
+	assert.InDelta(t, (time.Hour + retentionAfterAttack).Seconds(), mr.TTL("steadybit:backup:exec-2:0").Seconds(), 60, "chunks expire well after the attack")
+
+	// When
+	require.NoError(t, Delete(ctx, ref))
+
+	// Then
+	assert.Empty(t, mr.Keys())
+	assert.ErrorContains(t, Load(ctx, ref, &loaded), "is missing")
+}
+
+func TestSaveLoad_RedisUsesEndpointCredentials(t *testing.T) {
+	// Given
+	mr := miniredis.RunT(t)
+	mr.RequireUserAuth("backup", "s3cret")
+	passwordFile := filepath.Join(t.TempDir(), "password")
+	require.NoError(t, os.WriteFile(passwordFile, []byte("s3cret"), 0o600))
+	orig := config.Config
+	t.Cleanup(func() { config.Config = orig })
+	redisURL := fmt.Sprintf("redis://%s", mr.Addr())
+	config.Config.Endpoints = []config.RedisEndpoint{{URL: redisURL, Username: "backup", PasswordFile: passwordFile}}
+	config.Config.BackupStore = config.BackupStoreRedis
+	config.Config.BackupStoreRedisURL = redisURL
+	config.Config.BackupStoreChunkSizeBytes = 2048
+	t.Cleanup(Close)
+	backup := largeBackup(10)
+	ctx := context.Background()
+
+	// When
+	ref, err := Save(ctx, "exec-3", time.Now(), backup)
+	require.NoError(t, err)
+	var loaded testBackup
+	err = Load(ctx, ref, &loaded)
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, backup, loaded)
+}
+
+func TestClose_ClosesRedisClients(t *testing.T) {
+	// Given
+	mr := miniredis.RunT(t)
+	orig := config.Config
+	t.Cleanup(func() { config.Config = orig })
+	config.Config.BackupStoreRedisURL = fmt.Sprintf("redis://%s", mr.Addr())
+	backend, err := newRedisBackend()
+	require.NoError(t, err)
+
+	// When
+	Close()
+
+	// Then
+	assert.ErrorIs(t, backend.(*redisBackend).client.Ping(context.Background()).Err(), redis.ErrClosed)
+	assert.Empty(t, redisClients)
+}
+
+func TestSave_InvalidID(t *testing.T) {
+	useDiskStore(t, 1024)
+
+	for _, id := range []string{"", "../escape", "a/b", ".hidden"} {
+		_, err := Save(context.Background(), id, time.Now(), testBackup{})
+		assert.Error(t, err, id)
+	}
+}
+
+func TestLoad_UnsupportedStore(t *testing.T) {
+	err := Load(context.Background(), &Ref{Store: "tape", ID: "exec-1"}, &testBackup{})
+
+	assert.ErrorContains(t, err, `unsupported backup store "tape"`)
+}
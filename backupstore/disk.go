@@ -0,0 +1,78 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package backupstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/steadybit/extension-redis/config"
+)
+
+// diskBackend writes every chunk to a file in STEADYBIT_EXTENSION_BACKUP_STORE_DIR.
+type diskBackend struct {
+	dir string
+}
+
+func newDiskBackend() (Backend, error) {
+	if config.Config.BackupStoreDir == "" {
+		return nil, errors.New("STEADYBIT_EXTENSION_BACKUP_STORE_DIR is not set")
+	}
+	return &diskBackend{dir: config.Config.BackupStoreDir}, nil
+}
+
+// PutChunk keeps the chunk until it is deleted, the directory is not cleaned up by the extension.
+func (b *diskBackend) PutChunk(_ context.Context, id string, index int, data []byte, _ time.Duration) error {
+	if err := os.MkdirAll(b.dir, 0o700); err != nil {
+		return fmt.Errorf("create backup directory: %w", err)
+	}
+	// Backups hold the values of keys, so they are only readable by the extension
+	tmp, err := os.CreateTemp(b.dir, ".chunk-*")
+	if err != nil {
+		return fmt.Errorf("create backup chunk: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("write backup chunk: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("sync backup chunk: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close backup chunk: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), b.chunkPath(id, index)); err != nil {
+		return fmt.Errorf("write backup chunk: %w", err)
+	}
+	return nil
+}
+
+func (b *diskBackend) GetChunk(_ context.Context, id string, index int) ([]byte, error) {
+	data, err := os.ReadFile(b.chunkPath(id, index))
+	if err != nil {
+		return nil, fmt.Errorf("read backup chunk: %w", err)
+	}
+	return data, nil
+}
+
+func (b *diskBackend) Delete(_ context.Context, id string, chunks int) error {
+	var errs []error
+	for i := range chunks {
+		if err := os.Remove(b.chunkPath(id, i)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (b *diskBackend) chunkPath(id string, index int) string {
+	return filepath.Join(b.dir, fmt.Sprintf("%s.%d.chunk", id, index))
+}
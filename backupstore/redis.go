@@ -0,0 +1,90 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package backupstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/steadybit/extension-redis/clients"
+	"github.com/steadybit/extension-redis/config"
+)
+
+const redisKeyPrefix = "steadybit:backup:"
+
+// redisBackend writes every chunk to a key of the Redis in STEADYBIT_EXTENSION_BACKUP_STORE_REDIS_URL. It must not
+// be one of the attacked endpoints, an attack could evict or expire its own backup.
+type redisBackend struct {
+	client *redis.Client
+}
+
+var (
+	redisClients      = make(map[string]*redis.Client)
+	redisClientsMutex sync.Mutex
+)
+
+func newRedisBackend() (Backend, error) {
+	url := config.Config.BackupStoreRedisURL
+	if url == "" {
+		return nil, errors.New("STEADYBIT_EXTENSION_BACKUP_STORE_REDIS_URL is not set")
+	}
+	redisClientsMutex.Lock()
+	defer redisClientsMutex.Unlock()
+	if client, ok := redisClients[url]; ok {
+		return &redisBackend{client: client}, nil
+	}
+	// The TLS, CA and credential settings of an endpoint with the same URL apply to the backup Redis as well
+	endpoint := config.GetEndpointByURL(url)
+	if endpoint == nil {
+		endpoint = &config.RedisEndpoint{URL: url}
+	}
+	client, err := clients.CreateRedisClient(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid STEADYBIT_EXTENSION_BACKUP_STORE_REDIS_URL: %w", err)
+	}
+	redisClients[url] = client
+	return &redisBackend{client: client}, nil
+}
+
+// Close closes the clients of the redis store for graceful shutdown.
+func Close() {
+	redisClientsMutex.Lock()
+	defer redisClientsMutex.Unlock()
+	for url, client := range redisClients {
+		_ = client.Close()
+		delete(redisClients, url)
+	}
+}
+
+func (b *redisBackend) PutChunk(ctx context.Context, id string, index int, data []byte, retention time.Duration) error {
+	return b.client.Set(ctx, chunkKey(id, index), data, retention).Err()
+}
+
+func (b *redisBackend) GetChunk(ctx context.Context, id string, index int) ([]byte, error) {
+	data, err := b.client.Get(ctx, chunkKey(id, index)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("backup chunk %s is missing", chunkKey(id, index))
+	}
+	return data, err
+}
+
+func (b *redisBackend) Delete(ctx context.Context, id string, chunks int) error {
+	if chunks == 0 {
+		return nil
+	}
+	keys := make([]string, chunks)
+	for i := range keys {
+		keys[i] = chunkKey(id, i)
+	}
+	return b.client.Del(ctx, keys...).Err()
+}
+
+func chunkKey(id string, index int) string {
+	return fmt.Sprintf("%s%s:%d", redisKeyPrefix, id, index)
+}
@@ -0,0 +1,82 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package clients
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+const defaultRedisPort = "6379"
+
+// SplitHostPort splits a node address into host and port. Besides host:port and [ipv6]:port it accepts the
+// unbracketed IPv6 form Redis uses in CLUSTER NODES (e.g. 2001:db8::1:6379) and unix socket paths, which have
+// no port. A missing port defaults to 6379; an IPv6 address without port must be bracketed.
+func SplitHostPort(addr string) (string, string) {
+	if strings.HasPrefix(addr, "/") {
+		return addr, ""
+	}
+	if host, port, err := net.SplitHostPort(addr); err == nil {
+		return host, port
+	}
+	if strings.HasPrefix(addr, "[") && strings.HasSuffix(addr, "]") {
+		return addr[1 : len(addr)-1], defaultRedisPort
+	}
+	if idx := strings.LastIndex(addr, ":"); idx != -1 && isPort(addr[idx+1:]) {
+		return addr[:idx], addr[idx+1:]
+	}
+	return addr, defaultRedisPort
+}
+
+// JoinHostPort is the inverse of SplitHostPort: IPv6 hosts are bracketed, unix socket paths are returned as is.
+func JoinHostPort(host, port string) string {
+	if port == "" {
+		return host
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// EndpointHostPort returns host and port of an endpoint URL. For unix:// URLs the host is the socket path and the
+// port is empty.
+func EndpointHostPort(rawURL string) (string, string, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to parse Redis URL: %w", err)
+	}
+	if parsed.Scheme == "unix" {
+		if parsed.Path == "" {
+			return "", "", fmt.Errorf("unix socket URL %q has no path", rawURL)
+		}
+		return parsed.Path, "", nil
+	}
+	port := parsed.Port()
+	if port == "" {
+		port = defaultRedisPort
+	}
+	return parsed.Hostname(), port, nil
+}
+
+// NodeURL builds the URL of a single node. Unix socket paths become unix:// URLs regardless of scheme.
+func NodeURL(scheme, addr string) string {
+	host, port := SplitHostPort(addr)
+	if port == "" {
+		return "unix://" + host
+	}
+	return fmt.Sprintf("%s://%s", scheme, JoinHostPort(host, port))
+}
+
+func isPort(s string) bool {
+	if s == "" || len(s) > 5 {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
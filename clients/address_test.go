@@ -0,0 +1,110 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2024 Steadybit GmbH
+
+package clients
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitHostPort(t *testing.T) {
+	tests := []struct {
+		addr     string
+		wantHost string
+		wantPort string
+	}{
+		{"10.0.0.1:6379", "10.0.0.1", "6379"},
+		{"redis.local:6380", "redis.local", "6380"},
+		{"redis.local", "redis.local", "6379"},
+		{"[::1]:6379", "::1", "6379"},
+		{"[2001:db8::1]:7000", "2001:db8::1", "7000"},
+		{"[2001:db8::1]", "2001:db8::1", "6379"},
+		// CLUSTER NODES reports IPv6 addresses without brackets
+		{"2001:db8::1:7000", "2001:db8::1", "7000"},
+		{"::1:6379", "::1", "6379"},
+		{"/var/run/redis/redis.sock", "/var/run/redis/redis.sock", ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.addr, func(t *testing.T) {
+			host, port := SplitHostPort(tc.addr)
+
+			assert.Equal(t, tc.wantHost, host)
+			assert.Equal(t, tc.wantPort, port)
+		})
+	}
+}
+
+func TestJoinHostPort(t *testing.T) {
+	tests := []struct {
+		host string
+		port string
+		want string
+	}{
+		{"10.0.0.1", "6379", "10.0.0.1:6379"},
+		{"::1", "6379", "[::1]:6379"},
+		{"2001:db8::1", "7000", "[2001:db8::1]:7000"},
+		{"/var/run/redis/redis.sock", "", "/var/run/redis/redis.sock"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.want, func(t *testing.T) {
+			assert.Equal(t, tc.want, JoinHostPort(tc.host, tc.port))
+		})
+	}
+}
+
+func TestEndpointHostPort(t *testing.T) {
+	tests := []struct {
+		url      string
+		wantHost string
+		wantPort string
+		wantErr  string
+	}{
+		{url: "redis://redis.local:6380", wantHost: "redis.local", wantPort: "6380"},
+		{url: "rediss://:secret@redis.local/2", wantHost: "redis.local", wantPort: "6379"},
+		{url: "redis://[::1]:6379", wantHost: "::1", wantPort: "6379"},
+		{url: "redis://[2001:db8::1]", wantHost: "2001:db8::1", wantPort: "6379"},
+		{url: "unix:///var/run/redis/redis.sock", wantHost: "/var/run/redis/redis.sock", wantPort: ""},
+		{url: "unix://:secret@/tmp/redis.sock?db=1", wantHost: "/tmp/redis.sock", wantPort: ""},
+		{url: "unix://", wantErr: "has no path"},
+		{url: "://invalid", wantErr: "failed to parse Redis URL"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.url, func(t *testing.T) {
+			host, port, err := EndpointHostPort(tc.url)
+
+			if tc.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantHost, host)
+			assert.Equal(t, tc.wantPort, port)
+		})
+	}
+}
+
+func TestNodeURL(t *testing.T) {
+	tests := []struct {
+		scheme string
+		addr   string
+		want   string
+	}{
+		{"redis", "10.0.0.1:6379", "redis://10.0.0.1:6379"},
+		{"rediss", "[2001:db8::1]:7000", "rediss://[2001:db8::1]:7000"},
+		{"redis", "2001:db8::1:7000", "redis://[2001:db8::1]:7000"},
+		{"redis", "/var/run/redis/redis.sock", "unix:///var/run/redis/redis.sock"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.want, func(t *testing.T) {
+			assert.Equal(t, tc.want, NodeURL(tc.scheme, tc.addr))
+		})
+	}
+}
@@ -6,8 +6,8 @@ package clients
 
 import (
 	"context"
-	"crypto/tls"
 	"fmt"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -17,15 +17,44 @@ import (
 	"github.com/steadybit/extension-redis/config"
 )
 
-// clientPool stores long-lived Redis clients keyed by (url, password, db).
+// clientPool stores long-lived Redis clients keyed by (url, password, db), cluster clients by (url, password, "cluster").
 var clientPool sync.Map
 
+// evictedClientCloseDelay is how long an evicted client stays open for commands that already hold it.
+var evictedClientCloseDelay = 30 * time.Second
+
 // ClusterNodeInfo represents a node parsed from CLUSTER NODES output.
 type ClusterNodeInfo struct {
-	ID    string
-	Addr  string // host:port
-	Role  string // "master" or "slave"
-	Flags string
+	ID          string
+	Addr        string // host:port
+	Role        string // "master" or "slave"
+	Flags       string
+	MasterID    string      // ID of the master of a replica, empty for masters
+	Slots       []SlotRange // Slots served by a master
+	ConfigEpoch string
+}
+
+// SlotRange is an inclusive range of hash slots.
+type SlotRange struct {
+	Start int
+	End   int
+}
+
+// String formats the range like CLUSTER NODES does, e.g. "0-5460" or "5462".
+func (r SlotRange) String() string {
+	if r.Start == r.End {
+		return strconv.Itoa(r.Start)
+	}
+	return fmt.Sprintf("%d-%d", r.Start, r.End)
+}
+
+// SlotCount returns the number of slots in the ranges.
+func SlotCount(ranges []SlotRange) int {
+	count := 0
+	for _, r := range ranges {
+		count += r.End - r.Start + 1
+	}
+	return count
 }
 
 // CreateRedisClient creates a new standalone Redis client from an endpoint configuration.
@@ -34,9 +63,12 @@ func CreateRedisClient(endpoint *config.RedisEndpoint) (*redis.Client, error) {
 	if err != nil {
 		return nil, err
 	}
+	if endpoint.IsSentinel() {
+		return instrument(newFailoverClient(endpoint, opts), endpoint.URL), nil
+	}
 
 	client := redis.NewClient(opts)
-	return client, nil
+	return instrument(client, endpoint.URL), nil
 }
 
 // GetRedisClient returns a pooled standalone Redis client for the given (url, password, db) combination.
@@ -60,10 +92,71 @@ func GetRedisClient(url string, password string, db int) (*redis.Client, error)
 	return client, nil
 }
 
+// GetUniversalClient returns a pooled client for key-level commands. For cluster endpoints it is a redis.ClusterClient
+// that routes every key to the shard owning its slot and follows MOVED and ASK redirects, otherwise the standalone
+// client of GetRedisClient. The returned client must NOT be closed — it is shared and long-lived.
+func GetUniversalClient(url string, password string, db int, clusterMode bool) (redis.UniversalClient, error) {
+	if !clusterMode {
+		return GetRedisClient(url, password, db)
+	}
+
+	key := fmt.Sprintf("%s|%s|cluster", url, password)
+	if v, ok := clientPool.Load(key); ok {
+		return v.(*redis.ClusterClient), nil
+	}
+
+	endpoint := config.GetEndpointByURL(url)
+	if endpoint == nil {
+		return nil, fmt.Errorf("no endpoint configured for %s", url)
+	}
+	client, err := CreateClusterClient(endpoint, password)
+	if err != nil {
+		return nil, err
+	}
+
+	actual, loaded := clientPool.LoadOrStore(key, client)
+	if loaded {
+		_ = client.Close()
+		return actual.(*redis.ClusterClient), nil
+	}
+	return client, nil
+}
+
+// CreateClusterClient creates a new cluster client seeded with the endpoint address. Credentials, TLS settings and
+// timeouts are the same as for standalone clients, a non-empty password overrides the configured credentials.
+func CreateClusterClient(endpoint *config.RedisEndpoint, password string) (*redis.ClusterClient, error) {
+	opts, err := parseRedisURL(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if password != "" {
+		opts.Password = password
+		opts.CredentialsProviderContext = nil
+	}
+
+	clusterOpts := &redis.ClusterOptions{
+		Addrs:                      []string{opts.Addr},
+		Username:                   opts.Username,
+		Password:                   opts.Password,
+		CredentialsProviderContext: opts.CredentialsProviderContext,
+		DialTimeout:                opts.DialTimeout,
+		ReadTimeout:                opts.ReadTimeout,
+		WriteTimeout:               opts.WriteTimeout,
+		PoolSize:                   opts.PoolSize,
+		MinIdleConns:               opts.MinIdleConns,
+	}
+	if opts.TLSConfig != nil {
+		// The shards are dialed by their announced addresses, so the seed host is not a valid server name
+		clusterOpts.TLSConfig = NewTLSConfig(endpoint, "")
+		clusterOpts.Dialer = newTLSDialer(endpoint, clusterOpts.DialTimeout)
+	}
+	return instrument(redis.NewClusterClient(clusterOpts), endpoint.URL), nil
+}
+
 // CloseAllClients closes all pooled Redis clients for graceful shutdown.
 func CloseAllClients() {
 	clientPool.Range(func(key, value any) bool {
-		if client, ok := value.(*redis.Client); ok {
+		if client, ok := value.(redis.UniversalClient); ok {
 			_ = client.Close()
 		}
 		clientPool.Delete(key)
@@ -71,10 +164,28 @@ func CloseAllClients() {
 	})
 }
 
+// EvictClients removes all pooled clients and the cached topology of the given URL, so the next GetRedisClient picks
+// up a changed endpoint configuration. The evicted clients are closed after a grace period to let in-flight commands
+// finish.
+func EvictClients(url string) {
+	// Actions get their clients by the redacted URL of their state
+	prefixes := []string{url + "|", config.RedactURL(url) + "|"}
+	clientPool.Range(func(key, value any) bool {
+		if !strings.HasPrefix(key.(string), prefixes[0]) && !strings.HasPrefix(key.(string), prefixes[1]) {
+			return true
+		}
+		clientPool.Delete(key)
+		if client, ok := value.(redis.UniversalClient); ok {
+			time.AfterFunc(evictedClientCloseDelay, func() { _ = client.Close() })
+		}
+		return true
+	})
+	InvalidateTopology(url)
+}
+
 // Deprecated: Use GetRedisClient for pooled clients.
 func CreateRedisClientFromURL(url string, password string, db int) (*redis.Client, error) {
 	endpoint := config.GetEndpointByURL(url)
-
 	var opts *redis.Options
 	var err error
 
@@ -92,13 +203,17 @@ func CreateRedisClientFromURL(url string, password string, db int) (*redis.Clien
 
 	if password != "" {
 		opts.Password = password
+		opts.CredentialsProviderContext = nil
 	}
 	if db >= 0 {
 		opts.DB = db
 	}
 
+	if endpoint != nil && endpoint.IsSentinel() {
+		return instrument(newFailoverClient(endpoint, opts), url), nil
+	}
 	client := redis.NewClient(opts)
-	return client, nil
+	return instrument(client, url), nil
 }
 
 func parseRedisURL(endpoint *config.RedisEndpoint) (*redis.Options, error) {
@@ -113,16 +228,17 @@ func parseRedisURL(endpoint *config.RedisEndpoint) (*redis.Options, error) {
 	if endpoint.Username != "" {
 		opts.Username = endpoint.Username
 	}
+	ApplyCredentialReferences(opts, endpoint)
 	if endpoint.DB > 0 {
 		opts.DB = endpoint.DB
 	}
 
 	if strings.HasPrefix(endpoint.URL, "rediss://") {
-		if opts.TLSConfig == nil {
-			opts.TLSConfig = &tls.Config{}
+		serverName := ""
+		if opts.TLSConfig != nil {
+			serverName = opts.TLSConfig.ServerName
 		}
-		opts.TLSConfig.InsecureSkipVerify = endpoint.InsecureSkipVerify
-		opts.TLSConfig.MinVersion = tls.VersionTLS12
+		opts.TLSConfig = NewTLSConfig(endpoint, serverName)
 	}
 
 	opts.DialTimeout = 3 * time.Second
@@ -134,26 +250,43 @@ func parseRedisURL(endpoint *config.RedisEndpoint) (*redis.Options, error) {
 	return opts, nil
 }
 
+// ApplyCredentialReferences makes the client resolve passwordFile, passwordEnv and usernameFile whenever it opens
+// a new connection. Rotated secrets are used for new connections, established ones stay authenticated.
+func ApplyCredentialReferences(opts *redis.Options, endpoint *config.RedisEndpoint) {
+	if !endpoint.HasCredentialReferences() {
+		return
+	}
+	opts.CredentialsProviderContext = func(ctx context.Context) (string, string, error) {
+		username, password, err := endpoint.ResolveCredentials()
+		if err != nil {
+			log.Warn().Err(err).Str("url", endpoint.URL).Msg("Failed to resolve Redis credentials")
+		}
+		return username, password, err
+	}
+}
+
 // ---------------------------------------------------------------------------
 // Cluster support
 // ---------------------------------------------------------------------------
 
-// DetectClusterMode connects to the endpoint and checks redis_mode.
+// DetectClusterMode reports whether the endpoint is a cluster. Auto-detection uses the cached topology.
 func DetectClusterMode(ctx context.Context, endpoint *config.RedisEndpoint) (bool, error) {
 	if endpoint.ClusterMode == "cluster" {
 		return true, nil
 	}
-	if endpoint.ClusterMode == "standalone" {
+	if endpoint.ClusterMode == "standalone" || endpoint.IsSentinel() {
 		return false, nil
 	}
 
-	// Auto-detect
-	client, err := CreateRedisClient(endpoint)
+	topology, err := GetTopology(ctx, endpoint)
 	if err != nil {
 		return false, err
 	}
-	defer client.Close()
+	return topology.Cluster, nil
+}
 
+// detectClusterMode checks redis_mode of a reachable node.
+func detectClusterMode(ctx context.Context, client *redis.Client) bool {
 	info, err := GetRedisInfo(ctx, client, "server")
 	if err != nil {
 		// If INFO server fails (e.g. miniredis), try CLUSTER INFO as fallback
@@ -161,47 +294,29 @@ func DetectClusterMode(ctx context.Context, endpoint *config.RedisEndpoint) (boo
 		if clusterErr != nil {
 			// Can't determine — assume standalone
 			log.Debug().Err(err).Msg("Cannot auto-detect cluster mode, assuming standalone")
-			return false, nil
+			return false
 		}
-		return strings.Contains(clusterInfo, "cluster_state:ok"), nil
+		return strings.Contains(clusterInfo, "cluster_state:ok")
 	}
-	return info["redis_mode"] == "cluster", nil
+	return info["redis_mode"] == "cluster"
 }
 
-// GetMasterNodes returns ClusterNodeInfo for each master in the cluster.
+// GetMasterNodes returns ClusterNodeInfo for each master in the cluster, taken from the cached topology.
 // For standalone endpoints it returns a single entry for the configured endpoint.
 func GetMasterNodes(ctx context.Context, endpoint *config.RedisEndpoint) ([]ClusterNodeInfo, bool, error) {
-	isCluster, err := DetectClusterMode(ctx, endpoint)
+	topology, err := GetTopology(ctx, endpoint)
 	if err != nil {
 		return nil, false, err
 	}
 
-	if !isCluster {
-		opts, err := parseRedisURL(endpoint)
+	if !topology.Cluster {
+		addr, err := ResolveMasterAddr(ctx, endpoint)
 		if err != nil {
 			return nil, false, err
 		}
-		return []ClusterNodeInfo{{Addr: opts.Addr, Role: "master"}}, false, nil
-	}
-
-	client, err := CreateRedisClient(endpoint)
-	if err != nil {
-		return nil, true, err
+		return []ClusterNodeInfo{{Addr: addr, Role: "master"}}, false, nil
 	}
-	defer client.Close()
-
-	nodes, err := ParseClusterNodes(ctx, client)
-	if err != nil {
-		return nil, true, err
-	}
-
-	var masters []ClusterNodeInfo
-	for _, n := range nodes {
-		if n.Role == "master" {
-			masters = append(masters, n)
-		}
-	}
-	return masters, true, nil
+	return topology.Masters(), true, nil
 }
 
 // ParseClusterNodes runs CLUSTER NODES and parses the output.
@@ -230,11 +345,12 @@ func parseClusterNodesOutput(raw string) []ClusterNodeInfo {
 		addrRaw := parts[1] // e.g. "10.0.0.1:6379@16379" or "10.0.0.1:6379@16379,hostname"
 		flags := parts[2]
 
-		// Strip cport and optional hostname
+		// Strip cport and optional hostname, bracket IPv6 hosts so the address can be dialed
 		addr := addrRaw
 		if idx := strings.Index(addr, "@"); idx != -1 {
 			addr = addr[:idx]
 		}
+		addr = JoinHostPort(SplitHostPort(addr))
 
 		// Determine role from flags
 		role := "slave"
@@ -247,16 +363,49 @@ func parseClusterNodesOutput(raw string) []ClusterNodeInfo {
 			continue
 		}
 
+		masterID := parts[3]
+		if masterID == "-" {
+			masterID = ""
+		}
+
 		nodes = append(nodes, ClusterNodeInfo{
-			ID:    id,
-			Addr:  addr,
-			Role:  role,
-			Flags: flags,
+			ID:          id,
+			Addr:        addr,
+			Role:        role,
+			Flags:       flags,
+			MasterID:    masterID,
+			Slots:       parseSlotRanges(parts[8:]),
+			ConfigEpoch: parts[6],
 		})
 	}
 	return nodes
 }
 
+// parseSlotRanges parses the slot fields of a CLUSTER NODES line, e.g. "0-5460" or "5461". Slots that are
+// being imported or migrated ("[slot->-id]", "[slot-<-id]") are not served yet, or no longer, and are skipped.
+func parseSlotRanges(fields []string) []SlotRange {
+	var ranges []SlotRange
+	for _, field := range fields {
+		if strings.HasPrefix(field, "[") {
+			continue
+		}
+		startRaw, endRaw, isRange := strings.Cut(field, "-")
+		if !isRange {
+			endRaw = startRaw
+		}
+		start, err := strconv.Atoi(startRaw)
+		if err != nil {
+			continue
+		}
+		end, err := strconv.Atoi(endRaw)
+		if err != nil {
+			continue
+		}
+		ranges = append(ranges, SlotRange{Start: start, End: end})
+	}
+	return ranges
+}
+
 // CreateDirectClient creates a standalone client connected directly to a specific address,
 // inheriting credentials and TLS settings from the endpoint.
 func CreateDirectClient(endpoint *config.RedisEndpoint, addr string) (*redis.Client, error) {
@@ -270,43 +419,14 @@ func CreateDirectClient(endpoint *config.RedisEndpoint, addr string) (*redis.Cli
 		WriteTimeout: 3 * time.Second,
 		PoolSize:     10,
 	}
+	ApplyCredentialReferences(opts, endpoint)
 
 	if strings.HasPrefix(endpoint.URL, "rediss://") {
-		opts.TLSConfig = &tls.Config{
-			InsecureSkipVerify: endpoint.InsecureSkipVerify,
-			MinVersion:         tls.VersionTLS12,
-		}
+		host, _ := SplitHostPort(addr)
+		opts.TLSConfig = NewTLSConfig(endpoint, host)
 	}
 
-	return redis.NewClient(opts), nil
-}
-
-// ForEachMaster executes fn on each master node in a cluster.
-// For standalone Redis, fn is called once on the single node.
-// Errors from individual nodes are collected and returned as a combined error.
-func ForEachMaster(ctx context.Context, endpoint *config.RedisEndpoint, fn func(ctx context.Context, client *redis.Client, addr string) error) error {
-	masters, _, err := GetMasterNodes(ctx, endpoint)
-	if err != nil {
-		return err
-	}
-
-	var errs []string
-	for _, node := range masters {
-		nodeClient, err := CreateDirectClient(endpoint, node.Addr)
-		if err != nil {
-			errs = append(errs, fmt.Sprintf("node %s: create client: %v", node.Addr, err))
-			continue
-		}
-		if err := fn(ctx, nodeClient, node.Addr); err != nil {
-			errs = append(errs, fmt.Sprintf("node %s: %v", node.Addr, err))
-		}
-		nodeClient.Close()
-	}
-
-	if len(errs) > 0 {
-		return fmt.Errorf("errors on %d/%d nodes: %s", len(errs), len(masters), strings.Join(errs, "; "))
-	}
-	return nil
+	return instrument(redis.NewClient(opts), endpoint.URL), nil
 }
 
 // ScanAllKeys scans keys matching pattern across all master nodes in a cluster,
@@ -316,7 +436,7 @@ func ScanAllKeys(ctx context.Context, endpoint *config.RedisEndpoint, pattern st
 	seen := make(map[string]struct{})
 	var allKeys []string
 
-	err := ForEachMaster(ctx, endpoint, func(ctx context.Context, client *redis.Client, addr string) error {
+	_, err := ForEachMaster(ctx, endpoint, func(ctx context.Context, client *redis.Client, addr string) error {
 		var cursor uint64
 		for {
 			keys, nextCursor, err := client.Scan(ctx, cursor, pattern, 100).Result()
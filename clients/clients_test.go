@@ -7,10 +7,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/alicebob/miniredis/v2"
 	"github.com/go-redis/redismock/v9"
+	"github.com/redis/go-redis/v9"
 	"github.com/steadybit/extension-redis/config"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -154,6 +158,108 @@ func TestCreateRedisClientFromURL_WithOverride(t *testing.T) {
 	defer client.Close()
 }
 
+func TestEvictClients_RemovesOnlyClientsOfURL(t *testing.T) {
+	// Given
+	config.Config.Endpoints = []config.RedisEndpoint{}
+	origDelay := evictedClientCloseDelay
+	evictedClientCloseDelay = 0
+	defer func() { evictedClientCloseDelay = origDelay }()
+
+	evicted, err := GetRedisClient("redis://evict.local:6379", "", 0)
+	require.NoError(t, err)
+	evictedOtherDB, err := GetRedisClient("redis://evict.local:6379", "", 1)
+	require.NoError(t, err)
+	kept, err := GetRedisClient("redis://evict.local:63790", "", 0)
+	require.NoError(t, err)
+
+	// When
+	EvictClients("redis://evict.local:6379")
+
+	// Then
+	recreated, err := GetRedisClient("redis://evict.local:6379", "", 0)
+	require.NoError(t, err)
+	assert.NotSame(t, evicted, recreated)
+	again, err := GetRedisClient("redis://evict.local:63790", "", 0)
+	require.NoError(t, err)
+	assert.Same(t, kept, again)
+	assert.Eventually(t, func() bool {
+		return evictedOtherDB.Ping(context.Background()).Err() == redis.ErrClosed
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestEvictClients_RedactedURL(t *testing.T) {
+	// Given - actions get their clients by the redacted URL of their state
+	config.Config.Endpoints = []config.RedisEndpoint{{URL: "redis://:s3cret@evict-redacted.local:6379"}}
+	defer func() { config.Config.Endpoints = []config.RedisEndpoint{} }()
+	origDelay := evictedClientCloseDelay
+	evictedClientCloseDelay = 0
+	defer func() { evictedClientCloseDelay = origDelay }()
+
+	pooled, err := GetRedisClient("redis://evict-redacted.local:6379", "", 0)
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret", pooled.Options().Password)
+
+	// When
+	EvictClients("redis://:s3cret@evict-redacted.local:6379")
+
+	// Then
+	recreated, err := GetRedisClient("redis://evict-redacted.local:6379", "", 0)
+	require.NoError(t, err)
+	assert.NotSame(t, pooled, recreated)
+}
+
+func TestGetRedisClient_PasswordFileRotation(t *testing.T) {
+	// Given
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	mr.RequireAuth("first")
+
+	passwordFile := filepath.Join(t.TempDir(), "password")
+	require.NoError(t, os.WriteFile(passwordFile, []byte("first\n"), 0o600))
+	url := fmt.Sprintf("redis://%s", mr.Addr())
+	origEndpoints := config.Config.Endpoints
+	defer func() { config.Config.Endpoints = origEndpoints }()
+	config.Config.Endpoints = []config.RedisEndpoint{{URL: url, PasswordFile: passwordFile}}
+	defer EvictClients(url)
+
+	client, err := GetRedisClient(url, "", 0)
+	require.NoError(t, err)
+	require.NoError(t, client.Ping(context.Background()).Err())
+
+	// When - the secret is rotated and the server drops existing connections
+	mr.RequireAuth("second")
+	require.NoError(t, os.WriteFile(passwordFile, []byte("second\n"), 0o600))
+	mr.Close()
+	require.NoError(t, mr.Restart())
+
+	// Then - the pooled client reconnects with the rotated password
+	pooled, err := GetRedisClient(url, "", 0)
+	require.NoError(t, err)
+	assert.Same(t, client, pooled)
+	assert.NoError(t, pooled.Ping(context.Background()).Err())
+}
+
+func TestCreateDirectClient_UsesCredentialReferences(t *testing.T) {
+	// Given
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	mr.RequireUserAuth("alice", "from-env")
+	t.Setenv("TEST_DIRECT_CLIENT_PASSWORD", "from-env")
+	usernameFile := filepath.Join(t.TempDir(), "username")
+	require.NoError(t, os.WriteFile(usernameFile, []byte("alice"), 0o600))
+	endpoint := &config.RedisEndpoint{URL: "redis://seed:6379", UsernameFile: usernameFile, PasswordEnv: "TEST_DIRECT_CLIENT_PASSWORD"}
+
+	// When
+	client, err := CreateDirectClient(endpoint, mr.Addr())
+	require.NoError(t, err)
+	defer client.Close()
+
+	// Then
+	assert.NoError(t, client.Ping(context.Background()).Err())
+}
+
 func TestParseRedisURL_TLSConfig(t *testing.T) {
 	// Given
 	endpoint := &config.RedisEndpoint{
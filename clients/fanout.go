@@ -0,0 +1,235 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package clients
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+	"github.com/steadybit/extension-redis/config"
+)
+
+const (
+	defaultFanOutConcurrency = 8
+	defaultNodeTimeout       = 10 * time.Second
+)
+
+// NodeFunc is run on a single master node with a client connected directly to addr.
+type NodeFunc func(ctx context.Context, client *redis.Client, addr string) error
+
+// PartialFailurePolicy decides what happens when a cluster-wide change fails on some of the nodes.
+type PartialFailurePolicy string
+
+const (
+	// PartialFailureAbort skips nodes that were not started yet, rolls back the nodes that succeeded and fails.
+	PartialFailureAbort PartialFailurePolicy = "abort"
+	// PartialFailureContinue runs on all nodes and keeps the change on the nodes that succeeded.
+	PartialFailureContinue PartialFailurePolicy = "continue"
+)
+
+// FanOutOptions controls how ForEachMasterWithOptions runs on the master nodes. Zero values use the defaults.
+type FanOutOptions struct {
+	// Concurrency is the maximum number of nodes processed at once
+	Concurrency int
+	// NodeTimeout bounds the time fn may take on a single node
+	NodeTimeout time.Duration
+	// Policy defaults to PartialFailureContinue
+	Policy PartialFailurePolicy
+	// Rollback undoes fn on a node, it is called for the succeeded nodes when PartialFailureAbort kicks in
+	Rollback NodeFunc
+}
+
+// NodeResult is the outcome of a fan-out on a single master node.
+type NodeResult struct {
+	Addr     string
+	NodeID   string
+	Err      error
+	Duration time.Duration
+	// Skipped is set for nodes that were not attempted because the fan-out was aborted
+	Skipped     bool
+	RolledBack  bool
+	RollbackErr error
+}
+
+// FanOutResult holds the per-node results in topology order.
+type FanOutResult struct {
+	Nodes []NodeResult
+}
+
+// Succeeded returns the nodes on which fn completed without error.
+func (r *FanOutResult) Succeeded() []NodeResult {
+	var nodes []NodeResult
+	for _, n := range r.Nodes {
+		if n.Err == nil {
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes
+}
+
+// Failed returns the nodes on which fn failed or that were skipped.
+func (r *FanOutResult) Failed() []NodeResult {
+	var nodes []NodeResult
+	for _, n := range r.Nodes {
+		if n.Err != nil {
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes
+}
+
+// Err combines the errors of all failed nodes, or returns nil if every node succeeded.
+func (r *FanOutResult) Err() error {
+	failed := r.Failed()
+	if len(failed) == 0 {
+		return nil
+	}
+	errs := make([]error, 0, len(failed))
+	for _, n := range failed {
+		errs = append(errs, fmt.Errorf("node %s: %w", n.Addr, n.Err))
+	}
+	return fmt.Errorf("errors on %d/%d nodes: %w", len(failed), len(r.Nodes), errors.Join(errs...))
+}
+
+// RollbackErr combines the errors of nodes that could not be rolled back.
+func (r *FanOutResult) RollbackErr() error {
+	var errs []error
+	for _, n := range r.Nodes {
+		if n.RollbackErr != nil {
+			errs = append(errs, fmt.Errorf("node %s: %w", n.Addr, n.RollbackErr))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ForEachMaster executes fn on each master node in a cluster with the default options.
+// For standalone Redis, fn is called once on the single node.
+func ForEachMaster(ctx context.Context, endpoint *config.RedisEndpoint, fn NodeFunc) (*FanOutResult, error) {
+	return ForEachMasterWithOptions(ctx, endpoint, fn, FanOutOptions{})
+}
+
+// ForEachMasterWithOptions executes fn on the master nodes in parallel. The result is nil only if the topology
+// could not be fetched. The error also reports failed nodes, details are in the per-node results.
+func ForEachMasterWithOptions(ctx context.Context, endpoint *config.RedisEndpoint, fn NodeFunc, opts FanOutOptions) (*FanOutResult, error) {
+	masters, _, err := GetMasterNodes(ctx, endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	result := runOnNodes(ctx, endpoint, masters, fn, opts.withDefaults())
+	return result, result.Err()
+}
+
+// ForEachNode executes fn on every node of a cluster, masters and replicas, with the default options.
+// For standalone Redis, fn is called once on the single node.
+func ForEachNode(ctx context.Context, endpoint *config.RedisEndpoint, fn NodeFunc) (*FanOutResult, error) {
+	topology, err := GetTopology(ctx, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if !topology.Cluster {
+		return ForEachMaster(ctx, endpoint, fn)
+	}
+
+	result := runOnNodes(ctx, endpoint, topology.Nodes, fn, FanOutOptions{}.withDefaults())
+	return result, result.Err()
+}
+
+func (o FanOutOptions) withDefaults() FanOutOptions {
+	if o.Concurrency <= 0 {
+		o.Concurrency = config.Config.ClusterFanOutConcurrency
+	}
+	if o.Concurrency <= 0 {
+		o.Concurrency = defaultFanOutConcurrency
+	}
+	if o.NodeTimeout <= 0 {
+		o.NodeTimeout = time.Duration(config.Config.ClusterNodeTimeoutSeconds) * time.Second
+	}
+	if o.NodeTimeout <= 0 {
+		o.NodeTimeout = defaultNodeTimeout
+	}
+	if o.Policy == "" {
+		o.Policy = PartialFailureContinue
+	}
+	return o
+}
+
+func runOnNodes(ctx context.Context, endpoint *config.RedisEndpoint, nodes []ClusterNodeInfo, fn NodeFunc, opts FanOutOptions) *FanOutResult {
+	result := &FanOutResult{Nodes: make([]NodeResult, len(nodes))}
+	abortCtx, abort := context.WithCancel(ctx)
+	defer abort()
+
+	sem := make(chan struct{}, opts.Concurrency)
+	var wg sync.WaitGroup
+	for i, node := range nodes {
+		nodeResult := &result.Nodes[i]
+		nodeResult.Addr = node.Addr
+		nodeResult.NodeID = node.ID
+		wg.Go(func() {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			// Nodes already in flight finish, so they are either changed completely or failed and can be rolled back
+			if abortCtx.Err() != nil {
+				nodeResult.Skipped = true
+				nodeResult.Err = errors.New("skipped after a failure on another node")
+				return
+			}
+			start := time.Now()
+			nodeResult.Err = runOnNode(ctx, endpoint, node.Addr, fn, opts.NodeTimeout)
+			nodeResult.Duration = time.Since(start)
+			InvalidateTopologyOnError(endpoint.URL, nodeResult.Err)
+			if nodeResult.Err != nil && opts.Policy == PartialFailureAbort {
+				abort()
+			}
+		})
+	}
+	wg.Wait()
+
+	if opts.Policy == PartialFailureAbort && opts.Rollback != nil && len(result.Failed()) > 0 {
+		rollbackSucceeded(ctx, endpoint, result, opts)
+	}
+	return result
+}
+
+// rollbackSucceeded undoes fn on all nodes that succeeded. It must not be aborted, every changed node is attempted.
+func rollbackSucceeded(ctx context.Context, endpoint *config.RedisEndpoint, result *FanOutResult, opts FanOutOptions) {
+	sem := make(chan struct{}, opts.Concurrency)
+	var wg sync.WaitGroup
+	for i := range result.Nodes {
+		nodeResult := &result.Nodes[i]
+		if nodeResult.Err != nil {
+			continue
+		}
+		wg.Go(func() {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			nodeResult.RollbackErr = runOnNode(ctx, endpoint, nodeResult.Addr, opts.Rollback, opts.NodeTimeout)
+			nodeResult.RolledBack = nodeResult.RollbackErr == nil
+			if nodeResult.RollbackErr != nil {
+				log.Error().Err(nodeResult.RollbackErr).Str("addr", nodeResult.Addr).Msg("Failed to roll back node after partial failure")
+			}
+		})
+	}
+	wg.Wait()
+}
+
+func runOnNode(ctx context.Context, endpoint *config.RedisEndpoint, addr string, fn NodeFunc, timeout time.Duration) error {
+	nodeClient, err := CreateDirectClient(endpoint, addr)
+	if err != nil {
+		return fmt.Errorf("create client: %w", err)
+	}
+	defer nodeClient.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return fn(ctx, nodeClient, addr)
+}
@@ -0,0 +1,184 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2024 Steadybit GmbH
+
+package clients
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/steadybit/extension-redis/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func runFanOutNodes(t *testing.T, count int) ([]*miniredis.Miniredis, []ClusterNodeInfo) {
+	t.Helper()
+	var servers []*miniredis.Miniredis
+	var nodes []ClusterNodeInfo
+	for range count {
+		mr := miniredis.RunT(t)
+		servers = append(servers, mr)
+		nodes = append(nodes, ClusterNodeInfo{Addr: mr.Addr(), Role: "master"})
+	}
+	return servers, nodes
+}
+
+func setMarker(ctx context.Context, client *redis.Client, addr string) error {
+	return client.Set(ctx, "marker", "1", 0).Err()
+}
+
+func TestRunOnNodes_BoundsConcurrency(t *testing.T) {
+	// Given
+	_, nodes := runFanOutNodes(t, 6)
+	endpoint := &config.RedisEndpoint{URL: "redis://seed:6379"}
+	var active, maxActive atomic.Int32
+
+	// When
+	result := runOnNodes(context.Background(), endpoint, nodes, func(ctx context.Context, client *redis.Client, addr string) error {
+		n := active.Add(1)
+		defer active.Add(-1)
+		for {
+			m := maxActive.Load()
+			if n <= m || maxActive.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(50 * time.Millisecond)
+		return PingRedis(ctx, client)
+	}, FanOutOptions{Concurrency: 2}.withDefaults())
+
+	// Then
+	require.NoError(t, result.Err())
+	assert.Len(t, result.Succeeded(), 6)
+	assert.Equal(t, int32(2), maxActive.Load())
+}
+
+func TestRunOnNodes_NodeTimeout(t *testing.T) {
+	// Given
+	_, nodes := runFanOutNodes(t, 2)
+	endpoint := &config.RedisEndpoint{URL: "redis://seed:6379"}
+
+	// When
+	result := runOnNodes(context.Background(), endpoint, nodes, func(ctx context.Context, client *redis.Client, addr string) error {
+		if addr == nodes[0].Addr {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		return nil
+	}, FanOutOptions{NodeTimeout: 50 * time.Millisecond}.withDefaults())
+
+	// Then
+	require.Len(t, result.Failed(), 1)
+	assert.Equal(t, nodes[0].Addr, result.Failed()[0].Addr)
+	assert.ErrorIs(t, result.Err(), context.DeadlineExceeded)
+	assert.Less(t, result.Nodes[0].Duration, time.Second)
+	assert.NoError(t, result.Nodes[1].Err)
+}
+
+func TestRunOnNodes_ContinueKeepsSucceededNodes(t *testing.T) {
+	// Given - the second node is down
+	servers, nodes := runFanOutNodes(t, 3)
+	servers[1].Close()
+	endpoint := &config.RedisEndpoint{URL: "redis://seed:6379"}
+
+	// When
+	result := runOnNodes(context.Background(), endpoint, nodes, setMarker, FanOutOptions{
+		Policy: PartialFailureContinue,
+		Rollback: func(ctx context.Context, client *redis.Client, addr string) error {
+			return client.Del(ctx, "marker").Err()
+		},
+	}.withDefaults())
+
+	// Then
+	require.Error(t, result.Err())
+	assert.Contains(t, result.Err().Error(), "errors on 1/3 nodes")
+	assert.Contains(t, result.Err().Error(), nodes[1].Addr)
+	assert.Len(t, result.Succeeded(), 2)
+	servers[0].CheckGet(t, "marker", "1")
+	servers[2].CheckGet(t, "marker", "1")
+	for _, n := range result.Nodes {
+		assert.False(t, n.RolledBack)
+	}
+}
+
+func TestRunOnNodes_AbortRollsBackSucceededNodes(t *testing.T) {
+	// Given - the second node is down
+	servers, nodes := runFanOutNodes(t, 4)
+	servers[1].Close()
+	endpoint := &config.RedisEndpoint{URL: "redis://seed:6379"}
+
+	// When
+	result := runOnNodes(context.Background(), endpoint, nodes, setMarker, FanOutOptions{
+		Policy: PartialFailureAbort,
+		Rollback: func(ctx context.Context, client *redis.Client, addr string) error {
+			return client.Del(ctx, "marker").Err()
+		},
+	}.withDefaults())
+
+	// Then
+	require.Error(t, result.Err())
+	require.NoError(t, result.RollbackErr())
+	for i, n := range result.Nodes {
+		if i == 1 {
+			assert.Error(t, n.Err)
+			continue
+		}
+		assert.True(t, n.RolledBack || n.Skipped, "node %s was neither rolled back nor skipped", n.Addr)
+		assert.False(t, servers[i].Exists("marker"))
+	}
+}
+
+func TestRunOnNodes_AbortSkipsPendingNodes(t *testing.T) {
+	// Given - every node fails, only one runs at a time
+	_, nodes := runFanOutNodes(t, 4)
+	endpoint := &config.RedisEndpoint{URL: "redis://seed:6379"}
+	var calls atomic.Int32
+
+	// When
+	result := runOnNodes(context.Background(), endpoint, nodes, func(ctx context.Context, client *redis.Client, addr string) error {
+		calls.Add(1)
+		return errors.New("boom")
+	}, FanOutOptions{Concurrency: 1, Policy: PartialFailureAbort}.withDefaults())
+
+	// Then
+	assert.Equal(t, int32(1), calls.Load())
+	skipped := 0
+	for _, n := range result.Nodes {
+		if n.Skipped {
+			skipped++
+		}
+	}
+	assert.Equal(t, 3, skipped)
+	assert.Len(t, result.Failed(), 4)
+}
+
+func TestFanOutOptions_Defaults(t *testing.T) {
+	// Given
+	origConfig := config.Config
+	defer func() { config.Config = origConfig }()
+	config.Config.ClusterFanOutConcurrency = 0
+	config.Config.ClusterNodeTimeoutSeconds = 0
+
+	// When
+	opts := FanOutOptions{}.withDefaults()
+
+	// Then
+	assert.Equal(t, defaultFanOutConcurrency, opts.Concurrency)
+	assert.Equal(t, defaultNodeTimeout, opts.NodeTimeout)
+	assert.Equal(t, PartialFailureContinue, opts.Policy)
+
+	// When - configured values
+	config.Config.ClusterFanOutConcurrency = 3
+	config.Config.ClusterNodeTimeoutSeconds = 2
+	opts = FanOutOptions{}.withDefaults()
+
+	// Then
+	assert.Equal(t, 3, opts.Concurrency)
+	assert.Equal(t, 2*time.Second, opts.NodeTimeout)
+}
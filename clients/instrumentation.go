@@ -0,0 +1,30 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package clients
+
+import (
+	"github.com/redis/go-redis/extra/redisotel/v9"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+	"github.com/steadybit/extension-redis/config"
+	"github.com/steadybit/extension-redis/tracing"
+)
+
+// instrument adds the command error hook to a new client and, with tracing enabled, a span for every command. The
+// spans are children of the span in the context of the command.
+func instrument[C redis.UniversalClient](client C, url string) C {
+	endpoint := config.RedactURL(url)
+	client.AddHook(commandErrorHook{endpoint: endpoint})
+	if tracing.Enabled() {
+		// Commands carry values and AUTH arguments, spans only name the command
+		err := redisotel.InstrumentTracing(client,
+			redisotel.WithDBStatement(false),
+			redisotel.WithAttributes(tracing.AttrRedisURL.String(endpoint)))
+		if err != nil {
+			log.Warn().Err(err).Str("url", endpoint).Msg("Failed to add tracing to Redis client")
+		}
+	}
+	return client
+}
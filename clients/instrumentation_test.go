@@ -0,0 +1,80 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2024 Steadybit GmbH
+
+package clients
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/steadybit/extension-redis/config"
+	"github.com/steadybit/extension-redis/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func useSpanRecorder(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://localhost:4318")
+	recorder := tracetest.NewSpanRecorder()
+	orig := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(orig) })
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	return recorder
+}
+
+func TestInstrument_CommandSpansAreChildren(t *testing.T) {
+	// Given
+	recorder := useSpanRecorder(t)
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	mr.RequireAuth("secret")
+	endpoint := &config.RedisEndpoint{URL: fmt.Sprintf("redis://:secret@%s", mr.Addr())}
+	client, err := CreateRedisClient(endpoint)
+	require.NoError(t, err)
+	defer client.Close()
+
+	// When
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "start")
+	require.NoError(t, client.Set(ctx, "traced-key", "sensitive-value", 0).Err())
+	parent.End()
+
+	// Then
+	var set sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.Name() == "set" {
+			set = span
+		}
+	}
+	require.NotNil(t, set)
+	assert.Equal(t, parent.SpanContext().SpanID(), set.Parent().SpanID())
+	for _, attr := range set.Attributes() {
+		assert.NotContains(t, attr.Value.Emit(), "sensitive-value")
+		assert.NotContains(t, attr.Value.Emit(), "secret")
+	}
+	assert.Contains(t, set.Attributes(), tracing.AttrRedisURL.String(fmt.Sprintf("redis://%s", mr.Addr())))
+}
+
+func TestInstrument_NoSpansWithoutTracing(t *testing.T) {
+	// Given
+	recorder := useSpanRecorder(t)
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	client, err := CreateRedisClient(&config.RedisEndpoint{URL: fmt.Sprintf("redis://%s", mr.Addr())})
+	require.NoError(t, err)
+	defer client.Close()
+
+	// When
+	require.NoError(t, client.Ping(context.Background()).Err())
+
+	// Then
+	assert.Empty(t, recorder.Ended())
+}
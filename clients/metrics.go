@@ -0,0 +1,109 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package clients
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
+	"github.com/steadybit/extension-redis/config"
+	"github.com/steadybit/extension-redis/metrics"
+)
+
+// commandErrorHook counts failed commands of a client in metrics.CommandErrors.
+type commandErrorHook struct {
+	endpoint string
+}
+
+func (h commandErrorHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := next(ctx, network, addr)
+		if err != nil {
+			h.count("dial", err)
+		}
+		return conn, err
+	}
+}
+
+func (h commandErrorHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		// The error is set on cmd only after the hooks returned
+		err := next(ctx, cmd)
+		h.count(cmd.Name(), err)
+		return err
+	}
+}
+
+func (h commandErrorHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		err := next(ctx, cmds)
+		for _, cmd := range cmds {
+			h.count(cmd.Name(), cmd.Err())
+		}
+		return err
+	}
+}
+
+func (h commandErrorHook) count(command string, err error) {
+	if err == nil || errors.Is(err, redis.Nil) {
+		return
+	}
+	metrics.CommandErrors.WithLabelValues(h.endpoint, command).Inc()
+}
+
+var poolConnectionsDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(metrics.Namespace, "client_pool", "connections"),
+	"Number of connections of a pooled client.",
+	[]string{"client", "state"}, nil)
+
+// poolCollector reports the connection pools of the clients in clientPool when metrics are scraped.
+type poolCollector struct{}
+
+// NewPoolCollector returns a collector of the connection pool sizes of the pooled clients. Clients are labeled by
+// their pool key without the password.
+func NewPoolCollector() prometheus.Collector {
+	return poolCollector{}
+}
+
+func (poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- poolConnectionsDesc
+}
+
+func (poolCollector) Collect(ch chan<- prometheus.Metric) {
+	// Keys that only differ in the password share a label, their pools are summed
+	stats := make(map[string]redis.PoolStats)
+	clientPool.Range(func(key, value any) bool {
+		client, ok := value.(interface{ PoolStats() *redis.PoolStats })
+		if !ok {
+			return true
+		}
+		label := poolKeyLabel(key.(string))
+		s := client.PoolStats()
+		sum := stats[label]
+		sum.TotalConns += s.TotalConns
+		sum.IdleConns += s.IdleConns
+		stats[label] = sum
+		return true
+	})
+	for label, s := range stats {
+		active := max(int64(s.TotalConns)-int64(s.IdleConns), 0)
+		ch <- prometheus.MustNewConstMetric(poolConnectionsDesc, prometheus.GaugeValue, float64(active), label, "active")
+		ch <- prometheus.MustNewConstMetric(poolConnectionsDesc, prometheus.GaugeValue, float64(s.IdleConns), label, "idle")
+	}
+}
+
+// poolKeyLabel turns a "url|password|db" pool key into "url|db" with the password removed from the URL as well.
+func poolKeyLabel(key string) string {
+	url, rest, _ := strings.Cut(key, "|")
+	suffix := rest
+	if i := strings.LastIndex(rest, "|"); i >= 0 {
+		suffix = rest[i+1:]
+	}
+	return config.RedactURL(url) + "|" + suffix
+}
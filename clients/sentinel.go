@@ -0,0 +1,111 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package clients
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/steadybit/extension-redis/config"
+)
+
+// newFailoverClient creates a client for a Sentinel endpoint. It asks the sentinels for the current master and
+// reconnects to the new master after a failover, so pooled clients keep working across failovers.
+// Credentials, db and timeouts are taken from opts, the options parsed from the endpoint URL.
+func newFailoverClient(endpoint *config.RedisEndpoint, opts *redis.Options) *redis.Client {
+	failoverOpts := &redis.FailoverOptions{
+		MasterName:                 endpoint.Sentinel.MasterName,
+		SentinelAddrs:              endpoint.Sentinel.Addresses,
+		SentinelUsername:           endpoint.Sentinel.Username,
+		SentinelPassword:           endpoint.Sentinel.Password,
+		Username:                   opts.Username,
+		Password:                   opts.Password,
+		CredentialsProviderContext: opts.CredentialsProviderContext,
+		DB:                         opts.DB,
+		DialTimeout:                opts.DialTimeout,
+		ReadTimeout:                opts.ReadTimeout,
+		WriteTimeout:               opts.WriteTimeout,
+		PoolSize:                   opts.PoolSize,
+		MinIdleConns:               opts.MinIdleConns,
+	}
+	// Sentinels and data nodes are dialed by address, the server name is derived from it unless overridden
+	failoverOpts.TLSConfig = sentinelTLSConfig(endpoint, "")
+	if failoverOpts.TLSConfig != nil {
+		failoverOpts.Dialer = newTLSDialer(endpoint, failoverOpts.DialTimeout)
+	}
+	return redis.NewFailoverClient(failoverOpts)
+}
+
+// ResolveMasterAddr returns the address of the node an endpoint currently points to. For Sentinel endpoints the
+// sentinels are asked for the current master, which changes on failover.
+func ResolveMasterAddr(ctx context.Context, endpoint *config.RedisEndpoint) (string, error) {
+	if !endpoint.IsSentinel() {
+		opts, err := parseRedisURL(endpoint)
+		if err != nil {
+			return "", err
+		}
+		return opts.Addr, nil
+	}
+
+	var errs []error
+	for _, addr := range endpoint.Sentinel.Addresses {
+		sentinel := newSentinelClient(endpoint, addr)
+		master, err := sentinel.GetMasterAddrByName(ctx, endpoint.Sentinel.MasterName).Result()
+		_ = sentinel.Close()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("sentinel %s: %w", addr, err))
+			continue
+		}
+		if len(master) != 2 {
+			errs = append(errs, fmt.Errorf("sentinel %s: unexpected reply %v", addr, master))
+			continue
+		}
+		return JoinHostPort(master[0], master[1]), nil
+	}
+	return "", fmt.Errorf("failed to resolve master '%s' from sentinels: %w", endpoint.Sentinel.MasterName, errors.Join(errs...))
+}
+
+// CountSentinels returns the number of sentinels monitoring the master of a Sentinel endpoint, as known to the
+// first sentinel that answers.
+func CountSentinels(ctx context.Context, endpoint *config.RedisEndpoint) (int, error) {
+	var errs []error
+	for _, addr := range endpoint.Sentinel.Addresses {
+		sentinel := newSentinelClient(endpoint, addr)
+		others, err := sentinel.Sentinels(ctx, endpoint.Sentinel.MasterName).Result()
+		_ = sentinel.Close()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("sentinel %s: %w", addr, err))
+			continue
+		}
+		return len(others) + 1, nil
+	}
+	return 0, fmt.Errorf("failed to count sentinels of master '%s': %w", endpoint.Sentinel.MasterName, errors.Join(errs...))
+}
+
+func newSentinelClient(endpoint *config.RedisEndpoint, addr string) *redis.SentinelClient {
+	host, _ := SplitHostPort(addr)
+	return redis.NewSentinelClient(&redis.Options{
+		Addr:        addr,
+		Username:    endpoint.Sentinel.Username,
+		Password:    endpoint.Sentinel.Password,
+		DialTimeout: 3 * time.Second,
+		ReadTimeout: 3 * time.Second,
+		TLSConfig:   sentinelTLSConfig(endpoint, host),
+		// The next sentinel is tried instead
+		MaxRetries: -1,
+	})
+}
+
+func sentinelTLSConfig(endpoint *config.RedisEndpoint, serverName string) *tls.Config {
+	if !strings.HasPrefix(endpoint.URL, "rediss://") {
+		return nil
+	}
+	return NewTLSConfig(endpoint, serverName)
+}
@@ -0,0 +1,155 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2024 Steadybit GmbH
+
+package clients
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/alicebob/miniredis/v2/server"
+	"github.com/steadybit/extension-redis/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runStubSentinel starts a miniredis answering SENTINEL GET-MASTER-ADDR-BY-NAME for masterName with the address in master.
+// Other SENTINEL subcommands reply with an empty list.
+func runStubSentinel(t *testing.T, masterName string, master *atomic.Value) *miniredis.Miniredis {
+	t.Helper()
+	sentinel := miniredis.RunT(t)
+	require.NoError(t, sentinel.Server().Register("SENTINEL", func(c *server.Peer, cmd string, args []string) {
+		if len(args) == 2 && strings.EqualFold(args[0], "get-master-addr-by-name") {
+			if args[1] != masterName {
+				c.WriteNull()
+				return
+			}
+			host, port, _ := strings.Cut(master.Load().(string), ":")
+			c.WriteLen(2)
+			c.WriteBulk(host)
+			c.WriteBulk(port)
+			return
+		}
+		c.WriteLen(0)
+	}))
+	return sentinel
+}
+
+func TestResolveMasterAddr_Standalone(t *testing.T) {
+	addr, err := ResolveMasterAddr(context.Background(), &config.RedisEndpoint{URL: "redis://redis.local:6380"})
+
+	require.NoError(t, err)
+	assert.Equal(t, "redis.local:6380", addr)
+}
+
+func TestResolveMasterAddr_FollowsFailover(t *testing.T) {
+	// Given
+	var master atomic.Value
+	master.Store("10.0.0.1:6379")
+	sentinel := runStubSentinel(t, "mymaster", &master)
+	endpoint := &config.RedisEndpoint{
+		URL:      "redis://mymaster",
+		Sentinel: &config.SentinelConfig{MasterName: "mymaster", Addresses: []string{"127.0.0.1:1", sentinel.Addr()}},
+	}
+
+	// When
+	before, err := ResolveMasterAddr(context.Background(), endpoint)
+	require.NoError(t, err)
+	master.Store("10.0.0.2:6379")
+	after, err := ResolveMasterAddr(context.Background(), endpoint)
+
+	// Then - the unreachable first sentinel is skipped
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.1:6379", before)
+	assert.Equal(t, "10.0.0.2:6379", after)
+}
+
+func TestResolveMasterAddr_UnknownMaster(t *testing.T) {
+	// Given
+	var master atomic.Value
+	master.Store("10.0.0.1:6379")
+	sentinel := runStubSentinel(t, "mymaster", &master)
+	endpoint := &config.RedisEndpoint{
+		URL:      "redis://other",
+		Sentinel: &config.SentinelConfig{MasterName: "other", Addresses: []string{sentinel.Addr()}},
+	}
+
+	// When
+	_, err := ResolveMasterAddr(context.Background(), endpoint)
+
+	// Then
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to resolve master 'other'")
+}
+
+func TestCountSentinels(t *testing.T) {
+	// Given - the stub knows no other sentinels
+	var master atomic.Value
+	master.Store("10.0.0.1:6379")
+	sentinel := runStubSentinel(t, "mymaster", &master)
+	endpoint := &config.RedisEndpoint{
+		URL:      "redis://mymaster",
+		Sentinel: &config.SentinelConfig{MasterName: "mymaster", Addresses: []string{"127.0.0.1:1", sentinel.Addr()}},
+	}
+
+	// When
+	count, err := CountSentinels(context.Background(), endpoint)
+
+	// Then - the unreachable first sentinel is skipped
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func TestCountSentinels_Unreachable(t *testing.T) {
+	endpoint := &config.RedisEndpoint{
+		URL:      "redis://mymaster",
+		Sentinel: &config.SentinelConfig{MasterName: "mymaster", Addresses: []string{"127.0.0.1:1"}},
+	}
+
+	_, err := CountSentinels(context.Background(), endpoint)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "mymaster")
+}
+
+func TestGetRedisClient_Sentinel(t *testing.T) {
+	// Given
+	data := miniredis.RunT(t)
+	data.RequireAuth("secret")
+	data.Select(2)
+	data.Set("key", "from-master")
+	var master atomic.Value
+	master.Store(data.Addr())
+	sentinel := runStubSentinel(t, "mymaster", &master)
+
+	url := "redis://:secret@mymaster/2"
+	origEndpoints := config.Config.Endpoints
+	defer func() { config.Config.Endpoints = origEndpoints }()
+	config.Config.Endpoints = []config.RedisEndpoint{{
+		URL:      url,
+		Sentinel: &config.SentinelConfig{MasterName: "mymaster", Addresses: []string{sentinel.Addr()}},
+	}}
+	defer EvictClients(url)
+
+	// When
+	client, err := GetRedisClient(url, "", 2)
+	require.NoError(t, err)
+	value, err := client.Get(context.Background(), "key").Result()
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, "from-master", value)
+}
+
+func TestDetectClusterMode_SentinelIsStandalone(t *testing.T) {
+	isCluster, err := DetectClusterMode(context.Background(), &config.RedisEndpoint{
+		URL:      "redis://mymaster",
+		Sentinel: &config.SentinelConfig{MasterName: "mymaster", Addresses: []string{"127.0.0.1:1"}},
+	})
+
+	require.NoError(t, err)
+	assert.False(t, isCluster)
+}
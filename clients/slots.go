@@ -0,0 +1,179 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package clients
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ClusterSlotCount is the number of hash slots of a Redis Cluster.
+const ClusterSlotCount = 16384
+
+// SlotState is a subcommand of CLUSTER SETSLOT.
+type SlotState string
+
+const (
+	SlotImporting SlotState = "IMPORTING"
+	SlotMigrating SlotState = "MIGRATING"
+	SlotNode      SlotState = "NODE"
+	SlotStable    SlotState = "STABLE"
+)
+
+// ClusterSetSlot runs CLUSTER SETSLOT on a single node. nodeID is ignored for SlotStable.
+func ClusterSetSlot(ctx context.Context, client *redis.Client, slot int, state SlotState, nodeID string) error {
+	args := []any{"CLUSTER", "SETSLOT", slot, string(state)}
+	if state != SlotStable {
+		args = append(args, nodeID)
+	}
+	if err := client.Do(ctx, args...).Err(); err != nil {
+		return fmt.Errorf("CLUSTER SETSLOT %d %s: %w", slot, state, err)
+	}
+	return nil
+}
+
+// MigrateKeys moves keys from the node of client to destAddr with a single MIGRATE. Existing keys on the
+// destination are not replaced. The destination is authenticated with the credentials of client.
+func MigrateKeys(ctx context.Context, client *redis.Client, destAddr string, keys []string, timeout time.Duration) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	host, port, err := net.SplitHostPort(destAddr)
+	if err != nil {
+		return fmt.Errorf("invalid destination address %s: %w", destAddr, err)
+	}
+
+	args := []any{"MIGRATE", host, port, "", 0, timeout.Milliseconds()}
+	auth, err := migrateAuthArgs(ctx, client.Options())
+	if err != nil {
+		return err
+	}
+	args = append(args, auth...)
+	args = append(args, "KEYS")
+	for _, key := range keys {
+		args = append(args, key)
+	}
+
+	// MIGRATE replies NOKEY, not an error, if all keys vanished in the meantime
+	if err := client.Do(ctx, args...).Err(); err != nil {
+		return fmt.Errorf("MIGRATE %d key(s) to %s: %w", len(keys), destAddr, err)
+	}
+	return nil
+}
+
+func migrateAuthArgs(ctx context.Context, opts *redis.Options) ([]any, error) {
+	username, password := opts.Username, opts.Password
+	if opts.CredentialsProviderContext != nil {
+		var err error
+		username, password, err = opts.CredentialsProviderContext(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("resolve credentials for MIGRATE: %w", err)
+		}
+	}
+	switch {
+	case password == "":
+		return nil, nil
+	case username == "" || username == "default":
+		return []any{"AUTH", password}, nil
+	default:
+		return []any{"AUTH2", username, password}, nil
+	}
+}
+
+// ParseSlotList parses a comma separated list of slots and inclusive ranges like "100,200-205".
+// The result is sorted and free of duplicates.
+func ParseSlotList(value string) ([]int, error) {
+	seen := make(map[int]struct{})
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		startStr, endStr, isRange := strings.Cut(part, "-")
+		if !isRange {
+			endStr = startStr
+		}
+		start, err := parseSlot(startStr)
+		if err != nil {
+			return nil, err
+		}
+		end, err := parseSlot(endStr)
+		if err != nil {
+			return nil, err
+		}
+		if start > end {
+			return nil, fmt.Errorf("invalid slot range %q", part)
+		}
+		for slot := start; slot <= end; slot++ {
+			seen[slot] = struct{}{}
+		}
+	}
+
+	slots := make([]int, 0, len(seen))
+	for slot := range seen {
+		slots = append(slots, slot)
+	}
+	sort.Ints(slots)
+	return slots, nil
+}
+
+func parseSlot(value string) (int, error) {
+	slot, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || slot < 0 || slot >= ClusterSlotCount {
+		return 0, fmt.Errorf("invalid slot %q (expected 0-%d)", value, ClusterSlotCount-1)
+	}
+	return slot, nil
+}
+
+// KeySlot returns the hash slot of key like CLUSTER KEYSLOT without a round trip: the CRC16 (XMODEM) of the
+// hash tag, or of the whole key if it has none, modulo ClusterSlotCount.
+func KeySlot(key string) int {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+	var crc uint16
+	for i := 0; i < len(key); i++ {
+		crc ^= uint16(key[i]) << 8
+		for range 8 {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return int(crc) % ClusterSlotCount
+}
+
+// SlotOwner returns the master serving slot.
+func (t *Topology) SlotOwner(slot int) (ClusterNodeInfo, bool) {
+	for _, node := range t.Masters() {
+		for _, r := range node.Slots {
+			if slot >= r.Start && slot <= r.End {
+				return node, true
+			}
+		}
+	}
+	return ClusterNodeInfo{}, false
+}
+
+// NodeByID returns the node with the given ID.
+func (t *Topology) NodeByID(id string) (ClusterNodeInfo, bool) {
+	for _, node := range t.Nodes {
+		if node.ID == id {
+			return node, true
+		}
+	}
+	return ClusterNodeInfo{}, false
+}
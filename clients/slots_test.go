@@ -0,0 +1,164 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2024 Steadybit GmbH
+
+package clients
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSlotList(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    []int
+		wantErr bool
+	}{
+		{name: "single slot", value: "42", want: []int{42}},
+		{name: "range", value: "100-103", want: []int{100, 101, 102, 103}},
+		{name: "mixed and unsorted", value: " 7, 1-2 ,5", want: []int{1, 2, 5, 7}},
+		{name: "duplicates", value: "3,2-4", want: []int{2, 3, 4}},
+		{name: "bounds", value: "0,16383", want: []int{0, 16383}},
+		{name: "empty", value: "", want: []int{}},
+		{name: "out of range", value: "16384", wantErr: true},
+		{name: "negative", value: "-1", wantErr: true},
+		{name: "reversed range", value: "10-5", wantErr: true},
+		{name: "not a number", value: "abc", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// When
+			slots, err := ParseSlotList(tt.value)
+
+			// Then
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, slots)
+		})
+	}
+}
+
+func TestKeySlot(t *testing.T) {
+	tests := []struct {
+		key  string
+		want int
+	}{
+		{key: "123456789", want: 0x31C3},
+		{key: "foo", want: 12182},
+		{key: "{user1000}.following", want: KeySlot("user1000")},
+		{key: "foo{{bar}}zap", want: KeySlot("{bar")},
+		{key: "", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			assert.Equal(t, tt.want, KeySlot(tt.key))
+		})
+	}
+}
+
+func TestTopology_SlotOwnerAndNodeByID(t *testing.T) {
+	// Given
+	topology := &Topology{Cluster: true, Nodes: []ClusterNodeInfo{
+		{ID: "m1", Addr: "10.0.0.1:6379", Role: "master", Slots: []SlotRange{{Start: 0, End: 5460}}},
+		{ID: "m2", Addr: "10.0.0.2:6379", Role: "master", Slots: []SlotRange{{Start: 5461, End: 10922}}},
+		{ID: "r1", Addr: "10.0.0.3:6379", Role: "slave", MasterID: "m1"},
+	}}
+
+	// When
+	owner, ok := topology.SlotOwner(5461)
+	_, unowned := topology.SlotOwner(16000)
+	replica, found := topology.NodeByID("r1")
+	_, missing := topology.NodeByID("unknown")
+
+	// Then
+	require.True(t, ok)
+	assert.Equal(t, "m2", owner.ID)
+	assert.False(t, unowned)
+	require.True(t, found)
+	assert.Equal(t, "m1", replica.MasterID)
+	assert.False(t, missing)
+}
+
+func TestClusterSetSlot(t *testing.T) {
+	// Given
+	client, mock := redismock.NewClientMock()
+	defer client.Close()
+	mock.ExpectDo("CLUSTER", "SETSLOT", 7, "IMPORTING", "src").SetVal("OK")
+	mock.ExpectDo("CLUSTER", "SETSLOT", 7, "STABLE").SetVal("OK")
+	mock.ExpectDo("CLUSTER", "SETSLOT", 8, "NODE", "dst").SetErr(errors.New("ERR I'm not the owner of hash slot 8"))
+	ctx := context.Background()
+
+	// When
+	importingErr := ClusterSetSlot(ctx, client, 7, SlotImporting, "src")
+	stableErr := ClusterSetSlot(ctx, client, 7, SlotStable, "ignored")
+	nodeErr := ClusterSetSlot(ctx, client, 8, SlotNode, "dst")
+
+	// Then
+	assert.NoError(t, importingErr)
+	assert.NoError(t, stableErr)
+	require.Error(t, nodeErr)
+	assert.Contains(t, nodeErr.Error(), "CLUSTER SETSLOT 8 NODE")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrateKeys(t *testing.T) {
+	// Given
+	client, mock := redismock.NewClientMock()
+	defer client.Close()
+	mock.ExpectDo("MIGRATE", "10.0.0.2", "6380", "", 0, int64(2000), "KEYS", "a", "b").SetVal("OK")
+	ctx := context.Background()
+
+	// When
+	err := MigrateKeys(ctx, client, "10.0.0.2:6380", []string{"a", "b"}, 2*time.Second)
+	noKeysErr := MigrateKeys(ctx, client, "10.0.0.2:6380", nil, 2*time.Second)
+	badAddrErr := MigrateKeys(ctx, client, "no-port", []string{"a"}, 2*time.Second)
+
+	// Then
+	assert.NoError(t, err)
+	assert.NoError(t, noKeysErr)
+	assert.Error(t, badAddrErr)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrateAuthArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		opts *redis.Options
+		want []any
+	}{
+		{name: "no password", opts: &redis.Options{}, want: nil},
+		{name: "password only", opts: &redis.Options{Password: "secret"}, want: []any{"AUTH", "secret"}},
+		{name: "default user", opts: &redis.Options{Username: "default", Password: "secret"}, want: []any{"AUTH", "secret"}},
+		{name: "acl user", opts: &redis.Options{Username: "chaos", Password: "secret"}, want: []any{"AUTH2", "chaos", "secret"}},
+		{
+			name: "credentials provider",
+			opts: &redis.Options{Password: "stale", CredentialsProviderContext: func(ctx context.Context) (string, string, error) {
+				return "rotated", "fresh", nil
+			}},
+			want: []any{"AUTH2", "rotated", "fresh"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// When
+			args, err := migrateAuthArgs(context.Background(), tt.opts)
+
+			// Then
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, args)
+		})
+	}
+}
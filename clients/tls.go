@@ -0,0 +1,97 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package clients
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/steadybit/extension-redis/config"
+)
+
+// NewTLSConfig builds the TLS configuration of an endpoint. CA bundle, client certificate and key are read on every
+// handshake, so rotated files are used for new connections without a restart.
+// serverName is used when the endpoint doesn't override it. Clients that dial several hosts with one configuration
+// pass an empty serverName and use newTLSDialer, which sets the dialed host per connection.
+func NewTLSConfig(endpoint *config.RedisEndpoint, serverName string) *tls.Config {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: endpoint.InsecureSkipVerify,
+		ServerName:         serverName,
+	}
+	if endpoint.ServerName != "" {
+		tlsConfig.ServerName = endpoint.ServerName
+	}
+
+	if endpoint.CertFile != "" {
+		certFile, keyFile := endpoint.CertFile, endpoint.KeyFile
+		tlsConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load client certificate: %w", err)
+			}
+			return &cert, nil
+		}
+	}
+
+	if endpoint.CAFile != "" && !endpoint.InsecureSkipVerify {
+		// The default verification uses a fixed RootCAs pool. It is disabled in favor of verifyWithCAFile,
+		// which reads the current CA bundle.
+		// The expected name is captured here, the ServerName of the connection state is empty for IP addresses.
+		caFile, expectedName := endpoint.CAFile, tlsConfig.ServerName
+		tlsConfig.InsecureSkipVerify = true
+		tlsConfig.VerifyConnection = func(state tls.ConnectionState) error {
+			return verifyWithCAFile(state, caFile, expectedName)
+		}
+	}
+	return tlsConfig
+}
+
+// newTLSDialer returns a dialer that builds the TLS configuration for the dialed host, for cluster and failover
+// clients whose nodes share the endpoint settings but not the server name.
+func newTLSDialer(endpoint *config.RedisEndpoint, timeout time.Duration) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, _ := SplitHostPort(addr)
+		dialer := &tls.Dialer{
+			NetDialer: &net.Dialer{Timeout: timeout, KeepAlive: 5 * time.Minute},
+			Config:    NewTLSConfig(endpoint, host),
+		}
+		return dialer.DialContext(ctx, network, addr)
+	}
+}
+
+func verifyWithCAFile(state tls.ConnectionState, caFile string, expectedName string) error {
+	if expectedName == "" {
+		return errors.New("no server name to verify the certificate against")
+	}
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return fmt.Errorf("failed to read CA file: %w", err)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(pem) {
+		return fmt.Errorf("no certificates found in CA file %s", caFile)
+	}
+	if len(state.PeerCertificates) == 0 {
+		return errors.New("server presented no certificate")
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err = state.PeerCertificates[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		DNSName:       expectedName,
+	})
+	return err
+}
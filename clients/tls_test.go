@@ -0,0 +1,257 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2024 Steadybit GmbH
+
+package clients
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/steadybit/extension-redis/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns a PEM certificate and key signed by the CA.
+func (ca *testCA) issue(t *testing.T, commonName string, usage x509.ExtKeyUsage, dnsNames ...string) ([]byte, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     dnsNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+}
+
+// runMutualTLSRedis starts miniredis requiring a client certificate signed by ca, presenting a certificate for redis.internal.
+func runMutualTLSRedis(t *testing.T, ca *testCA) *miniredis.Miniredis {
+	t.Helper()
+	certPEM, keyPEM := ca.issue(t, "redis", x509.ExtKeyUsageServerAuth, "redis.internal")
+	serverCert, err := tls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.cert)
+
+	mr, err := miniredis.RunTLS(&tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientCAs:    clientCAs,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	})
+	require.NoError(t, err)
+	t.Cleanup(mr.Close)
+	return mr
+}
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+}
+
+func TestNewTLSConfig_Defaults(t *testing.T) {
+	// When
+	tlsConfig := NewTLSConfig(&config.RedisEndpoint{URL: "rediss://redis:6379", InsecureSkipVerify: true}, "redis")
+
+	// Then
+	assert.Equal(t, uint16(tls.VersionTLS12), tlsConfig.MinVersion)
+	assert.True(t, tlsConfig.InsecureSkipVerify)
+	assert.Equal(t, "redis", tlsConfig.ServerName)
+	assert.Nil(t, tlsConfig.GetClientCertificate)
+	assert.Nil(t, tlsConfig.VerifyConnection)
+}
+
+func TestNewTLSConfig_ServerNameOverride(t *testing.T) {
+	tlsConfig := NewTLSConfig(&config.RedisEndpoint{URL: "rediss://10.0.0.1:6379", ServerName: "redis.internal"}, "10.0.0.1")
+
+	assert.Equal(t, "redis.internal", tlsConfig.ServerName)
+}
+
+func TestGetRedisClient_MutualTLS(t *testing.T) {
+	// Given
+	ca := newTestCA(t, "test-ca")
+	mr := runMutualTLSRedis(t, ca)
+	dir := t.TempDir()
+	certPEM, keyPEM := ca.issue(t, "extension", x509.ExtKeyUsageClientAuth)
+	writeFile(t, filepath.Join(dir, "ca.pem"), ca.pem)
+	writeFile(t, filepath.Join(dir, "client.pem"), certPEM)
+	writeFile(t, filepath.Join(dir, "client-key.pem"), keyPEM)
+
+	url := fmt.Sprintf("rediss://%s", mr.Addr())
+	origEndpoints := config.Config.Endpoints
+	defer func() { config.Config.Endpoints = origEndpoints }()
+	config.Config.Endpoints = []config.RedisEndpoint{{
+		URL:        url,
+		CAFile:     filepath.Join(dir, "ca.pem"),
+		CertFile:   filepath.Join(dir, "client.pem"),
+		KeyFile:    filepath.Join(dir, "client-key.pem"),
+		ServerName: "redis.internal",
+	}}
+	defer EvictClients(url)
+
+	// When
+	client, err := GetRedisClient(url, "", 0)
+	require.NoError(t, err)
+
+	// Then
+	assert.NoError(t, client.Ping(context.Background()).Err())
+}
+
+func TestCreateDirectClient_MutualTLS_RotatedFiles(t *testing.T) {
+	// Given - the CA file initially holds an unrelated CA, and no client certificate exists yet
+	ca := newTestCA(t, "test-ca")
+	mr := runMutualTLSRedis(t, ca)
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	writeFile(t, caFile, newTestCA(t, "other-ca").pem)
+	endpoint := &config.RedisEndpoint{
+		URL:        "rediss://seed:6379",
+		CAFile:     caFile,
+		CertFile:   filepath.Join(dir, "client.pem"),
+		KeyFile:    filepath.Join(dir, "client-key.pem"),
+		ServerName: "redis.internal",
+	}
+
+	client, err := CreateDirectClient(endpoint, mr.Addr())
+	require.NoError(t, err)
+	defer client.Close()
+	require.Error(t, client.Ping(context.Background()).Err())
+
+	// When
+	certPEM, keyPEM := ca.issue(t, "extension", x509.ExtKeyUsageClientAuth)
+	writeFile(t, caFile, ca.pem)
+	writeFile(t, endpoint.CertFile, certPEM)
+	writeFile(t, endpoint.KeyFile, keyPEM)
+
+	// Then - the same client picks up the files on the next handshake
+	assert.NoError(t, client.Ping(context.Background()).Err())
+}
+
+func TestCreateDirectClient_TLS_WrongServerName(t *testing.T) {
+	// Given
+	ca := newTestCA(t, "test-ca")
+	mr := runMutualTLSRedis(t, ca)
+	dir := t.TempDir()
+	certPEM, keyPEM := ca.issue(t, "extension", x509.ExtKeyUsageClientAuth)
+	writeFile(t, filepath.Join(dir, "ca.pem"), ca.pem)
+	writeFile(t, filepath.Join(dir, "client.pem"), certPEM)
+	writeFile(t, filepath.Join(dir, "client-key.pem"), keyPEM)
+	endpoint := &config.RedisEndpoint{
+		URL:        "rediss://seed:6379",
+		CAFile:     filepath.Join(dir, "ca.pem"),
+		CertFile:   filepath.Join(dir, "client.pem"),
+		KeyFile:    filepath.Join(dir, "client-key.pem"),
+		ServerName: "other.internal",
+	}
+
+	// When
+	client, err := CreateDirectClient(endpoint, mr.Addr())
+	require.NoError(t, err)
+	defer client.Close()
+	err = client.Ping(context.Background()).Err()
+
+	// Then
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "other.internal")
+}
+
+func TestCreateDirectClient_TLS_IPAddressWithOtherSAN(t *testing.T) {
+	// Given - the server presents a certificate for redis.internal and is dialed by IP without server name override
+	ca := newTestCA(t, "test-ca")
+	mr := runMutualTLSRedis(t, ca)
+	dir := t.TempDir()
+	certPEM, keyPEM := ca.issue(t, "extension", x509.ExtKeyUsageClientAuth)
+	writeFile(t, filepath.Join(dir, "ca.pem"), ca.pem)
+	writeFile(t, filepath.Join(dir, "client.pem"), certPEM)
+	writeFile(t, filepath.Join(dir, "client-key.pem"), keyPEM)
+	endpoint := &config.RedisEndpoint{
+		URL:      "rediss://seed:6379",
+		CAFile:   filepath.Join(dir, "ca.pem"),
+		CertFile: filepath.Join(dir, "client.pem"),
+		KeyFile:  filepath.Join(dir, "client-key.pem"),
+	}
+
+	// When
+	client, err := CreateDirectClient(endpoint, mr.Addr())
+	require.NoError(t, err)
+	defer client.Close()
+	err = client.Ping(context.Background()).Err()
+
+	// Then
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "127.0.0.1")
+}
+
+func TestCreateClusterClient_TLS_IPAddressWithOtherSAN(t *testing.T) {
+	// Given
+	ca := newTestCA(t, "test-ca")
+	mr := runMutualTLSRedis(t, ca)
+	dir := t.TempDir()
+	certPEM, keyPEM := ca.issue(t, "extension", x509.ExtKeyUsageClientAuth)
+	writeFile(t, filepath.Join(dir, "ca.pem"), ca.pem)
+	writeFile(t, filepath.Join(dir, "client.pem"), certPEM)
+	writeFile(t, filepath.Join(dir, "client-key.pem"), keyPEM)
+	endpoint := &config.RedisEndpoint{
+		URL:      fmt.Sprintf("rediss://%s", mr.Addr()),
+		CAFile:   filepath.Join(dir, "ca.pem"),
+		CertFile: filepath.Join(dir, "client.pem"),
+		KeyFile:  filepath.Join(dir, "client-key.pem"),
+	}
+
+	// When
+	client, err := CreateClusterClient(endpoint, "")
+	require.NoError(t, err)
+	defer client.Close()
+	_, err = client.ClusterNodes(context.Background()).Result()
+
+	// Then
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "127.0.0.1")
+}
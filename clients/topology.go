@@ -0,0 +1,153 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package clients
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+	"github.com/steadybit/extension-redis/config"
+)
+
+const defaultTopologyCacheTTL = 30 * time.Second
+
+// Topology is the cluster layout of an endpoint. For standalone and Sentinel endpoints Cluster is false and
+// Nodes is empty. A cached Topology is shared and must not be modified.
+type Topology struct {
+	Cluster   bool
+	Nodes     []ClusterNodeInfo
+	FetchedAt time.Time
+}
+
+// Masters returns the master nodes in CLUSTER NODES order.
+func (t *Topology) Masters() []ClusterNodeInfo {
+	return t.nodesWithRole("master")
+}
+
+// Replicas returns the replica nodes in CLUSTER NODES order.
+func (t *Topology) Replicas() []ClusterNodeInfo {
+	return t.nodesWithRole("slave")
+}
+
+func (t *Topology) nodesWithRole(role string) []ClusterNodeInfo {
+	var nodes []ClusterNodeInfo
+	for _, n := range t.Nodes {
+		if n.Role == role {
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes
+}
+
+// topologyEntry caches the topology of one endpoint. Its mutex is held while fetching, so concurrent callers
+// wait for a single CLUSTER NODES round trip instead of each running their own.
+type topologyEntry struct {
+	mu       sync.Mutex
+	topology *Topology
+	expires  time.Time
+}
+
+// topologyCache stores a *topologyEntry per endpoint URL.
+var topologyCache sync.Map
+
+// GetTopology returns the cached topology of the endpoint and fetches it when it is missing or older than
+// STEADYBIT_EXTENSION_CLUSTER_TOPOLOGY_CACHE_SECONDS. Failed fetches are not cached.
+func GetTopology(ctx context.Context, endpoint *config.RedisEndpoint) (*Topology, error) {
+	if endpoint.ClusterMode == "standalone" || endpoint.IsSentinel() {
+		return &Topology{}, nil
+	}
+
+	v, _ := topologyCache.LoadOrStore(endpoint.URL, &topologyEntry{})
+	entry := v.(*topologyEntry)
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+
+	if entry.topology != nil && time.Now().Before(entry.expires) {
+		return entry.topology, nil
+	}
+
+	topology, err := fetchTopology(ctx, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	entry.topology = topology
+	entry.expires = topology.FetchedAt.Add(topologyCacheTTL())
+	return topology, nil
+}
+
+// InvalidateTopology drops the cached topology of the endpoint, the next GetTopology fetches it again.
+func InvalidateTopology(url string) {
+	if v, ok := topologyCache.Load(url); ok {
+		entry := v.(*topologyEntry)
+		entry.mu.Lock()
+		entry.topology = nil
+		entry.mu.Unlock()
+	}
+}
+
+// InvalidateTopologyOnError drops the cached topology of the endpoint if err indicates that it is stale:
+// a MOVED or ASK redirect, a cluster that is down, or a node that cannot be reached.
+func InvalidateTopologyOnError(url string, err error) {
+	if isStaleTopologyError(err) {
+		log.Debug().Err(err).Str("url", url).Msg("Invalidating cached cluster topology")
+		InvalidateTopology(url)
+	}
+}
+
+func isStaleTopologyError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if _, ok := redis.IsMovedError(err); ok {
+		return true
+	}
+	if _, ok := redis.IsAskError(err); ok {
+		return true
+	}
+	if redis.IsClusterDownError(err) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET)
+}
+
+func topologyCacheTTL() time.Duration {
+	if config.Config.ClusterTopologyCacheSeconds > 0 {
+		return time.Duration(config.Config.ClusterTopologyCacheSeconds) * time.Second
+	}
+	return defaultTopologyCacheTTL
+}
+
+// fetchTopology detects the cluster mode and reads CLUSTER NODES over a single connection.
+func fetchTopology(ctx context.Context, endpoint *config.RedisEndpoint) (*Topology, error) {
+	client, err := CreateRedisClient(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	topology := &Topology{Cluster: endpoint.ClusterMode == "cluster", FetchedAt: time.Now()}
+	if !topology.Cluster {
+		if err := PingRedis(ctx, client); err != nil {
+			return nil, err
+		}
+		topology.Cluster = detectClusterMode(ctx, client)
+	}
+	if !topology.Cluster {
+		return topology, nil
+	}
+
+	topology.Nodes, err = ParseClusterNodes(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	return topology, nil
+}
@@ -0,0 +1,170 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2024 Steadybit GmbH
+
+package clients
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/alicebob/miniredis/v2/server"
+	"github.com/redis/go-redis/v9"
+	"github.com/steadybit/extension-redis/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetTopology_CachesClusterNodes(t *testing.T) {
+	// Given - miniredis answers CLUSTER NODES as a single-master cluster
+	mr := miniredis.RunT(t)
+	endpoint := &config.RedisEndpoint{URL: "redis://" + mr.Addr(), ClusterMode: "cluster"}
+	defer InvalidateTopology(endpoint.URL)
+
+	// When
+	topology, err := GetTopology(context.Background(), endpoint)
+	require.NoError(t, err)
+	connections := mr.TotalConnectionCount()
+	cached, err := GetTopology(context.Background(), endpoint)
+	require.NoError(t, err)
+
+	// Then
+	assert.True(t, topology.Cluster)
+	require.Len(t, topology.Masters(), 1)
+	assert.Equal(t, mr.Addr(), topology.Masters()[0].Addr)
+	assert.Equal(t, []SlotRange{{Start: 0, End: 16383}}, topology.Masters()[0].Slots)
+	assert.Empty(t, topology.Replicas())
+	assert.Same(t, topology, cached)
+	assert.Equal(t, connections, mr.TotalConnectionCount())
+}
+
+func TestGetTopology_RefetchesAfterExpiryAndInvalidation(t *testing.T) {
+	// Given
+	mr := miniredis.RunT(t)
+	endpoint := &config.RedisEndpoint{URL: "redis://" + mr.Addr(), ClusterMode: "cluster"}
+	defer InvalidateTopology(endpoint.URL)
+	first, err := GetTopology(context.Background(), endpoint)
+	require.NoError(t, err)
+
+	// When - the entry expires
+	v, _ := topologyCache.Load(endpoint.URL)
+	v.(*topologyEntry).expires = time.Now().Add(-time.Second)
+	expired, err := GetTopology(context.Background(), endpoint)
+	require.NoError(t, err)
+
+	// When - the entry is invalidated
+	InvalidateTopology(endpoint.URL)
+	invalidated, err := GetTopology(context.Background(), endpoint)
+	require.NoError(t, err)
+
+	// Then
+	assert.NotSame(t, first, expired)
+	assert.NotSame(t, expired, invalidated)
+}
+
+func TestGetTopology_StandaloneAndSentinelAreNotFetched(t *testing.T) {
+	// Given
+	mr := miniredis.RunT(t)
+	endpoints := []*config.RedisEndpoint{
+		{URL: "redis://" + mr.Addr(), ClusterMode: "standalone"},
+		{URL: "redis://mymaster", Sentinel: &config.SentinelConfig{MasterName: "mymaster", Addresses: []string{mr.Addr()}}},
+	}
+
+	for _, endpoint := range endpoints {
+		// When
+		topology, err := GetTopology(context.Background(), endpoint)
+
+		// Then
+		require.NoError(t, err)
+		assert.False(t, topology.Cluster)
+		assert.Empty(t, topology.Nodes)
+	}
+	assert.Equal(t, 0, mr.TotalConnectionCount())
+}
+
+func TestGetTopology_FailedFetchIsNotCached(t *testing.T) {
+	// Given - auto-detection against a server that is down
+	mr := miniredis.RunT(t)
+	addr := mr.Addr()
+	mr.Close()
+	endpoint := &config.RedisEndpoint{URL: "redis://" + addr}
+	defer InvalidateTopology(endpoint.URL)
+
+	// When
+	_, err := GetTopology(context.Background(), endpoint)
+
+	// Then
+	require.Error(t, err)
+	v, ok := topologyCache.Load(endpoint.URL)
+	require.True(t, ok)
+	assert.Nil(t, v.(*topologyEntry).topology)
+}
+
+func TestInvalidateTopologyOnError(t *testing.T) {
+	// Given - a server that answers with redirects and cluster errors
+	mr := miniredis.RunT(t)
+	for cmd, reply := range map[string]string{
+		"MOVEDCMD": "MOVED 3999 127.0.0.1:6381",
+		"ASKCMD":   "ASK 3999 127.0.0.1:6381",
+		"DOWNCMD":  "CLUSTERDOWN The cluster is down",
+		"OTHERCMD": "ERR unknown",
+	} {
+		require.NoError(t, mr.Server().Register(cmd, func(c *server.Peer, cmd string, args []string) {
+			c.WriteError(reply)
+		}))
+	}
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	down := miniredis.RunT(t)
+	downAddr := down.Addr()
+	down.Close()
+	downClient := redis.NewClient(&redis.Options{Addr: downAddr, MaxRetries: -1})
+	defer downClient.Close()
+
+	tests := []struct {
+		name  string
+		err   error
+		stale bool
+	}{
+		{name: "moved", err: client.Do(context.Background(), "MOVEDCMD").Err(), stale: true},
+		{name: "ask", err: client.Do(context.Background(), "ASKCMD").Err(), stale: true},
+		{name: "cluster down", err: client.Do(context.Background(), "DOWNCMD").Err(), stale: true},
+		{name: "connection refused", err: downClient.Ping(context.Background()).Err(), stale: true},
+		{name: "wrapped", err: fmt.Errorf("node x: %w", client.Do(context.Background(), "MOVEDCMD").Err()), stale: true},
+		{name: "other redis error", err: client.Do(context.Background(), "OTHERCMD").Err(), stale: false},
+		{name: "plain error", err: errors.New("boom"), stale: false},
+		{name: "nil", err: nil, stale: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			url := "redis://stale-" + tt.name + ":6379"
+			entry := &topologyEntry{topology: &Topology{Cluster: true}, expires: time.Now().Add(time.Hour)}
+			topologyCache.Store(url, entry)
+			defer topologyCache.Delete(url)
+
+			// When
+			InvalidateTopologyOnError(url, tt.err)
+
+			// Then
+			assert.Equal(t, tt.stale, entry.topology == nil)
+		})
+	}
+}
+
+func TestEvictClients_InvalidatesTopology(t *testing.T) {
+	// Given
+	url := "redis://evict-topology.local:6379"
+	entry := &topologyEntry{topology: &Topology{Cluster: true}, expires: time.Now().Add(time.Hour)}
+	topologyCache.Store(url, entry)
+	defer topologyCache.Delete(url)
+
+	// When
+	EvictClients(url)
+
+	// Then
+	assert.Nil(t, entry.topology)
+}
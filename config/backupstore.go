@@ -0,0 +1,36 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package config
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	BackupStoreDisk  = "disk"
+	BackupStoreRedis = "redis"
+)
+
+func validateBackupStore() error {
+	switch Config.BackupStore {
+	case "":
+		return nil
+	case BackupStoreDisk:
+		if Config.BackupStoreDir == "" {
+			return fmt.Errorf("STEADYBIT_EXTENSION_BACKUP_STORE_DIR is required for the %s backup store", BackupStoreDisk)
+		}
+	case BackupStoreRedis:
+		if Config.BackupStoreRedisURL == "" {
+			return fmt.Errorf("STEADYBIT_EXTENSION_BACKUP_STORE_REDIS_URL is required for the %s backup store", BackupStoreRedis)
+		}
+	default:
+		return fmt.Errorf("unsupported backup store %q (expected %s or %s)", Config.BackupStore, BackupStoreDisk, BackupStoreRedis)
+	}
+	if Config.BackupStoreChunkSizeBytes <= 0 {
+		return errors.New("STEADYBIT_EXTENSION_BACKUP_STORE_CHUNK_SIZE_BYTES must be positive")
+	}
+	return nil
+}
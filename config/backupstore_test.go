@@ -0,0 +1,48 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2024 Steadybit GmbH
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateBackupStore(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    Specification
+		wantErr string
+	}{
+		{"in state", Specification{}, ""},
+		{"disk", Specification{BackupStore: "disk", BackupStoreDir: "/backups", BackupStoreChunkSizeBytes: 1024}, ""},
+		{"disk without dir", Specification{BackupStore: "disk", BackupStoreChunkSizeBytes: 1024}, "STEADYBIT_EXTENSION_BACKUP_STORE_DIR is required"},
+		{"redis", Specification{BackupStore: "redis", BackupStoreRedisURL: "redis://backup:6379", BackupStoreChunkSizeBytes: 1024}, ""},
+		{"redis without url", Specification{BackupStore: "redis", BackupStoreChunkSizeBytes: 1024}, "STEADYBIT_EXTENSION_BACKUP_STORE_REDIS_URL is required"},
+		{"unknown", Specification{BackupStore: "s3"}, `unsupported backup store "s3"`},
+		{"chunk size", Specification{BackupStore: "disk", BackupStoreDir: "/backups"}, "must be positive"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			orig := Config
+			defer func() { Config = orig }()
+			Config = tc.spec
+
+			err := validateBackupStore()
+
+			if tc.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetMaxStoredBackupSizeBytes(t *testing.T) {
+	assert.Equal(t, int64(DefaultMaxStoredBackupSizeBytes), (&RedisEndpoint{}).GetMaxStoredBackupSizeBytes())
+	assert.Equal(t, int64(2048), (&RedisEndpoint{MaxBackupSizeBytes: 2048}).GetMaxStoredBackupSizeBytes())
+	assert.Equal(t, int64(DefaultMaxBackupSizeBytes), (&RedisEndpoint{}).GetMaxBackupSizeBytes())
+}
@@ -5,7 +5,6 @@
 package config
 
 import (
-	"encoding/json"
 	"github.com/kelseyhightower/envconfig"
 	"github.com/rs/zerolog/log"
 )
@@ -14,17 +13,47 @@ type RedisEndpoint struct {
 	URL                string `json:"url"`                          // Redis connection URL (redis:// or rediss://)
 	Password           string `json:"password,omitempty"`           // Redis password
 	Username           string `json:"username,omitempty"`           // Redis username (Redis 6+ ACL)
+	PasswordFile       string `json:"passwordFile,omitempty"`       // File containing the password, re-read for every new connection
+	PasswordEnv        string `json:"passwordEnv,omitempty"`        // Environment variable containing the password
+	UsernameFile       string `json:"usernameFile,omitempty"`       // File containing the username, re-read for every new connection
 	DB                 int    `json:"db,omitempty"`                 // Database number (default 0, ignored in cluster mode)
 	InsecureSkipVerify bool   `json:"insecureSkipVerify,omitempty"` // Skip TLS verification
+	CAFile             string `json:"caFile,omitempty"`             // PEM bundle of CAs to verify the server certificate
+	CertFile           string `json:"certFile,omitempty"`           // PEM client certificate for mutual TLS
+	KeyFile            string `json:"keyFile,omitempty"`            // PEM private key of the client certificate
+	ServerName         string `json:"serverName,omitempty"`         // Server name to verify instead of the host of the URL
 	Name               string `json:"name,omitempty"`               // Friendly name for this endpoint
 
+	// Sentinel support, URL then only provides scheme, credentials and db
+	Sentinel *SentinelConfig `json:"sentinel,omitempty"`
+
 	// Cluster support
 	ClusterMode        string `json:"clusterMode,omitempty"`        // "auto" (default), "standalone", or "cluster"
 	MaxBackupSizeBytes int64  `json:"maxBackupSizeBytes,omitempty"` // Max total backup size for cache expiration (default 10MB)
+
+	// Guardrails restrict what attacks may do to this endpoint
+	Guardrails *Guardrails `json:"guardrails,omitempty"`
+}
+
+// SentinelConfig describes a master that is monitored by Redis Sentinel. Connections go to the current master.
+type SentinelConfig struct {
+	MasterName string   `json:"masterName"`         // Name of the monitored master
+	Addresses  []string `json:"addresses"`          // host:port of the sentinels
+	Username   string   `json:"username,omitempty"` // Sentinel username (Redis 6+ ACL)
+	Password   string   `json:"password,omitempty"` // Sentinel password
 }
 
-const DefaultMaxBackupSizeBytes = 10 * 1024 * 1024 // 10MB
+// IsSentinel reports whether the endpoint connects through Redis Sentinel.
+func (e *RedisEndpoint) IsSentinel() bool {
+	return e.Sentinel != nil
+}
+
+const (
+	DefaultMaxBackupSizeBytes       = 10 * 1024 * 1024  // 10MB
+	DefaultMaxStoredBackupSizeBytes = 512 * 1024 * 1024 // 512MB, with a backup store
+)
 
+// GetMaxBackupSizeBytes returns the limit for backups that are kept in action state.
 func (e *RedisEndpoint) GetMaxBackupSizeBytes() int64 {
 	if e.MaxBackupSizeBytes > 0 {
 		return e.MaxBackupSizeBytes
@@ -32,15 +61,49 @@ func (e *RedisEndpoint) GetMaxBackupSizeBytes() int64 {
 	return DefaultMaxBackupSizeBytes
 }
 
+// GetMaxStoredBackupSizeBytes returns the limit for backups that are offloaded to the backup store.
+func (e *RedisEndpoint) GetMaxStoredBackupSizeBytes() int64 {
+	if e.MaxBackupSizeBytes > 0 {
+		return e.MaxBackupSizeBytes
+	}
+	return DefaultMaxStoredBackupSizeBytes
+}
+
 type Specification struct {
 	// JSON array of Redis endpoints
-	EndpointsJSON string `json:"endpointsJson" split_words:"true" required:"true"`
-	Endpoints     []RedisEndpoint
+	EndpointsJSON string `json:"endpointsJson" split_words:"true"`
+	// Path to a JSON or YAML file with the Redis endpoints, reloaded when it changes. Takes precedence over EndpointsJSON.
+	EndpointsFile                      string `json:"endpointsFile" split_words:"true"`
+	EndpointsFileReloadIntervalSeconds int    `json:"endpointsFileReloadIntervalSeconds" split_words:"true" default:"10"`
+	Endpoints                          []RedisEndpoint
 
 	// Discovery intervals in seconds
 	DiscoveryIntervalInstanceSeconds int `json:"discoveryIntervalInstanceSeconds" split_words:"true" default:"30"`
 	DiscoveryIntervalDatabaseSeconds int `json:"discoveryIntervalDatabaseSeconds" split_words:"true" default:"60"`
 
+	// Cluster-wide actions run on this many master nodes at once, each node gets the timeout in seconds
+	ClusterFanOutConcurrency  int `json:"clusterFanOutConcurrency" split_words:"true" default:"8"`
+	ClusterNodeTimeoutSeconds int `json:"clusterNodeTimeoutSeconds" split_words:"true" default:"10"`
+	// Cluster topology (masters, replicas, slots) is cached for this many seconds, shared by discovery and actions
+	ClusterTopologyCacheSeconds int `json:"clusterTopologyCacheSeconds" split_words:"true" default:"30"`
+
+	// Directory of the attack journal, attacks left behind by a crash are rolled back on startup. Empty disables it.
+	JournalDir string `json:"journalDir" split_words:"true"`
+
+	// Backups of cache expiration are kept in action state when empty, or offloaded to a "disk" or "redis" backup store
+	BackupStore               string `json:"backupStore" split_words:"true"`
+	BackupStoreDir            string `json:"backupStoreDir" split_words:"true"`
+	BackupStoreRedisURL       string `json:"-" split_words:"true"`
+	BackupStoreChunkSizeBytes int    `json:"backupStoreChunkSizeBytes" split_words:"true" default:"4194304"`
+
+	// Base64 encoded AES key (16, 24 or 32 bytes) to encrypt backed up values in action state. Empty stores them in plain text.
+	StateEncryptionKey string `json:"-" split_words:"true"`
+
+	// Experiment event listeners report drift of the targeted endpoints after this many seconds, and set drifted
+	// settings back to their snapshot with DriftRepair
+	DriftRepair            bool `json:"driftRepair" split_words:"true"`
+	DriftCheckDelaySeconds int  `json:"driftCheckDelaySeconds" split_words:"true" default:"10"`
+
 	// Attribute exclusion patterns
 	DiscoveryAttributesExcludesInstances []string `json:"discoveryAttributesExcludesInstances" split_words:"true"`
 	DiscoveryAttributesExcludesDatabases []string `json:"discoveryAttributesExcludesDatabases" split_words:"true"`
@@ -58,40 +121,59 @@ func ParseConfiguration() {
 }
 
 func ValidateConfiguration() {
-	if Config.EndpointsJSON == "" {
-		log.Fatal().Msg("STEADYBIT_EXTENSION_ENDPOINTS_JSON is required")
+	var endpoints []RedisEndpoint
+	var err error
+	switch {
+	case Config.EndpointsFile != "":
+		if Config.EndpointsJSON != "" {
+			log.Warn().Msg("Both STEADYBIT_EXTENSION_ENDPOINTS_FILE and STEADYBIT_EXTENSION_ENDPOINTS_JSON are set, using the file")
+		}
+		endpoints, endpointsFileChecksum, err = loadEndpointsFile(Config.EndpointsFile)
+		if err != nil {
+			log.Fatal().Err(err).Msgf("Failed to load endpoints from %s", Config.EndpointsFile)
+		}
+	case Config.EndpointsJSON != "":
+		endpoints, err = ParseEndpoints([]byte(Config.EndpointsJSON))
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to parse STEADYBIT_EXTENSION_ENDPOINTS_JSON")
+		}
+	default:
+		log.Fatal().Msg("STEADYBIT_EXTENSION_ENDPOINTS_JSON or STEADYBIT_EXTENSION_ENDPOINTS_FILE is required")
 	}
 
-	err := json.Unmarshal([]byte(Config.EndpointsJSON), &Config.Endpoints)
-	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to parse STEADYBIT_EXTENSION_ENDPOINTS_JSON")
+	if err := ValidateEndpoints(endpoints); err != nil {
+		log.Fatal().Err(err).Msg("Invalid Redis endpoint configuration")
 	}
-
-	if len(Config.Endpoints) == 0 {
-		log.Fatal().Msg("At least one Redis endpoint must be configured")
+	if err := validateLegacyEndpointFields(endpoints); err != nil {
+		log.Warn().Err(err).Msg("Questionable Redis endpoint configuration, a reload of the endpoints file with it is rejected")
+	}
+	if _, err := stateCipher(); err != nil {
+		log.Fatal().Err(err).Msg("Invalid STEADYBIT_EXTENSION_STATE_ENCRYPTION_KEY")
 	}
+	if err := validateBackupStore(); err != nil {
+		log.Fatal().Err(err).Msg("Invalid backup store configuration")
+	}
+	SetEndpoints(endpoints)
+	logEndpoints(endpoints)
+}
 
-	for i, endpoint := range Config.Endpoints {
-		if endpoint.URL == "" {
-			log.Fatal().Msgf("Endpoint %d: URL is required", i)
-		}
+func logEndpoints(endpoints []RedisEndpoint) {
+	for i, endpoint := range endpoints {
 		log.Info().
 			Int("index", i).
-			Str("url", maskURL(endpoint.URL)).
+			Str("url", RedactURL(endpoint.URL)).
 			Str("name", endpoint.Name).
 			Int("db", endpoint.DB).
 			Msg("Configured Redis endpoint")
 	}
 }
 
-func maskURL(url string) string {
-	// Simple masking - replace password in URL if present
-	return url
-}
-
+// GetEndpointByURL returns the endpoint configured with the URL, or with a password in the URL that RedactURL removed.
 func GetEndpointByURL(url string) *RedisEndpoint {
+	endpointsMutex.RLock()
+	defer endpointsMutex.RUnlock()
 	for _, endpoint := range Config.Endpoints {
-		if endpoint.URL == url {
+		if endpoint.URL == url || RedactURL(endpoint.URL) == url {
 			return &endpoint
 		}
 	}
@@ -0,0 +1,93 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// HasCredentialReferences reports whether the credentials are read from files or the environment instead of inline values.
+func (e *RedisEndpoint) HasCredentialReferences() bool {
+	return e.PasswordFile != "" || e.PasswordEnv != "" || e.UsernameFile != ""
+}
+
+// ResolveCredentials returns the username and password of the endpoint. Referenced files and environment variables
+// are read on every call, so rotated credentials are picked up without a restart.
+func (e *RedisEndpoint) ResolveCredentials() (username, password string, err error) {
+	username = e.Username
+	password = e.Password
+
+	if e.UsernameFile != "" {
+		username, err = readSecretFile(e.UsernameFile)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to read username: %w", err)
+		}
+	}
+
+	switch {
+	case e.PasswordFile != "":
+		password, err = readSecretFile(e.PasswordFile)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to read password: %w", err)
+		}
+	case e.PasswordEnv != "":
+		value, ok := os.LookupEnv(e.PasswordEnv)
+		if !ok {
+			return "", "", fmt.Errorf("failed to read password: environment variable %s is not set", e.PasswordEnv)
+		}
+		password = value
+	}
+	return username, password, nil
+}
+
+// RedactURL removes the password from the userinfo of an endpoint URL. The redacted URL is what leaves the extension in
+// target attributes, action state and logs, GetEndpointByURL resolves it back to the configured endpoint.
+func RedactURL(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.User == nil {
+		return rawURL
+	}
+	if _, ok := parsed.User.Password(); !ok {
+		return rawURL
+	}
+	if username := parsed.User.Username(); username != "" {
+		parsed.User = url.User(username)
+	} else {
+		parsed.User = nil
+	}
+	return parsed.String()
+}
+
+// readSecretFile reads a mounted secret. The trailing newline editors and `echo` add is not part of the secret.
+func readSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// validateCredentialReferences rejects ambiguous credential sources. Unreadable files are not rejected,
+// the secret may be mounted later and is resolved at connection time.
+func validateCredentialReferences(e *RedisEndpoint) error {
+	var errs []error
+	sources := 0
+	for _, source := range []string{e.Password, e.PasswordFile, e.PasswordEnv} {
+		if source != "" {
+			sources++
+		}
+	}
+	if sources > 1 {
+		errs = append(errs, errors.New("only one of password, passwordFile and passwordEnv may be set"))
+	}
+	if e.Username != "" && e.UsernameFile != "" {
+		errs = append(errs, errors.New("only one of username and usernameFile may be set"))
+	}
+	return errors.Join(errs...)
+}
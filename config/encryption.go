@@ -0,0 +1,75 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// StateEncryptionEnabled reports whether values that attacks back up are encrypted before they are stored in action state.
+func StateEncryptionEnabled() bool {
+	return Config.StateEncryptionKey != ""
+}
+
+// EncryptStateValue encrypts value with AES-GCM and the key of STEADYBIT_EXTENSION_STATE_ENCRYPTION_KEY.
+// The result is the base64 encoded nonce followed by the ciphertext.
+func EncryptStateValue(value string) (string, error) {
+	aead, err := stateCipher()
+	if err != nil {
+		return "", err
+	}
+	if aead == nil {
+		return "", errors.New("STEADYBIT_EXTENSION_STATE_ENCRYPTION_KEY is not set")
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(aead.Seal(nonce, nonce, []byte(value), nil)), nil
+}
+
+// DecryptStateValue decrypts a value of EncryptStateValue. It fails if the key changed since the value was encrypted.
+func DecryptStateValue(encrypted string) (string, error) {
+	aead, err := stateCipher()
+	if err != nil {
+		return "", err
+	}
+	if aead == nil {
+		return "", errors.New("the value is encrypted, but STEADYBIT_EXTENSION_STATE_ENCRYPTION_KEY is not set")
+	}
+	data, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return "", fmt.Errorf("invalid encrypted value: %w", err)
+	}
+	if len(data) < aead.NonceSize() {
+		return "", errors.New("invalid encrypted value: too short")
+	}
+	plain, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt value, was STEADYBIT_EXTENSION_STATE_ENCRYPTION_KEY changed? %w", err)
+	}
+	return string(plain), nil
+}
+
+// stateCipher returns the AEAD of the configured key, nil when encryption is disabled.
+func stateCipher() (cipher.AEAD, error) {
+	if Config.StateEncryptionKey == "" {
+		return nil, nil
+	}
+	key, err := base64.StdEncoding.DecodeString(Config.StateEncryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("state encryption key is not valid base64: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("state encryption key must be 16, 24 or 32 bytes: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,85 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2024 Steadybit GmbH
+
+package config
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func useStateEncryptionKey(t *testing.T, key string) {
+	t.Helper()
+	orig := Config.StateEncryptionKey
+	t.Cleanup(func() { Config.StateEncryptionKey = orig })
+	Config.StateEncryptionKey = key
+}
+
+func TestStateEncryption_RoundTrip(t *testing.T) {
+	// Given
+	useStateEncryptionKey(t, base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32))))
+
+	// When
+	first, err := EncryptStateValue("session-token")
+	require.NoError(t, err)
+	second, err := EncryptStateValue("session-token")
+	require.NoError(t, err)
+	decrypted, err := DecryptStateValue(first)
+
+	// Then
+	require.NoError(t, err)
+	assert.True(t, StateEncryptionEnabled())
+	assert.Equal(t, "session-token", decrypted)
+	assert.NotContains(t, first, "session-token")
+	assert.NotEqual(t, first, second, "every value gets its own nonce")
+}
+
+func TestStateEncryption_ChangedKey(t *testing.T) {
+	// Given
+	useStateEncryptionKey(t, base64.StdEncoding.EncodeToString([]byte(strings.Repeat("a", 16))))
+	encrypted, err := EncryptStateValue("value")
+	require.NoError(t, err)
+
+	// When
+	Config.StateEncryptionKey = base64.StdEncoding.EncodeToString([]byte(strings.Repeat("b", 16)))
+	_, err = DecryptStateValue(encrypted)
+
+	// Then
+	assert.ErrorContains(t, err, "failed to decrypt value")
+}
+
+func TestStateEncryption_Disabled(t *testing.T) {
+	useStateEncryptionKey(t, "")
+
+	_, encryptErr := EncryptStateValue("value")
+	_, decryptErr := DecryptStateValue("AAAA")
+
+	assert.False(t, StateEncryptionEnabled())
+	assert.Error(t, encryptErr)
+	assert.ErrorContains(t, decryptErr, "not set")
+}
+
+func TestStateCipher_InvalidKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		wantErr string
+	}{
+		{"not base64", "not-base64!", "not valid base64"},
+		{"wrong length", base64.StdEncoding.EncodeToString([]byte("short")), "must be 16, 24 or 32 bytes"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			useStateEncryptionKey(t, tc.key)
+
+			_, err := stateCipher()
+
+			assert.ErrorContains(t, err, tc.wantErr)
+		})
+	}
+}
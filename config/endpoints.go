@@ -0,0 +1,249 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"sigs.k8s.io/yaml"
+)
+
+// EndpointsChangedListener is notified with the previous and the new endpoints after a successful reload.
+type EndpointsChangedListener func(previous, current []RedisEndpoint)
+
+var (
+	// endpointsMutex guards Config.Endpoints, which is replaced when the endpoints file is reloaded.
+	endpointsMutex sync.RWMutex
+
+	// endpointsFileChecksum is the checksum of the last applied or rejected endpoints file content.
+	endpointsFileChecksum [sha256.Size]byte
+
+	listenersMutex sync.Mutex
+	listeners      []EndpointsChangedListener
+	subscribers    []chan struct{}
+)
+
+// GetEndpoints returns a copy of the currently configured endpoints.
+func GetEndpoints() []RedisEndpoint {
+	endpointsMutex.RLock()
+	defer endpointsMutex.RUnlock()
+	return slices.Clone(Config.Endpoints)
+}
+
+// SetEndpoints replaces the configured endpoints without notifying listeners.
+func SetEndpoints(endpoints []RedisEndpoint) {
+	endpointsMutex.Lock()
+	defer endpointsMutex.Unlock()
+	Config.Endpoints = endpoints
+}
+
+// OnEndpointsChanged registers a listener that is called after the endpoints were reloaded.
+func OnEndpointsChanged(listener EndpointsChangedListener) {
+	listenersMutex.Lock()
+	defer listenersMutex.Unlock()
+	listeners = append(listeners, listener)
+}
+
+// SubscribeEndpointsChanged returns a channel that receives a signal whenever the endpoints were reloaded.
+// Signals are coalesced, a slow receiver only sees the latest change.
+func SubscribeEndpointsChanged() <-chan struct{} {
+	listenersMutex.Lock()
+	defer listenersMutex.Unlock()
+	ch := make(chan struct{}, 1)
+	subscribers = append(subscribers, ch)
+	return ch
+}
+
+func notifyEndpointsChanged(previous, current []RedisEndpoint) {
+	listenersMutex.Lock()
+	defer listenersMutex.Unlock()
+	for _, listener := range listeners {
+		listener(previous, current)
+	}
+	for _, ch := range subscribers {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// ParseEndpoints parses a JSON or YAML array of endpoints.
+func ParseEndpoints(data []byte) ([]RedisEndpoint, error) {
+	var endpoints []RedisEndpoint
+	if err := yaml.Unmarshal(data, &endpoints); err != nil {
+		return nil, fmt.Errorf("failed to parse endpoints: %w", err)
+	}
+	return endpoints, nil
+}
+
+// ValidateEndpoints checks that the endpoints are usable. All problems are reported at once.
+func ValidateEndpoints(endpoints []RedisEndpoint) error {
+	if len(endpoints) == 0 {
+		return errors.New("at least one Redis endpoint must be configured")
+	}
+
+	var errs []error
+	for i, endpoint := range endpoints {
+		if endpoint.URL == "" {
+			errs = append(errs, fmt.Errorf("endpoint %d: URL is required", i))
+			continue
+		}
+		parsed, _ := url.Parse(endpoint.URL)
+		if (endpoint.CertFile == "") != (endpoint.KeyFile == "") {
+			errs = append(errs, fmt.Errorf("endpoint %d: certFile and keyFile must be set together", i))
+		}
+		usesTLSSettings := endpoint.CAFile != "" || endpoint.CertFile != "" || endpoint.ServerName != ""
+		if usesTLSSettings && parsed != nil && parsed.Scheme != "rediss" {
+			errs = append(errs, fmt.Errorf("endpoint %d: caFile, certFile, keyFile and serverName require a rediss:// URL", i))
+		}
+		if endpoint.Sentinel != nil {
+			if endpoint.Sentinel.MasterName == "" {
+				errs = append(errs, fmt.Errorf("endpoint %d: sentinel.masterName is required", i))
+			}
+			if len(endpoint.Sentinel.Addresses) == 0 {
+				errs = append(errs, fmt.Errorf("endpoint %d: sentinel.addresses must contain at least one sentinel", i))
+			}
+			if endpoint.ClusterMode == "cluster" {
+				errs = append(errs, fmt.Errorf("endpoint %d: sentinel endpoints cannot use clusterMode cluster", i))
+			}
+		}
+		if err := validateCredentialReferences(&endpoint); err != nil {
+			errs = append(errs, fmt.Errorf("endpoint %d: %w", i, err))
+		}
+		for _, err := range validateGuardrails(endpoint.Guardrails) {
+			errs = append(errs, fmt.Errorf("endpoint %d: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validateLegacyEndpointFields checks url, clusterMode and db, which were accepted unchecked before the endpoints
+// were validated. Configurations from before are only warned about at startup, a reloaded endpoints file is rejected.
+func validateLegacyEndpointFields(endpoints []RedisEndpoint) error {
+	var errs []error
+	seen := make(map[string]int, len(endpoints))
+	for i, endpoint := range endpoints {
+		if endpoint.URL == "" {
+			continue
+		}
+		parsed, err := url.Parse(endpoint.URL)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("endpoint %d: invalid URL: %w", i, err))
+		} else if parsed.Scheme != "redis" && parsed.Scheme != "rediss" && parsed.Scheme != "unix" {
+			errs = append(errs, fmt.Errorf("endpoint %d: unsupported URL scheme %q (expected redis, rediss or unix)", i, parsed.Scheme))
+		} else if parsed.Scheme == "unix" && parsed.Path == "" {
+			errs = append(errs, fmt.Errorf("endpoint %d: unix socket URL requires a path, e.g. unix:///var/run/redis/redis.sock", i))
+		}
+		// Action state refers to endpoints by the redacted URL, so it must be unique as well
+		redacted := RedactURL(endpoint.URL)
+		if first, ok := seen[redacted]; !ok {
+			seen[redacted] = i
+		} else if endpoints[first].URL == endpoint.URL {
+			errs = append(errs, fmt.Errorf("endpoint %d: URL is already used by endpoint %d", i, first))
+		} else {
+			errs = append(errs, fmt.Errorf("endpoint %d: URL differs from endpoint %d only in the password", i, first))
+		}
+		switch endpoint.ClusterMode {
+		case "", "auto", "standalone", "cluster":
+		default:
+			errs = append(errs, fmt.Errorf("endpoint %d: unsupported clusterMode %q (expected auto, standalone or cluster)", i, endpoint.ClusterMode))
+		}
+		if endpoint.DB < 0 {
+			errs = append(errs, fmt.Errorf("endpoint %d: db must not be negative", i))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// loadEndpointsFile reads, parses and validates the endpoints file. It also returns the checksum of the content.
+func loadEndpointsFile(path string) ([]RedisEndpoint, [sha256.Size]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, [sha256.Size]byte{}, fmt.Errorf("failed to read endpoints file: %w", err)
+	}
+	checksum := sha256.Sum256(data)
+
+	endpoints, err := ParseEndpoints(data)
+	if err != nil {
+		return nil, checksum, err
+	}
+	if err := ValidateEndpoints(endpoints); err != nil {
+		return nil, checksum, err
+	}
+	return endpoints, checksum, nil
+}
+
+// WatchEndpointsFile polls the endpoints file and applies valid changes until ctx is done.
+// Polling, rather than file system events, also picks up the symlink swaps used for mounted ConfigMaps and Secrets.
+// Invalid updates are logged and ignored, the previous endpoints stay active.
+func WatchEndpointsFile(ctx context.Context, path string, interval time.Duration) {
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloadEndpointsFile(path)
+		}
+	}
+}
+
+// reloadEndpointsFile applies the endpoints file if its content changed since it was last loaded.
+func reloadEndpointsFile(path string) {
+	endpoints, checksum, err := loadEndpointsFile(path)
+	if checksum == endpointsFileChecksum {
+		return
+	}
+	if err == nil {
+		err = validateLegacyEndpointFields(endpoints)
+	}
+	if err != nil {
+		log.Error().Err(err).Str("file", path).Msg("Rejected endpoints file update, keeping the current endpoints")
+		if checksum != ([sha256.Size]byte{}) {
+			// Remember the rejected content to not log it on every poll. An unreadable file, e.g. while a mount
+			// is swapped, is retried on the next tick.
+			endpointsFileChecksum = checksum
+		}
+		return
+	}
+	endpointsFileChecksum = checksum
+
+	previous := GetEndpoints()
+	SetEndpoints(endpoints)
+	log.Info().Str("file", path).Int("endpoints", len(endpoints)).Msg("Reloaded Redis endpoints")
+	logEndpoints(endpoints)
+	notifyEndpointsChanged(previous, endpoints)
+}
+
+// ChangedEndpointURLs returns the URLs of endpoints that were removed or whose configuration changed.
+func ChangedEndpointURLs(previous, current []RedisEndpoint) []string {
+	currentByURL := make(map[string]RedisEndpoint, len(current))
+	for _, endpoint := range current {
+		currentByURL[endpoint.URL] = endpoint
+	}
+
+	var changed []string
+	for _, endpoint := range previous {
+		if now, ok := currentByURL[endpoint.URL]; !ok || !reflect.DeepEqual(now, endpoint) {
+			changed = append(changed, endpoint.URL)
+		}
+	}
+	return changed
+}
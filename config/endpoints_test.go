@@ -0,0 +1,229 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2024 Steadybit GmbH
+
+package config
+
+import (
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEndpoints_JSON(t *testing.T) {
+	// When
+	endpoints, err := ParseEndpoints([]byte(`[{"url":"redis://redis-a:6379","password":"secret","db":2,"name":"a"}]`))
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, []RedisEndpoint{{URL: "redis://redis-a:6379", Password: "secret", DB: 2, Name: "a"}}, endpoints)
+}
+
+func TestParseEndpoints_YAML(t *testing.T) {
+	// Given
+	data := `
+- url: redis://redis-a:6379
+  name: a
+- url: rediss://redis-b:6380
+  username: alice
+  clusterMode: cluster
+  maxBackupSizeBytes: 1024
+`
+
+	// When
+	endpoints, err := ParseEndpoints([]byte(data))
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, []RedisEndpoint{
+		{URL: "redis://redis-a:6379", Name: "a"},
+		{URL: "rediss://redis-b:6380", Username: "alice", ClusterMode: "cluster", MaxBackupSizeBytes: 1024},
+	}, endpoints)
+}
+
+func TestParseEndpoints_Invalid(t *testing.T) {
+	_, err := ParseEndpoints([]byte(`{"url": "redis://redis-a:6379"}`))
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to parse endpoints")
+}
+
+func TestValidateEndpoints(t *testing.T) {
+	tests := []struct {
+		name      string
+		endpoints []RedisEndpoint
+		wantErr   []string
+	}{
+		{"valid", []RedisEndpoint{{URL: "redis://a:6379"}, {URL: "rediss://b:6380", ClusterMode: "auto"}}, nil},
+		{"empty", nil, []string{"at least one Redis endpoint"}},
+		{"missing url", []RedisEndpoint{{Name: "a"}}, []string{"endpoint 0: URL is required"}},
+		{"mutual tls", []RedisEndpoint{{URL: "rediss://a:6379", CAFile: "/ca.pem", CertFile: "/c.pem", KeyFile: "/k.pem", ServerName: "a"}}, nil},
+		{"cert without key", []RedisEndpoint{{URL: "rediss://a:6379", CertFile: "/c.pem"}}, []string{"certFile and keyFile must be set together"}},
+		{"tls settings without rediss", []RedisEndpoint{{URL: "redis://a:6379", CAFile: "/ca.pem"}}, []string{"require a rediss:// URL"}},
+		{"sentinel", []RedisEndpoint{{URL: "redis://mymaster", Sentinel: &SentinelConfig{MasterName: "mymaster", Addresses: []string{"s1:26379"}}}}, nil},
+		{"sentinel without master name", []RedisEndpoint{{URL: "redis://mymaster", Sentinel: &SentinelConfig{Addresses: []string{"s1:26379"}}}}, []string{"sentinel.masterName is required"}},
+		{"sentinel without addresses", []RedisEndpoint{{URL: "redis://mymaster", Sentinel: &SentinelConfig{MasterName: "mymaster"}}}, []string{"sentinel.addresses must contain at least one sentinel"}},
+		{"sentinel in cluster mode", []RedisEndpoint{{URL: "redis://mymaster", ClusterMode: "cluster", Sentinel: &SentinelConfig{MasterName: "m", Addresses: []string{"s1:26379"}}}}, []string{"cannot use clusterMode cluster"}},
+		{"unix socket", []RedisEndpoint{{URL: "unix:///var/run/redis/redis.sock"}}, nil},
+		{"ipv6", []RedisEndpoint{{URL: "redis://[2001:db8::1]:6379"}}, nil},
+		{"reports all problems", []RedisEndpoint{{URL: "rediss://a", CertFile: "/c.pem"}, {URL: "redis://b", CAFile: "/ca.pem"}}, []string{"endpoint 0", "endpoint 1"}},
+		{"tolerates fields accepted before validation", []RedisEndpoint{{URL: "http://a:6379", DB: -1}, {URL: "http://a:6379", ClusterMode: "sharded"}}, nil},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateEndpoints(tc.endpoints)
+
+			if tc.wantErr == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			for _, want := range tc.wantErr {
+				assert.Contains(t, err.Error(), want)
+			}
+		})
+	}
+}
+
+func TestValidateLegacyEndpointFields(t *testing.T) {
+	tests := []struct {
+		name      string
+		endpoints []RedisEndpoint
+		wantErr   []string
+	}{
+		{"valid", []RedisEndpoint{{URL: "redis://a:6379"}, {URL: "rediss://b:6380"}, {URL: "unix:///var/run/redis/redis.sock"}}, nil},
+		{"unsupported scheme", []RedisEndpoint{{URL: "http://a:6379"}}, []string{`unsupported URL scheme "http"`}},
+		{"duplicate url", []RedisEndpoint{{URL: "redis://a:6379"}, {URL: "redis://a:6379"}}, []string{"endpoint 1: URL is already used by endpoint 0"}},
+		{"unix socket without path", []RedisEndpoint{{URL: "unix://"}}, []string{"unix socket URL requires a path"}},
+		{"invalid cluster mode", []RedisEndpoint{{URL: "redis://a:6379", ClusterMode: "sharded"}}, []string{`unsupported clusterMode "sharded"`}},
+		{"negative db", []RedisEndpoint{{URL: "redis://a:6379", DB: -1}}, []string{"db must not be negative"}},
+		{"reports all problems", []RedisEndpoint{{URL: "http://a"}, {URL: "redis://b", ClusterMode: "x"}}, []string{"endpoint 0", "endpoint 1"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateLegacyEndpointFields(tc.endpoints)
+
+			if tc.wantErr == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			for _, want := range tc.wantErr {
+				assert.Contains(t, err.Error(), want)
+			}
+		})
+	}
+}
+
+func writeEndpointsFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+}
+
+func TestReloadEndpointsFile_AppliesChangesAndNotifies(t *testing.T) {
+	// Given
+	path := filepath.Join(t.TempDir(), "endpoints.yaml")
+	writeEndpointsFile(t, path, "- url: redis://a:6379\n")
+	endpointsFileChecksum = [sha256.Size]byte{}
+	reloadEndpointsFile(path)
+	changed := SubscribeEndpointsChanged()
+	var notified [][]RedisEndpoint
+	OnEndpointsChanged(func(previous, current []RedisEndpoint) {
+		notified = append(notified, previous, current)
+	})
+
+	// When
+	writeEndpointsFile(t, path, "- url: redis://a:6379\n  password: rotated\n- url: redis://b:6379\n")
+	reloadEndpointsFile(path)
+
+	// Then
+	assert.Equal(t, []RedisEndpoint{{URL: "redis://a:6379", Password: "rotated"}, {URL: "redis://b:6379"}}, GetEndpoints())
+	assert.Equal(t, "rotated", GetEndpointByURL("redis://a:6379").Password)
+	require.Len(t, notified, 2)
+	assert.Equal(t, []RedisEndpoint{{URL: "redis://a:6379"}}, notified[0])
+	select {
+	case <-changed:
+	default:
+		t.Fatal("expected a change signal")
+	}
+
+	// unchanged content does not notify again
+	reloadEndpointsFile(path)
+	assert.Len(t, notified, 2)
+}
+
+func TestReloadEndpointsFile_KeepsEndpointsOnInvalidUpdate(t *testing.T) {
+	// Given
+	path := filepath.Join(t.TempDir(), "endpoints.json")
+	writeEndpointsFile(t, path, `[{"url":"redis://a:6379"}]`)
+	endpointsFileChecksum = [sha256.Size]byte{}
+	reloadEndpointsFile(path)
+	validChecksum := endpointsFileChecksum
+	require.Equal(t, []RedisEndpoint{{URL: "redis://a:6379"}}, GetEndpoints())
+
+	// When
+	writeEndpointsFile(t, path, `[{"url":"http://a:6379"}]`)
+	reloadEndpointsFile(path)
+	invalidChecksum := endpointsFileChecksum
+	require.NoError(t, os.Remove(path))
+	reloadEndpointsFile(path)
+
+	// Then
+	assert.Equal(t, []RedisEndpoint{{URL: "redis://a:6379"}}, GetEndpoints())
+	assert.NotEqual(t, validChecksum, invalidChecksum, "the rejected content is remembered to not log it on every poll")
+	assert.Equal(t, invalidChecksum, endpointsFileChecksum, "an unreadable file is retried")
+}
+
+func TestWatchEndpointsFile_PicksUpChanges(t *testing.T) {
+	// Given
+	path := filepath.Join(t.TempDir(), "endpoints.yaml")
+	writeEndpointsFile(t, path, "- url: redis://a:6379\n")
+	endpointsFileChecksum = [sha256.Size]byte{}
+	reloadEndpointsFile(path)
+	ctx := t.Context()
+	go WatchEndpointsFile(ctx, path, 10*time.Millisecond)
+
+	// When
+	writeEndpointsFile(t, path, "- url: redis://b:6379\n")
+
+	// Then
+	assert.Eventually(t, func() bool {
+		endpoints := GetEndpoints()
+		return len(endpoints) == 1 && endpoints[0].URL == "redis://b:6379"
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestChangedEndpointURLs(t *testing.T) {
+	// Given
+	previous := []RedisEndpoint{
+		{URL: "redis://unchanged:6379", Name: "u"},
+		{URL: "redis://rotated:6379", Password: "old"},
+		{URL: "redis://removed:6379"},
+	}
+	current := []RedisEndpoint{
+		{URL: "redis://unchanged:6379", Name: "u"},
+		{URL: "redis://rotated:6379", Password: "new"},
+		{URL: "redis://added:6379"},
+	}
+
+	// When
+	changed := ChangedEndpointURLs(previous, current)
+
+	// Then
+	assert.Equal(t, []string{"redis://rotated:6379", "redis://removed:6379"}, changed)
+}
+
+func TestChangedEndpointURLs_ComparesSentinelByValue(t *testing.T) {
+	previous := []RedisEndpoint{{URL: "redis://m", Sentinel: &SentinelConfig{MasterName: "m", Addresses: []string{"s1:26379"}}}}
+	unchanged := []RedisEndpoint{{URL: "redis://m", Sentinel: &SentinelConfig{MasterName: "m", Addresses: []string{"s1:26379"}}}}
+	moved := []RedisEndpoint{{URL: "redis://m", Sentinel: &SentinelConfig{MasterName: "m", Addresses: []string{"s2:26379"}}}}
+
+	assert.Empty(t, ChangedEndpointURLs(previous, unchanged))
+	assert.Equal(t, []string{"redis://m"}, ChangedEndpointURLs(previous, moved))
+}
@@ -0,0 +1,212 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ErrGuardrail is wrapped by all errors of attacks refused by the guardrails of an endpoint.
+var ErrGuardrail = errors.New("blocked by endpoint guardrails")
+
+// Guardrails restrict what attacks may do to an endpoint. Attacks enforce them in Prepare, before anything is changed.
+type Guardrails struct {
+	ReadOnly                  bool     `json:"readOnly,omitempty"`                  // Refuse all attacks that change configuration, data or cluster state
+	AllowedActions            []string `json:"allowedActions,omitempty"`            // IDs of the attacks that may run, all when empty
+	ProtectedKeyPatterns      []string `json:"protectedKeyPatterns,omitempty"`      // Glob patterns of keys that attacks never touch
+	MaxAffectedKeys           int      `json:"maxAffectedKeys,omitempty"`           // Max number of keys a single attack may change, unlimited when 0
+	ForbiddenEvictionPolicies []string `json:"forbiddenEvictionPolicies,omitempty"` // Eviction policies attacks must not set
+	DryRun                    bool     `json:"dryRun,omitempty"`                    // Run all attacks as dry runs that only report what they would change
+}
+
+// GetGuardrails returns the guardrails of the endpoint, nil when none are configured. It is safe to call on a nil endpoint.
+func (e *RedisEndpoint) GetGuardrails() *Guardrails {
+	if e == nil {
+		return nil
+	}
+	return e.Guardrails
+}
+
+// HasGuardrails reports whether any configured endpoint has guardrails.
+func HasGuardrails() bool {
+	for _, endpoint := range GetEndpoints() {
+		if endpoint.Guardrails != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// IsDryRun reports whether all attacks on the endpoint run as dry runs.
+func (g *Guardrails) IsDryRun() bool {
+	return g != nil && g.DryRun
+}
+
+// CheckAction refuses attacks that are not in AllowedActions and, for read-only endpoints, attacks that mutate.
+func (g *Guardrails) CheckAction(actionID string, mutating bool) error {
+	if g == nil {
+		return nil
+	}
+	if mutating && g.ReadOnly {
+		return fmt.Errorf("%w: the endpoint is read-only (guardrails.readOnly), %s changes configuration, data or cluster state", ErrGuardrail, actionID)
+	}
+	if len(g.AllowedActions) > 0 && !slices.Contains(g.AllowedActions, actionID) {
+		return fmt.Errorf("%w: %s is not in guardrails.allowedActions", ErrGuardrail, actionID)
+	}
+	return nil
+}
+
+// ProtectedPattern returns the first protected pattern that matches key.
+func (g *Guardrails) ProtectedPattern(key string) (string, bool) {
+	if g == nil {
+		return "", false
+	}
+	for _, pattern := range g.ProtectedKeyPatterns {
+		if MatchKeyPattern(pattern, key) {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+// HasProtectedKeys reports whether any protected key patterns are configured.
+func (g *Guardrails) HasProtectedKeys() bool {
+	return g != nil && len(g.ProtectedKeyPatterns) > 0
+}
+
+// CheckProtectedKeys refuses keys that match a protected pattern.
+func (g *Guardrails) CheckProtectedKeys(keys []string) error {
+	for _, key := range keys {
+		if pattern, ok := g.ProtectedPattern(key); ok {
+			return fmt.Errorf("%w: key '%s' matches the protected pattern '%s' (guardrails.protectedKeyPatterns)", ErrGuardrail, key, pattern)
+		}
+	}
+	return nil
+}
+
+// CheckAffectedKeys refuses attacks that would change more than MaxAffectedKeys keys.
+func (g *Guardrails) CheckAffectedKeys(count int) error {
+	if g == nil || g.MaxAffectedKeys <= 0 || count <= g.MaxAffectedKeys {
+		return nil
+	}
+	return fmt.Errorf("%w: the attack would affect %d keys, at most %d are allowed (guardrails.maxAffectedKeys)", ErrGuardrail, count, g.MaxAffectedKeys)
+}
+
+// CheckEvictionPolicy refuses eviction policies listed in ForbiddenEvictionPolicies.
+func (g *Guardrails) CheckEvictionPolicy(policy string) error {
+	if g == nil || policy == "" {
+		return nil
+	}
+	for _, forbidden := range g.ForbiddenEvictionPolicies {
+		if strings.EqualFold(forbidden, policy) {
+			return fmt.Errorf("%w: eviction policy %s is forbidden (guardrails.forbiddenEvictionPolicies)", ErrGuardrail, policy)
+		}
+	}
+	return nil
+}
+
+func validateGuardrails(g *Guardrails) []error {
+	if g == nil {
+		return nil
+	}
+	var errs []error
+	if g.MaxAffectedKeys < 0 {
+		errs = append(errs, errors.New("guardrails.maxAffectedKeys must not be negative"))
+	}
+	for _, pattern := range g.ProtectedKeyPatterns {
+		if pattern == "" {
+			errs = append(errs, errors.New("guardrails.protectedKeyPatterns must not contain empty patterns"))
+		}
+	}
+	for _, id := range g.AllowedActions {
+		if id == "" {
+			errs = append(errs, errors.New("guardrails.allowedActions must not contain empty action IDs"))
+		}
+	}
+	return errs
+}
+
+// MatchKeyPattern reports whether key matches the glob pattern with the semantics of Redis KEYS and SCAN MATCH:
+// * and ? wildcards, [abc], [^abc] and [a-z] classes, and \ to escape the next character.
+func MatchKeyPattern(pattern, key string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 0 && pattern[0] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 0 {
+				return true
+			}
+			for i := 0; i <= len(key); i++ {
+				if MatchKeyPattern(pattern, key[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(key) == 0 {
+				return false
+			}
+		case '[':
+			if len(key) == 0 {
+				return false
+			}
+			var matched bool
+			matched, pattern = matchClass(pattern[1:], key[0])
+			if !matched {
+				return false
+			}
+			key = key[1:]
+			continue
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if len(key) == 0 || key[0] != pattern[0] {
+				return false
+			}
+		}
+		pattern = pattern[1:]
+		key = key[1:]
+	}
+	return len(key) == 0
+}
+
+// matchClass matches c against the character class at the start of pattern, after the opening bracket.
+// It returns the pattern after the closing bracket, an unclosed class ends with the pattern like in Redis.
+func matchClass(pattern string, c byte) (bool, string) {
+	negate := len(pattern) > 0 && pattern[0] == '^'
+	if negate {
+		pattern = pattern[1:]
+	}
+	matched := false
+	for len(pattern) > 0 && pattern[0] != ']' {
+		switch {
+		case pattern[0] == '\\' && len(pattern) > 1:
+			matched = matched || pattern[1] == c
+			pattern = pattern[2:]
+		case len(pattern) > 2 && pattern[1] == '-' && pattern[2] != ']':
+			lo, hi := pattern[0], pattern[2]
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+			matched = matched || (c >= lo && c <= hi)
+			pattern = pattern[3:]
+		default:
+			matched = matched || pattern[0] == c
+			pattern = pattern[1:]
+		}
+	}
+	if len(pattern) > 0 {
+		pattern = pattern[1:]
+	}
+	return matched != negate, pattern
+}
@@ -0,0 +1,158 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2024 Steadybit GmbH
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchKeyPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		key     string
+		want    bool
+	}{
+		{"*", "", true},
+		{"*", "anything", true},
+		{"session:*", "session:42", true},
+		{"session:*", "sessions:42", false},
+		{"*:admin", "user:admin", true},
+		{"user:*:token", "user:1/2:token", true},
+		{"user:*:token", "user:1:secret", false},
+		{"h?llo", "hello", true},
+		{"h?llo", "hllo", false},
+		{"h[ae]llo", "hallo", true},
+		{"h[ae]llo", "hillo", false},
+		{"h[^e]llo", "hallo", true},
+		{"h[^e]llo", "hello", false},
+		{"h[a-b]llo", "hbllo", true},
+		{"h[b-a]llo", "hallo", true},
+		{"h[a-b]llo", "hcllo", false},
+		{`h\*llo`, "h*llo", true},
+		{`h\*llo`, "hello", false},
+		{`h[\]]llo`, "h]llo", true},
+		{"exact", "exact", true},
+		{"exact", "exactly", false},
+		{"a*b*c", "axxbyyc", true},
+		{"a*b*c", "axxbyy", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+"/"+tt.key, func(t *testing.T) {
+			assert.Equal(t, tt.want, MatchKeyPattern(tt.pattern, tt.key))
+		})
+	}
+}
+
+func TestGuardrails_Nil(t *testing.T) {
+	var endpoint *RedisEndpoint
+	guardrails := endpoint.GetGuardrails()
+
+	assert.Nil(t, guardrails)
+	assert.NoError(t, guardrails.CheckAction("any", true))
+	assert.NoError(t, guardrails.CheckProtectedKeys([]string{"key"}))
+	assert.NoError(t, guardrails.CheckAffectedKeys(1_000_000))
+	assert.NoError(t, guardrails.CheckEvictionPolicy("allkeys-random"))
+	assert.False(t, guardrails.HasProtectedKeys())
+	assert.False(t, guardrails.IsDryRun())
+}
+
+func TestGuardrails_CheckAction(t *testing.T) {
+	tests := []struct {
+		name       string
+		guardrails Guardrails
+		actionID   string
+		mutating   bool
+		wantErr    string
+	}{
+		{name: "no restrictions", actionID: "attack", mutating: true},
+		{name: "read-only refuses mutating", guardrails: Guardrails{ReadOnly: true}, actionID: "attack", mutating: true, wantErr: "read-only"},
+		{name: "read-only allows non-mutating", guardrails: Guardrails{ReadOnly: true}, actionID: "attack", mutating: false},
+		{name: "allowed action", guardrails: Guardrails{AllowedActions: []string{"a", "b"}}, actionID: "b", mutating: true},
+		{name: "action not allowed", guardrails: Guardrails{AllowedActions: []string{"a"}}, actionID: "b", mutating: false, wantErr: "allowedActions"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.guardrails.CheckAction(tt.actionID, tt.mutating)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, ErrGuardrail)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestGuardrails_Keys(t *testing.T) {
+	// Given
+	guardrails := &Guardrails{ProtectedKeyPatterns: []string{"session:*", "config"}, MaxAffectedKeys: 2}
+
+	// Then
+	assert.True(t, guardrails.HasProtectedKeys())
+	assert.NoError(t, guardrails.CheckProtectedKeys([]string{"cache:1", "configs"}))
+	err := guardrails.CheckProtectedKeys([]string{"cache:1", "session:9"})
+	require.ErrorIs(t, err, ErrGuardrail)
+	assert.Contains(t, err.Error(), "'session:9' matches the protected pattern 'session:*'")
+
+	assert.NoError(t, guardrails.CheckAffectedKeys(2))
+	err = guardrails.CheckAffectedKeys(3)
+	require.ErrorIs(t, err, ErrGuardrail)
+	assert.Contains(t, err.Error(), "at most 2")
+}
+
+func TestGuardrails_CheckEvictionPolicy(t *testing.T) {
+	guardrails := &Guardrails{ForbiddenEvictionPolicies: []string{"allkeys-random"}}
+
+	assert.NoError(t, guardrails.CheckEvictionPolicy(""))
+	assert.NoError(t, guardrails.CheckEvictionPolicy("allkeys-lru"))
+	assert.ErrorIs(t, guardrails.CheckEvictionPolicy("ALLKEYS-RANDOM"), ErrGuardrail)
+}
+
+func TestValidateEndpoints_Guardrails(t *testing.T) {
+	// When
+	err := ValidateEndpoints([]RedisEndpoint{{
+		URL: "redis://localhost:6379",
+		Guardrails: &Guardrails{
+			MaxAffectedKeys:      -1,
+			ProtectedKeyPatterns: []string{""},
+			AllowedActions:       []string{""},
+		},
+	}})
+
+	// Then
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "maxAffectedKeys must not be negative")
+	assert.Contains(t, err.Error(), "protectedKeyPatterns must not contain empty patterns")
+	assert.Contains(t, err.Error(), "allowedActions must not contain empty action IDs")
+}
+
+func TestParseEndpoints_Guardrails(t *testing.T) {
+	// When
+	endpoints, err := ParseEndpoints([]byte(`
+- url: redis://prod:6379
+  guardrails:
+    readOnly: true
+    allowedActions: [com.steadybit.extension_redis.instance.client-pause]
+    protectedKeyPatterns: ["session:*"]
+    maxAffectedKeys: 500
+    forbiddenEvictionPolicies: [noeviction]
+    dryRun: true
+`))
+
+	// Then
+	require.NoError(t, err)
+	require.Len(t, endpoints, 1)
+	require.NotNil(t, endpoints[0].Guardrails)
+	assert.Equal(t, Guardrails{
+		ReadOnly:                  true,
+		AllowedActions:            []string{"com.steadybit.extension_redis.instance.client-pause"},
+		ProtectedKeyPatterns:      []string{"session:*"},
+		MaxAffectedKeys:           500,
+		ForbiddenEvictionPolicies: []string{"noeviction"},
+		DryRun:                    true,
+	}, *endpoints[0].Guardrails)
+}
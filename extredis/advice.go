@@ -0,0 +1,237 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package extredis
+
+import (
+	"fmt"
+
+	"github.com/steadybit/discovery-kit/go/discovery_kit_api"
+	"github.com/steadybit/extension-kit/extbuild"
+	"github.com/steadybit/extension-kit/exthttp"
+)
+
+const (
+	adviceIDNoReplicas         = "com.steadybit.extension_redis.advice.no-replicas"
+	adviceIDMaxmemoryUnset     = "com.steadybit.extension_redis.advice.maxmemory-unset"
+	adviceIDNoEvictionCache    = "com.steadybit.extension_redis.advice.noeviction-cache"
+	adviceIDNoPersistence      = "com.steadybit.extension_redis.advice.no-persistence"
+	adviceIDMinReplicasToWrite = "com.steadybit.extension_redis.advice.min-replicas-to-write"
+	adviceIDClusterFullCover   = "com.steadybit.extension_redis.advice.cluster-require-full-coverage"
+
+	// Experiments of the advice target the advised instance or its database 0
+	adviceInstanceQuery = `redis.url="${target.attr.redis.url}"`
+	adviceDatabaseQuery = `redis.url="${target.attr.redis.url}" AND redis.database.index="0"`
+	// Cluster experiments target the shard of the advised master or the masters of the other shards
+	adviceShardQuery        = `redis.cluster.shard_id="${target.attr.redis.cluster.shard_id}"`
+	adviceOtherMastersQuery = `redis.endpoint.url="${target.attr.redis.endpoint.url}" AND redis.role="master" AND redis.cluster.shard_id!="${target.attr.redis.cluster.shard_id}"`
+)
+
+func instanceQuery(conditions string) string {
+	return fmt.Sprintf(`target.type="%s" AND %s`, TargetTypeInstance, conditions)
+}
+
+// GetAdviceList returns the endpoints of all advice definitions.
+func GetAdviceList() AdviceList {
+	list := AdviceList{Advice: make([]discovery_kit_api.DescribingEndpointReference, 0)}
+	for _, advice := range adviceDefinitions() {
+		list.Advice = append(list.Advice, discovery_kit_api.DescribingEndpointReference{
+			Method: "GET",
+			Path:   advicePath(advice.Id),
+		})
+	}
+	return list
+}
+
+// RegisterAdviceHandlers registers the HTTP handlers of GetAdviceList.
+func RegisterAdviceHandlers() {
+	for _, advice := range adviceDefinitions() {
+		exthttp.RegisterHttpHandler(advicePath(advice.Id), exthttp.GetterAsHandler(func() AdviceDefinition {
+			return advice
+		}))
+	}
+}
+
+func advicePath(id string) string {
+	return "/advice/" + id
+}
+
+func adviceDefinitions() []AdviceDefinition {
+	return []AdviceDefinition{
+		noReplicasAdvice(),
+		maxmemoryUnsetAdvice(),
+		noEvictionCacheAdvice(),
+		noPersistenceAdvice(),
+		minReplicasToWriteAdvice(),
+		clusterFullCoverageAdvice(),
+	}
+}
+
+func newAdvice(id, label, applicable, actionNeeded string, description AdviceActionNeededMarkdown, validation AdviceValidation) AdviceDefinition {
+	return AdviceDefinition{
+		Id:                        id,
+		Label:                     label,
+		Version:                   extbuild.GetSemverVersionStringOrUnknown(),
+		Icon:                      redisIcon,
+		Tags:                      new([]string{"redis"}),
+		AssessmentQueryApplicable: applicable,
+		Status: AdviceDefinitionStatus{
+			ActionNeeded: AdviceDefinitionStatusActionNeeded{
+				AssessmentQuery: actionNeeded,
+				Description:     description,
+			},
+			ValidationNeeded: AdviceDefinitionStatusValidationNeeded{
+				Description: AdviceSummaryMarkdown{Summary: "Validate with an experiment that the instance behaves as expected."},
+				Validation:  new([]AdviceValidation{validation}),
+			},
+			Implemented: AdviceDefinitionStatusImplemented{
+				Description: AdviceSummaryMarkdown{Summary: "The instance follows this advice and the experiment validated it."},
+			},
+		},
+	}
+}
+
+// experimentValidation runs attack and check in parallel lanes.
+func experimentValidation(id, name, shortDescription, hypothesis string, attack, check AdviceExperimentStep) AdviceValidation {
+	return AdviceValidation{
+		Id:               id,
+		Name:             name,
+		Type:             AdviceValidationTypeExperiment,
+		ShortDescription: shortDescription,
+		Experiment: &AdviceExperiment{
+			Name:       name,
+			Hypothesis: hypothesis,
+			Lanes: []AdviceExperimentLane{
+				{Steps: []AdviceExperimentStep{attack}},
+				{Steps: []AdviceExperimentStep{check}},
+			},
+		},
+	}
+}
+
+func adviceStep(actionType, targetType, query, label string, parameters map[string]any) AdviceExperimentStep {
+	return AdviceExperimentStep{
+		Type:        "action",
+		ActionType:  actionType,
+		CustomLabel: label,
+		Parameters:  parameters,
+		Radius: &AdviceExperimentRadius{
+			TargetType: targetType,
+			Query:      query,
+			Percentage: 100,
+		},
+	}
+}
+
+func instanceStep(actionType, label string, parameters map[string]any) AdviceExperimentStep {
+	return adviceStep(actionType, TargetTypeInstance, adviceInstanceQuery, label, parameters)
+}
+
+func noReplicasAdvice() AdviceDefinition {
+	return newAdvice(adviceIDNoReplicas, "Redis master without replicas",
+		instanceQuery(AttrRedisRole+`="master"`),
+		AttrRedisConnectedReplicas+`="0"`,
+		AdviceActionNeededMarkdown{
+			Summary:     "The Redis master has no connected replicas.",
+			Motivation:  "Without a replica, a crash of the master loses all data written since the last persistence and the instance stays unavailable until it is restarted. Replicas allow a failover through Sentinel or Redis Cluster and can serve reads.",
+			Instruction: "Add at least one replica with `replicaof <master-host> <master-port>`, ideally in another availability zone, and let Sentinel or Redis Cluster promote it when the master fails.",
+		},
+		experimentValidation("redis-replication-under-write-pause", "Replication recovers after a write pause",
+			"Pause writes on the master and check that the replicas catch up",
+			"When writes to the master are paused, the replication link stays up and the replication lag recovers after the pause.",
+			instanceStep(clientPauseActionID, "Pause writes", map[string]any{"duration": "30s", "pauseMode": "WRITE"}),
+			instanceStep(replicationCheckActionID, "Check replication lag", map[string]any{"duration": "60s", "maxLagSeconds": "10", "requireLinkUp": true}),
+		),
+	)
+}
+
+func maxmemoryUnsetAdvice() AdviceDefinition {
+	return newAdvice(adviceIDMaxmemoryUnset, "Redis without memory limit",
+		instanceQuery(AttrRedisRole+`="master"`),
+		AttrRedisMemoryMax+`="0"`,
+		AdviceActionNeededMarkdown{
+			Summary:     "`maxmemory` is not set, Redis grows until the host or container runs out of memory.",
+			Motivation:  "Without a limit, Redis is killed by the OOM killer or the container runtime instead of evicting keys or rejecting writes, and all clients lose their connection at once.",
+			Instruction: "Set `maxmemory` to about 75% of the memory available to Redis, leaving room for replication buffers and forks, and pick a `maxmemory-policy` that fits the usage.",
+		},
+		experimentValidation("redis-memory-limit", "Memory limit is handled gracefully",
+			"Lower maxmemory and check the latency",
+			"When the memory limit is reached, Redis evicts or rejects writes without affecting the latency of reads.",
+			instanceStep(maxmemoryLimitActionID, "Limit memory", map[string]any{"duration": "60s", "maxmemory": "10mb", "evictionPolicy": "allkeys-lru"}),
+			instanceStep(latencyCheckActionID, "Check latency", map[string]any{"duration": "60s", "maxLatencyMs": "100"}),
+		),
+	)
+}
+
+func noEvictionCacheAdvice() AdviceDefinition {
+	return newAdvice(adviceIDNoEvictionCache, "Redis cache without eviction",
+		instanceQuery(AttrRedisUsage+`="cache"`),
+		AttrRedisMemoryPolicy+`="noeviction"`,
+		AdviceActionNeededMarkdown{
+			Summary:     "Most keys of this instance expire, it is used as a cache, but `maxmemory-policy` is `noeviction`.",
+			Motivation:  "With `noeviction`, a full cache rejects all writes with an OOM error instead of dropping the least used entries, which turns a cache that is too small into an outage.",
+			Instruction: "Set `maxmemory-policy` to `allkeys-lru` or `allkeys-lfu`, or `volatile-lru` if keys without TTL must be kept.",
+		},
+		experimentValidation("redis-cache-full", "Full cache keeps accepting writes",
+			"Lower maxmemory with an LRU eviction policy and check the latency",
+			"When the cache is full, Redis evicts the least recently used entries and the application keeps working with a lower hit rate.",
+			instanceStep(maxmemoryLimitActionID, "Fill cache", map[string]any{"duration": "60s", "maxmemory": "10mb", "evictionPolicy": "allkeys-lru"}),
+			instanceStep(latencyCheckActionID, "Check latency", map[string]any{"duration": "60s", "maxLatencyMs": "100"}),
+		),
+	)
+}
+
+func noPersistenceAdvice() AdviceDefinition {
+	return newAdvice(adviceIDNoPersistence, "Redis store without persistence",
+		instanceQuery(AttrRedisRole+`="master" AND `+AttrRedisUsage+`="store"`),
+		AttrRedisPersistence+`="none"`,
+		AdviceActionNeededMarkdown{
+			Summary:     "Most keys of this instance never expire, it is used as a store, but neither RDB snapshots nor AOF are enabled.",
+			Motivation:  "A restart of the instance, or of a master without replicas, loses all data.",
+			Instruction: "Enable AOF with `appendonly yes` and `appendfsync everysec`, or RDB snapshots with `save`, and keep the files on a persistent volume.",
+		},
+		experimentValidation("redis-data-loss", "Application survives lost keys",
+			"Expire keys of the database and check that the application recovers",
+			"When keys are lost, the application rebuilds or reports them, and the keys are restored after the experiment.",
+			adviceStep(cacheExpirationActionID, TargetTypeDatabase, adviceDatabaseQuery, "Lose keys", map[string]any{"duration": "60s", "pattern": "*", "ttl": "5", "maxKeys": "100", "restoreOnStop": true}),
+			instanceStep(latencyCheckActionID, "Check latency", map[string]any{"duration": "60s", "maxLatencyMs": "100"}),
+		),
+	)
+}
+
+func minReplicasToWriteAdvice() AdviceDefinition {
+	return newAdvice(adviceIDMinReplicasToWrite, "Redis master accepts writes without replicas",
+		instanceQuery(AttrRedisRole+`="master"`),
+		AttrRedisMinReplicasToWrite+`="0"`,
+		AdviceActionNeededMarkdown{
+			Summary:     "`min-replicas-to-write` is 0, the master keeps accepting writes when it lost all replicas.",
+			Motivation:  "A master that is cut off from its replicas keeps accepting writes, which are lost when a replica is promoted in the meantime.",
+			Instruction: "Set `min-replicas-to-write` to at least 1 and `min-replicas-max-lag` to a few seconds, so an isolated master stops accepting writes.",
+		},
+		experimentValidation("redis-replica-disconnect", "Replica disconnects are detected",
+			"Disconnect the replicas through their output buffer limit and check the replication",
+			"When the replicas are disconnected, the master stops accepting writes and the replicas resync after the experiment.",
+			instanceStep(outputBufferLimitActionID, "Disconnect replicas", map[string]any{"duration": "60s", "clientClass": outputBufferClassReplica, "hardLimit": "256kb"}),
+			instanceStep(replicationCheckActionID, "Check replication lag", map[string]any{"duration": "90s", "maxLagSeconds": "10"}),
+		),
+	)
+}
+
+func clusterFullCoverageAdvice() AdviceDefinition {
+	return newAdvice(adviceIDClusterFullCover, "Redis Cluster stops on uncovered slots",
+		instanceQuery(AttrRedisClusterMode+`="1" AND `+AttrRedisRole+`="master"`),
+		AttrRedisClusterRequireFullCoverage+`="yes"`,
+		AdviceActionNeededMarkdown{
+			Summary:     "`cluster-require-full-coverage` is `yes`, the whole cluster stops serving when a single slot is not covered.",
+			Motivation:  "When a shard fails without a replica to take over, all shards reject commands, including the ones for keys that are still available.",
+			Instruction: "Decide whether partial availability is acceptable for the application. If so, set `cluster-require-full-coverage no` on all nodes, and consider `cluster-allow-reads-when-down yes`.",
+		},
+		experimentValidation("redis-cluster-shard-stop", "Cluster serves without a shard",
+			"Stop all nodes of a shard and check the latency of the other masters",
+			"When a shard fails, the masters of the other shards keep serving their slots.",
+			adviceStep(clusterNodeStopActionID, TargetTypeInstance, adviceShardQuery, "Stop the shard", map[string]any{"duration": "60s"}),
+			adviceStep(latencyCheckActionID, TargetTypeInstance, adviceOtherMastersQuery, "Check latency of the other masters", map[string]any{"duration": "60s", "maxLatencyMs": "100"}),
+		),
+	)
+}
@@ -0,0 +1,102 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package extredis
+
+import "github.com/steadybit/discovery-kit/go/discovery_kit_api"
+
+// The types below mirror the JSON of the Steadybit advice API, which the platform reads from the extension list and
+// the advice endpoints.
+
+// AdviceList lists the endpoints of all advice definitions.
+type AdviceList struct {
+	Advice []discovery_kit_api.DescribingEndpointReference `json:"advice"`
+}
+
+// AdviceDefinition describes a piece of advice, evaluated by the platform against the discovered targets.
+type AdviceDefinition struct {
+	Id      string    `json:"id"`
+	Label   string    `json:"label"`
+	Version string    `json:"version"`
+	Icon    string    `json:"icon"`
+	Tags    *[]string `json:"tags,omitempty"`
+	// AssessmentQueryApplicable selects the targets the advice applies to
+	AssessmentQueryApplicable string                 `json:"assessmentQueryApplicable"`
+	Status                    AdviceDefinitionStatus `json:"status"`
+}
+
+type AdviceDefinitionStatus struct {
+	ActionNeeded     AdviceDefinitionStatusActionNeeded     `json:"actionNeeded"`
+	ValidationNeeded AdviceDefinitionStatusValidationNeeded `json:"validationNeeded"`
+	Implemented      AdviceDefinitionStatusImplemented      `json:"implemented"`
+}
+
+// AdviceDefinitionStatusActionNeeded applies to the targets matching AssessmentQuery, the other applicable targets
+// need a validation.
+type AdviceDefinitionStatusActionNeeded struct {
+	AssessmentQuery string                     `json:"assessmentQuery"`
+	Description     AdviceActionNeededMarkdown `json:"description"`
+}
+
+type AdviceActionNeededMarkdown struct {
+	Instruction string `json:"instruction"`
+	Motivation  string `json:"motivation"`
+	Summary     string `json:"summary"`
+}
+
+type AdviceDefinitionStatusValidationNeeded struct {
+	Description AdviceSummaryMarkdown `json:"description"`
+	Validation  *[]AdviceValidation   `json:"validation,omitempty"`
+}
+
+type AdviceDefinitionStatusImplemented struct {
+	Description AdviceSummaryMarkdown `json:"description"`
+}
+
+type AdviceSummaryMarkdown struct {
+	Summary string `json:"summary"`
+}
+
+type AdviceValidationType string
+
+const (
+	AdviceValidationTypeExperiment AdviceValidationType = "EXPERIMENT"
+	AdviceValidationTypeText       AdviceValidationType = "TEXT"
+)
+
+// AdviceValidation is an experiment, or a text to confirm, that validates the advice was followed.
+type AdviceValidation struct {
+	Id               string               `json:"id"`
+	Name             string               `json:"name"`
+	Type             AdviceValidationType `json:"type"`
+	ShortDescription string               `json:"shortDescription"`
+	Description      *string              `json:"description,omitempty"`
+	Experiment       *AdviceExperiment    `json:"experiment,omitempty"`
+}
+
+// AdviceExperiment is an experiment template. ${target.attr.<name>} placeholders are replaced with the attributes of
+// the advised target.
+type AdviceExperiment struct {
+	Name       string                 `json:"name"`
+	Hypothesis string                 `json:"hypothesis"`
+	Lanes      []AdviceExperimentLane `json:"lanes"`
+}
+
+type AdviceExperimentLane struct {
+	Steps []AdviceExperimentStep `json:"steps"`
+}
+
+type AdviceExperimentStep struct {
+	Type        string                  `json:"type"`
+	ActionType  string                  `json:"actionType"`
+	CustomLabel string                  `json:"customLabel,omitempty"`
+	Parameters  map[string]any          `json:"parameters"`
+	Radius      *AdviceExperimentRadius `json:"radius,omitempty"`
+}
+
+type AdviceExperimentRadius struct {
+	TargetType string `json:"targetType"`
+	Query      string `json:"query"`
+	Percentage int    `json:"percentage"`
+}
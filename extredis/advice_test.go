@@ -0,0 +1,89 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2024 Steadybit GmbH
+
+package extredis
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetAdviceList(t *testing.T) {
+	// When
+	list := GetAdviceList()
+
+	// Then
+	require.Len(t, list.Advice, len(adviceDefinitions()))
+	for _, ref := range list.Advice {
+		assert.Equal(t, "GET", string(ref.Method))
+		assert.True(t, strings.HasPrefix(ref.Path, "/advice/com.steadybit.extension_redis.advice."))
+	}
+}
+
+func TestAdviceDefinitions(t *testing.T) {
+	actionIDs := map[string]bool{}
+	for _, id := range []string{
+		clientPauseActionID, maxmemoryLimitActionID, cacheExpirationActionID, outputBufferLimitActionID,
+		sentinelStopActionID, slotMigrationActionID, clusterNodeStopActionID, latencyCheckActionID, replicationCheckActionID,
+	} {
+		actionIDs[id] = true
+	}
+
+	ids := map[string]bool{}
+	for _, advice := range adviceDefinitions() {
+		t.Run(advice.Id, func(t *testing.T) {
+			assert.False(t, ids[advice.Id], "duplicate advice id")
+			ids[advice.Id] = true
+			assert.Contains(t, advice.AssessmentQueryApplicable, TargetTypeInstance)
+			assert.NotEmpty(t, advice.Status.ActionNeeded.AssessmentQuery)
+			assert.NotEmpty(t, advice.Status.ActionNeeded.Description.Summary)
+			assert.NotEmpty(t, advice.Status.ActionNeeded.Description.Motivation)
+			assert.NotEmpty(t, advice.Status.ActionNeeded.Description.Instruction)
+
+			// Every advice links to an experiment made from the actions of this extension
+			require.NotNil(t, advice.Status.ValidationNeeded.Validation)
+			require.Len(t, *advice.Status.ValidationNeeded.Validation, 1)
+			validation := (*advice.Status.ValidationNeeded.Validation)[0]
+			assert.Equal(t, AdviceValidationTypeExperiment, validation.Type)
+			require.NotNil(t, validation.Experiment)
+			for _, lane := range validation.Experiment.Lanes {
+				for _, step := range lane.Steps {
+					assert.True(t, actionIDs[step.ActionType], "unknown action %s", step.ActionType)
+					assert.Contains(t, step.Radius.Query, "${target.attr.redis.")
+				}
+			}
+		})
+	}
+}
+
+func TestClusterFullCoverageAdvice_StopsTheShardAndChecksTheOtherMasters(t *testing.T) {
+	// When
+	experiment := (*clusterFullCoverageAdvice().Status.ValidationNeeded.Validation)[0].Experiment
+
+	// Then - all nodes of the shard stop, so its slots are uncovered until it recovers
+	require.NotNil(t, experiment)
+	attack := experiment.Lanes[0].Steps[0]
+	assert.Equal(t, clusterNodeStopActionID, attack.ActionType)
+	assert.Equal(t, `redis.cluster.shard_id="${target.attr.redis.cluster.shard_id}"`, attack.Radius.Query)
+	assert.Equal(t, 100, attack.Radius.Percentage)
+	check := experiment.Lanes[1].Steps[0]
+	assert.Equal(t, latencyCheckActionID, check.ActionType)
+	assert.Contains(t, check.Radius.Query, `redis.cluster.shard_id!="${target.attr.redis.cluster.shard_id}"`)
+}
+
+func TestAdviceDefinition_JSON(t *testing.T) {
+	// When
+	data, err := json.Marshal(noReplicasAdvice())
+
+	// Then
+	require.NoError(t, err)
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, adviceIDNoReplicas, decoded["id"])
+	status := decoded["status"].(map[string]any)
+	assert.Equal(t, AttrRedisConnectedReplicas+`="0"`, status["actionNeeded"].(map[string]any)["assessmentQuery"])
+}
@@ -16,10 +16,14 @@ import (
 	"github.com/steadybit/action-kit/go/action_kit_sdk"
 	"github.com/steadybit/extension-kit/extbuild"
 	"github.com/steadybit/extension-kit/extutil"
+	"github.com/steadybit/extension-redis/backupstore"
 	"github.com/steadybit/extension-redis/clients"
 	"github.com/steadybit/extension-redis/config"
+	"github.com/steadybit/extension-redis/journal"
 )
 
+const cacheExpirationActionID = "com.steadybit.extension_redis.database.cache-expiration"
+
 type cacheExpirationAttack struct{}
 
 type KeyBackup struct {
@@ -29,7 +33,8 @@ type KeyBackup struct {
 
 type CacheExpirationState struct {
 	RedisURL         string               `json:"redisUrl"`
-	Password         string               `json:"password"`
+	DryRun           bool                 `json:"dryRun"`
+	ExecutionID      string               `json:"executionId"`
 	DB               int                  `json:"db"`
 	Pattern          string               `json:"pattern"`
 	MaxKeys          int                  `json:"maxKeys"`
@@ -40,9 +45,33 @@ type CacheExpirationState struct {
 	RestoreOnStop    bool                 `json:"restoreOnStop"`
 	EndTime          int64                `json:"endTime"`
 	SkippedNonString int                  `json:"skippedNonString"`
+	SkippedProtected int                  `json:"skippedProtected"`
 	ClusterMode      bool                 `json:"clusterMode"`
 	TotalBackupBytes int64                `json:"totalBackupBytes"`
 	MaxBackupBytes   int64                `json:"maxBackupBytes"`
+	Mode             string               `json:"mode"`
+	FieldPattern     string               `json:"fieldPattern"`
+	MatchedFields    map[string][]string  `json:"matchedFields,omitempty"`
+	AffectedFields   map[string][]string  `json:"affectedFields,omitempty"`
+	SkippedNonHash   int                  `json:"skippedNonHash"`
+	// HashBackupData holds the backed up fields per hash key in hash-fields mode
+	HashBackupData map[string]map[string]FieldBackup `json:"hashBackupData,omitempty"`
+	// BackupRef replaces BackupData and HashBackupData when the backup was offloaded to the backup store
+	BackupRef *backupstore.Ref `json:"backupRef,omitempty"`
+}
+
+// withoutProtectedKeys removes the keys matching a protected pattern of the guardrails and returns how many were removed.
+func withoutProtectedKeys(guardrails *config.Guardrails, keys []string) ([]string, int) {
+	if !guardrails.HasProtectedKeys() {
+		return keys, 0
+	}
+	remaining := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if _, protected := guardrails.ProtectedPattern(key); !protected {
+			remaining = append(remaining, key)
+		}
+	}
+	return remaining, len(keys) - len(remaining)
 }
 
 // lockedKeys tracks keys currently under attack to prevent parallel attacks from overlapping.
@@ -93,9 +122,9 @@ func (a *cacheExpirationAttack) NewEmptyState() CacheExpirationState {
 
 func (a *cacheExpirationAttack) Describe() action_kit_api.ActionDescription {
 	return action_kit_api.ActionDescription{
-		Id:          "com.steadybit.extension_redis.database.cache-expiration",
+		Id:          cacheExpirationActionID,
 		Label:       "Force Cache Expiration",
-		Description: "Sets TTL on string keys matching a pattern to force them to expire. Non-string keys are skipped. Optionally restores keys when attack stops.",
+		Description: "Sets TTL on string keys matching a pattern to force them to expire. Non-string keys are skipped. In Hash Fields mode, sets a per-field TTL via HEXPIRE on hash fields matching a field pattern (requires Redis 7.4+ or Valkey 9+). Optionally restores keys and fields when attack stops.",
 		Version:     extbuild.GetSemverVersionStringOrUnknown(),
 		Icon:        new(redisIcon),
 		TargetSelection: new(action_kit_api.TargetSelection{
@@ -121,14 +150,40 @@ func (a *cacheExpirationAttack) Describe() action_kit_api.ActionDescription {
 				DefaultValue: new("60s"),
 				Required:     new(true),
 			},
+			{
+				Name:         "mode",
+				Label:        "Mode",
+				Description:  new("Expire whole string keys or individual fields of hash keys"),
+				Type:         action_kit_api.ActionParameterTypeString,
+				DefaultValue: new(cacheExpirationModeKeys),
+				Required:     new(false),
+				Options: new([]action_kit_api.ParameterOption{
+					action_kit_api.ExplicitParameterOption{
+						Label: "String Keys",
+						Value: cacheExpirationModeKeys,
+					},
+					action_kit_api.ExplicitParameterOption{
+						Label: "Hash Fields (HEXPIRE)",
+						Value: cacheExpirationModeHashFields,
+					},
+				}),
+			},
 			{
 				Name:         "pattern",
 				Label:        "Key Pattern",
-				Description:  new("Pattern to match keys for expiration (e.g., 'session:*', 'cache:*'). Only string keys will be affected."),
+				Description:  new("Pattern to match keys for expiration (e.g., 'session:*', 'cache:*'). Only string keys will be affected, or hash keys in Hash Fields mode."),
 				Type:         action_kit_api.ActionParameterTypeString,
 				DefaultValue: new(""),
 				Required:     new(true),
 			},
+			{
+				Name:         "fieldPattern",
+				Label:        "Field Pattern",
+				Description:  new("Pattern to match hash fields in Hash Fields mode (e.g., 'token:*'). Ignored for string keys."),
+				Type:         action_kit_api.ActionParameterTypeString,
+				DefaultValue: new("*"),
+				Required:     new(false),
+			},
 			{
 				Name:         "ttl",
 				Label:        "TTL (seconds)",
@@ -140,7 +195,7 @@ func (a *cacheExpirationAttack) Describe() action_kit_api.ActionDescription {
 			{
 				Name:         "maxKeys",
 				Label:        "Max Keys",
-				Description:  new("Maximum number of keys to affect (0 = unlimited, use with caution)"),
+				Description:  new("Maximum number of keys to affect, hash keys in Hash Fields mode (0 = unlimited, use with caution)"),
 				Type:         action_kit_api.ActionParameterTypeInteger,
 				DefaultValue: new("100"),
 				Required:     new(true),
@@ -148,12 +203,13 @@ func (a *cacheExpirationAttack) Describe() action_kit_api.ActionDescription {
 			{
 				Name:         "restoreOnStop",
 				Label:        "Restore on Stop",
-				Description:  new("Restore expired keys with their original values and TTLs when attack stops"),
+				Description:  new("Restore expired keys or hash fields with their original values and TTLs when attack stops"),
 				Type:         action_kit_api.ActionParameterTypeBoolean,
 				DefaultValue: new("true"),
 				Required:     new(false),
 				Advanced:     new(true),
 			},
+			dryRunParameter(),
 		},
 	}
 }
@@ -163,6 +219,11 @@ func (a *cacheExpirationAttack) Prepare(ctx context.Context, state *CacheExpirat
 	if len(redisURL) == 0 {
 		return nil, fmt.Errorf("redis URL not found in target attributes")
 	}
+	dryRun := dryRunRequested(request)
+	guardrails, err := checkGuardrails(request.Target.Attributes, cacheExpirationActionID, !dryRun)
+	if err != nil {
+		return nil, err
+	}
 
 	dbIndex := request.Target.Attributes[AttrDatabaseIndex]
 	db := 0
@@ -175,15 +236,29 @@ func (a *cacheExpirationAttack) Prepare(ctx context.Context, state *CacheExpirat
 	ttl := int(extutil.ToInt64(request.Config["ttl"]))
 	maxKeys := int(extutil.ToInt64(request.Config["maxKeys"]))
 	restoreOnStop := extutil.ToBool(request.Config["restoreOnStop"])
+	mode := extutil.ToString(request.Config["mode"])
+	fieldPattern := extutil.ToString(request.Config["fieldPattern"])
 
 	if pattern == "" {
 		return nil, fmt.Errorf("pattern is required")
 	}
+	switch mode {
+	case "":
+		mode = cacheExpirationModeKeys
+	case cacheExpirationModeKeys, cacheExpirationModeHashFields:
+	default:
+		return nil, fmt.Errorf("unsupported mode %q (expected %q or %q)", mode, cacheExpirationModeKeys, cacheExpirationModeHashFields)
+	}
+	if fieldPattern == "" {
+		fieldPattern = "*"
+	}
 	if ttl < 1 {
 		ttl = 1
 	}
 
-	state.RedisURL = redisURL[0]
+	state.RedisURL = config.RedactURL(redisURL[0])
+	state.DryRun = dryRun
+	state.ExecutionID = request.ExecutionId.String()
 	state.DB = db
 	state.Pattern = pattern
 	state.TTLSeconds = ttl
@@ -193,6 +268,10 @@ func (a *cacheExpirationAttack) Prepare(ctx context.Context, state *CacheExpirat
 	state.RestoreOnStop = restoreOnStop
 	state.EndTime = time.Now().Add(time.Duration(duration) * time.Second).Unix()
 	state.SkippedNonString = 0
+	state.Mode = mode
+	state.FieldPattern = fieldPattern
+	state.AffectedFields = make(map[string][]string)
+	state.HashBackupData = make(map[string]map[string]FieldBackup)
 
 	// Detect cluster mode and set backup size limit
 	endpoint := config.GetEndpointByURL(state.RedisURL)
@@ -204,12 +283,18 @@ func (a *cacheExpirationAttack) Prepare(ctx context.Context, state *CacheExpirat
 			state.ClusterMode = isCluster
 		}
 		state.MaxBackupBytes = endpoint.GetMaxBackupSizeBytes()
+		if backupstore.Enabled() {
+			state.MaxBackupBytes = endpoint.GetMaxStoredBackupSizeBytes()
+		}
 	} else {
 		state.MaxBackupBytes = config.DefaultMaxBackupSizeBytes
+		if backupstore.Enabled() {
+			state.MaxBackupBytes = config.DefaultMaxStoredBackupSizeBytes
+		}
 	}
 
 	// Validate that pattern matches keys — fail fast in Prepare
-	client, err := clients.GetRedisClient(state.RedisURL, state.Password, state.DB)
+	client, err := clients.GetUniversalClient(state.RedisURL, "", state.DB, state.ClusterMode)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Redis client: %w", err)
 	}
@@ -242,6 +327,19 @@ func (a *cacheExpirationAttack) Prepare(ctx context.Context, state *CacheExpirat
 		return nil, fmt.Errorf("no keys found matching pattern '%s'", state.Pattern)
 	}
 
+	// Protected keys are never touched, the attack continues with the remaining keys
+	candidateKeys, state.SkippedProtected = withoutProtectedKeys(guardrails, candidateKeys)
+	if len(candidateKeys) == 0 {
+		return nil, fmt.Errorf("%w: all %d keys matching pattern '%s' are protected (guardrails.protectedKeyPatterns)", config.ErrGuardrail, state.SkippedProtected, state.Pattern)
+	}
+
+	if state.Mode == cacheExpirationModeHashFields {
+		if err := prepareHashFields(ctx, client, state, candidateKeys); err != nil {
+			return nil, err
+		}
+		return nil, guardrails.CheckAffectedKeys(len(state.MatchedKeys))
+	}
+
 	// Filter to string keys only and apply max limit
 	var stringKeys []string
 	skippedNonString := 0
@@ -265,6 +363,9 @@ func (a *cacheExpirationAttack) Prepare(ctx context.Context, state *CacheExpirat
 		return nil, fmt.Errorf("no string keys found matching pattern '%s' (found %d keys but %d were non-string types)", state.Pattern, len(candidateKeys), skippedNonString)
 	}
 
+	if err := guardrails.CheckAffectedKeys(len(stringKeys)); err != nil {
+		return nil, err
+	}
 	state.MatchedKeys = stringKeys
 	state.SkippedNonString = skippedNonString
 
@@ -278,7 +379,10 @@ func (a *cacheExpirationAttack) Prepare(ctx context.Context, state *CacheExpirat
 }
 
 func (a *cacheExpirationAttack) Start(ctx context.Context, state *CacheExpirationState) (*action_kit_api.StartResult, error) {
-	client, err := clients.GetRedisClient(state.RedisURL, state.Password, state.DB)
+	if state.DryRun {
+		return a.dryRun(ctx, state)
+	}
+	client, err := clients.GetUniversalClient(state.RedisURL, "", state.DB, state.ClusterMode)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Redis client: %w", err)
 	}
@@ -287,6 +391,10 @@ func (a *cacheExpirationAttack) Start(ctx context.Context, state *CacheExpiratio
 		return nil, fmt.Errorf("failed to ping Redis: %w", err)
 	}
 
+	if state.Mode == cacheExpirationModeHashFields {
+		return a.startHashFields(ctx, client, state)
+	}
+
 	// Keys were already scanned and validated in Prepare
 	stringKeys := state.MatchedKeys
 
@@ -335,10 +443,20 @@ func (a *cacheExpirationAttack) Start(ctx context.Context, state *CacheExpiratio
 			Int64("totalBytes", state.TotalBackupBytes).
 			Str("pattern", state.Pattern).
 			Msg("Backup phase complete: all key values and TTLs saved before modification")
+
+		if err := offloadBackup(ctx, state); err != nil {
+			return nil, err
+		}
+		if err := recordJournal(cacheExpirationActionID, state.ExecutionID, state.RedisURL, state); err != nil {
+			discardBackup(ctx, state)
+			return nil, err
+		}
 	}
 
 	// Lock keys to prevent overlapping parallel attacks
 	if err := lockKeys(stringKeys); err != nil {
+		journal.Remove(state.ExecutionID)
+		discardBackup(ctx, state)
 		return nil, err
 	}
 
@@ -360,6 +478,9 @@ func (a *cacheExpirationAttack) Start(ctx context.Context, state *CacheExpiratio
 	if state.SkippedNonString > 0 {
 		msg += fmt.Sprintf(" (skipped %d non-string keys)", state.SkippedNonString)
 	}
+	if state.SkippedProtected > 0 {
+		msg += fmt.Sprintf(" (skipped %d protected keys)", state.SkippedProtected)
+	}
 	if state.RestoreOnStop {
 		msg += ". Keys will be restored on stop."
 	}
@@ -374,12 +495,80 @@ func (a *cacheExpirationAttack) Start(ctx context.Context, state *CacheExpiratio
 	}, nil
 }
 
+// dryRun reports the keys Start would expire and the size of their backup.
+func (a *cacheExpirationAttack) dryRun(ctx context.Context, state *CacheExpirationState) (*action_kit_api.StartResult, error) {
+	client, err := clients.GetUniversalClient(state.RedisURL, "", state.DB, state.ClusterMode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Redis client: %w", err)
+	}
+	if err := clients.PingRedis(ctx, client); err != nil {
+		return nil, fmt.Errorf("failed to ping Redis: %w", err)
+	}
+
+	if state.Mode == cacheExpirationModeHashFields {
+		return dryRunHashFields(ctx, client, state)
+	}
+
+	plan := &dryRunPlan{}
+	plan.info("would set a TTL of %d seconds on %d string keys matching pattern '%s' (skipped %d non-string and %d protected keys): %s",
+		state.TTLSeconds, len(state.MatchedKeys), state.Pattern, state.SkippedNonString, state.SkippedProtected, formatList(state.MatchedKeys))
+
+	if !state.RestoreOnStop {
+		plan.warn("restoreOnStop is disabled, expired keys are lost")
+		return plan.startResult(), nil
+	}
+	var backupBytes int64
+	for _, key := range state.MatchedKeys {
+		size, err := client.StrLen(ctx, key).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get size of key '%s': %w", key, err)
+		}
+		backupBytes += size
+	}
+	addBackupSizeToPlan(plan, backupBytes, state.MaxBackupBytes)
+	return plan.startResult(), nil
+}
+
+// addBackupSizeToPlan reports the backup size of a dry run, or that the real attack would fail on the backup limit.
+func addBackupSizeToPlan(plan *dryRunPlan, backupBytes, maxBackupBytes int64) {
+	if maxBackupBytes > 0 && backupBytes > maxBackupBytes {
+		plan.warn("the backup of %d bytes would exceed the limit of %d MB, the attack would fail before changing anything. "+
+			"Reduce the number of affected keys or increase 'maxBackupSizeBytes' in the endpoint configuration", backupBytes, maxBackupBytes/1024/1024)
+		return
+	}
+	plan.info("would back up %d bytes (limit: %d MB) and restore them on stop", backupBytes, maxBackupBytes/1024/1024)
+	if backupstore.Enabled() {
+		plan.info("the backup would be kept in the %s backup store instead of the action state", config.Config.BackupStore)
+	}
+}
+
 func (a *cacheExpirationAttack) Status(ctx context.Context, state *CacheExpirationState) (*action_kit_api.StatusResult, error) {
+	if state.DryRun {
+		return dryRunStatusResult(), nil
+	}
 	now := time.Now().Unix()
 	completed := now >= state.EndTime
 
 	// Check how many keys still exist
-	client, err := clients.GetRedisClient(state.RedisURL, state.Password, state.DB)
+	client, err := clients.GetUniversalClient(state.RedisURL, "", state.DB, state.ClusterMode)
+
+	if state.Mode == cacheExpirationModeHashFields {
+		affectedFields := countFields(state.AffectedFields)
+		remainingFields := affectedFields
+		if err == nil {
+			remainingFields = countRemainingHashFields(ctx, client, state.AffectedFields)
+		}
+		return &action_kit_api.StatusResult{
+			Completed: completed,
+			Messages: new(append([]action_kit_api.Message{
+				{
+					Level:   extutil.Ptr(action_kit_api.Info),
+					Message: fmt.Sprintf("Cache expiration: %d/%d hash fields expired", affectedFields-remainingFields, affectedFields),
+				},
+			}, journalRecoveryMessages(state.ExecutionID)...)),
+		}, nil
+	}
+
 	remainingKeys := 0
 	if err == nil {
 		for _, key := range state.AffectedKeys {
@@ -394,20 +583,40 @@ func (a *cacheExpirationAttack) Status(ctx context.Context, state *CacheExpirati
 
 	return &action_kit_api.StatusResult{
 		Completed: completed,
-		Messages: new([]action_kit_api.Message{
+		Messages: new(append([]action_kit_api.Message{
 			{
 				Level:   extutil.Ptr(action_kit_api.Info),
 				Message: fmt.Sprintf("Cache expiration: %d/%d keys expired", expiredCount, len(state.AffectedKeys)),
 			},
-		}),
+		}, journalRecoveryMessages(state.ExecutionID)...)),
 	}, nil
 }
 
 func (a *cacheExpirationAttack) Stop(ctx context.Context, state *CacheExpirationState) (*action_kit_api.StopResult, error) {
+	if state.DryRun {
+		return dryRunStopResult(), nil
+	}
+	if recoveredByJournal(state.ExecutionID) {
+		return journalRecoveredStopResult(state.ExecutionID), nil
+	}
+	result, err := a.restore(ctx, state)
+	if err != nil {
+		// Some keys were not restored, the backup is kept for a retry of Stop or the journal
+		return nil, err
+	}
+	journal.Remove(state.ExecutionID)
+	discardBackup(ctx, state)
+	return result, nil
+}
+
+func (a *cacheExpirationAttack) restore(ctx context.Context, state *CacheExpirationState) (*action_kit_api.StopResult, error) {
 	// Always release locked keys
 	defer unlockKeys(state.AffectedKeys)
 
-	if !state.RestoreOnStop || len(state.BackupData) == 0 {
+	if err := loadBackup(ctx, state); err != nil {
+		return nil, err
+	}
+	if !state.RestoreOnStop || len(state.BackupData) == 0 && len(state.HashBackupData) == 0 {
 		return &action_kit_api.StopResult{
 			Messages: new([]action_kit_api.Message{
 				{
@@ -418,16 +627,14 @@ func (a *cacheExpirationAttack) Stop(ctx context.Context, state *CacheExpiration
 		}, nil
 	}
 
-	client, err := clients.GetRedisClient(state.RedisURL, state.Password, state.DB)
+	// An error keeps the journal entry and the backup, so that the restore can be retried
+	client, err := clients.GetUniversalClient(state.RedisURL, "", state.DB, state.ClusterMode)
 	if err != nil {
-		return &action_kit_api.StopResult{
-			Messages: new([]action_kit_api.Message{
-				{
-					Level:   extutil.Ptr(action_kit_api.Warn),
-					Message: fmt.Sprintf("Failed to connect to Redis for restore: %v", err),
-				},
-			}),
-		}, nil
+		return nil, fmt.Errorf("failed to connect to Redis for restore, no keys were restored: %w", err)
+	}
+
+	if state.Mode == cacheExpirationModeHashFields {
+		return a.stopHashFields(ctx, client, state)
 	}
 
 	// Restore backed up keys
@@ -532,3 +739,55 @@ func (a *cacheExpirationAttack) Stop(ctx context.Context, state *CacheExpiration
 		}),
 	}, nil
 }
+
+// cacheExpirationBackup is what the backup store holds for an execution.
+type cacheExpirationBackup struct {
+	Keys   map[string]KeyBackup              `json:"keys,omitempty"`
+	Fields map[string]map[string]FieldBackup `json:"fields,omitempty"`
+}
+
+// offloadBackup moves the backup from the state to the backup store, if one is configured. It must be called before
+// any key is modified.
+func offloadBackup(ctx context.Context, state *CacheExpirationState) error {
+	if !backupstore.Enabled() {
+		return nil
+	}
+	ref, err := backupstore.Save(ctx, state.ExecutionID, time.Unix(state.EndTime, 0), cacheExpirationBackup{Keys: state.BackupData, Fields: state.HashBackupData})
+	if err != nil {
+		return fmt.Errorf("failed to save backup to the %s backup store, no keys were modified: %w", config.Config.BackupStore, err)
+	}
+	log.Info().
+		Str("executionId", state.ExecutionID).
+		Int64("size", ref.Size).
+		Int64("storedSize", ref.StoredSize).
+		Int("chunks", len(ref.Chunks)).
+		Msg("Saved backup to the backup store")
+	state.BackupRef = ref
+	state.BackupData = make(map[string]KeyBackup)
+	state.HashBackupData = make(map[string]map[string]FieldBackup)
+	return nil
+}
+
+// loadBackup reads an offloaded backup back into the state before it is restored.
+func loadBackup(ctx context.Context, state *CacheExpirationState) error {
+	if state.BackupRef == nil {
+		return nil
+	}
+	var backup cacheExpirationBackup
+	if err := backupstore.Load(ctx, state.BackupRef, &backup); err != nil {
+		return fmt.Errorf("failed to load backup from the %s backup store, nothing was restored: %w", state.BackupRef.Store, err)
+	}
+	state.BackupData = backup.Keys
+	state.HashBackupData = backup.Fields
+	return nil
+}
+
+// discardBackup deletes an offloaded backup. It is only called when no key was modified or all keys were restored.
+func discardBackup(ctx context.Context, state *CacheExpirationState) {
+	if state.BackupRef == nil {
+		return
+	}
+	if err := backupstore.Delete(ctx, state.BackupRef); err != nil {
+		log.Warn().Err(err).Str("executionId", state.ExecutionID).Msg("Failed to delete backup from the backup store")
+	}
+}
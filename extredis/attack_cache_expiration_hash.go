@@ -0,0 +1,354 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package extredis
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+	"github.com/steadybit/action-kit/go/action_kit_api/v2"
+	"github.com/steadybit/extension-kit/extutil"
+	"github.com/steadybit/extension-redis/journal"
+)
+
+const (
+	cacheExpirationModeKeys       = "keys"
+	cacheExpirationModeHashFields = "hash-fields"
+)
+
+// FieldBackup is the value and TTL of a single hash field before the attack.
+type FieldBackup struct {
+	Value      string `json:"value"`
+	TTLSeconds int64  `json:"ttlSeconds"` // -1 means no TTL (persistent)
+}
+
+// prepareHashFields selects the hash keys among the candidates and the fields matching the field pattern.
+// HEXPIRE and HTTL require Redis 7.4+ or Valkey 9+, which is verified with a read-only HTTL.
+func prepareHashFields(ctx context.Context, client redis.Cmdable, state *CacheExpirationState, candidateKeys []string) error {
+	matchedFields := make(map[string][]string)
+	var hashKeys []string
+	skippedNonHash := 0
+
+	for _, key := range candidateKeys {
+		keyType, err := client.Type(ctx, key).Result()
+		if err != nil {
+			log.Warn().Err(err).Str("key", key).Msg("Failed to get key type")
+			continue
+		}
+		if keyType != "hash" {
+			skippedNonHash++
+			continue
+		}
+
+		fields, err := scanHashFields(ctx, client, key, state.FieldPattern)
+		if err != nil {
+			return err
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		matchedFields[key] = fields
+		hashKeys = append(hashKeys, key)
+		if state.MaxKeys > 0 && len(hashKeys) >= state.MaxKeys {
+			break
+		}
+	}
+
+	if len(hashKeys) == 0 {
+		return fmt.Errorf("no hash fields matching '%s' found in keys matching pattern '%s' (found %d keys, %d were not hashes)", state.FieldPattern, state.Pattern, len(candidateKeys), skippedNonHash)
+	}
+
+	if err := client.HTTL(ctx, hashKeys[0], matchedFields[hashKeys[0]][0]).Err(); err != nil {
+		return fmt.Errorf("hash field expiration is not supported by this server (requires Redis 7.4+ or Valkey 9+): %w", err)
+	}
+
+	state.MatchedKeys = hashKeys
+	state.MatchedFields = matchedFields
+	state.SkippedNonHash = skippedNonHash
+
+	log.Info().
+		Int("matchedKeys", len(hashKeys)).
+		Int("matchedFields", countFields(matchedFields)).
+		Int("skippedNonHash", skippedNonHash).
+		Str("pattern", state.Pattern).
+		Str("fieldPattern", state.FieldPattern).
+		Msg("Prepare: pattern validated, hash fields matched")
+
+	return nil
+}
+
+func scanHashFields(ctx context.Context, client redis.Cmdable, key, fieldPattern string) ([]string, error) {
+	var fields []string
+	var cursor uint64
+	for {
+		// HSCAN returns field/value pairs
+		result, next, err := client.HScan(ctx, key, cursor, fieldPattern, 100).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan fields of hash '%s': %w", key, err)
+		}
+		for i := 0; i < len(result); i += 2 {
+			fields = append(fields, result[i])
+		}
+		cursor = next
+		if cursor == 0 {
+			break
+		}
+	}
+	return fields, nil
+}
+
+// backupHashFields saves value and TTL of every matched field. It aborts before anything is modified
+// when a field cannot be read or the backup would exceed MaxBackupBytes, as Start expires every matched field.
+func backupHashFields(ctx context.Context, client redis.Cmdable, state *CacheExpirationState) error {
+	fieldCount := countFields(state.MatchedFields)
+	for _, key := range state.MatchedKeys {
+		fields := state.MatchedFields[key]
+		values, err := client.HMGet(ctx, key, fields...).Result()
+		if err != nil {
+			return fmt.Errorf("failed to get the values of the fields of hash '%s' for backup, no fields were modified: %w", key, err)
+		}
+		ttls, err := client.HTTL(ctx, key, fields...).Result()
+		if err != nil {
+			return fmt.Errorf("failed to get the TTLs of the fields of hash '%s' for backup, no fields were modified: %w", key, err)
+		}
+
+		backups := make(map[string]FieldBackup, len(fields))
+		for i, field := range fields {
+			value, ok := values[i].(string)
+			if !ok {
+				// Field was removed since Prepare
+				continue
+			}
+
+			size := int64(len(field) + len(value))
+			if state.MaxBackupBytes > 0 && state.TotalBackupBytes+size > state.MaxBackupBytes {
+				return fmt.Errorf(
+					"backup size would exceed limit: %d matching hash fields require more than %d MB of backup storage (already accumulated %d bytes, next field is %d bytes). "+
+						"No fields were modified. Reduce the number of affected keys using the 'maxKeys' parameter or a more specific pattern, "+
+						"or increase 'maxBackupSizeBytes' in the endpoint configuration",
+					fieldCount, state.MaxBackupBytes/1024/1024, state.TotalBackupBytes, size)
+			}
+			state.TotalBackupBytes += size
+
+			var ttlSeconds int64 = -1
+			if i < len(ttls) && ttls[i] > 0 {
+				ttlSeconds = ttls[i]
+			}
+			backups[field] = FieldBackup{Value: value, TTLSeconds: ttlSeconds}
+		}
+		if len(backups) > 0 {
+			state.HashBackupData[key] = backups
+		}
+	}
+
+	log.Info().
+		Int("keyCount", len(state.HashBackupData)).
+		Int64("totalBytes", state.TotalBackupBytes).
+		Str("pattern", state.Pattern).
+		Msg("Backup phase complete: all hash field values and TTLs saved before modification")
+	return nil
+}
+
+func (a *cacheExpirationAttack) startHashFields(ctx context.Context, client redis.Cmdable, state *CacheExpirationState) (*action_kit_api.StartResult, error) {
+	// Phase 1: Backup all fields BEFORE modifying anything
+	if state.RestoreOnStop {
+		if err := backupHashFields(ctx, client, state); err != nil {
+			return nil, err
+		}
+		if err := offloadBackup(ctx, state); err != nil {
+			return nil, err
+		}
+		if err := recordJournal(cacheExpirationActionID, state.ExecutionID, state.RedisURL, state); err != nil {
+			discardBackup(ctx, state)
+			return nil, err
+		}
+	}
+
+	// Lock keys to prevent overlapping parallel attacks
+	if err := lockKeys(state.MatchedKeys); err != nil {
+		journal.Remove(state.ExecutionID)
+		discardBackup(ctx, state)
+		return nil, err
+	}
+
+	// Phase 2: Apply field TTLs
+	ttlDuration := time.Duration(state.TTLSeconds) * time.Second
+	expireCount := 0
+	for _, key := range state.MatchedKeys {
+		results, err := client.HExpire(ctx, key, ttlDuration, state.MatchedFields[key]...).Result()
+		if err != nil {
+			log.Warn().Err(err).Str("key", key).Msg("Failed to set TTL on hash fields")
+			continue
+		}
+
+		var affected []string
+		for i, field := range state.MatchedFields[key] {
+			// 1 = TTL set, 2 = field deleted because the TTL is 0
+			if i < len(results) && (results[i] == 1 || results[i] == 2) {
+				affected = append(affected, field)
+			}
+		}
+		if len(affected) > 0 {
+			state.AffectedKeys = append(state.AffectedKeys, key)
+			state.AffectedFields[key] = affected
+			expireCount += len(affected)
+		}
+	}
+
+	msg := fmt.Sprintf("Set TTL of %d seconds on %d fields matching '%s' in %d hash keys matching pattern '%s'", state.TTLSeconds, expireCount, state.FieldPattern, len(state.AffectedFields), state.Pattern)
+	if state.SkippedNonHash > 0 {
+		msg += fmt.Sprintf(" (skipped %d non-hash keys)", state.SkippedNonHash)
+	}
+	if state.SkippedProtected > 0 {
+		msg += fmt.Sprintf(" (skipped %d protected keys)", state.SkippedProtected)
+	}
+	if state.RestoreOnStop {
+		msg += ". Fields will be restored on stop."
+	}
+
+	return &action_kit_api.StartResult{
+		Messages: new([]action_kit_api.Message{
+			{
+				Level:   extutil.Ptr(action_kit_api.Info),
+				Message: msg,
+			},
+		}),
+	}, nil
+}
+
+// dryRunHashFields reports the hash fields startHashFields would expire and the size of their backup.
+func dryRunHashFields(ctx context.Context, client redis.Cmdable, state *CacheExpirationState) (*action_kit_api.StartResult, error) {
+	plan := &dryRunPlan{}
+	plan.info("would set a TTL of %d seconds on %d fields matching '%s' in %d hash keys matching pattern '%s' (skipped %d non-hash and %d protected keys): %s",
+		state.TTLSeconds, countFields(state.MatchedFields), state.FieldPattern, len(state.MatchedKeys), state.Pattern, state.SkippedNonHash, state.SkippedProtected, formatList(state.MatchedKeys))
+
+	if !state.RestoreOnStop {
+		plan.warn("restoreOnStop is disabled, expired fields are lost")
+		return plan.startResult(), nil
+	}
+	var backupBytes int64
+	for _, key := range state.MatchedKeys {
+		for _, field := range state.MatchedFields[key] {
+			size, err := client.HStrLen(ctx, key, field).Result()
+			if err != nil {
+				return nil, fmt.Errorf("failed to get size of field '%s' of hash '%s': %w", field, key, err)
+			}
+			backupBytes += int64(len(field)) + size
+		}
+	}
+	addBackupSizeToPlan(plan, backupBytes, state.MaxBackupBytes)
+	return plan.startResult(), nil
+}
+
+// countRemainingHashFields returns how many of the affected fields still exist.
+func countRemainingHashFields(ctx context.Context, client redis.Cmdable, affectedFields map[string][]string) int {
+	remaining := 0
+	for key, fields := range affectedFields {
+		values, err := client.HMGet(ctx, key, fields...).Result()
+		if err != nil {
+			continue
+		}
+		for _, v := range values {
+			if v != nil {
+				remaining++
+			}
+		}
+	}
+	return remaining
+}
+
+// restoreHashFields recreates expired fields and resets the TTL of remaining ones to the backed up state.
+// It returns the number of restored fields, how many of them had expired, and the number of failures.
+func restoreHashFields(ctx context.Context, client redis.Cmdable, backupData map[string]map[string]FieldBackup) (restored, recreated, failed int) {
+	keys := make([]string, 0, len(backupData))
+	for key := range backupData {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		for field, backup := range backupData[key] {
+			exists, err := client.HExists(ctx, key, field).Result()
+			if err != nil {
+				log.Warn().Err(err).Str("key", key).Str("field", field).Msg("Failed to check hash field existence")
+				failed++
+				continue
+			}
+
+			if !exists {
+				if err := client.HSet(ctx, key, field, backup.Value).Err(); err != nil {
+					log.Warn().Err(err).Str("key", key).Str("field", field).Msg("Failed to restore hash field")
+					failed++
+					continue
+				}
+				recreated++
+			}
+
+			switch {
+			case backup.TTLSeconds > 0:
+				err = client.HExpire(ctx, key, time.Duration(backup.TTLSeconds)*time.Second, field).Err()
+			case exists:
+				// A recreated field has no TTL, only a remaining one needs HPERSIST
+				err = client.HPersist(ctx, key, field).Err()
+			}
+			if err != nil {
+				log.Warn().Err(err).Str("key", key).Str("field", field).Msg("Failed to restore hash field TTL")
+				failed++
+				continue
+			}
+			restored++
+		}
+	}
+	return restored, recreated, failed
+}
+
+func (a *cacheExpirationAttack) stopHashFields(ctx context.Context, client redis.Cmdable, state *CacheExpirationState) (*action_kit_api.StopResult, error) {
+	total := 0
+	for _, backups := range state.HashBackupData {
+		total += len(backups)
+	}
+	restored, recreated, failed := restoreHashFields(ctx, client, state.HashBackupData)
+
+	if failed > 0 {
+		log.Error().
+			Int("restoredCount", restored).
+			Int("totalFields", total).
+			Int("failed", failed).
+			Str("pattern", state.Pattern).
+			Msg("Restore phase completed with failures")
+
+		return nil, fmt.Errorf(
+			"restore failed: %d/%d hash fields could not be restored (%d recreated after expiration, %d had TTL restored). Check logs for per-field errors",
+			failed, total, recreated, restored-recreated)
+	}
+
+	log.Info().
+		Int("restoredCount", restored).
+		Int("totalFields", total).
+		Int("recreated", recreated).
+		Str("pattern", state.Pattern).
+		Msg("Restore phase complete: all hash fields restored successfully")
+
+	return &action_kit_api.StopResult{
+		Messages: new([]action_kit_api.Message{
+			{
+				Level:   extutil.Ptr(action_kit_api.Info),
+				Message: fmt.Sprintf("Restore complete: %d/%d hash fields restored (%d recreated after expiration, %d had TTL restored)", restored, total, recreated, restored-recreated),
+			},
+		}),
+	}, nil
+}
+
+func countFields(fields map[string][]string) int {
+	count := 0
+	for _, f := range fields {
+		count += len(f)
+	}
+	return count
+}
@@ -0,0 +1,343 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2024 Steadybit GmbH
+
+package extredis
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/alicebob/miniredis/v2/server"
+	"github.com/go-redis/redismock/v9"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/steadybit/action-kit/go/action_kit_api/v2"
+	"github.com/steadybit/extension-kit/extutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func hashFieldsPrepareRequest(redisURL string, cfg map[string]any) action_kit_api.PrepareActionRequestBody {
+	return extutil.JsonMangle(action_kit_api.PrepareActionRequestBody{
+		Target: &action_kit_api.Target{
+			Attributes: map[string][]string{
+				AttrRedisURL:      {redisURL},
+				AttrDatabaseIndex: {"0"},
+			},
+		},
+		Config:      cfg,
+		ExecutionId: uuid.New(),
+	})
+}
+
+// registerHashFieldTTLStubs adds HTTL and HPERSIST, which miniredis lacks. HTTL answers from ttls
+// (keyed by field, -1 when absent), HPERSIST reports success for every field.
+func registerHashFieldTTLStubs(t *testing.T, mr *miniredis.Miniredis, ttls map[string]int64) {
+	t.Helper()
+	fieldsOf := func(args []string) []string {
+		// <key> FIELDS <numfields> <field>...
+		if len(args) < 3 {
+			return nil
+		}
+		return args[3:]
+	}
+	require.NoError(t, mr.Server().Register("HTTL", func(c *server.Peer, cmd string, args []string) {
+		fields := fieldsOf(args)
+		c.WriteLen(len(fields))
+		for _, f := range fields {
+			if ttl, ok := ttls[f]; ok {
+				c.WriteInt(int(ttl))
+			} else {
+				c.WriteInt(-1)
+			}
+		}
+	}))
+	require.NoError(t, mr.Server().Register("HPERSIST", func(c *server.Peer, cmd string, args []string) {
+		fields := fieldsOf(args)
+		c.WriteLen(len(fields))
+		for range fields {
+			c.WriteInt(1)
+		}
+	}))
+}
+
+func TestCacheExpirationAttack_Prepare_InvalidMode(t *testing.T) {
+	// Given
+	action := &cacheExpirationAttack{}
+	state := CacheExpirationState{}
+
+	// When
+	_, err := action.Prepare(context.Background(), &state, hashFieldsPrepareRequest("redis://localhost:6379", map[string]any{
+		"duration": float64(30000),
+		"pattern":  "session:*",
+		"ttl":      float64(5),
+		"mode":     "lists",
+	}))
+
+	// Then
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported mode")
+}
+
+func TestCacheExpirationAttack_Prepare_HashFields_NoMatchingFields(t *testing.T) {
+	// Given
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	mr.HSet("session:1", "user", "alice")
+	mr.Set("session:2", "plain")
+
+	action := &cacheExpirationAttack{}
+	state := CacheExpirationState{}
+
+	// When
+	_, err = action.Prepare(context.Background(), &state, hashFieldsPrepareRequest(fmt.Sprintf("redis://%s", mr.Addr()), map[string]any{
+		"duration":     float64(30000),
+		"pattern":      "session:*",
+		"ttl":          float64(5),
+		"mode":         cacheExpirationModeHashFields,
+		"fieldPattern": "token:*",
+	}))
+
+	// Then
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no hash fields matching 'token:*'")
+	assert.Contains(t, err.Error(), "1 were not hashes")
+}
+
+func TestCacheExpirationAttack_Prepare_HashFields_RequiresHTTL(t *testing.T) {
+	// Given - miniredis supports HEXPIRE but not HTTL, like a server without full hash field expiration support
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	mr.HSet("session:1", "token:a", "1", "user", "alice")
+
+	action := &cacheExpirationAttack{}
+	state := CacheExpirationState{}
+
+	// When
+	_, err = action.Prepare(context.Background(), &state, hashFieldsPrepareRequest(fmt.Sprintf("redis://%s", mr.Addr()), map[string]any{
+		"duration":     float64(30000),
+		"pattern":      "session:*",
+		"ttl":          float64(5),
+		"mode":         cacheExpirationModeHashFields,
+		"fieldPattern": "token:*",
+	}))
+
+	// Then
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "requires Redis 7.4+")
+	assert.Equal(t, cacheExpirationModeHashFields, state.Mode)
+	assert.Equal(t, "token:*", state.FieldPattern)
+}
+
+func TestCacheExpirationAttack_Prepare_HashFields_SetsState(t *testing.T) {
+	// Given
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	registerHashFieldTTLStubs(t, mr, nil)
+	mr.HSet("session:1", "token:a", "1", "token:b", "2", "user", "alice")
+	mr.HSet("session:2", "user", "bob")
+	mr.Set("session:3", "plain")
+
+	action := &cacheExpirationAttack{}
+	state := CacheExpirationState{}
+
+	// When
+	_, err = action.Prepare(context.Background(), &state, hashFieldsPrepareRequest(fmt.Sprintf("redis://%s", mr.Addr()), map[string]any{
+		"duration":      float64(30000),
+		"pattern":       "session:*",
+		"ttl":           float64(5),
+		"maxKeys":       float64(10),
+		"mode":          cacheExpirationModeHashFields,
+		"fieldPattern":  "token:*",
+		"restoreOnStop": true,
+	}))
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, []string{"session:1"}, state.MatchedKeys)
+	assert.ElementsMatch(t, []string{"token:a", "token:b"}, state.MatchedFields["session:1"])
+	assert.Equal(t, 1, state.SkippedNonHash)
+}
+
+func TestBackupHashFields_RecordsValuesAndTTLs(t *testing.T) {
+	// Given
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	registerHashFieldTTLStubs(t, mr, map[string]int64{"token:b": 300})
+	mr.HSet("session:1", "token:a", "v1", "token:b", "v22")
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	state := &CacheExpirationState{
+		MatchedKeys:    []string{"session:1"},
+		MatchedFields:  map[string][]string{"session:1": {"token:a", "token:b", "token:gone"}},
+		HashBackupData: make(map[string]map[string]FieldBackup),
+		MaxBackupBytes: 1024,
+	}
+
+	// When
+	err = backupHashFields(context.Background(), client, state)
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, map[string]FieldBackup{
+		"token:a": {Value: "v1", TTLSeconds: -1},
+		"token:b": {Value: "v22", TTLSeconds: 300},
+	}, state.HashBackupData["session:1"])
+	assert.Equal(t, int64(len("token:a")+2+len("token:b")+3), state.TotalBackupBytes)
+}
+
+func TestBackupHashFields_ExceedsMaxBackupBytes(t *testing.T) {
+	// Given
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	registerHashFieldTTLStubs(t, mr, nil)
+	mr.HSet("session:1", "token:a", "a-very-long-value")
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	state := &CacheExpirationState{
+		MatchedKeys:    []string{"session:1"},
+		MatchedFields:  map[string][]string{"session:1": {"token:a"}},
+		HashBackupData: make(map[string]map[string]FieldBackup),
+		MaxBackupBytes: 10,
+	}
+
+	// When
+	err = backupHashFields(context.Background(), client, state)
+
+	// Then
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "backup size would exceed limit")
+	assert.Contains(t, err.Error(), "No fields were modified")
+}
+
+func TestBackupHashFields_FailsWhenTTLsCannotBeRead(t *testing.T) {
+	// Given - no HTTL stub, so reading the field TTLs fails
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	mr.HSet("session:1", "token:a", "v1")
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	state := &CacheExpirationState{
+		MatchedKeys:    []string{"session:1"},
+		MatchedFields:  map[string][]string{"session:1": {"token:a"}},
+		HashBackupData: make(map[string]map[string]FieldBackup),
+		MaxBackupBytes: 1024,
+	}
+
+	// When
+	err = backupHashFields(context.Background(), client, state)
+
+	// Then
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no fields were modified")
+	assert.Empty(t, state.HashBackupData)
+}
+
+func TestCacheExpirationAttack_HashFields_StartStatusStop(t *testing.T) {
+	// Given
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	registerHashFieldTTLStubs(t, mr, map[string]int64{"token:b": 600})
+	mr.HSet("session:1", "token:a", "1", "token:b", "2", "user", "alice")
+
+	action := &cacheExpirationAttack{}
+	state := CacheExpirationState{}
+	ctx := context.Background()
+	_, err = action.Prepare(ctx, &state, hashFieldsPrepareRequest(fmt.Sprintf("redis://%s", mr.Addr()), map[string]any{
+		"duration":      float64(30000),
+		"pattern":       "session:*",
+		"ttl":           float64(5),
+		"maxKeys":       float64(10),
+		"mode":          cacheExpirationModeHashFields,
+		"fieldPattern":  "token:*",
+		"restoreOnStop": true,
+	}))
+	require.NoError(t, err)
+
+	// When
+	startResult, err := action.Start(ctx, &state)
+	require.NoError(t, err)
+	mr.FastForward(10 * time.Second)
+
+	// Then
+	assert.Contains(t, (*startResult.Messages)[0].Message, "Set TTL of 5 seconds on 2 fields")
+	assert.Equal(t, []string{"session:1"}, state.AffectedKeys)
+	assert.Len(t, state.HashBackupData["session:1"], 2)
+	assert.Empty(t, mr.HGet("session:1", "token:a"))
+	assert.Equal(t, "alice", mr.HGet("session:1", "user"))
+
+	statusResult, err := action.Status(ctx, &state)
+	require.NoError(t, err)
+	assert.Contains(t, (*statusResult.Messages)[0].Message, "2/2 hash fields expired")
+
+	stopResult, err := action.Stop(ctx, &state)
+	require.NoError(t, err)
+	assert.Contains(t, (*stopResult.Messages)[0].Message, "2/2 hash fields restored (2 recreated after expiration")
+	assert.Equal(t, "1", mr.HGet("session:1", "token:a"))
+	assert.Equal(t, "2", mr.HGet("session:1", "token:b"))
+
+	// token:b got its original TTL back, token:a stays persistent
+	mr.FastForward(700 * time.Second)
+	assert.Equal(t, "1", mr.HGet("session:1", "token:a"))
+	assert.Empty(t, mr.HGet("session:1", "token:b"))
+}
+
+func TestRestoreHashFields_RestoresTTLOfRemainingFields(t *testing.T) {
+	// Given
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	registerHashFieldTTLStubs(t, mr, nil)
+	mr.HSet("session:1", "token:a", "changed")
+	mr.HSet("session:2", "token:b", "2")
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	backup := map[string]map[string]FieldBackup{
+		"session:1": {"token:a": {Value: "1", TTLSeconds: -1}},
+		"session:2": {"token:b": {Value: "2", TTLSeconds: 120}},
+	}
+
+	// When
+	restored, recreated, failed := restoreHashFields(context.Background(), client, backup)
+
+	// Then
+	assert.Equal(t, 2, restored)
+	assert.Equal(t, 0, recreated)
+	assert.Equal(t, 0, failed)
+	assert.Equal(t, "changed", mr.HGet("session:1", "token:a"))
+	mr.FastForward(121 * time.Second)
+	assert.Empty(t, mr.HGet("session:2", "token:b"))
+}
+
+func TestRestoreHashFields_CountsFailures(t *testing.T) {
+	// Given
+	client, mock := redismock.NewClientMock()
+	defer client.Close()
+
+	backup := map[string]map[string]FieldBackup{
+		"session:1": {"token:a": {Value: "1", TTLSeconds: -1}},
+	}
+	mock.ExpectHExists("session:1", "token:a").SetVal(false)
+	mock.ExpectHSet("session:1", "token:a", "1").SetErr(redis.ErrClosed)
+
+	// When
+	restored, _, failed := restoreHashFields(context.Background(), client, backup)
+
+	// Then
+	assert.Equal(t, 0, restored)
+	assert.Equal(t, 1, failed)
+}
@@ -5,14 +5,20 @@ package extredis
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/alicebob/miniredis/v2"
 	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
 	"github.com/steadybit/action-kit/go/action_kit_api/v2"
 	"github.com/steadybit/extension-kit/extutil"
+	"github.com/steadybit/extension-redis/clients"
+	"github.com/steadybit/extension-redis/config"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -565,3 +571,239 @@ func TestCacheExpirationAttack_Prepare_DefaultDB(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, 0, state.DB)
 }
+
+func TestCacheExpirationAttack_ClusterMode_UsesClusterClient(t *testing.T) {
+	// Given - miniredis answers CLUSTER NODES and CLUSTER SLOTS as a single-shard cluster
+	mr := miniredis.RunT(t)
+	mr.Set("session:1", "v1")
+	mr.Set("session:2", "v2")
+	redisURL := fmt.Sprintf("redis://%s", mr.Addr())
+
+	origEndpoints := config.Config.Endpoints
+	defer func() { config.Config.Endpoints = origEndpoints }()
+	config.Config.Endpoints = []config.RedisEndpoint{{URL: redisURL, ClusterMode: "cluster"}}
+	defer clients.EvictClients(redisURL)
+
+	action := &cacheExpirationAttack{}
+	state := CacheExpirationState{}
+	ctx := context.Background()
+	_, err := action.Prepare(ctx, &state, extutil.JsonMangle(action_kit_api.PrepareActionRequestBody{
+		Target: &action_kit_api.Target{
+			Attributes: map[string][]string{
+				AttrRedisURL:      {redisURL},
+				AttrDatabaseIndex: {"0"},
+			},
+		},
+		Config: map[string]any{
+			"duration":      float64(30000),
+			"pattern":       "session:*",
+			"ttl":           float64(5),
+			"maxKeys":       float64(10),
+			"restoreOnStop": true,
+		},
+		ExecutionId: uuid.New(),
+	}))
+	require.NoError(t, err)
+	require.True(t, state.ClusterMode)
+
+	client, err := clients.GetUniversalClient(state.RedisURL, "", state.DB, state.ClusterMode)
+	require.NoError(t, err)
+	require.IsType(t, &redis.ClusterClient{}, client)
+
+	// When
+	_, err = action.Start(ctx, &state)
+	require.NoError(t, err)
+	mr.FastForward(10 * time.Second)
+	_, err = action.Stop(ctx, &state)
+
+	// Then
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"session:1", "session:2"}, state.AffectedKeys)
+	mr.CheckGet(t, "session:1", "v1")
+	mr.CheckGet(t, "session:2", "v2")
+}
+
+func TestCacheExpirationAttack_StateHasNoCredentials(t *testing.T) {
+	// Given - the password is part of the configured URL, targets carry the redacted URL
+	mr := miniredis.RunT(t)
+	mr.RequireAuth("s3cret")
+	mr.Set("session:1", "v1")
+	configuredURL := fmt.Sprintf("redis://:s3cret@%s", mr.Addr())
+	targetURL := config.RedactURL(configuredURL)
+
+	origEndpoints := config.Config.Endpoints
+	defer func() { config.Config.Endpoints = origEndpoints }()
+	config.Config.Endpoints = []config.RedisEndpoint{{URL: configuredURL, ClusterMode: "standalone"}}
+	defer clients.EvictClients(configuredURL)
+
+	action := &cacheExpirationAttack{}
+	state := CacheExpirationState{}
+	ctx := context.Background()
+
+	// When
+	_, err := action.Prepare(ctx, &state, extutil.JsonMangle(action_kit_api.PrepareActionRequestBody{
+		Target: &action_kit_api.Target{
+			Attributes: map[string][]string{
+				AttrRedisURL:      {targetURL},
+				AttrDatabaseIndex: {"0"},
+			},
+		},
+		Config: map[string]any{
+			"duration":      float64(30000),
+			"pattern":       "session:*",
+			"ttl":           float64(5),
+			"restoreOnStop": true,
+		},
+		ExecutionId: uuid.New(),
+	}))
+	require.NoError(t, err)
+	_, err = action.Start(ctx, &state)
+	require.NoError(t, err)
+
+	// Then - the attack authenticates with the configured password, which never appears in the state
+	assert.Equal(t, []string{"session:1"}, state.AffectedKeys)
+	assert.Positive(t, mr.TTL("session:1"))
+	serialized, err := json.Marshal(state)
+	require.NoError(t, err)
+	assert.NotContains(t, string(serialized), "s3cret")
+	assert.NotContains(t, string(serialized), `"password"`)
+
+	_, err = action.Stop(ctx, &state)
+	require.NoError(t, err)
+	mr.CheckGet(t, "session:1", "v1")
+}
+
+func useDiskBackupStore(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	orig := config.Config
+	t.Cleanup(func() { config.Config = orig })
+	config.Config.BackupStore = config.BackupStoreDisk
+	config.Config.BackupStoreDir = dir
+	config.Config.BackupStoreChunkSizeBytes = 4096
+	return dir
+}
+
+func TestCacheExpirationAttack_BackupStore(t *testing.T) {
+	// Given
+	dir := useDiskBackupStore(t)
+	mr := miniredis.RunT(t)
+	keyCount := 300
+	matchedKeys := make([]string, keyCount)
+	values := make(map[string]string, keyCount)
+	for i := range keyCount {
+		key := fmt.Sprintf("stored:key:%04d", i)
+		values[key] = "value-" + uuid.NewString()
+		mr.Set(key, values[key])
+		matchedKeys[i] = key
+	}
+
+	action := &cacheExpirationAttack{}
+	state := CacheExpirationState{
+		RedisURL:       fmt.Sprintf("redis://%s", mr.Addr()),
+		ExecutionID:    uuid.NewString(),
+		Pattern:        "stored:key:*",
+		TTLSeconds:     1,
+		AffectedKeys:   []string{},
+		MatchedKeys:    matchedKeys,
+		BackupData:     make(map[string]KeyBackup),
+		RestoreOnStop:  true,
+		EndTime:        time.Now().Add(60 * time.Second).Unix(),
+		MaxBackupBytes: config.DefaultMaxStoredBackupSizeBytes,
+	}
+
+	// When
+	_, err := action.Start(context.Background(), &state)
+	require.NoError(t, err)
+
+	// Then - the state only references the backup
+	require.NotNil(t, state.BackupRef)
+	assert.Empty(t, state.BackupData)
+	assert.Greater(t, len(state.BackupRef.Chunks), 1)
+	serialized, err := json.Marshal(state)
+	require.NoError(t, err)
+	assert.NotContains(t, string(serialized), values["stored:key:0001"])
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, files, len(state.BackupRef.Chunks))
+
+	// When - keys expire and the attack stops with the state of the platform
+	mr.FastForward(2 * time.Second)
+	var stopState CacheExpirationState
+	require.NoError(t, json.Unmarshal(serialized, &stopState))
+	_, err = action.Stop(context.Background(), &stopState)
+
+	// Then
+	require.NoError(t, err)
+	for key, value := range values {
+		mr.CheckGet(t, key, value)
+	}
+	files, err = os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, files, "the backup is deleted after the restore")
+}
+
+func TestCacheExpirationAttack_BackupStore_CorruptBackup(t *testing.T) {
+	// Given
+	dir := useDiskBackupStore(t)
+	mr := miniredis.RunT(t)
+	mr.Set("stored:key", "value")
+	action := &cacheExpirationAttack{}
+	state := CacheExpirationState{
+		RedisURL:       fmt.Sprintf("redis://%s", mr.Addr()),
+		ExecutionID:    uuid.NewString(),
+		Pattern:        "stored:*",
+		TTLSeconds:     1,
+		AffectedKeys:   []string{},
+		MatchedKeys:    []string{"stored:key"},
+		BackupData:     make(map[string]KeyBackup),
+		RestoreOnStop:  true,
+		EndTime:        time.Now().Add(60 * time.Second).Unix(),
+		MaxBackupBytes: config.DefaultMaxStoredBackupSizeBytes,
+	}
+	_, err := action.Start(context.Background(), &state)
+	require.NoError(t, err)
+	chunk := filepath.Join(dir, state.ExecutionID+".0.chunk")
+	require.NoError(t, os.WriteFile(chunk, []byte("tampered"), 0o600))
+
+	// When
+	_, err = action.Stop(context.Background(), &state)
+
+	// Then - nothing is restored and the backup is kept
+	assert.ErrorContains(t, err, "chunk 0 is corrupt")
+	assert.FileExists(t, chunk)
+}
+
+func TestCacheExpirationAttack_BackupStore_KeptWhenRestoreFails(t *testing.T) {
+	// Given
+	dir := useDiskBackupStore(t)
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	mr.Set("stored:key", "value")
+	action := &cacheExpirationAttack{}
+	state := CacheExpirationState{
+		RedisURL:       fmt.Sprintf("redis://%s", mr.Addr()),
+		ExecutionID:    uuid.NewString(),
+		Pattern:        "stored:*",
+		TTLSeconds:     1,
+		AffectedKeys:   []string{},
+		MatchedKeys:    []string{"stored:key"},
+		BackupData:     make(map[string]KeyBackup),
+		RestoreOnStop:  true,
+		EndTime:        time.Now().Add(60 * time.Second).Unix(),
+		MaxBackupBytes: config.DefaultMaxStoredBackupSizeBytes,
+	}
+	_, err = action.Start(context.Background(), &state)
+	require.NoError(t, err)
+	defer clients.EvictClients(state.RedisURL)
+	mr.Close()
+
+	// When
+	_, err = action.Stop(context.Background(), &state)
+
+	// Then
+	require.Error(t, err)
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, files, len(state.BackupRef.Chunks), "the backup is kept for a retry")
+}
@@ -16,17 +16,24 @@ import (
 	"github.com/steadybit/extension-kit/extutil"
 	"github.com/steadybit/extension-redis/clients"
 	"github.com/steadybit/extension-redis/config"
+	"github.com/steadybit/extension-redis/journal"
 )
 
+const clientPauseActionID = "com.steadybit.extension_redis.instance.client-pause"
+
 type clientPauseAttack struct{}
 
 type ClientPauseState struct {
 	RedisURL    string `json:"redisUrl"`
-	Password    string `json:"password"`
+	DryRun      bool   `json:"dryRun"`
+	ExecutionID string `json:"executionId"`
 	DB          int    `json:"db"`
 	PauseMode   string `json:"pauseMode"`
 	EndTime     int64  `json:"endTime"`
 	ClusterMode bool   `json:"clusterMode"`
+	NodeAddr    string `json:"nodeAddr,omitempty"`
+	// PartialFailurePolicy decides whether a failure on some cluster masters unpauses the others
+	PartialFailurePolicy clients.PartialFailurePolicy `json:"partialFailurePolicy,omitempty"`
 }
 
 var _ action_kit_sdk.Action[ClientPauseState] = (*clientPauseAttack)(nil)
@@ -43,7 +50,7 @@ func (a *clientPauseAttack) NewEmptyState() ClientPauseState {
 
 func (a *clientPauseAttack) Describe() action_kit_api.ActionDescription {
 	return action_kit_api.ActionDescription{
-		Id:          "com.steadybit.extension_redis.instance.client-pause",
+		Id:          clientPauseActionID,
 		Label:       "Pause Clients",
 		Description: "Suspends all client command processing for a duration using CLIENT PAUSE. Can pause all commands or only write commands. Clients automatically resume when the pause expires. Combine with Latency Check to verify application timeout handling.",
 		Version:     extbuild.GetSemverVersionStringOrUnknown(),
@@ -89,6 +96,8 @@ func (a *clientPauseAttack) Describe() action_kit_api.ActionDescription {
 					},
 				}),
 			},
+			partialFailureParameter(),
+			dryRunParameter(),
 		},
 	}
 }
@@ -98,6 +107,10 @@ func (a *clientPauseAttack) Prepare(ctx context.Context, state *ClientPauseState
 	if len(redisURL) == 0 {
 		return nil, fmt.Errorf("redis URL not found in target attributes")
 	}
+	dryRun := dryRunRequested(request)
+	if _, err := checkGuardrails(request.Target.Attributes, clientPauseActionID, !dryRun); err != nil {
+		return nil, err
+	}
 
 	duration := extutil.ToInt64(request.Config["duration"]) / 1000 // Convert ms to seconds
 	pauseMode := extutil.ToString(request.Config["pauseMode"])
@@ -105,10 +118,17 @@ func (a *clientPauseAttack) Prepare(ctx context.Context, state *ClientPauseState
 	if pauseMode != "ALL" && pauseMode != "WRITE" {
 		pauseMode = "ALL"
 	}
+	policy, err := parsePartialFailurePolicy(extutil.ToString(request.Config["partialFailurePolicy"]))
+	if err != nil {
+		return nil, err
+	}
 
-	state.RedisURL = redisURL[0]
+	state.RedisURL = config.RedactURL(redisURL[0])
+	state.DryRun = dryRun
+	state.ExecutionID = request.ExecutionId.String()
 	state.DB = 0
 	state.PauseMode = pauseMode
+	state.PartialFailurePolicy = policy
 	state.EndTime = time.Now().Add(time.Duration(duration) * time.Second).Unix()
 
 	endpoint := config.GetEndpointByURL(state.RedisURL)
@@ -132,6 +152,9 @@ func (a *clientPauseAttack) Prepare(ctx context.Context, state *ClientPauseState
 }
 
 func (a *clientPauseAttack) Start(ctx context.Context, state *ClientPauseState) (*action_kit_api.StartResult, error) {
+	if state.DryRun {
+		return a.dryRun(ctx, state)
+	}
 	pauseDurationMs := (state.EndTime - time.Now().Unix()) * 1000
 	if pauseDurationMs <= 0 {
 		return nil, fmt.Errorf("pause duration must be positive")
@@ -157,33 +180,70 @@ func (a *clientPauseAttack) Start(ctx context.Context, state *ClientPauseState)
 
 	endpoint := config.GetEndpointByURL(state.RedisURL)
 	nodeCount := 1
+	var warnings []action_kit_api.Message
 	if state.ClusterMode && endpoint != nil {
-		if err := clients.ForEachMaster(ctx, endpoint, pauseNode); err != nil {
+		if err := recordJournal(clientPauseActionID, state.ExecutionID, state.RedisURL, state); err != nil {
+			return nil, err
+		}
+		changed, messages, err := applyOnMasters(ctx, endpoint, state.PartialFailurePolicy, pauseNode, unpauseNode)
+		if err != nil {
+			forgetFailedStart(state.ExecutionID, err)
 			return nil, err
 		}
-		masters, _, _ := clients.GetMasterNodes(ctx, endpoint)
-		nodeCount = len(masters)
+		nodeCount = changed
+		warnings = messages
 	} else {
-		client, err := clients.GetRedisClient(state.RedisURL, state.Password, state.DB)
+		client, addr, release, err := nodeClient(ctx, state.RedisURL, state.DB, "")
 		if err != nil {
-			return nil, fmt.Errorf("failed to create Redis client: %w", err)
+			return nil, err
+		}
+		defer release()
+		state.NodeAddr = addr
+		if err := recordJournal(clientPauseActionID, state.ExecutionID, state.RedisURL, state); err != nil {
+			return nil, err
 		}
-		if err := pauseNode(ctx, client, client.Options().Addr); err != nil {
+		if err := pauseNode(ctx, client, addr); err != nil {
+			forgetFailedStart(state.ExecutionID, err)
 			return nil, err
 		}
 	}
 
 	return &action_kit_api.StartResult{
-		Messages: new([]action_kit_api.Message{
+		Messages: new(append([]action_kit_api.Message{
 			{
 				Level:   extutil.Ptr(action_kit_api.Info),
 				Message: fmt.Sprintf("Paused Redis clients (mode: %s) for %d ms on %d node(s)", state.PauseMode, pauseDurationMs, nodeCount),
 			},
-		}),
+		}, warnings...)),
 	}, nil
 }
 
+// dryRun reports the nodes Start would pause and how many clients are connected to each of them.
+func (a *clientPauseAttack) dryRun(ctx context.Context, state *ClientPauseState) (*action_kit_api.StartResult, error) {
+	pauseDurationMs := max(state.EndTime-time.Now().Unix(), 0) * 1000
+	lines, err := dryRunOnNodes(ctx, state.RedisURL, state.DB, state.ClusterMode, func(ctx context.Context, nodeClient *redis.Client, addr string) (string, error) {
+		info, err := clients.GetRedisInfo(ctx, nodeClient, "clients")
+		if err != nil {
+			return "", fmt.Errorf("failed to get clients of %s: %w", addr, err)
+		}
+		return fmt.Sprintf("%s (%s connected clients)", addr, info["connected_clients"]), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &dryRunPlan{}
+	plan.info("would pause clients (mode: %s) for %d ms on %d node(s)", state.PauseMode, pauseDurationMs, len(lines))
+	for _, line := range lines {
+		plan.info("would execute CLIENT PAUSE on %s", line)
+	}
+	return plan.startResult(), nil
+}
+
 func (a *clientPauseAttack) Status(ctx context.Context, state *ClientPauseState) (*action_kit_api.StatusResult, error) {
+	if state.DryRun {
+		return dryRunStatusResult(), nil
+	}
 	now := time.Now().Unix()
 	completed := now >= state.EndTime
 
@@ -191,34 +251,42 @@ func (a *clientPauseAttack) Status(ctx context.Context, state *ClientPauseState)
 
 	return &action_kit_api.StatusResult{
 		Completed: completed,
-		Messages: new([]action_kit_api.Message{
+		Messages: new(append([]action_kit_api.Message{
 			{
 				Level:   extutil.Ptr(action_kit_api.Info),
 				Message: fmt.Sprintf("Client pause active (mode: %s), %d seconds remaining", state.PauseMode, remainingSeconds),
 			},
-		}),
+		}, journalRecoveryMessages(state.ExecutionID)...)),
 	}, nil
 }
 
+func unpauseNode(ctx context.Context, nodeClient *redis.Client, addr string) error {
+	return nodeClient.Do(ctx, "CLIENT", "UNPAUSE").Err()
+}
+
 func (a *clientPauseAttack) Stop(ctx context.Context, state *ClientPauseState) (*action_kit_api.StopResult, error) {
-	unpauseNode := func(ctx context.Context, nodeClient *redis.Client, addr string) error {
-		return nodeClient.Do(ctx, "CLIENT", "UNPAUSE").Err()
+	if state.DryRun {
+		return dryRunStopResult(), nil
+	}
+	if recoveredByJournal(state.ExecutionID) {
+		return journalRecoveredStopResult(state.ExecutionID), nil
 	}
-
 	endpoint := config.GetEndpointByURL(state.RedisURL)
 	if state.ClusterMode && endpoint != nil {
-		if err := clients.ForEachMaster(ctx, endpoint, unpauseNode); err != nil {
+		if _, err := clients.ForEachMaster(ctx, endpoint, unpauseNode); err != nil {
 			return nil, fmt.Errorf("failed to execute CLIENT UNPAUSE on cluster: %w", err)
 		}
 	} else {
-		client, err := clients.GetRedisClient(state.RedisURL, state.Password, state.DB)
+		client, addr, release, err := nodeClient(ctx, state.RedisURL, state.DB, state.NodeAddr)
 		if err != nil {
-			return nil, fmt.Errorf("failed to create Redis client: %w", err)
+			return nil, err
 		}
-		if err := unpauseNode(ctx, client, ""); err != nil {
+		defer release()
+		if err := unpauseNode(ctx, client, addr); err != nil {
 			return nil, fmt.Errorf("failed to execute CLIENT UNPAUSE: %w", err)
 		}
 	}
+	journal.Remove(state.ExecutionID)
 
 	return &action_kit_api.StopResult{
 		Messages: new([]action_kit_api.Message{
@@ -34,7 +34,7 @@ func TestClientPauseAttack_Describe(t *testing.T) {
 
 	// Check parameters
 	require.NotNil(t, desc.Parameters)
-	require.Len(t, desc.Parameters, 2)
+	require.Len(t, desc.Parameters, 4)
 
 	paramNames := make([]string, len(desc.Parameters))
 	for i, p := range desc.Parameters {
@@ -42,6 +42,8 @@ func TestClientPauseAttack_Describe(t *testing.T) {
 	}
 	assert.Contains(t, paramNames, "duration")
 	assert.Contains(t, paramNames, "pauseMode")
+	assert.Contains(t, paramNames, "partialFailurePolicy")
+	assert.Contains(t, paramNames, "dryRun")
 }
 
 func TestClientPauseAttack_Prepare_MissingURL(t *testing.T) {
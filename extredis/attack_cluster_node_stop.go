@@ -0,0 +1,226 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package extredis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/steadybit/action-kit/go/action_kit_api/v2"
+	"github.com/steadybit/action-kit/go/action_kit_sdk"
+	"github.com/steadybit/extension-kit/extbuild"
+	"github.com/steadybit/extension-kit/extutil"
+	"github.com/steadybit/extension-redis/clients"
+	"github.com/steadybit/extension-redis/config"
+)
+
+const clusterNodeStopActionID = "com.steadybit.extension_redis.instance.cluster-node-stop"
+
+type clusterNodeStopAttack struct{}
+
+type ClusterNodeStopState struct {
+	RedisURL    string `json:"redisUrl"`
+	EndpointURL string `json:"endpointUrl"`
+	NodeAddr    string `json:"nodeAddr"`
+	DryRun      bool   `json:"dryRun"`
+	EndTime     int64  `json:"endTime"`
+}
+
+var _ action_kit_sdk.Action[ClusterNodeStopState] = (*clusterNodeStopAttack)(nil)
+var _ action_kit_sdk.ActionWithStatus[ClusterNodeStopState] = (*clusterNodeStopAttack)(nil)
+
+func NewClusterNodeStopAttack() action_kit_sdk.Action[ClusterNodeStopState] {
+	return &clusterNodeStopAttack{}
+}
+
+func (a *clusterNodeStopAttack) NewEmptyState() ClusterNodeStopState {
+	return ClusterNodeStopState{}
+}
+
+func (a *clusterNodeStopAttack) Describe() action_kit_api.ActionDescription {
+	return action_kit_api.ActionDescription{
+		Id:          clusterNodeStopActionID,
+		Label:       "Stop Cluster Node",
+		Description: "Stops a Redis Cluster node for a specific duration using DEBUG SLEEP. The other nodes mark it as failed after cluster-node-timeout, so stopping all nodes of a shard takes its slots away",
+		Version:     extbuild.GetSemverVersionStringOrUnknown(),
+		Icon:        new(redisIcon),
+		TargetSelection: new(action_kit_api.TargetSelection{
+			TargetType: TargetTypeInstance,
+			SelectionTemplates: new([]action_kit_api.TargetSelectionTemplate{
+				{
+					Label:       "by shard",
+					Description: new("Find the master and replicas of a Redis Cluster shard"),
+					Query:       AttrRedisClusterShardID + "=\"\"",
+				},
+				{
+					Label:       "by host and port",
+					Description: new("Find a Redis Cluster node by host and port"),
+					Query:       "redis.host=\"\" AND redis.port=\"\"",
+				},
+			}),
+		}),
+		Technology:  new("Redis"),
+		Category:    new("availability"),
+		Kind:        action_kit_api.Attack,
+		TimeControl: action_kit_api.TimeControlInternal,
+		Parameters: []action_kit_api.ActionParameter{
+			{
+				Name:         "duration",
+				Label:        "Duration",
+				Description:  new("How long the node should be unresponsive. The node automatically recovers after this duration."),
+				Type:         action_kit_api.ActionParameterTypeDuration,
+				DefaultValue: new("60s"),
+				Required:     new(true),
+			},
+			dryRunParameter(),
+		},
+	}
+}
+
+func (a *clusterNodeStopAttack) Prepare(ctx context.Context, state *ClusterNodeStopState, request action_kit_api.PrepareActionRequestBody) (*action_kit_api.PrepareResult, error) {
+	redisURL := request.Target.Attributes[AttrRedisURL]
+	if len(redisURL) == 0 {
+		return nil, fmt.Errorf("redis URL not found in target attributes")
+	}
+	dryRun := dryRunRequested(request)
+	if _, err := checkGuardrails(request.Target.Attributes, clusterNodeStopActionID, !dryRun); err != nil {
+		return nil, err
+	}
+	// The node is dialed directly, with the credentials and TLS settings of its endpoint
+	endpoint := targetEndpoint(request.Target.Attributes)
+	if endpoint == nil {
+		return nil, fmt.Errorf("the cluster node does not belong to a configured endpoint")
+	}
+	host, port, err := clients.EndpointHostPort(redisURL[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis URL of the cluster node: %w", err)
+	}
+
+	duration := extutil.ToInt64(request.Config["duration"]) / 1000
+
+	state.RedisURL = config.RedactURL(redisURL[0])
+	state.EndpointURL = config.RedactURL(endpoint.URL)
+	state.NodeAddr = clients.JoinHostPort(host, port)
+	state.DryRun = dryRun
+	state.EndTime = time.Now().Add(time.Duration(duration) * time.Second).Unix()
+
+	// Validate connectivity before Start
+	client, err := state.client()
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+	if err := clients.PingRedis(ctx, client); err != nil {
+		return nil, fmt.Errorf("failed to ping Redis: %w", err)
+	}
+
+	return nil, nil
+}
+
+// client connects directly to the node, it must be closed by the caller.
+func (s *ClusterNodeStopState) client() (*redis.Client, error) {
+	endpoint := config.GetEndpointByURL(s.EndpointURL)
+	if endpoint == nil {
+		return nil, fmt.Errorf("endpoint %s of the cluster node is no longer configured", s.EndpointURL)
+	}
+	client, err := clients.CreateDirectClient(endpoint, s.NodeAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Redis client for %s: %w", s.NodeAddr, err)
+	}
+	return client, nil
+}
+
+func (a *clusterNodeStopAttack) Start(ctx context.Context, state *ClusterNodeStopState) (*action_kit_api.StartResult, error) {
+	client, err := state.client()
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	// Stopping a standalone or Sentinel-managed master would stop the whole database, use Stop Sentinel for sentinels
+	info, err := clients.GetRedisInfo(ctx, client, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify that %s is a cluster node: %w", state.NodeAddr, err)
+	}
+	if info["redis_mode"] != "cluster" {
+		return nil, fmt.Errorf("stop cluster node is only applicable to Redis Cluster nodes, %s reports redis_mode=%q", state.NodeAddr, info["redis_mode"])
+	}
+
+	sleepSeconds := state.EndTime - time.Now().Unix()
+	if sleepSeconds <= 0 {
+		return nil, fmt.Errorf("sleep duration must be positive")
+	}
+
+	if state.DryRun {
+		plan := &dryRunPlan{}
+		plan.info("would stop cluster node %s (%s) via DEBUG SLEEP for %d seconds", state.NodeAddr, info["role"], sleepSeconds)
+		return plan.startResult(), nil
+	}
+
+	// DEBUG SLEEP blocks the event loop and the cluster bus of the node, it only answers after the sleep. A timeout
+	// while waiting for the answer means the node is sleeping.
+	err = client.Do(ctx, "DEBUG", "SLEEP", sleepSeconds).Err()
+	var netErr net.Error
+	if err != nil && !(errors.As(err, &netErr) && netErr.Timeout()) {
+		return nil, fmt.Errorf("failed to execute DEBUG SLEEP: %w", err)
+	}
+
+	return &action_kit_api.StartResult{
+		Messages: new([]action_kit_api.Message{
+			{
+				Level:   extutil.Ptr(action_kit_api.Info),
+				Message: fmt.Sprintf("Cluster node %s (%s) stopped via DEBUG SLEEP for %d seconds. It will automatically recover.", state.NodeAddr, info["role"], sleepSeconds),
+			},
+		}),
+	}, nil
+}
+
+func (a *clusterNodeStopAttack) Status(ctx context.Context, state *ClusterNodeStopState) (*action_kit_api.StatusResult, error) {
+	if state.DryRun {
+		return dryRunStatusResult(), nil
+	}
+	remaining := state.EndTime - time.Now().Unix()
+
+	if remaining <= 0 {
+		// Try to ping — if it succeeds, the DEBUG SLEEP is over
+		if client, err := state.client(); err == nil {
+			defer client.Close()
+			if clients.PingRedis(ctx, client) == nil {
+				return &action_kit_api.StatusResult{
+					Completed: true,
+					Messages: new([]action_kit_api.Message{
+						{
+							Level:   extutil.Ptr(action_kit_api.Info),
+							Message: fmt.Sprintf("Cluster node %s is responsive again", state.NodeAddr),
+						},
+					}),
+				}, nil
+			}
+		}
+		return &action_kit_api.StatusResult{
+			Completed: true,
+			Messages: new([]action_kit_api.Message{
+				{
+					Level:   extutil.Ptr(action_kit_api.Warn),
+					Message: fmt.Sprintf("Expected sleep duration elapsed but cluster node %s is still unresponsive", state.NodeAddr),
+				},
+			}),
+		}, nil
+	}
+
+	return &action_kit_api.StatusResult{
+		Completed: false,
+		Messages: new([]action_kit_api.Message{
+			{
+				Level:   extutil.Ptr(action_kit_api.Info),
+				Message: fmt.Sprintf("Cluster node %s is sleeping, %d seconds remaining", state.NodeAddr, remaining),
+			},
+		}),
+	}, nil
+}
@@ -0,0 +1,142 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2024 Steadybit GmbH
+
+package extredis
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/alicebob/miniredis/v2/server"
+	"github.com/google/uuid"
+	"github.com/steadybit/action-kit/go/action_kit_api/v2"
+	"github.com/steadybit/extension-kit/extutil"
+	"github.com/steadybit/extension-redis/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runClusterNode starts a miniredis that reports redisMode in INFO and counts DEBUG SLEEP calls.
+func runClusterNode(t *testing.T, redisMode string) (*miniredis.Miniredis, *int) {
+	mr := miniredis.RunT(t)
+	sleeps := 0
+	mr.Server().SetPreHook(func(c *server.Peer, cmd string, args ...string) bool {
+		switch cmd {
+		case "INFO":
+			c.WriteBulk(fmt.Sprintf("# Server\r\nredis_mode:%s\r\n# Replication\r\nrole:master\r\n", redisMode))
+		case "DEBUG":
+			sleeps++
+			c.WriteInline("OK")
+		default:
+			return false
+		}
+		return true
+	})
+	return mr, &sleeps
+}
+
+// clusterNodeRequest targets the node of a cluster endpoint that is configured by host name.
+func clusterNodeRequest(t *testing.T, mr *miniredis.Miniredis, cfg map[string]any) action_kit_api.PrepareActionRequestBody {
+	endpointURL := fmt.Sprintf("redis://localhost:%d", mr.Server().Addr().Port)
+	origEndpoints := config.Config.Endpoints
+	t.Cleanup(func() { config.Config.Endpoints = origEndpoints })
+	config.Config.Endpoints = []config.RedisEndpoint{{URL: endpointURL, ClusterMode: "cluster"}}
+	return extutil.JsonMangle(action_kit_api.PrepareActionRequestBody{
+		Target: &action_kit_api.Target{
+			Attributes: map[string][]string{
+				AttrRedisURL:         {fmt.Sprintf("redis://%s", mr.Addr())},
+				AttrRedisEndpointURL: {endpointURL},
+			},
+		},
+		Config:      cfg,
+		ExecutionId: uuid.New(),
+	})
+}
+
+func TestClusterNodeStopAttack_Describe(t *testing.T) {
+	// When
+	desc := (&clusterNodeStopAttack{}).Describe()
+
+	// Then
+	assert.Equal(t, "com.steadybit.extension_redis.instance.cluster-node-stop", desc.Id)
+	assert.Equal(t, "Stop Cluster Node", desc.Label)
+	assert.Contains(t, desc.Description, "DEBUG SLEEP")
+	assert.Equal(t, TargetTypeInstance, desc.TargetSelection.TargetType)
+	assert.Equal(t, action_kit_api.TimeControlInternal, desc.TimeControl)
+	require.Len(t, desc.Parameters, 2)
+	assert.Equal(t, "duration", desc.Parameters[0].Name)
+	assert.Equal(t, "dryRun", desc.Parameters[1].Name)
+}
+
+func TestClusterNodeStopAttack_Prepare_RequiresEndpoint(t *testing.T) {
+	// Given - the node does not belong to any configured endpoint
+	mr := miniredis.RunT(t)
+	origEndpoints := config.Config.Endpoints
+	defer func() { config.Config.Endpoints = origEndpoints }()
+	config.Config.Endpoints = nil
+	req := guardrailRequest(fmt.Sprintf("redis://%s", mr.Addr()), map[string]any{"duration": float64(30000)})
+
+	// When
+	_, err := (&clusterNodeStopAttack{}).Prepare(context.Background(), &ClusterNodeStopState{}, req)
+
+	// Then
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "does not belong to a configured endpoint")
+}
+
+func TestClusterNodeStopAttack_StopsClusterNode(t *testing.T) {
+	// Given
+	mr, sleeps := runClusterNode(t, "cluster")
+	action := &clusterNodeStopAttack{}
+	state := ClusterNodeStopState{}
+	_, err := action.Prepare(context.Background(), &state, clusterNodeRequest(t, mr, map[string]any{"duration": float64(30000)}))
+	require.NoError(t, err)
+
+	// When
+	result, err := action.Start(context.Background(), &state)
+
+	// Then - the node is dialed by its own address with the endpoint settings
+	require.NoError(t, err)
+	assert.Equal(t, mr.Addr(), state.NodeAddr)
+	assert.Equal(t, 1, *sleeps)
+	assert.Contains(t, messageTexts(result.Messages), fmt.Sprintf("Cluster node %s (master) stopped via DEBUG SLEEP", mr.Addr()))
+	status, err := action.Status(context.Background(), &state)
+	require.NoError(t, err)
+	assert.False(t, status.Completed)
+}
+
+func TestClusterNodeStopAttack_DryRun(t *testing.T) {
+	// Given
+	mr, sleeps := runClusterNode(t, "cluster")
+	action := &clusterNodeStopAttack{}
+	state := ClusterNodeStopState{}
+	_, err := action.Prepare(context.Background(), &state, clusterNodeRequest(t, mr, map[string]any{"duration": float64(30000), "dryRun": true}))
+	require.NoError(t, err)
+
+	// When
+	result, err := action.Start(context.Background(), &state)
+
+	// Then
+	require.NoError(t, err)
+	assert.Zero(t, *sleeps)
+	assert.Contains(t, messageTexts(result.Messages), fmt.Sprintf("Dry run: would stop cluster node %s (master) via DEBUG SLEEP for", mr.Addr()))
+}
+
+func TestClusterNodeStopAttack_RefusesStandalone(t *testing.T) {
+	// Given - a standalone master would take the whole database down
+	mr, sleeps := runClusterNode(t, "standalone")
+	action := &clusterNodeStopAttack{}
+	state := ClusterNodeStopState{}
+	_, err := action.Prepare(context.Background(), &state, clusterNodeRequest(t, mr, map[string]any{"duration": float64(30000)}))
+	require.NoError(t, err)
+
+	// When
+	_, err = action.Start(context.Background(), &state)
+
+	// Then
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "only applicable to Redis Cluster nodes")
+	assert.Zero(t, *sleeps)
+}
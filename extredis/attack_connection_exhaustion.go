@@ -22,11 +22,13 @@ import (
 	"github.com/steadybit/extension-redis/config"
 )
 
+const connectionExhaustionActionID = "com.steadybit.extension_redis.instance.connection-exhaustion"
+
 type connectionExhaustionAttack struct{}
 
 type ConnectionExhaustionState struct {
 	RedisURL        string `json:"redisUrl"`
-	Password        string `json:"password"`
+	DryRun          bool   `json:"dryRun"`
 	DB              int    `json:"db"`
 	NumConnections  int    `json:"numConnections"`
 	EndTime         int64  `json:"endTime"`
@@ -52,6 +54,10 @@ func NewConnectionExhaustionAttack() action_kit_sdk.Action[ConnectionExhaustionS
 func createSingleConnectionClient(url string, db int) (*redis.Client, error) {
 	// Get endpoint config to retrieve password and other settings
 	endpoint := config.GetEndpointByURL(url)
+	// State carries the redacted URL, the password in the configured one is still needed
+	if endpoint != nil {
+		url = endpoint.URL
+	}
 
 	opts, err := redis.ParseURL(url)
 	if err != nil {
@@ -66,6 +72,7 @@ func createSingleConnectionClient(url string, db int) (*redis.Client, error) {
 		if endpoint.Username != "" {
 			opts.Username = endpoint.Username
 		}
+		clients.ApplyCredentialReferences(opts, endpoint)
 	}
 
 	if db >= 0 {
@@ -74,12 +81,10 @@ func createSingleConnectionClient(url string, db int) (*redis.Client, error) {
 
 	// Configure TLS if using rediss://
 	if strings.HasPrefix(url, "rediss://") {
-		if opts.TLSConfig == nil {
-			opts.TLSConfig = &tls.Config{}
-		}
-		opts.TLSConfig.MinVersion = tls.VersionTLS12
 		if endpoint != nil {
-			opts.TLSConfig.InsecureSkipVerify = endpoint.InsecureSkipVerify
+			opts.TLSConfig = clients.NewTLSConfig(endpoint, opts.TLSConfig.ServerName)
+		} else {
+			opts.TLSConfig.MinVersion = tls.VersionTLS12
 		}
 	}
 
@@ -101,7 +106,7 @@ func (a *connectionExhaustionAttack) NewEmptyState() ConnectionExhaustionState {
 
 func (a *connectionExhaustionAttack) Describe() action_kit_api.ActionDescription {
 	return action_kit_api.ActionDescription{
-		Id:          "com.steadybit.extension_redis.instance.connection-exhaustion",
+		Id:          connectionExhaustionActionID,
 		Label:       "Exhaust Connections",
 		Description: "Opens many connections to Redis to exhaust the connection pool and test connection limit handling. Combine with Connection Count Check to verify your application handles connection pressure gracefully.",
 		Version:     extbuild.GetSemverVersionStringOrUnknown(),
@@ -139,6 +144,7 @@ func (a *connectionExhaustionAttack) Describe() action_kit_api.ActionDescription
 				MinValue:     new(1),
 				MaxValue:     new(10000),
 			},
+			dryRunParameter(),
 		},
 	}
 }
@@ -148,11 +154,16 @@ func (a *connectionExhaustionAttack) Prepare(ctx context.Context, state *Connect
 	if len(redisURL) == 0 {
 		return nil, fmt.Errorf("redis URL not found in target attributes")
 	}
+	dryRun := dryRunRequested(request)
+	if _, err := checkGuardrails(request.Target.Attributes, connectionExhaustionActionID, false); err != nil {
+		return nil, err
+	}
 
 	duration := extutil.ToInt64(request.Config["duration"]) / 1000 // Convert ms to seconds
 	numConnections := int(extutil.ToInt64(request.Config["numConnections"]))
 
-	state.RedisURL = redisURL[0]
+	state.RedisURL = config.RedactURL(redisURL[0])
+	state.DryRun = dryRun
 	state.DB = 0
 	state.NumConnections = numConnections
 	state.EndTime = time.Now().Add(time.Duration(duration) * time.Second).Unix()
@@ -180,7 +191,7 @@ func (a *connectionExhaustionAttack) Prepare(ctx context.Context, state *Connect
 
 func (a *connectionExhaustionAttack) Start(ctx context.Context, state *ConnectionExhaustionState) (*action_kit_api.StartResult, error) {
 	// Test connection first
-	testClient, err := clients.GetRedisClient(state.RedisURL, state.Password, state.DB)
+	testClient, err := clients.GetRedisClient(state.RedisURL, "", state.DB)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Redis client: %w", err)
 	}
@@ -188,40 +199,9 @@ func (a *connectionExhaustionAttack) Start(ctx context.Context, state *Connectio
 		return nil, fmt.Errorf("failed to ping Redis: %w", err)
 	}
 
-	// Determine target addresses (cluster: distribute across masters, standalone: single node)
-	type targetNode struct {
-		url            string
-		connectionsNum int
-	}
-	var targets []targetNode
-
-	if state.ClusterMode {
-		endpoint := config.GetEndpointByURL(state.RedisURL)
-		if endpoint != nil {
-			masters, _, err := clients.GetMasterNodes(ctx, endpoint)
-			if err == nil && len(masters) > 0 {
-				perNode := state.NumConnections / len(masters)
-				remainder := state.NumConnections % len(masters)
-				scheme := "redis"
-				if strings.HasPrefix(state.RedisURL, "rediss://") {
-					scheme = "rediss"
-				}
-				for i, m := range masters {
-					n := perNode
-					if i < remainder {
-						n++
-					}
-					targets = append(targets, targetNode{
-						url:            fmt.Sprintf("%s://%s", scheme, m.Addr),
-						connectionsNum: n,
-					})
-				}
-			}
-		}
-	}
-
-	if len(targets) == 0 {
-		targets = []targetNode{{url: state.RedisURL, connectionsNum: state.NumConnections}}
+	targets := connectionTargets(ctx, state)
+	if state.DryRun {
+		return dryRunConnectionExhaustion(ctx, state, targets), nil
 	}
 
 	attackKey := fmt.Sprintf("%s-%d", state.RedisURL, time.Now().UnixNano())
@@ -302,6 +282,62 @@ func (a *connectionExhaustionAttack) Start(ctx context.Context, state *Connectio
 	}, nil
 }
 
+// connectionTarget is a node connections are opened to and how many.
+type connectionTarget struct {
+	url            string
+	connectionsNum int
+}
+
+// connectionTargets distributes the connections across the masters in cluster mode, otherwise all go to the target.
+func connectionTargets(ctx context.Context, state *ConnectionExhaustionState) []connectionTarget {
+	var targets []connectionTarget
+	if state.ClusterMode {
+		endpoint := config.GetEndpointByURL(state.RedisURL)
+		if endpoint != nil {
+			masters, _, err := clients.GetMasterNodes(ctx, endpoint)
+			if err == nil && len(masters) > 0 {
+				perNode := state.NumConnections / len(masters)
+				remainder := state.NumConnections % len(masters)
+				scheme := "redis"
+				if strings.HasPrefix(state.RedisURL, "rediss://") {
+					scheme = "rediss"
+				}
+				for i, m := range masters {
+					n := perNode
+					if i < remainder {
+						n++
+					}
+					targets = append(targets, connectionTarget{
+						url:            clients.NodeURL(scheme, m.Addr),
+						connectionsNum: n,
+					})
+				}
+			}
+		}
+	}
+
+	if len(targets) == 0 {
+		targets = []connectionTarget{{url: state.RedisURL, connectionsNum: state.NumConnections}}
+	}
+	return targets
+}
+
+// dryRunConnectionExhaustion reports the connections Start would open per node with the current client count.
+func dryRunConnectionExhaustion(ctx context.Context, state *ConnectionExhaustionState, targets []connectionTarget) *action_kit_api.StartResult {
+	plan := &dryRunPlan{}
+	for _, target := range targets {
+		connectedClients := "unknown"
+		if client, err := createSingleConnectionClient(target.url, state.DB); err == nil {
+			if clientsInfo, err := clients.GetRedisInfo(ctx, client, "clients"); err == nil {
+				connectedClients = clientsInfo["connected_clients"]
+			}
+			_ = client.Close()
+		}
+		plan.info("would open %d connections to %s (connected_clients: %s)", target.connectionsNum, config.RedactURL(target.url), connectedClients)
+	}
+	return plan.startResult()
+}
+
 func (a *connectionExhaustionAttack) keepAlive(attackKey string, endTime int64) {
 	ticker := time.NewTicker(10 * time.Second)
 	defer ticker.Stop()
@@ -325,12 +361,15 @@ func (a *connectionExhaustionAttack) keepAlive(attackKey string, endTime int64)
 }
 
 func (a *connectionExhaustionAttack) Status(ctx context.Context, state *ConnectionExhaustionState) (*action_kit_api.StatusResult, error) {
+	if state.DryRun {
+		return dryRunStatusResult(), nil
+	}
 	now := time.Now().Unix()
 	completed := now >= state.EndTime
 
 	// Get current connection count from Redis
 	connectedClients := "unknown (connection exhausted)"
-	client, err := clients.GetRedisClient(state.RedisURL, state.Password, state.DB)
+	client, err := clients.GetRedisClient(state.RedisURL, "", state.DB)
 	if err == nil {
 		clientsInfo, err := clients.GetRedisInfo(ctx, client, "clients")
 		if err == nil {
@@ -356,6 +395,9 @@ func (a *connectionExhaustionAttack) Status(ctx context.Context, state *Connecti
 }
 
 func (a *connectionExhaustionAttack) Stop(ctx context.Context, state *ConnectionExhaustionState) (*action_kit_api.StopResult, error) {
+	if state.DryRun {
+		return dryRunStopResult(), nil
+	}
 	// Find and close all connections for this attack
 	attackKey := ""
 	activeConnectionsMutex.Lock()
@@ -13,6 +13,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/steadybit/action-kit/go/action_kit_api/v2"
 	"github.com/steadybit/extension-kit/extutil"
+	"github.com/steadybit/extension-redis/clients"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -34,7 +35,7 @@ func TestConnectionExhaustionAttack_Describe(t *testing.T) {
 
 	// Check parameters
 	require.NotNil(t, desc.Parameters)
-	require.Len(t, desc.Parameters, 2)
+	require.Len(t, desc.Parameters, 3)
 
 	paramNames := make([]string, len(desc.Parameters))
 	for i, p := range desc.Parameters {
@@ -42,6 +43,7 @@ func TestConnectionExhaustionAttack_Describe(t *testing.T) {
 	}
 	assert.Contains(t, paramNames, "duration")
 	assert.Contains(t, paramNames, "numConnections")
+	assert.Contains(t, paramNames, "dryRun")
 
 	// Check numConnections has min/max
 	for _, p := range desc.Parameters {
@@ -248,6 +250,37 @@ func TestCreateSingleConnectionClient(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestCreateSingleConnectionClient_Addresses(t *testing.T) {
+	mr := miniredis.RunT(t)
+	socket := serveUnixSocket(t, mr.Addr())
+
+	tests := []struct {
+		name string
+		url  func(t *testing.T) string
+	}{
+		{"ipv4", func(t *testing.T) string { return fmt.Sprintf("redis://%s", mr.Addr()) }},
+		{"unix socket", func(t *testing.T) string { return "unix://" + socket }},
+		{"ipv6", func(t *testing.T) string {
+			mr6 := miniredis.NewMiniRedis()
+			if err := mr6.StartAddr("[::1]:0"); err != nil {
+				t.Skipf("IPv6 loopback not available: %v", err)
+			}
+			t.Cleanup(mr6.Close)
+			return clients.NodeURL("redis", mr6.Addr())
+		}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			client, err := createSingleConnectionClient(tc.url(t), 0)
+			require.NoError(t, err)
+			defer client.Close()
+
+			assert.NoError(t, client.Ping(context.Background()).Err())
+		})
+	}
+}
+
 func TestCreateSingleConnectionClient_InvalidURL(t *testing.T) {
 	// When
 	_, err := createSingleConnectionClient("invalid://not-valid", 0)
@@ -6,7 +6,11 @@ package extredis
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -17,13 +21,17 @@ import (
 	"github.com/steadybit/extension-kit/extutil"
 	"github.com/steadybit/extension-redis/clients"
 	"github.com/steadybit/extension-redis/config"
+	"github.com/steadybit/extension-redis/journal"
 )
 
+const maxmemoryLimitActionID = "com.steadybit.extension_redis.instance.maxmemory-limit"
+
 type maxmemoryLimitAttack struct{}
 
 type MaxmemoryLimitState struct {
 	RedisURL          string            `json:"redisUrl"`
-	Password          string            `json:"password"`
+	DryRun            bool              `json:"dryRun"`
+	ExecutionID       string            `json:"executionId"`
 	DB                int               `json:"db"`
 	OriginalMaxmemory string            `json:"originalMaxmemory"`
 	OriginalPolicy    string            `json:"originalPolicy"`
@@ -33,6 +41,9 @@ type MaxmemoryLimitState struct {
 	ClusterMode       bool              `json:"clusterMode"`
 	PerNodeOrigMaxmem map[string]string `json:"perNodeOrigMaxmem,omitempty"`
 	PerNodeOrigPolicy map[string]string `json:"perNodeOrigPolicy,omitempty"`
+	NodeAddr          string            `json:"nodeAddr,omitempty"`
+	// PartialFailurePolicy decides whether a failure on some cluster masters rolls back the others
+	PartialFailurePolicy clients.PartialFailurePolicy `json:"partialFailurePolicy,omitempty"`
 }
 
 var _ action_kit_sdk.Action[MaxmemoryLimitState] = (*maxmemoryLimitAttack)(nil)
@@ -49,7 +60,7 @@ func (a *maxmemoryLimitAttack) NewEmptyState() MaxmemoryLimitState {
 
 func (a *maxmemoryLimitAttack) Describe() action_kit_api.ActionDescription {
 	return action_kit_api.ActionDescription{
-		Id:          "com.steadybit.extension_redis.instance.maxmemory-limit",
+		Id:          maxmemoryLimitActionID,
 		Label:       "Limit MaxMemory",
 		Description: "Reduces Redis maxmemory configuration to force key evictions or OOM errors. The original maxmemory and eviction policy are restored when the attack ends. Combine with Memory Usage Check to monitor the impact.",
 		Version:     extbuild.GetSemverVersionStringOrUnknown(),
@@ -123,6 +134,8 @@ func (a *maxmemoryLimitAttack) Describe() action_kit_api.ActionDescription {
 					},
 				}),
 			},
+			partialFailureParameter(),
+			dryRunParameter(),
 		},
 	}
 }
@@ -132,6 +145,11 @@ func (a *maxmemoryLimitAttack) Prepare(ctx context.Context, state *MaxmemoryLimi
 	if len(redisURL) == 0 {
 		return nil, fmt.Errorf("redis URL not found in target attributes")
 	}
+	dryRun := dryRunRequested(request)
+	guardrails, err := checkGuardrails(request.Target.Attributes, maxmemoryLimitActionID, !dryRun)
+	if err != nil {
+		return nil, err
+	}
 
 	duration := extutil.ToInt64(request.Config["duration"]) / 1000 // Convert ms to seconds
 	maxmemory := extutil.ToString(request.Config["maxmemory"])
@@ -140,14 +158,26 @@ func (a *maxmemoryLimitAttack) Prepare(ctx context.Context, state *MaxmemoryLimi
 	if maxmemory == "" {
 		return nil, fmt.Errorf("maxmemory is required")
 	}
+	if evictionPolicy != "keep" {
+		if err := guardrails.CheckEvictionPolicy(evictionPolicy); err != nil {
+			return nil, err
+		}
+	}
+	policy, err := parsePartialFailurePolicy(extutil.ToString(request.Config["partialFailurePolicy"]))
+	if err != nil {
+		return nil, err
+	}
 
-	state.RedisURL = redisURL[0]
+	state.RedisURL = config.RedactURL(redisURL[0])
+	state.DryRun = dryRun
+	state.ExecutionID = request.ExecutionId.String()
 	state.DB = 0
 	state.NewMaxmemory = maxmemory
 	state.NewPolicy = evictionPolicy
 	state.EndTime = time.Now().Add(time.Duration(duration) * time.Second).Unix()
 	state.PerNodeOrigMaxmem = make(map[string]string)
 	state.PerNodeOrigPolicy = make(map[string]string)
+	state.PartialFailurePolicy = policy
 
 	endpoint := config.GetEndpointByURL(state.RedisURL)
 	if endpoint != nil {
@@ -174,38 +204,50 @@ func (a *maxmemoryLimitAttack) Prepare(ctx context.Context, state *MaxmemoryLimi
 }
 
 func (a *maxmemoryLimitAttack) Start(ctx context.Context, state *MaxmemoryLimitState) (*action_kit_api.StartResult, error) {
+	if state.DryRun {
+		return a.dryRun(ctx, state)
+	}
 	endpoint := config.GetEndpointByURL(state.RedisURL)
 
+	// Masters are changed in parallel, the per-node originals are shared
+	var mu sync.Mutex
 	applyToNode := func(ctx context.Context, nodeClient *redis.Client, addr string) error {
 		if err := clients.PingRedis(ctx, nodeClient); err != nil {
 			return fmt.Errorf("failed to ping Redis: %w", err)
 		}
 
 		// Save original config per node
-		configResult, err := nodeClient.ConfigGet(ctx, "maxmemory").Result()
+		maxmemResult, err := nodeClient.ConfigGet(ctx, "maxmemory").Result()
 		if err != nil {
 			return fmt.Errorf("failed to get current maxmemory: %w", err)
 		}
-		if len(configResult) >= 2 {
-			state.PerNodeOrigMaxmem[addr] = configResult["maxmemory"]
-			if state.OriginalMaxmemory == "" {
-				state.OriginalMaxmemory = configResult["maxmemory"]
-			}
-		}
-
-		configResult, err = nodeClient.ConfigGet(ctx, "maxmemory-policy").Result()
+		policyResult, err := nodeClient.ConfigGet(ctx, "maxmemory-policy").Result()
 		if err != nil {
 			return fmt.Errorf("failed to get current maxmemory-policy: %w", err)
 		}
-		if len(configResult) >= 2 {
-			state.PerNodeOrigPolicy[addr] = configResult["maxmemory-policy"]
+
+		mu.Lock()
+		if len(maxmemResult) > 0 {
+			state.PerNodeOrigMaxmem[addr] = maxmemResult["maxmemory"]
+			if state.OriginalMaxmemory == "" {
+				state.OriginalMaxmemory = maxmemResult["maxmemory"]
+			}
+		}
+		if len(policyResult) > 0 {
+			state.PerNodeOrigPolicy[addr] = policyResult["maxmemory-policy"]
 			if state.OriginalPolicy == "" {
-				state.OriginalPolicy = configResult["maxmemory-policy"]
+				state.OriginalPolicy = policyResult["maxmemory-policy"]
 			}
 		}
+		origMaxmem := state.PerNodeOrigMaxmem[addr]
+		err = recordJournal(maxmemoryLimitActionID, state.ExecutionID, state.RedisURL, state)
+		mu.Unlock()
+		if err != nil {
+			return err
+		}
 
 		log.Info().Str("addr", addr).
-			Str("originalMaxmemory", state.PerNodeOrigMaxmem[addr]).
+			Str("originalMaxmemory", origMaxmem).
 			Str("newMaxmemory", state.NewMaxmemory).
 			Msg("Applying maxmemory limit")
 
@@ -215,7 +257,7 @@ func (a *maxmemoryLimitAttack) Start(ctx context.Context, state *MaxmemoryLimitS
 
 		if state.NewPolicy != "keep" && state.NewPolicy != "" {
 			if err := nodeClient.ConfigSet(ctx, "maxmemory-policy", state.NewPolicy).Err(); err != nil {
-				_ = nodeClient.ConfigSet(ctx, "maxmemory", state.PerNodeOrigMaxmem[addr]).Err()
+				_ = nodeClient.ConfigSet(ctx, "maxmemory", origMaxmem).Err()
 				return fmt.Errorf("failed to set maxmemory-policy: %w", err)
 			}
 		}
@@ -223,16 +265,25 @@ func (a *maxmemoryLimitAttack) Start(ctx context.Context, state *MaxmemoryLimitS
 		return nil
 	}
 
+	nodeCount := 1
+	var messages []action_kit_api.Message
 	if state.ClusterMode && endpoint != nil {
-		if err := clients.ForEachMaster(ctx, endpoint, applyToNode); err != nil {
+		changed, warnings, err := applyOnMasters(ctx, endpoint, state.PartialFailurePolicy, applyToNode, restoreMaxmemoryNode(state))
+		if err != nil {
+			forgetFailedStart(state.ExecutionID, err)
 			return nil, err
 		}
+		nodeCount = changed
+		messages = append(messages, warnings...)
 	} else {
-		client, err := clients.GetRedisClient(state.RedisURL, state.Password, state.DB)
+		client, addr, release, err := nodeClient(ctx, state.RedisURL, state.DB, "")
 		if err != nil {
-			return nil, fmt.Errorf("failed to create Redis client: %w", err)
+			return nil, err
 		}
-		if err := applyToNode(ctx, client, client.Options().Addr); err != nil {
+		defer release()
+		state.NodeAddr = addr
+		if err := applyToNode(ctx, client, addr); err != nil {
+			forgetFailedStart(state.ExecutionID, err)
 			return nil, err
 		}
 	}
@@ -242,32 +293,21 @@ func (a *maxmemoryLimitAttack) Start(ctx context.Context, state *MaxmemoryLimitS
 		policyMsg = state.OriginalPolicy + " (unchanged)"
 	}
 
-	nodeCount := len(state.PerNodeOrigMaxmem)
-	if nodeCount == 0 {
-		nodeCount = 1
-	}
-
-	messages := []action_kit_api.Message{
+	messages = append([]action_kit_api.Message{
 		{
 			Level:   extutil.Ptr(action_kit_api.Info),
 			Message: fmt.Sprintf("Set maxmemory to %s (was: %s), policy: %s on %d node(s)", state.NewMaxmemory, state.OriginalMaxmemory, policyMsg, nodeCount),
 		},
-	}
+	}, messages...)
 
 	activePolicy := state.NewPolicy
 	if activePolicy == "keep" || activePolicy == "" {
 		activePolicy = state.OriginalPolicy
 	}
-	switch activePolicy {
-	case "allkeys-lru", "allkeys-lfu", "allkeys-random":
-		messages = append(messages, action_kit_api.Message{
-			Level:   extutil.Ptr(action_kit_api.Warn),
-			Message: fmt.Sprintf("WARNING: Eviction policy '%s' will PERMANENTLY DELETE keys when memory limit is reached. Evicted data cannot be recovered. Consider using 'noeviction' to return OOM errors instead.", activePolicy),
-		})
-	case "volatile-lru", "volatile-lfu", "volatile-random", "volatile-ttl":
+	if warning, ok := evictionPolicyWarning(activePolicy); ok {
 		messages = append(messages, action_kit_api.Message{
 			Level:   extutil.Ptr(action_kit_api.Warn),
-			Message: fmt.Sprintf("WARNING: Eviction policy '%s' will permanently delete keys with TTL when memory limit is reached.", activePolicy),
+			Message: warning,
 		})
 	}
 
@@ -276,11 +316,78 @@ func (a *maxmemoryLimitAttack) Start(ctx context.Context, state *MaxmemoryLimitS
 	}, nil
 }
 
+// evictionPolicyWarning describes the data loss caused by an eviction policy once the memory limit is reached.
+func evictionPolicyWarning(policy string) (string, bool) {
+	switch policy {
+	case "allkeys-lru", "allkeys-lfu", "allkeys-random":
+		return fmt.Sprintf("WARNING: Eviction policy '%s' will PERMANENTLY DELETE keys when memory limit is reached. Evicted data cannot be recovered. Consider using 'noeviction' to return OOM errors instead.", policy), true
+	case "volatile-lru", "volatile-lfu", "volatile-random", "volatile-ttl":
+		return fmt.Sprintf("WARNING: Eviction policy '%s' will permanently delete keys with TTL when memory limit is reached.", policy), true
+	}
+	return "", false
+}
+
+// dryRun reports the maxmemory settings Start would change on every node, next to the current memory usage.
+func (a *maxmemoryLimitAttack) dryRun(ctx context.Context, state *MaxmemoryLimitState) (*action_kit_api.StartResult, error) {
+	var mu sync.Mutex
+	activePolicies := make(map[string]struct{})
+	lines, err := dryRunOnNodes(ctx, state.RedisURL, state.DB, state.ClusterMode, func(ctx context.Context, nodeClient *redis.Client, addr string) (string, error) {
+		maxmemResult, err := nodeClient.ConfigGet(ctx, "maxmemory").Result()
+		if err != nil {
+			return "", fmt.Errorf("failed to get current maxmemory on %s: %w", addr, err)
+		}
+		policyResult, err := nodeClient.ConfigGet(ctx, "maxmemory-policy").Result()
+		if err != nil {
+			return "", fmt.Errorf("failed to get current maxmemory-policy on %s: %w", addr, err)
+		}
+		var usedMemory string
+		if info, err := clients.GetRedisInfo(ctx, nodeClient, "memory"); err == nil {
+			usedMemory = info["used_memory_human"]
+		}
+
+		currentPolicy := policyResult["maxmemory-policy"]
+		line := fmt.Sprintf("%s: maxmemory %s -> %s", addr, maxmemResult["maxmemory"], state.NewMaxmemory)
+		activePolicy := currentPolicy
+		if state.NewPolicy != "keep" && state.NewPolicy != "" {
+			line += fmt.Sprintf(", maxmemory-policy %s -> %s", currentPolicy, state.NewPolicy)
+			activePolicy = state.NewPolicy
+		} else {
+			line += fmt.Sprintf(", maxmemory-policy %s (unchanged)", currentPolicy)
+		}
+		if usedMemory != "" {
+			line += fmt.Sprintf(", used memory %s", usedMemory)
+		}
+
+		mu.Lock()
+		activePolicies[activePolicy] = struct{}{}
+		mu.Unlock()
+		return line, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &dryRunPlan{}
+	for _, line := range lines {
+		plan.info("would set %s", line)
+	}
+	policies := slices.Sorted(maps.Keys(activePolicies))
+	for _, policy := range policies {
+		if warning, ok := evictionPolicyWarning(policy); ok {
+			plan.warn("%s", warning)
+		}
+	}
+	return plan.startResult(), nil
+}
+
 func (a *maxmemoryLimitAttack) Status(ctx context.Context, state *MaxmemoryLimitState) (*action_kit_api.StatusResult, error) {
+	if state.DryRun {
+		return dryRunStatusResult(), nil
+	}
 	now := time.Now().Unix()
 	completed := now >= state.EndTime
 
-	client, err := clients.GetRedisClient(state.RedisURL, state.Password, state.DB)
+	client, err := clients.GetRedisClient(state.RedisURL, "", state.DB)
 	var memoryInfo string
 	if err == nil {
 		info, err := clients.GetRedisInfo(ctx, client, "memory")
@@ -293,57 +400,42 @@ func (a *maxmemoryLimitAttack) Status(ctx context.Context, state *MaxmemoryLimit
 
 	return &action_kit_api.StatusResult{
 		Completed: completed,
-		Messages: new([]action_kit_api.Message{
+		Messages: new(append([]action_kit_api.Message{
 			{
 				Level:   extutil.Ptr(action_kit_api.Info),
 				Message: fmt.Sprintf("MaxMemory limit active: %s, current usage: %s", state.NewMaxmemory, memoryInfo),
 			},
-		}),
+		}, journalRecoveryMessages(state.ExecutionID)...)),
 	}, nil
 }
 
 func (a *maxmemoryLimitAttack) Stop(ctx context.Context, state *MaxmemoryLimitState) (*action_kit_api.StopResult, error) {
-	endpoint := config.GetEndpointByURL(state.RedisURL)
-	var restoreErrors []string
-
-	restoreNode := func(ctx context.Context, nodeClient *redis.Client, addr string) error {
-		origMaxmem := state.OriginalMaxmemory
-		origPolicy := state.OriginalPolicy
-		if v, ok := state.PerNodeOrigMaxmem[addr]; ok {
-			origMaxmem = v
-		}
-		if v, ok := state.PerNodeOrigPolicy[addr]; ok {
-			origPolicy = v
-		}
-
-		if err := nodeClient.ConfigSet(ctx, "maxmemory", origMaxmem).Err(); err != nil {
-			restoreErrors = append(restoreErrors, fmt.Sprintf("maxmemory on %s: %v", addr, err))
-			log.Warn().Err(err).Str("addr", addr).Str("value", origMaxmem).Msg("Failed to restore maxmemory")
-		}
-
-		if state.NewPolicy != "keep" {
-			if err := nodeClient.ConfigSet(ctx, "maxmemory-policy", origPolicy).Err(); err != nil {
-				restoreErrors = append(restoreErrors, fmt.Sprintf("policy on %s: %v", addr, err))
-				log.Warn().Err(err).Str("addr", addr).Str("value", origPolicy).Msg("Failed to restore maxmemory-policy")
-			}
-		}
-		return nil
+	if state.DryRun {
+		return dryRunStopResult(), nil
+	}
+	if recoveredByJournal(state.ExecutionID) {
+		return journalRecoveredStopResult(state.ExecutionID), nil
 	}
+	endpoint := config.GetEndpointByURL(state.RedisURL)
+	restoreNode := restoreMaxmemoryNode(state)
 
+	var restoreErr error
 	if state.ClusterMode && endpoint != nil {
-		_ = clients.ForEachMaster(ctx, endpoint, restoreNode)
+		_, restoreErr = clients.ForEachMaster(ctx, endpoint, restoreNode)
 	} else {
-		client, err := clients.GetRedisClient(state.RedisURL, state.Password, state.DB)
+		client, addr, release, err := nodeClient(ctx, state.RedisURL, state.DB, state.NodeAddr)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create Redis client for restore: %w", err)
 		}
-		_ = restoreNode(ctx, client, client.Options().Addr)
+		defer release()
+		restoreErr = restoreNode(ctx, client, addr)
 	}
 
-	if len(restoreErrors) > 0 {
-		log.Error().Strs("errors", restoreErrors).Msg("Failed to restore maxmemory settings")
-		return nil, fmt.Errorf("restore failed: %v", restoreErrors)
+	if restoreErr != nil {
+		log.Error().Err(restoreErr).Msg("Failed to restore maxmemory settings")
+		return nil, fmt.Errorf("restore failed: %w", restoreErr)
 	}
+	journal.Remove(state.ExecutionID)
 
 	return &action_kit_api.StopResult{
 		Messages: new([]action_kit_api.Message{
@@ -354,3 +446,35 @@ func (a *maxmemoryLimitAttack) Stop(ctx context.Context, state *MaxmemoryLimitSt
 		}),
 	}, nil
 }
+
+// restoreMaxmemoryNode returns a function that restores the original maxmemory settings of a node. In cluster mode,
+// masters without recorded originals were never changed and are skipped.
+func restoreMaxmemoryNode(state *MaxmemoryLimitState) clients.NodeFunc {
+	return func(ctx context.Context, nodeClient *redis.Client, addr string) error {
+		origMaxmem, changed := state.PerNodeOrigMaxmem[addr]
+		if state.ClusterMode && len(state.PerNodeOrigMaxmem) > 0 && !changed {
+			return nil
+		}
+		if !changed {
+			origMaxmem = state.OriginalMaxmemory
+		}
+		origPolicy, ok := state.PerNodeOrigPolicy[addr]
+		if !ok {
+			origPolicy = state.OriginalPolicy
+		}
+
+		var errs []error
+		if err := nodeClient.ConfigSet(ctx, "maxmemory", origMaxmem).Err(); err != nil {
+			errs = append(errs, fmt.Errorf("maxmemory on %s: %w", addr, err))
+			log.Warn().Err(err).Str("addr", addr).Str("value", origMaxmem).Msg("Failed to restore maxmemory")
+		}
+
+		if state.NewPolicy != "keep" {
+			if err := nodeClient.ConfigSet(ctx, "maxmemory-policy", origPolicy).Err(); err != nil {
+				errs = append(errs, fmt.Errorf("policy on %s: %w", addr, err))
+				log.Warn().Err(err).Str("addr", addr).Str("value", origPolicy).Msg("Failed to restore maxmemory-policy")
+			}
+		}
+		return errors.Join(errs...)
+	}
+}
@@ -34,7 +34,7 @@ func TestMaxmemoryLimitAttack_Describe(t *testing.T) {
 
 	// Check parameters
 	require.NotNil(t, desc.Parameters)
-	require.Len(t, desc.Parameters, 3)
+	require.Len(t, desc.Parameters, 5)
 
 	paramNames := make([]string, len(desc.Parameters))
 	for i, p := range desc.Parameters {
@@ -43,6 +43,8 @@ func TestMaxmemoryLimitAttack_Describe(t *testing.T) {
 	assert.Contains(t, paramNames, "duration")
 	assert.Contains(t, paramNames, "maxmemory")
 	assert.Contains(t, paramNames, "evictionPolicy")
+	assert.Contains(t, paramNames, "partialFailurePolicy")
+	assert.Contains(t, paramNames, "dryRun")
 }
 
 func TestMaxmemoryLimitAttack_Prepare_MissingURL(t *testing.T) {
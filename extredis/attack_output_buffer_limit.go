@@ -0,0 +1,701 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package extredis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+	"github.com/steadybit/action-kit/go/action_kit_api/v2"
+	"github.com/steadybit/action-kit/go/action_kit_sdk"
+	"github.com/steadybit/extension-kit/extbuild"
+	"github.com/steadybit/extension-kit/extutil"
+	"github.com/steadybit/extension-redis/clients"
+	"github.com/steadybit/extension-redis/config"
+	"github.com/steadybit/extension-redis/journal"
+)
+
+const (
+	outputBufferLimitActionID = "com.steadybit.extension_redis.instance.output-buffer-limit"
+
+	outputBufferClassReplica = "replica"
+	outputBufferClassPubSub  = "pubsub"
+
+	outputBufferLimitConfig = "client-output-buffer-limit"
+
+	// outputBufferTrafficKeys is the number of keys written round-robin in replica mode. Overwriting
+	// a fixed set of keys keeps memory bounded while every write still hits the replication stream.
+	outputBufferTrafficKeys = 100
+
+	// outputBufferTickInterval is how often a batch of traffic is sent.
+	outputBufferTickInterval = 100 * time.Millisecond
+)
+
+type outputBufferLimitAttack struct{}
+
+type OutputBufferLimitState struct {
+	RedisURL               string `json:"redisUrl"`
+	DryRun                 bool   `json:"dryRun"`
+	DB                     int    `json:"db"`
+	ExecutionID            string `json:"executionId"`
+	ClientClass            string `json:"clientClass"`
+	HardLimit              string `json:"hardLimit"`
+	SoftLimit              string `json:"softLimit"`
+	SoftSeconds            int    `json:"softSeconds"`
+	Channel                string `json:"channel"`
+	OpsPerSecond           int    `json:"opsPerSecond"`
+	PayloadSizeBytes       int    `json:"payloadSizeBytes"`
+	EndTime                int64  `json:"endTime"`
+	KeyPrefix              string `json:"keyPrefix"`
+	OriginalLimits         string `json:"originalLimits"`
+	LimitApplied           bool   `json:"limitApplied"`
+	BaselineDisconnections int64  `json:"baselineDisconnections"`
+	BaselineClients        int    `json:"baselineClients"`
+	OpsSent                int64  `json:"opsSent"`
+	MaxObservedOmem        int64  `json:"maxObservedOmem"`
+	NodeAddr               string `json:"nodeAddr,omitempty"`
+}
+
+// outputBufferTraffic holds the traffic generator of a running output buffer attack.
+type outputBufferTraffic struct {
+	cancel  context.CancelFunc
+	done    chan struct{}
+	opsSent atomic.Int64
+	lastErr atomic.Value
+}
+
+// Track running traffic generators by execution ID for status and cleanup
+var (
+	activeOutputBufferTraffic      = make(map[string]*outputBufferTraffic)
+	activeOutputBufferTrafficMutex sync.Mutex
+)
+
+// lockedOutputBufferNodes maps the address of a node to the execution that lowered its limit. An overlapping
+// execution would save the lowered limit as original and set it again after the first one restored it.
+var (
+	lockedOutputBufferNodes      = make(map[string]string)
+	lockedOutputBufferNodesMutex sync.Mutex
+)
+
+// lockOutputBufferNode locks the node for an execution. Returns an error if another running execution, or a
+// journaled one of an earlier run of the extension that was not rolled back yet, holds the node.
+func lockOutputBufferNode(addr, executionID string) error {
+	lockedOutputBufferNodesMutex.Lock()
+	defer lockedOutputBufferNodesMutex.Unlock()
+
+	if holder, ok := lockedOutputBufferNodes[addr]; ok && holder != executionID {
+		return fmt.Errorf("the %s of node %s is already changed by execution %s, run output buffer attacks on a node one after another", outputBufferLimitConfig, addr, holder)
+	}
+	entries, err := journal.Pending()
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to read the journal, overlapping output buffer attacks are only detected within this run")
+	}
+	for _, entry := range entries {
+		if entry.ActionID != outputBufferLimitActionID || entry.ExecutionID == executionID {
+			continue
+		}
+		var held OutputBufferLimitState
+		if err := json.Unmarshal(entry.State, &held); err == nil && held.NodeAddr == addr {
+			return fmt.Errorf("the %s of node %s is still changed by execution %s in the journal, run output buffer attacks on a node one after another", outputBufferLimitConfig, addr, entry.ExecutionID)
+		}
+	}
+	lockedOutputBufferNodes[addr] = executionID
+	return nil
+}
+
+// unlockOutputBufferNode releases the node if the execution holds it.
+func unlockOutputBufferNode(addr, executionID string) {
+	lockedOutputBufferNodesMutex.Lock()
+	defer lockedOutputBufferNodesMutex.Unlock()
+	if lockedOutputBufferNodes[addr] == executionID {
+		delete(lockedOutputBufferNodes, addr)
+	}
+}
+
+var _ action_kit_sdk.Action[OutputBufferLimitState] = (*outputBufferLimitAttack)(nil)
+var _ action_kit_sdk.ActionWithStatus[OutputBufferLimitState] = (*outputBufferLimitAttack)(nil)
+var _ action_kit_sdk.ActionWithStop[OutputBufferLimitState] = (*outputBufferLimitAttack)(nil)
+
+func NewOutputBufferLimitAttack() action_kit_sdk.Action[OutputBufferLimitState] {
+	return &outputBufferLimitAttack{}
+}
+
+func (a *outputBufferLimitAttack) NewEmptyState() OutputBufferLimitState {
+	return OutputBufferLimitState{}
+}
+
+func (a *outputBufferLimitAttack) Describe() action_kit_api.ActionDescription {
+	return action_kit_api.ActionDescription{
+		Id:          outputBufferLimitActionID,
+		Label:       "Exhaust Output Buffers",
+		Description: "Lowers client-output-buffer-limit for the replica or pubsub client class via CONFIG SET and generates matching write or publish traffic, so that replicas or slow subscribers get disconnected. The original limits are restored when the attack ends.",
+		Version:     extbuild.GetSemverVersionStringOrUnknown(),
+		Icon:        new(redisIcon),
+		TargetSelection: new(action_kit_api.TargetSelection{
+			TargetType: TargetTypeInstance,
+			SelectionTemplates: new([]action_kit_api.TargetSelectionTemplate{
+				{
+					Label:       "by host and port",
+					Description: new("Find Redis instance by host and port"),
+					Query:       "redis.host=\"\" AND redis.port=\"\"",
+				},
+			}),
+		}),
+		Technology:  new("Redis"),
+		Category:    new("resource"),
+		Kind:        action_kit_api.Attack,
+		TimeControl: action_kit_api.TimeControlExternal,
+		Parameters: []action_kit_api.ActionParameter{
+			{
+				Name:         "duration",
+				Label:        "Duration",
+				Description:  new("How long to apply the lowered output buffer limit"),
+				Type:         action_kit_api.ActionParameterTypeDuration,
+				DefaultValue: new("60s"),
+				Required:     new(true),
+			},
+			{
+				Name:         "clientClass",
+				Label:        "Client Class",
+				Description:  new("Client class whose output buffer limit is lowered"),
+				Type:         action_kit_api.ActionParameterTypeString,
+				DefaultValue: new(outputBufferClassReplica),
+				Required:     new(true),
+				Options: new([]action_kit_api.ParameterOption{
+					action_kit_api.ExplicitParameterOption{
+						Label: "Replicas",
+						Value: outputBufferClassReplica,
+					},
+					action_kit_api.ExplicitParameterOption{
+						Label: "Pub/Sub Clients",
+						Value: outputBufferClassPubSub,
+					},
+				}),
+			},
+			{
+				Name:         "hardLimit",
+				Label:        "Hard Limit",
+				Description:  new("Output buffer size that disconnects a client immediately (e.g., '256kb', '1mb')"),
+				Type:         action_kit_api.ActionParameterTypeString,
+				DefaultValue: new("256kb"),
+				Required:     new(true),
+			},
+			{
+				Name:         "softLimit",
+				Label:        "Soft Limit",
+				Description:  new("Output buffer size that disconnects a client when exceeded for Soft Seconds"),
+				Type:         action_kit_api.ActionParameterTypeString,
+				DefaultValue: new("128kb"),
+				Required:     new(true),
+				Advanced:     new(true),
+			},
+			{
+				Name:         "softSeconds",
+				Label:        "Soft Seconds",
+				Description:  new("How long the soft limit may be exceeded"),
+				Type:         action_kit_api.ActionParameterTypeInteger,
+				DefaultValue: new("5"),
+				Required:     new(true),
+				Advanced:     new(true),
+			},
+			{
+				Name:         "channel",
+				Label:        "Channel",
+				Description:  new("Channel to publish to for the pubsub class. Ignored for replicas."),
+				Type:         action_kit_api.ActionParameterTypeString,
+				DefaultValue: new(""),
+				Required:     new(false),
+			},
+			{
+				Name:         "opsPerSecond",
+				Label:        "Operations per Second",
+				Description:  new("Writes (replica) or publishes (pubsub) per second to fill the output buffers (0 = only lower the limit)"),
+				Type:         action_kit_api.ActionParameterTypeInteger,
+				DefaultValue: new("1000"),
+				Required:     new(false),
+				MinValue:     new(0),
+				MaxValue:     new(100000),
+			},
+			{
+				Name:         "payloadSizeBytes",
+				Label:        "Payload Size (bytes)",
+				Description:  new("Size of each written value or published message"),
+				Type:         action_kit_api.ActionParameterTypeInteger,
+				DefaultValue: new("4096"),
+				Required:     new(false),
+				MinValue:     new(1),
+				MaxValue:     new(1048576),
+				Advanced:     new(true),
+			},
+			dryRunParameter(),
+		},
+	}
+}
+
+func (a *outputBufferLimitAttack) Prepare(ctx context.Context, state *OutputBufferLimitState, request action_kit_api.PrepareActionRequestBody) (*action_kit_api.PrepareResult, error) {
+	redisURL := request.Target.Attributes[AttrRedisURL]
+	if len(redisURL) == 0 {
+		return nil, fmt.Errorf("redis URL not found in target attributes")
+	}
+	dryRun := dryRunRequested(request)
+	if _, err := checkGuardrails(request.Target.Attributes, outputBufferLimitActionID, !dryRun); err != nil {
+		return nil, err
+	}
+
+	duration := extutil.ToInt64(request.Config["duration"]) / 1000 // Convert ms to seconds
+	clientClass := extutil.ToString(request.Config["clientClass"])
+	hardLimit := extutil.ToString(request.Config["hardLimit"])
+	softLimit := extutil.ToString(request.Config["softLimit"])
+	softSeconds := int(extutil.ToInt64(request.Config["softSeconds"]))
+	channel := extutil.ToString(request.Config["channel"])
+	opsPerSecond := int(extutil.ToInt64(request.Config["opsPerSecond"]))
+	payloadSizeBytes := int(extutil.ToInt64(request.Config["payloadSizeBytes"]))
+
+	switch clientClass {
+	case outputBufferClassReplica:
+	case outputBufferClassPubSub:
+		if channel == "" && opsPerSecond > 0 {
+			return nil, fmt.Errorf("channel is required to generate traffic for the %s class", outputBufferClassPubSub)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported client class %q (expected %q or %q)", clientClass, outputBufferClassReplica, outputBufferClassPubSub)
+	}
+	if hardLimit == "" || softLimit == "" {
+		return nil, fmt.Errorf("hardLimit and softLimit are required")
+	}
+	if softSeconds < 0 {
+		return nil, fmt.Errorf("softSeconds must not be negative")
+	}
+	if payloadSizeBytes <= 0 {
+		payloadSizeBytes = 4096
+	}
+
+	state.RedisURL = config.RedactURL(redisURL[0])
+	state.DryRun = dryRun
+	state.DB = 0
+	state.ExecutionID = request.ExecutionId.String()
+	state.ClientClass = clientClass
+	state.HardLimit = hardLimit
+	state.SoftLimit = softLimit
+	state.SoftSeconds = softSeconds
+	state.Channel = channel
+	state.OpsPerSecond = opsPerSecond
+	state.PayloadSizeBytes = payloadSizeBytes
+	state.EndTime = time.Now().Add(time.Duration(duration) * time.Second).Unix()
+	state.KeyPrefix = fmt.Sprintf("steadybit:output-buffer:%s:", state.ExecutionID)
+
+	// Validate connectivity and CONFIG access before Start
+	client, err := clients.GetRedisClient(state.RedisURL, "", state.DB)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Redis client: %w", err)
+	}
+	if err := clients.PingRedis(ctx, client); err != nil {
+		return nil, fmt.Errorf("failed to ping Redis: %w", err)
+	}
+	if _, err := client.ConfigGet(ctx, outputBufferLimitConfig).Result(); err != nil {
+		return nil, fmt.Errorf("CONFIG GET is not available on this Redis instance (may be disabled or require admin privileges): %w", err)
+	}
+
+	if clientClass == outputBufferClassReplica {
+		replInfo, err := clients.GetRedisInfo(ctx, client, "replication")
+		if err != nil {
+			return nil, fmt.Errorf("failed to get replication info: %w", err)
+		}
+		if role := replInfo["role"]; role != "master" {
+			return nil, fmt.Errorf("the replica class needs a master as target, but this instance is a %s", role)
+		}
+	}
+
+	return nil, nil
+}
+
+func (a *outputBufferLimitAttack) Start(ctx context.Context, state *OutputBufferLimitState) (*action_kit_api.StartResult, error) {
+	if state.DryRun {
+		return a.dryRun(ctx, state)
+	}
+	client, err := clients.GetRedisClient(state.RedisURL, "", state.DB)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Redis client: %w", err)
+	}
+	if err := clients.PingRedis(ctx, client); err != nil {
+		return nil, fmt.Errorf("failed to ping Redis: %w", err)
+	}
+
+	// The limit is changed on the node resolved now, traffic follows the master through the pooled client
+	configClient, addr, release, err := nodeClient(ctx, state.RedisURL, state.DB, "")
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	state.NodeAddr = addr
+	if err := lockOutputBufferNode(addr, state.ExecutionID); err != nil {
+		return nil, err
+	}
+
+	configResult, err := configClient.ConfigGet(ctx, outputBufferLimitConfig).Result()
+	if err != nil {
+		unlockOutputBufferNode(addr, state.ExecutionID)
+		return nil, fmt.Errorf("failed to get current %s: %w", outputBufferLimitConfig, err)
+	}
+	state.OriginalLimits = configResult[outputBufferLimitConfig]
+
+	state.BaselineDisconnections = outputBufferDisconnections(ctx, client)
+	if omems, err := outputBufferClientOmem(ctx, client, state.ClientClass); err == nil {
+		state.BaselineClients = len(omems)
+	}
+
+	// In cluster mode writes must go to slots served by the target node
+	if state.ClientClass == outputBufferClassReplica && state.OpsPerSecond > 0 {
+		if tag, err := clusterLocalHashTag(ctx, client); err != nil {
+			unlockOutputBufferNode(addr, state.ExecutionID)
+			return nil, err
+		} else if tag != "" {
+			state.KeyPrefix = fmt.Sprintf("{%s}:%s", tag, state.KeyPrefix)
+		}
+	}
+
+	newLimit := fmt.Sprintf("%s %s %s %d", state.ClientClass, state.HardLimit, state.SoftLimit, state.SoftSeconds)
+	// The journaled state restores the limit, even if the extension stops before it is set
+	journaled := *state
+	journaled.LimitApplied = true
+	if err := recordJournal(outputBufferLimitActionID, state.ExecutionID, state.RedisURL, journaled); err != nil {
+		unlockOutputBufferNode(addr, state.ExecutionID)
+		return nil, err
+	}
+	log.Info().Str("url", state.RedisURL).
+		Str("original", state.OriginalLimits).
+		Str("new", newLimit).
+		Msg("Lowering client output buffer limit")
+	if err := configClient.ConfigSet(ctx, outputBufferLimitConfig, newLimit).Err(); err != nil {
+		journal.Remove(state.ExecutionID)
+		unlockOutputBufferNode(addr, state.ExecutionID)
+		return nil, fmt.Errorf("failed to set %s: %w", outputBufferLimitConfig, err)
+	}
+	state.LimitApplied = true
+
+	message := fmt.Sprintf("Set %s for class %s to %s %s %ds (was: %s)", outputBufferLimitConfig, state.ClientClass, state.HardLimit, state.SoftLimit, state.SoftSeconds, state.OriginalLimits)
+
+	if state.OpsPerSecond > 0 {
+		workerCtx, cancel := context.WithDeadline(context.Background(), time.Unix(state.EndTime, 0))
+		traffic := &outputBufferTraffic{
+			cancel: cancel,
+			done:   make(chan struct{}),
+		}
+		go func() {
+			defer close(traffic.done)
+			generateOutputBufferTraffic(workerCtx, client, state, traffic)
+		}()
+
+		activeOutputBufferTrafficMutex.Lock()
+		activeOutputBufferTraffic[state.ExecutionID] = traffic
+		activeOutputBufferTrafficMutex.Unlock()
+
+		if state.ClientClass == outputBufferClassPubSub {
+			message += fmt.Sprintf(", publishing %d messages/s of %d bytes to '%s'", state.OpsPerSecond, state.PayloadSizeBytes, state.Channel)
+		} else {
+			message += fmt.Sprintf(", writing %d values/s of %d bytes", state.OpsPerSecond, state.PayloadSizeBytes)
+		}
+	}
+
+	return &action_kit_api.StartResult{
+		Messages: new([]action_kit_api.Message{
+			{
+				Level:   extutil.Ptr(action_kit_api.Info),
+				Message: message,
+			},
+		}),
+	}, nil
+}
+
+// dryRun reports the limit Start would set, the clients of the class it applies to and the traffic it would send.
+func (a *outputBufferLimitAttack) dryRun(ctx context.Context, state *OutputBufferLimitState) (*action_kit_api.StartResult, error) {
+	configClient, addr, release, err := nodeClient(ctx, state.RedisURL, state.DB, "")
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	configResult, err := configClient.ConfigGet(ctx, outputBufferLimitConfig).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current %s: %w", outputBufferLimitConfig, err)
+	}
+	omems, err := outputBufferClientOmem(ctx, configClient, state.ClientClass)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &dryRunPlan{}
+	plan.info("would set %s for class %s to %s %s %ds on %s (currently: %s)", outputBufferLimitConfig, state.ClientClass, state.HardLimit, state.SoftLimit, state.SoftSeconds, addr, configResult[outputBufferLimitConfig])
+	plan.info("%d %s client(s) connected, the ones exceeding the limit would be disconnected", len(omems), state.ClientClass)
+	if state.OpsPerSecond > 0 {
+		if state.ClientClass == outputBufferClassPubSub {
+			plan.info("would publish %d messages/s of %d bytes to '%s'", state.OpsPerSecond, state.PayloadSizeBytes, state.Channel)
+		} else {
+			plan.info("would write %d values/s of %d bytes to %d keys with prefix '%s', deleted on stop", state.OpsPerSecond, state.PayloadSizeBytes, outputBufferTrafficKeys, state.KeyPrefix)
+		}
+	}
+	return plan.startResult(), nil
+}
+
+// generateOutputBufferTraffic writes values (replica class) or publishes messages (pubsub class)
+// at the configured rate until ctx is done.
+func generateOutputBufferTraffic(ctx context.Context, client *redis.Client, state *OutputBufferLimitState, traffic *outputBufferTraffic) {
+	payload := strings.Repeat("x", state.PayloadSizeBytes)
+	ticksPerSecond := int(time.Second / outputBufferTickInterval)
+	ticker := time.NewTicker(outputBufferTickInterval)
+	defer ticker.Stop()
+
+	next := 0
+	for tick := 0; ; tick++ {
+		// Spread the remainder across the first ticks of each second
+		batch := state.OpsPerSecond / ticksPerSecond
+		if tick%ticksPerSecond < state.OpsPerSecond%ticksPerSecond {
+			batch++
+		}
+
+		if batch > 0 {
+			pipe := client.Pipeline()
+			for range batch {
+				if state.ClientClass == outputBufferClassPubSub {
+					pipe.Publish(ctx, state.Channel, payload)
+				} else {
+					pipe.Set(ctx, fmt.Sprintf("%s%d", state.KeyPrefix, next%outputBufferTrafficKeys), payload, 0)
+				}
+				next++
+			}
+			cmds, err := pipe.Exec(ctx)
+			if err != nil && ctx.Err() == nil {
+				traffic.lastErr.Store(err.Error())
+				log.Debug().Err(err).Msg("Failed to send output buffer traffic")
+			}
+			for _, cmd := range cmds {
+				if cmd.Err() == nil {
+					traffic.opsSent.Add(1)
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// clusterLocalHashTag returns a hash tag whose slot is served by the node the client is connected to,
+// or an empty string if cluster mode is disabled on the node.
+func clusterLocalHashTag(ctx context.Context, client *redis.Client) (string, error) {
+	clusterInfo, err := clients.GetRedisInfo(ctx, client, "cluster")
+	if err != nil || clusterInfo["cluster_enabled"] != "1" {
+		return "", nil
+	}
+
+	myID, err := client.ClusterMyID(ctx).Result()
+	if err != nil {
+		return "", fmt.Errorf("failed to get cluster node id: %w", err)
+	}
+	slots, err := client.ClusterSlots(ctx).Result()
+	if err != nil {
+		return "", fmt.Errorf("failed to get cluster slots: %w", err)
+	}
+
+	var local []redis.ClusterSlot
+	for _, s := range slots {
+		if len(s.Nodes) > 0 && s.Nodes[0].ID == myID {
+			local = append(local, s)
+		}
+	}
+	if len(local) == 0 {
+		return "", fmt.Errorf("node %s does not serve any slots", myID)
+	}
+
+	for i := range clients.ClusterSlotCount {
+		tag := fmt.Sprintf("steadybit-%d", i)
+		slot := clients.KeySlot(tag)
+		for _, s := range local {
+			if slot >= s.Start && slot <= s.End {
+				return tag, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no hash tag found for the slots of node %s", myID)
+}
+
+// outputBufferDisconnections returns client_output_buffer_limit_disconnections from INFO stats,
+// or -1 if this Redis version does not report it.
+func outputBufferDisconnections(ctx context.Context, client redis.Cmdable) int64 {
+	stats, err := clients.GetRedisInfo(ctx, client, "stats")
+	if err != nil {
+		return -1
+	}
+	value, ok := stats["client_output_buffer_limit_disconnections"]
+	if !ok {
+		return -1
+	}
+	disconnections, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return -1
+	}
+	return disconnections
+}
+
+// outputBufferClientOmem returns the output buffer memory (omem) of every client of the class from CLIENT LIST.
+func outputBufferClientOmem(ctx context.Context, client *redis.Client, clientClass string) ([]int64, error) {
+	raw, err := client.Do(ctx, "CLIENT", "LIST", "TYPE", clientClass).Text()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s clients: %w", clientClass, err)
+	}
+	return parseClientListOmem(raw), nil
+}
+
+func parseClientListOmem(raw string) []int64 {
+	var omems []int64
+	for line := range strings.SplitSeq(raw, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		var omem int64
+		for field := range strings.FieldsSeq(line) {
+			if value, ok := strings.CutPrefix(field, "omem="); ok {
+				omem, _ = strconv.ParseInt(value, 10, 64)
+			}
+		}
+		omems = append(omems, omem)
+	}
+	return omems
+}
+
+func (a *outputBufferLimitAttack) Status(ctx context.Context, state *OutputBufferLimitState) (*action_kit_api.StatusResult, error) {
+	if state.DryRun {
+		return dryRunStatusResult(), nil
+	}
+	now := time.Now().Unix()
+	completed := now >= state.EndTime
+
+	activeOutputBufferTrafficMutex.Lock()
+	traffic := activeOutputBufferTraffic[state.ExecutionID]
+	activeOutputBufferTrafficMutex.Unlock()
+
+	var messages []action_kit_api.Message
+	if traffic != nil {
+		state.OpsSent = traffic.opsSent.Load()
+		if lastErr, ok := traffic.lastErr.Load().(string); ok {
+			messages = append(messages, action_kit_api.Message{
+				Level:   extutil.Ptr(action_kit_api.Warn),
+				Message: fmt.Sprintf("Last error: %s", lastErr),
+			})
+		}
+	}
+
+	msg := fmt.Sprintf("Output buffer limit for class %s active: %s %s %ds, sent %d operations", state.ClientClass, state.HardLimit, state.SoftLimit, state.SoftSeconds, state.OpsSent)
+
+	client, err := clients.GetRedisClient(state.RedisURL, "", state.DB)
+	if err == nil {
+		if disconnections := outputBufferDisconnections(ctx, client); disconnections >= 0 && state.BaselineDisconnections >= 0 {
+			msg += fmt.Sprintf(", disconnections: %d", disconnections-state.BaselineDisconnections)
+		}
+		if omems, err := outputBufferClientOmem(ctx, client, state.ClientClass); err == nil {
+			var maxOmem int64
+			for _, omem := range omems {
+				maxOmem = max(maxOmem, omem)
+			}
+			state.MaxObservedOmem = max(state.MaxObservedOmem, maxOmem)
+			msg += fmt.Sprintf(", %s clients: %d (was: %d), max omem: %d bytes", state.ClientClass, len(omems), state.BaselineClients, maxOmem)
+		} else {
+			log.Debug().Err(err).Msg("Failed to get CLIENT LIST during output buffer attack")
+		}
+	}
+
+	messages = append([]action_kit_api.Message{
+		{
+			Level:   extutil.Ptr(action_kit_api.Info),
+			Message: msg,
+		},
+	}, messages...)
+	messages = append(messages, journalRecoveryMessages(state.ExecutionID)...)
+
+	return &action_kit_api.StatusResult{
+		Completed: completed,
+		Messages:  new(messages),
+	}, nil
+}
+
+func (a *outputBufferLimitAttack) Stop(ctx context.Context, state *OutputBufferLimitState) (*action_kit_api.StopResult, error) {
+	if state.DryRun {
+		return dryRunStopResult(), nil
+	}
+	if recoveredByJournal(state.ExecutionID) {
+		return journalRecoveredStopResult(state.ExecutionID), nil
+	}
+	activeOutputBufferTrafficMutex.Lock()
+	traffic := activeOutputBufferTraffic[state.ExecutionID]
+	delete(activeOutputBufferTraffic, state.ExecutionID)
+	activeOutputBufferTrafficMutex.Unlock()
+
+	if traffic != nil {
+		traffic.cancel()
+		<-traffic.done
+		state.OpsSent = traffic.opsSent.Load()
+	}
+
+	if !state.LimitApplied {
+		return nil, nil
+	}
+
+	client, err := clients.GetRedisClient(state.RedisURL, "", state.DB)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Redis client for restore: %w", err)
+	}
+
+	configClient, _, release, err := nodeClient(ctx, state.RedisURL, state.DB, state.NodeAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Redis client for restore: %w", err)
+	}
+	defer release()
+
+	var restoreErrors []string
+	if err := configClient.ConfigSet(ctx, outputBufferLimitConfig, state.OriginalLimits).Err(); err != nil {
+		restoreErrors = append(restoreErrors, fmt.Sprintf("%s: %v", outputBufferLimitConfig, err))
+		log.Warn().Err(err).Str("value", state.OriginalLimits).Msg("Failed to restore client-output-buffer-limit")
+	}
+
+	if state.ClientClass == outputBufferClassReplica && state.OpsPerSecond > 0 {
+		keys := make([]string, 0, outputBufferTrafficKeys)
+		for i := range outputBufferTrafficKeys {
+			keys = append(keys, fmt.Sprintf("%s%d", state.KeyPrefix, i))
+		}
+		if err := client.Del(ctx, keys...).Err(); err != nil {
+			restoreErrors = append(restoreErrors, fmt.Sprintf("delete traffic keys: %v", err))
+			log.Warn().Err(err).Str("prefix", state.KeyPrefix).Msg("Failed to delete output buffer traffic keys")
+		}
+	}
+
+	if len(restoreErrors) > 0 {
+		log.Error().Strs("errors", restoreErrors).Msg("Failed to restore output buffer limit")
+		return nil, fmt.Errorf("restore failed: %v", restoreErrors)
+	}
+	state.LimitApplied = false
+	journal.Remove(state.ExecutionID)
+	unlockOutputBufferNode(state.NodeAddr, state.ExecutionID)
+
+	return &action_kit_api.StopResult{
+		Messages: new([]action_kit_api.Message{
+			{
+				Level:   extutil.Ptr(action_kit_api.Info),
+				Message: fmt.Sprintf("Restored %s to %s after %d operations (max observed omem: %d bytes)", outputBufferLimitConfig, state.OriginalLimits, state.OpsSent, state.MaxObservedOmem),
+			},
+		}),
+	}, nil
+}
@@ -0,0 +1,314 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2024 Steadybit GmbH
+
+package extredis
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redismock/v9"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/steadybit/action-kit/go/action_kit_api/v2"
+	"github.com/steadybit/extension-kit/extutil"
+	"github.com/steadybit/extension-redis/config"
+	"github.com/steadybit/extension-redis/journal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutputBufferLimitAttack_Describe(t *testing.T) {
+	// Given
+	action := &outputBufferLimitAttack{}
+
+	// When
+	desc := action.Describe()
+
+	// Then
+	assert.Equal(t, "com.steadybit.extension_redis.instance.output-buffer-limit", desc.Id)
+	assert.Equal(t, "Exhaust Output Buffers", desc.Label)
+	assert.Contains(t, desc.Description, "client-output-buffer-limit")
+	assert.Equal(t, TargetTypeInstance, desc.TargetSelection.TargetType)
+	assert.Equal(t, action_kit_api.Attack, desc.Kind)
+	assert.Equal(t, action_kit_api.TimeControlExternal, desc.TimeControl)
+
+	paramNames := make([]string, len(desc.Parameters))
+	for i, p := range desc.Parameters {
+		paramNames[i] = p.Name
+	}
+	assert.Contains(t, paramNames, "clientClass")
+	assert.Contains(t, paramNames, "hardLimit")
+	assert.Contains(t, paramNames, "softLimit")
+	assert.Contains(t, paramNames, "softSeconds")
+	assert.Contains(t, paramNames, "channel")
+	assert.Contains(t, paramNames, "opsPerSecond")
+	assert.Contains(t, paramNames, "payloadSizeBytes")
+}
+
+func TestOutputBufferLimitAttack_Prepare_Validation(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     map[string]any
+		wantErr string
+	}{
+		{"invalid class", map[string]any{"clientClass": "normal", "hardLimit": "1mb", "softLimit": "1mb"}, "unsupported client class"},
+		{"pubsub without channel", map[string]any{"clientClass": outputBufferClassPubSub, "hardLimit": "1mb", "softLimit": "1mb", "opsPerSecond": float64(10)}, "channel is required"},
+		{"missing limits", map[string]any{"clientClass": outputBufferClassReplica, "hardLimit": "", "softLimit": "1mb"}, "hardLimit and softLimit are required"},
+		{"negative soft seconds", map[string]any{"clientClass": outputBufferClassReplica, "hardLimit": "1mb", "softLimit": "1mb", "softSeconds": float64(-1)}, "softSeconds must not be negative"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			action := &outputBufferLimitAttack{}
+			state := OutputBufferLimitState{}
+			tc.cfg["duration"] = float64(60000)
+			req := extutil.JsonMangle(action_kit_api.PrepareActionRequestBody{
+				Target: &action_kit_api.Target{
+					Attributes: map[string][]string{AttrRedisURL: {"redis://localhost:6379"}},
+				},
+				Config:      tc.cfg,
+				ExecutionId: uuid.New(),
+			})
+
+			_, err := action.Prepare(context.Background(), &state, req)
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.wantErr)
+		})
+	}
+}
+
+func TestOutputBufferLimitAttack_Prepare_SetsState(t *testing.T) {
+	// Given - miniredis doesn't support CONFIG GET, so Prepare fails after the state is set
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	action := &outputBufferLimitAttack{}
+	state := OutputBufferLimitState{}
+	redisURL := fmt.Sprintf("redis://%s", mr.Addr())
+	executionID := uuid.New()
+	req := extutil.JsonMangle(action_kit_api.PrepareActionRequestBody{
+		Target: &action_kit_api.Target{
+			Attributes: map[string][]string{AttrRedisURL: {redisURL}},
+		},
+		Config: map[string]any{
+			"duration":         float64(60000),
+			"clientClass":      outputBufferClassPubSub,
+			"hardLimit":        "64kb",
+			"softLimit":        "32kb",
+			"softSeconds":      float64(3),
+			"channel":          "events",
+			"opsPerSecond":     float64(200),
+			"payloadSizeBytes": float64(512),
+		},
+		ExecutionId: executionID,
+	})
+
+	// When
+	_, err = action.Prepare(context.Background(), &state, req)
+
+	// Then
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "CONFIG")
+	assert.Equal(t, redisURL, state.RedisURL)
+	assert.Equal(t, outputBufferClassPubSub, state.ClientClass)
+	assert.Equal(t, "64kb", state.HardLimit)
+	assert.Equal(t, "32kb", state.SoftLimit)
+	assert.Equal(t, 3, state.SoftSeconds)
+	assert.Equal(t, "events", state.Channel)
+	assert.Equal(t, 200, state.OpsPerSecond)
+	assert.Equal(t, 512, state.PayloadSizeBytes)
+	assert.Equal(t, fmt.Sprintf("steadybit:output-buffer:%s:", executionID), state.KeyPrefix)
+}
+
+func TestGenerateOutputBufferTraffic_Replica(t *testing.T) {
+	// Given
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	state := &OutputBufferLimitState{
+		ClientClass:      outputBufferClassReplica,
+		OpsPerSecond:     1000,
+		PayloadSizeBytes: 32,
+		KeyPrefix:        "steadybit:output-buffer:test:",
+	}
+	traffic := &outputBufferTraffic{}
+	ctx, cancel := context.WithTimeout(context.Background(), 350*time.Millisecond)
+	defer cancel()
+
+	// When
+	generateOutputBufferTraffic(ctx, client, state, traffic)
+
+	// Then
+	assert.GreaterOrEqual(t, traffic.opsSent.Load(), int64(outputBufferTrafficKeys))
+	keys := mr.Keys()
+	assert.Len(t, keys, outputBufferTrafficKeys)
+	value, err := mr.Get("steadybit:output-buffer:test:0")
+	require.NoError(t, err)
+	assert.Len(t, value, 32)
+}
+
+func TestGenerateOutputBufferTraffic_PubSub(t *testing.T) {
+	// Given
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	state := &OutputBufferLimitState{
+		ClientClass:      outputBufferClassPubSub,
+		Channel:          "events",
+		OpsPerSecond:     100,
+		PayloadSizeBytes: 16,
+	}
+	traffic := &outputBufferTraffic{}
+	ctx, cancel := context.WithTimeout(context.Background(), 250*time.Millisecond)
+	defer cancel()
+
+	// When
+	generateOutputBufferTraffic(ctx, client, state, traffic)
+
+	// Then
+	assert.Positive(t, traffic.opsSent.Load())
+	assert.Empty(t, mr.Keys())
+}
+
+func TestParseClientListOmem(t *testing.T) {
+	raw := "id=3 addr=127.0.0.1:50000 fd=8 name= age=10 flags=S omem=0 cmd=replconf\n" +
+		"id=4 addr=127.0.0.1:50001 fd=9 name= age=12 flags=S omem=16384 cmd=replconf\n"
+
+	assert.Equal(t, []int64{0, 16384}, parseClientListOmem(raw))
+	assert.Empty(t, parseClientListOmem(""))
+}
+
+func TestOutputBufferClientOmem(t *testing.T) {
+	// Given
+	client, mock := redismock.NewClientMock()
+	defer client.Close()
+	mock.ExpectDo("CLIENT", "LIST", "TYPE", "pubsub").SetVal("id=7 addr=10.0.0.1:4000 flags=P omem=2048 cmd=subscribe\n")
+
+	// When
+	omems, err := outputBufferClientOmem(context.Background(), client, outputBufferClassPubSub)
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, []int64{2048}, omems)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOutputBufferDisconnections(t *testing.T) {
+	// Given
+	client, mock := redismock.NewClientMock()
+	defer client.Close()
+	mock.ExpectInfo("stats").SetVal("# Stats\r\nclient_output_buffer_limit_disconnections:7\r\n")
+	mock.ExpectInfo("stats").SetVal("# Stats\r\ntotal_connections_received:5\r\n")
+
+	// When / Then
+	assert.Equal(t, int64(7), outputBufferDisconnections(context.Background(), client))
+	assert.Equal(t, int64(-1), outputBufferDisconnections(context.Background(), client))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestClusterLocalHashTag_Standalone(t *testing.T) {
+	// Given
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	// When
+	tag, err := clusterLocalHashTag(context.Background(), client)
+
+	// Then
+	require.NoError(t, err)
+	assert.Empty(t, tag)
+}
+
+func TestLockOutputBufferNode_RefusesOverlappingExecution(t *testing.T) {
+	// Given
+	first, second := uuid.New().String(), uuid.New().String()
+	require.NoError(t, lockOutputBufferNode("10.0.0.1:6379", first))
+	defer unlockOutputBufferNode("10.0.0.1:6379", first)
+
+	// When
+	err := lockOutputBufferNode("10.0.0.1:6379", second)
+
+	// Then
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), first)
+	require.NoError(t, lockOutputBufferNode("10.0.0.2:6379", second), "other nodes are not affected")
+	unlockOutputBufferNode("10.0.0.2:6379", second)
+	unlockOutputBufferNode("10.0.0.1:6379", first)
+	require.NoError(t, lockOutputBufferNode("10.0.0.1:6379", second))
+	unlockOutputBufferNode("10.0.0.1:6379", second)
+}
+
+func TestLockOutputBufferNode_RefusesJournaledExecution(t *testing.T) {
+	// Given - an execution of an earlier run still holds the node in the journal
+	orig := config.Config.JournalDir
+	defer func() { config.Config.JournalDir = orig }()
+	config.Config.JournalDir = t.TempDir()
+	held := uuid.New().String()
+	require.NoError(t, journal.Record(outputBufferLimitActionID, held, "redis://a:6379", OutputBufferLimitState{
+		ExecutionID:  held,
+		NodeAddr:     "10.0.0.1:6379",
+		LimitApplied: true,
+	}))
+
+	// When
+	err := lockOutputBufferNode("10.0.0.1:6379", uuid.New().String())
+
+	// Then
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), held)
+}
+
+func TestOutputBufferLimitAttack_Stop_WithoutLimitApplied(t *testing.T) {
+	// Given
+	action := &outputBufferLimitAttack{}
+	state := OutputBufferLimitState{
+		RedisURL:    "redis://nonexistent:6379",
+		ExecutionID: uuid.New().String(),
+	}
+
+	// When
+	result, err := action.Stop(context.Background(), &state)
+
+	// Then
+	require.NoError(t, err)
+	assert.Nil(t, result)
+}
+
+func TestOutputBufferLimitAttack_Stop_RestoreFails(t *testing.T) {
+	// Given - miniredis doesn't support CONFIG SET
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	action := &outputBufferLimitAttack{}
+	state := OutputBufferLimitState{
+		RedisURL:       fmt.Sprintf("redis://%s", mr.Addr()),
+		ExecutionID:    uuid.New().String(),
+		ClientClass:    outputBufferClassReplica,
+		OriginalLimits: "normal 0 0 0 slave 268435456 67108864 60 pubsub 33554432 8388608 60",
+		LimitApplied:   true,
+	}
+
+	// When
+	_, err = action.Stop(context.Background(), &state)
+
+	// Then
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "restore failed")
+	assert.True(t, state.LimitApplied)
+}
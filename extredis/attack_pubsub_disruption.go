@@ -0,0 +1,614 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package extredis
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+	"github.com/steadybit/action-kit/go/action_kit_api/v2"
+	"github.com/steadybit/action-kit/go/action_kit_sdk"
+	"github.com/steadybit/extension-kit/extbuild"
+	"github.com/steadybit/extension-kit/extutil"
+	"github.com/steadybit/extension-redis/clients"
+	"github.com/steadybit/extension-redis/config"
+)
+
+const (
+	pubSubDisruptionActionID = "com.steadybit.extension_redis.instance.pubsub-disruption"
+
+	pubSubModeFlood           = "flood"
+	pubSubModeKillSubscribers = "kill-subscribers"
+
+	// pubSubFloodTickInterval is how often a batch of messages is published. The configured
+	// rate is spread evenly across the ticks of one second.
+	pubSubFloodTickInterval = 100 * time.Millisecond
+
+	// pubSubFloodMaxBytesPerSecond bounds messagesPerSecond × messageSizeBytes. Every message is delivered to each
+	// subscriber and, in a cluster, forwarded to every node, so the load is a multiple of this.
+	pubSubFloodMaxBytesPerSecond = 100 * 1024 * 1024
+)
+
+type pubSubDisruptionAttack struct{}
+
+type PubSubDisruptionState struct {
+	RedisURL            string `json:"redisUrl"`
+	DryRun              bool   `json:"dryRun"`
+	DB                  int    `json:"db"`
+	ExecutionID         string `json:"executionId"`
+	Mode                string `json:"mode"`
+	Channel             string `json:"channel"`
+	MessagesPerSecond   int    `json:"messagesPerSecond"`
+	MessageSizeBytes    int    `json:"messageSizeBytes"`
+	KillIntervalSeconds int    `json:"killIntervalSeconds"`
+	EndTime             int64  `json:"endTime"`
+	ClusterMode         bool   `json:"clusterMode"`
+	MessagesPublished   int64  `json:"messagesPublished"`
+	MessagesReceived    int64  `json:"messagesReceived"`
+	ClientsKilled       int64  `json:"clientsKilled"`
+}
+
+// pubSubDisruption holds the background worker of a running Pub/Sub disruption.
+type pubSubDisruption struct {
+	cancel            context.CancelFunc
+	done              chan struct{}
+	messagesPublished atomic.Int64
+	messagesReceived  atomic.Int64
+	clientsKilled     atomic.Int64
+	lastErr           atomic.Value
+}
+
+// Track running disruptions by execution ID for status and cleanup
+var (
+	activePubSubDisruptions      = make(map[string]*pubSubDisruption)
+	activePubSubDisruptionsMutex sync.Mutex
+)
+
+var _ action_kit_sdk.Action[PubSubDisruptionState] = (*pubSubDisruptionAttack)(nil)
+var _ action_kit_sdk.ActionWithStatus[PubSubDisruptionState] = (*pubSubDisruptionAttack)(nil)
+var _ action_kit_sdk.ActionWithStop[PubSubDisruptionState] = (*pubSubDisruptionAttack)(nil)
+
+func NewPubSubDisruptionAttack() action_kit_sdk.Action[PubSubDisruptionState] {
+	return &pubSubDisruptionAttack{}
+}
+
+func (a *pubSubDisruptionAttack) NewEmptyState() PubSubDisruptionState {
+	return PubSubDisruptionState{}
+}
+
+func (a *pubSubDisruptionAttack) Describe() action_kit_api.ActionDescription {
+	return action_kit_api.ActionDescription{
+		Id:          pubSubDisruptionActionID,
+		Label:       "Disrupt Pub/Sub",
+		Description: "Disrupts Redis Pub/Sub messaging. Flood mode publishes messages at a configured rate and size to a channel (or all active channels matching a pattern) to stress subscribers and their output buffers. Kill Subscribers mode disconnects Pub/Sub clients via CLIENT KILL TYPE pubsub so they have to reconnect and resubscribe.",
+		Version:     extbuild.GetSemverVersionStringOrUnknown(),
+		Icon:        new(redisIcon),
+		TargetSelection: new(action_kit_api.TargetSelection{
+			TargetType: TargetTypeInstance,
+			SelectionTemplates: new([]action_kit_api.TargetSelectionTemplate{
+				{
+					Label:       "by host and port",
+					Description: new("Find Redis instance by host and port"),
+					Query:       "redis.host=\"\" AND redis.port=\"\"",
+				},
+			}),
+		}),
+		Technology:  new("Redis"),
+		Category:    new("network"),
+		Kind:        action_kit_api.Attack,
+		TimeControl: action_kit_api.TimeControlExternal,
+		Parameters: []action_kit_api.ActionParameter{
+			{
+				Name:         "duration",
+				Label:        "Duration",
+				Description:  new("How long to disrupt Pub/Sub"),
+				Type:         action_kit_api.ActionParameterTypeDuration,
+				DefaultValue: new("60s"),
+				Required:     new(true),
+			},
+			{
+				Name:         "mode",
+				Label:        "Mode",
+				Description:  new("Flood publishes messages to the channel, Kill Subscribers disconnects all Pub/Sub clients"),
+				Type:         action_kit_api.ActionParameterTypeString,
+				DefaultValue: new(pubSubModeFlood),
+				Required:     new(true),
+				Options: new([]action_kit_api.ParameterOption{
+					action_kit_api.ExplicitParameterOption{
+						Label: "Flood Channel",
+						Value: pubSubModeFlood,
+					},
+					action_kit_api.ExplicitParameterOption{
+						Label: "Kill Subscribers",
+						Value: pubSubModeKillSubscribers,
+					},
+				}),
+			},
+			{
+				Name:         "channel",
+				Label:        "Channel or Pattern",
+				Description:  new("Channel to flood (e.g., 'events'). A glob pattern (e.g., 'events.*') floods every active channel matching it. Ignored in Kill Subscribers mode."),
+				Type:         action_kit_api.ActionParameterTypeString,
+				DefaultValue: new(""),
+				Required:     new(false),
+			},
+			{
+				Name:         "messagesPerSecond",
+				Label:        "Messages per Second",
+				Description:  new("Number of messages to publish per second in Flood mode"),
+				Type:         action_kit_api.ActionParameterTypeInteger,
+				DefaultValue: new("1000"),
+				Required:     new(false),
+				MinValue:     new(1),
+				MaxValue:     new(100000),
+			},
+			{
+				Name:         "messageSizeBytes",
+				Label:        "Message Size (bytes)",
+				Description:  new("Payload size of each published message in Flood mode"),
+				Type:         action_kit_api.ActionParameterTypeInteger,
+				DefaultValue: new("1024"),
+				Required:     new(false),
+				MinValue:     new(1),
+				MaxValue:     new(1048576),
+				Advanced:     new(true),
+			},
+			{
+				Name:         "killIntervalSeconds",
+				Label:        "Kill Interval (seconds)",
+				Description:  new("Repeat CLIENT KILL at this interval in Kill Subscribers mode to disconnect clients that resubscribed (0 = kill once)"),
+				Type:         action_kit_api.ActionParameterTypeInteger,
+				DefaultValue: new("0"),
+				Required:     new(false),
+				Advanced:     new(true),
+			},
+			dryRunParameter(),
+		},
+	}
+}
+
+func (a *pubSubDisruptionAttack) Prepare(ctx context.Context, state *PubSubDisruptionState, request action_kit_api.PrepareActionRequestBody) (*action_kit_api.PrepareResult, error) {
+	redisURL := request.Target.Attributes[AttrRedisURL]
+	if len(redisURL) == 0 {
+		return nil, fmt.Errorf("redis URL not found in target attributes")
+	}
+	dryRun := dryRunRequested(request)
+	if _, err := checkGuardrails(request.Target.Attributes, pubSubDisruptionActionID, !dryRun); err != nil {
+		return nil, err
+	}
+
+	duration := extutil.ToInt64(request.Config["duration"]) / 1000 // Convert ms to seconds
+	mode := extutil.ToString(request.Config["mode"])
+	channel := extutil.ToString(request.Config["channel"])
+	messagesPerSecond := int(extutil.ToInt64(request.Config["messagesPerSecond"]))
+	messageSizeBytes := int(extutil.ToInt64(request.Config["messageSizeBytes"]))
+	killIntervalSeconds := int(extutil.ToInt64(request.Config["killIntervalSeconds"]))
+
+	switch mode {
+	case pubSubModeFlood:
+		if channel == "" {
+			return nil, fmt.Errorf("channel is required in flood mode")
+		}
+		if messagesPerSecond < 1 {
+			messagesPerSecond = 1
+		}
+		if messageSizeBytes < 1 {
+			messageSizeBytes = 1
+		}
+		if int64(messagesPerSecond)*int64(messageSizeBytes) > pubSubFloodMaxBytesPerSecond {
+			return nil, fmt.Errorf("flood of %d messages/s of %d bytes exceeds the limit of %d MB/s, reduce 'messagesPerSecond' or 'messageSizeBytes'",
+				messagesPerSecond, messageSizeBytes, pubSubFloodMaxBytesPerSecond/1024/1024)
+		}
+	case pubSubModeKillSubscribers:
+		if killIntervalSeconds < 0 {
+			killIntervalSeconds = 0
+		}
+	default:
+		return nil, fmt.Errorf("unsupported mode %q (expected %q or %q)", mode, pubSubModeFlood, pubSubModeKillSubscribers)
+	}
+
+	state.RedisURL = config.RedactURL(redisURL[0])
+	state.DryRun = dryRun
+	state.DB = 0
+	state.ExecutionID = request.ExecutionId.String()
+	state.Mode = mode
+	state.Channel = channel
+	state.MessagesPerSecond = messagesPerSecond
+	state.MessageSizeBytes = messageSizeBytes
+	state.KillIntervalSeconds = killIntervalSeconds
+	state.EndTime = time.Now().Add(time.Duration(duration) * time.Second).Unix()
+
+	endpoint := config.GetEndpointByURL(state.RedisURL)
+	if endpoint != nil {
+		isCluster, err := clients.DetectClusterMode(ctx, endpoint)
+		if err == nil {
+			state.ClusterMode = isCluster
+		}
+	}
+
+	// Validate connectivity before Start
+	client, err := clients.GetRedisClient(state.RedisURL, "", state.DB)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Redis client: %w", err)
+	}
+	if err := clients.PingRedis(ctx, client); err != nil {
+		return nil, fmt.Errorf("failed to ping Redis: %w", err)
+	}
+
+	return nil, nil
+}
+
+func (a *pubSubDisruptionAttack) Start(ctx context.Context, state *PubSubDisruptionState) (*action_kit_api.StartResult, error) {
+	if state.DryRun {
+		return a.dryRun(ctx, state)
+	}
+	client, err := clients.GetRedisClient(state.RedisURL, "", state.DB)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Redis client: %w", err)
+	}
+	if err := clients.PingRedis(ctx, client); err != nil {
+		return nil, fmt.Errorf("failed to ping Redis: %w", err)
+	}
+
+	workerCtx, cancel := context.WithDeadline(context.Background(), time.Unix(state.EndTime, 0))
+	disruption := &pubSubDisruption{
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	var message string
+	switch state.Mode {
+	case pubSubModeFlood:
+		channels, err := resolvePubSubChannels(ctx, client, state)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		go func() {
+			defer close(disruption.done)
+			floodPubSubChannels(workerCtx, client, channels, state.MessagesPerSecond, state.MessageSizeBytes, disruption)
+		}()
+		message = fmt.Sprintf("Flooding %d channel(s) matching '%s' with %d messages/s of %d bytes", len(channels), state.Channel, state.MessagesPerSecond, state.MessageSizeBytes)
+
+	case pubSubModeKillSubscribers:
+		killed, err := killPubSubClients(ctx, state)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		disruption.clientsKilled.Add(killed)
+		if state.KillIntervalSeconds > 0 {
+			go func() {
+				defer close(disruption.done)
+				a.keepKilling(workerCtx, state, disruption)
+			}()
+		} else {
+			close(disruption.done)
+		}
+		message = fmt.Sprintf("Killed %d Pub/Sub client(s) via CLIENT KILL TYPE pubsub", killed)
+		if state.KillIntervalSeconds > 0 {
+			message += fmt.Sprintf(", repeating every %d seconds", state.KillIntervalSeconds)
+		}
+
+	default:
+		cancel()
+		return nil, fmt.Errorf("unsupported mode %q", state.Mode)
+	}
+
+	activePubSubDisruptionsMutex.Lock()
+	activePubSubDisruptions[state.ExecutionID] = disruption
+	activePubSubDisruptionsMutex.Unlock()
+
+	return &action_kit_api.StartResult{
+		Messages: new([]action_kit_api.Message{
+			{
+				Level:   extutil.Ptr(action_kit_api.Info),
+				Message: message,
+			},
+		}),
+	}, nil
+}
+
+// dryRun reports the channels Start would flood with their subscribers, or the Pub/Sub clients it would kill.
+func (a *pubSubDisruptionAttack) dryRun(ctx context.Context, state *PubSubDisruptionState) (*action_kit_api.StartResult, error) {
+	plan := &dryRunPlan{}
+	switch state.Mode {
+	case pubSubModeFlood:
+		client, err := clients.GetRedisClient(state.RedisURL, "", state.DB)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Redis client: %w", err)
+		}
+		channels, err := resolvePubSubChannels(ctx, client, state)
+		if err != nil {
+			return nil, err
+		}
+		subscribers, err := client.PubSubNumSub(ctx, channels...).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to count subscribers: %w", err)
+		}
+		var total int64
+		for _, n := range subscribers {
+			total += n
+		}
+		plan.info("would flood %d channel(s) with %d subscriber(s) with %d messages/s of %d bytes: %s", len(channels), total, state.MessagesPerSecond, state.MessageSizeBytes, formatList(channels))
+
+	case pubSubModeKillSubscribers:
+		lines, err := dryRunOnNodes(ctx, state.RedisURL, state.DB, state.ClusterMode, func(ctx context.Context, nodeClient *redis.Client, addr string) (string, error) {
+			pubSubClients, err := outputBufferClientOmem(ctx, nodeClient, outputBufferClassPubSub)
+			if err != nil {
+				return "", fmt.Errorf("%s: %w", addr, err)
+			}
+			return fmt.Sprintf("%d Pub/Sub client(s) on %s", len(pubSubClients), addr), nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range lines {
+			plan.info("would kill %s", line)
+		}
+		if state.KillIntervalSeconds > 0 {
+			plan.info("would repeat the kill every %d seconds", state.KillIntervalSeconds)
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported mode %q", state.Mode)
+	}
+	return plan.startResult(), nil
+}
+
+// resolvePubSubChannels returns the channels to flood. A plain channel name is returned as-is,
+// a glob pattern is expanded to the active channels currently matching it. In cluster mode the channels
+// are collected from every master, as PUBSUB CHANNELS only lists the subscriptions of the node it runs on.
+func resolvePubSubChannels(ctx context.Context, client *redis.Client, state *PubSubDisruptionState) ([]string, error) {
+	channel := state.Channel
+	if !strings.ContainsAny(channel, "*?[") {
+		return []string{channel}, nil
+	}
+
+	channels, _, err := pubSubActivity(ctx, client, state, channel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list channels matching '%s': %w", channel, err)
+	}
+	if len(channels) == 0 {
+		return nil, fmt.Errorf("no active channels found matching pattern '%s'", channel)
+	}
+	return channels, nil
+}
+
+// pubSubActivity returns the active channels matching pattern and the number of pattern subscriptions. In cluster
+// mode both are collected from every master, as PUBSUB only reports the subscriptions of the node it runs on.
+func pubSubActivity(ctx context.Context, client *redis.Client, state *PubSubDisruptionState, pattern string) ([]string, int64, error) {
+	endpoint := config.GetEndpointByURL(state.RedisURL)
+	if !state.ClusterMode || endpoint == nil {
+		channels, err := client.PubSubChannels(ctx, pattern).Result()
+		if err != nil {
+			return nil, 0, err
+		}
+		numPat, err := client.PubSubNumPat(ctx).Result()
+		if err != nil {
+			return nil, 0, err
+		}
+		return channels, numPat, nil
+	}
+
+	var mu sync.Mutex
+	seen := make(map[string]bool)
+	var channels []string
+	var numPat int64
+	_, err := clients.ForEachMaster(ctx, endpoint, func(ctx context.Context, nodeClient *redis.Client, addr string) error {
+		nodeChannels, err := nodeClient.PubSubChannels(ctx, pattern).Result()
+		if err != nil {
+			return err
+		}
+		nodeNumPat, err := nodeClient.PubSubNumPat(ctx).Result()
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		numPat += nodeNumPat
+		for _, c := range nodeChannels {
+			if !seen[c] {
+				seen[c] = true
+				channels = append(channels, c)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	sort.Strings(channels)
+	return channels, numPat, nil
+}
+
+// floodPubSubChannels publishes messages round-robin across the channels until ctx is done.
+func floodPubSubChannels(ctx context.Context, client *redis.Client, channels []string, messagesPerSecond int, messageSizeBytes int, disruption *pubSubDisruption) {
+	payload := strings.Repeat("x", messageSizeBytes)
+	ticksPerSecond := int(time.Second / pubSubFloodTickInterval)
+	ticker := time.NewTicker(pubSubFloodTickInterval)
+	defer ticker.Stop()
+
+	next := 0
+	for tick := 0; ; tick++ {
+		// Spread the remainder across the first ticks of each second
+		batch := messagesPerSecond / ticksPerSecond
+		if tick%ticksPerSecond < messagesPerSecond%ticksPerSecond {
+			batch++
+		}
+
+		if batch > 0 {
+			pipe := client.Pipeline()
+			cmds := make([]*redis.IntCmd, 0, batch)
+			for range batch {
+				cmds = append(cmds, pipe.Publish(ctx, channels[next%len(channels)], payload))
+				next++
+			}
+			_, err := pipe.Exec(ctx)
+			if err != nil && ctx.Err() == nil {
+				disruption.lastErr.Store(err.Error())
+				log.Debug().Err(err).Msg("Failed to publish flood batch")
+			}
+			for _, cmd := range cmds {
+				if cmd.Err() == nil {
+					disruption.messagesPublished.Add(1)
+					disruption.messagesReceived.Add(cmd.Val())
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// killPubSubClients disconnects all Pub/Sub clients on the target (every master in cluster mode).
+func killPubSubClients(ctx context.Context, state *PubSubDisruptionState) (int64, error) {
+	var killed atomic.Int64
+	killNode := func(ctx context.Context, nodeClient *redis.Client, addr string) error {
+		n, err := nodeClient.ClientKillByFilter(ctx, "TYPE", "pubsub").Result()
+		if err != nil {
+			return fmt.Errorf("failed to execute CLIENT KILL TYPE pubsub: %w", err)
+		}
+		killed.Add(n)
+		return nil
+	}
+
+	endpoint := config.GetEndpointByURL(state.RedisURL)
+	if state.ClusterMode && endpoint != nil {
+		if _, err := clients.ForEachMaster(ctx, endpoint, killNode); err != nil {
+			return killed.Load(), err
+		}
+	} else {
+		client, err := clients.GetRedisClient(state.RedisURL, "", state.DB)
+		if err != nil {
+			return 0, fmt.Errorf("failed to create Redis client: %w", err)
+		}
+		if err := killNode(ctx, client, client.Options().Addr); err != nil {
+			return 0, err
+		}
+	}
+	return killed.Load(), nil
+}
+
+func (a *pubSubDisruptionAttack) keepKilling(ctx context.Context, state *PubSubDisruptionState, disruption *pubSubDisruption) {
+	ticker := time.NewTicker(time.Duration(state.KillIntervalSeconds) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		killed, err := killPubSubClients(ctx, state)
+		disruption.clientsKilled.Add(killed)
+		if err != nil && ctx.Err() == nil {
+			disruption.lastErr.Store(err.Error())
+			log.Warn().Err(err).Msg("Failed to kill Pub/Sub clients")
+		}
+	}
+}
+
+func (a *pubSubDisruptionAttack) Status(ctx context.Context, state *PubSubDisruptionState) (*action_kit_api.StatusResult, error) {
+	if state.DryRun {
+		return dryRunStatusResult(), nil
+	}
+	now := time.Now().Unix()
+	completed := now >= state.EndTime
+
+	activePubSubDisruptionsMutex.Lock()
+	disruption := activePubSubDisruptions[state.ExecutionID]
+	activePubSubDisruptionsMutex.Unlock()
+
+	var messages []action_kit_api.Message
+	if disruption != nil {
+		state.MessagesPublished = disruption.messagesPublished.Load()
+		state.MessagesReceived = disruption.messagesReceived.Load()
+		state.ClientsKilled = disruption.clientsKilled.Load()
+		if lastErr, ok := disruption.lastErr.Load().(string); ok {
+			messages = append(messages, action_kit_api.Message{
+				Level:   extutil.Ptr(action_kit_api.Warn),
+				Message: fmt.Sprintf("Last error: %s", lastErr),
+			})
+		}
+	}
+
+	pubSubChannels := "unknown"
+	pubSubPatterns := "unknown"
+	client, err := clients.GetRedisClient(state.RedisURL, "", state.DB)
+	if err == nil {
+		if channels, numPat, err := pubSubActivity(ctx, client, state, "*"); err == nil {
+			pubSubChannels = fmt.Sprintf("%d", len(channels))
+			pubSubPatterns = fmt.Sprintf("%d", numPat)
+		} else {
+			log.Debug().Err(err).Msg("Failed to get PUBSUB CHANNELS and NUMPAT during Pub/Sub disruption")
+		}
+	}
+
+	var summary string
+	if state.Mode == pubSubModeFlood {
+		summary = fmt.Sprintf("Published %d messages (%d deliveries to subscribers)", state.MessagesPublished, state.MessagesReceived)
+	} else {
+		summary = fmt.Sprintf("Killed %d Pub/Sub client(s)", state.ClientsKilled)
+	}
+
+	messages = append([]action_kit_api.Message{
+		{
+			Level:   extutil.Ptr(action_kit_api.Info),
+			Message: fmt.Sprintf("%s, pubsub_channels: %s, pubsub_patterns: %s", summary, pubSubChannels, pubSubPatterns),
+		},
+	}, messages...)
+
+	return &action_kit_api.StatusResult{
+		Completed: completed,
+		Messages:  new(messages),
+	}, nil
+}
+
+func (a *pubSubDisruptionAttack) Stop(ctx context.Context, state *PubSubDisruptionState) (*action_kit_api.StopResult, error) {
+	if state.DryRun {
+		return dryRunStopResult(), nil
+	}
+	activePubSubDisruptionsMutex.Lock()
+	disruption := activePubSubDisruptions[state.ExecutionID]
+	delete(activePubSubDisruptions, state.ExecutionID)
+	activePubSubDisruptionsMutex.Unlock()
+
+	if disruption != nil {
+		disruption.cancel()
+		<-disruption.done
+		state.MessagesPublished = disruption.messagesPublished.Load()
+		state.MessagesReceived = disruption.messagesReceived.Load()
+		state.ClientsKilled = disruption.clientsKilled.Load()
+	}
+
+	var message string
+	if state.Mode == pubSubModeFlood {
+		message = fmt.Sprintf("Stopped Pub/Sub flood after publishing %d messages (%d deliveries to subscribers)", state.MessagesPublished, state.MessagesReceived)
+	} else {
+		message = fmt.Sprintf("Stopped killing Pub/Sub clients after %d disconnect(s)", state.ClientsKilled)
+	}
+
+	return &action_kit_api.StopResult{
+		Messages: new([]action_kit_api.Message{
+			{
+				Level:   extutil.Ptr(action_kit_api.Info),
+				Message: message,
+			},
+		}),
+	}, nil
+}
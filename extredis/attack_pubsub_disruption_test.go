@@ -0,0 +1,436 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2024 Steadybit GmbH
+
+package extredis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/alicebob/miniredis/v2/server"
+	"github.com/google/uuid"
+	"github.com/steadybit/action-kit/go/action_kit_api/v2"
+	"github.com/steadybit/extension-kit/extutil"
+	"github.com/steadybit/extension-redis/clients"
+	"github.com/steadybit/extension-redis/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPubSubDisruptionAttack_Describe(t *testing.T) {
+	// Given
+	action := &pubSubDisruptionAttack{}
+
+	// When
+	desc := action.Describe()
+
+	// Then
+	assert.Equal(t, "com.steadybit.extension_redis.instance.pubsub-disruption", desc.Id)
+	assert.Equal(t, "Disrupt Pub/Sub", desc.Label)
+	assert.Contains(t, desc.Description, "CLIENT KILL TYPE pubsub")
+	assert.Equal(t, TargetTypeInstance, desc.TargetSelection.TargetType)
+	assert.Equal(t, action_kit_api.Attack, desc.Kind)
+	assert.Equal(t, action_kit_api.TimeControlExternal, desc.TimeControl)
+
+	paramNames := make([]string, len(desc.Parameters))
+	for i, p := range desc.Parameters {
+		paramNames[i] = p.Name
+	}
+	assert.Contains(t, paramNames, "duration")
+	assert.Contains(t, paramNames, "mode")
+	assert.Contains(t, paramNames, "channel")
+	assert.Contains(t, paramNames, "messagesPerSecond")
+	assert.Contains(t, paramNames, "messageSizeBytes")
+	assert.Contains(t, paramNames, "killIntervalSeconds")
+}
+
+func TestPubSubDisruptionAttack_Prepare_MissingURL(t *testing.T) {
+	// Given
+	action := &pubSubDisruptionAttack{}
+	state := PubSubDisruptionState{}
+	req := extutil.JsonMangle(action_kit_api.PrepareActionRequestBody{
+		Target: &action_kit_api.Target{
+			Attributes: map[string][]string{},
+		},
+		Config: map[string]any{
+			"duration": float64(30000),
+			"mode":     pubSubModeFlood,
+			"channel":  "events",
+		},
+		ExecutionId: uuid.New(),
+	})
+
+	// When
+	_, err := action.Prepare(context.Background(), &state, req)
+
+	// Then
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "redis URL not found")
+}
+
+func TestPubSubDisruptionAttack_Prepare_FloodRequiresChannel(t *testing.T) {
+	// Given
+	action := &pubSubDisruptionAttack{}
+	state := PubSubDisruptionState{}
+	req := extutil.JsonMangle(action_kit_api.PrepareActionRequestBody{
+		Target: &action_kit_api.Target{
+			Attributes: map[string][]string{
+				AttrRedisURL: {"redis://localhost:6379"},
+			},
+		},
+		Config: map[string]any{
+			"duration": float64(30000),
+			"mode":     pubSubModeFlood,
+			"channel":  "",
+		},
+		ExecutionId: uuid.New(),
+	})
+
+	// When
+	_, err := action.Prepare(context.Background(), &state, req)
+
+	// Then
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "channel is required")
+}
+
+func TestPubSubDisruptionAttack_Prepare_InvalidMode(t *testing.T) {
+	// Given
+	action := &pubSubDisruptionAttack{}
+	state := PubSubDisruptionState{}
+	req := extutil.JsonMangle(action_kit_api.PrepareActionRequestBody{
+		Target: &action_kit_api.Target{
+			Attributes: map[string][]string{
+				AttrRedisURL: {"redis://localhost:6379"},
+			},
+		},
+		Config: map[string]any{
+			"duration": float64(30000),
+			"mode":     "explode",
+		},
+		ExecutionId: uuid.New(),
+	})
+
+	// When
+	_, err := action.Prepare(context.Background(), &state, req)
+
+	// Then
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported mode")
+}
+
+func TestPubSubDisruptionAttack_Prepare_SetsState(t *testing.T) {
+	// Given
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	action := &pubSubDisruptionAttack{}
+	state := PubSubDisruptionState{}
+	redisURL := fmt.Sprintf("redis://%s", mr.Addr())
+	executionID := uuid.New()
+	req := extutil.JsonMangle(action_kit_api.PrepareActionRequestBody{
+		Target: &action_kit_api.Target{
+			Attributes: map[string][]string{
+				AttrRedisURL: {redisURL},
+			},
+		},
+		Config: map[string]any{
+			"duration":          float64(45000),
+			"mode":              pubSubModeFlood,
+			"channel":           "events.*",
+			"messagesPerSecond": float64(500),
+			"messageSizeBytes":  float64(256),
+		},
+		ExecutionId: executionID,
+	})
+
+	// When
+	_, err = action.Prepare(context.Background(), &state, req)
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, redisURL, state.RedisURL)
+	assert.Equal(t, executionID.String(), state.ExecutionID)
+	assert.Equal(t, pubSubModeFlood, state.Mode)
+	assert.Equal(t, "events.*", state.Channel)
+	assert.Equal(t, 500, state.MessagesPerSecond)
+	assert.Equal(t, 256, state.MessageSizeBytes)
+	assert.WithinDuration(t, time.Now().Add(45*time.Second), time.Unix(state.EndTime, 0), 2*time.Second)
+}
+
+func TestPubSubDisruptionAttack_Prepare_FloodExceedsThroughputLimit(t *testing.T) {
+	// Given
+	action := &pubSubDisruptionAttack{}
+	state := PubSubDisruptionState{}
+	req := extutil.JsonMangle(action_kit_api.PrepareActionRequestBody{
+		Target: &action_kit_api.Target{
+			Attributes: map[string][]string{
+				AttrRedisURL: {"redis://localhost:6379"},
+			},
+		},
+		Config: map[string]any{
+			"duration":          float64(30000),
+			"mode":              pubSubModeFlood,
+			"channel":           "events",
+			"messagesPerSecond": float64(100000),
+			"messageSizeBytes":  float64(1048576),
+		},
+		ExecutionId: uuid.New(),
+	})
+
+	// When
+	_, err := action.Prepare(context.Background(), &state, req)
+
+	// Then
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "exceeds the limit of 100 MB/s")
+}
+
+func TestPubSubDisruptionAttack_NewEmptyState(t *testing.T) {
+	// Given
+	action := &pubSubDisruptionAttack{}
+
+	// When
+	state := action.NewEmptyState()
+
+	// Then
+	assert.Equal(t, PubSubDisruptionState{}, state)
+}
+
+func TestPubSubDisruptionAttack_Flood_PublishesToSubscribers(t *testing.T) {
+	// Given
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	redisURL := fmt.Sprintf("redis://%s", mr.Addr())
+	subscriber, err := createSingleConnectionClient(redisURL, 0)
+	require.NoError(t, err)
+	defer subscriber.Close()
+
+	sub := subscriber.Subscribe(context.Background(), "events")
+	defer sub.Close()
+	_, err = sub.Receive(context.Background())
+	require.NoError(t, err)
+
+	var received atomic.Int64
+	var payloadSize atomic.Int64
+	go func() {
+		for msg := range sub.Channel() {
+			payloadSize.Store(int64(len(msg.Payload)))
+			received.Add(1)
+		}
+	}()
+
+	action := &pubSubDisruptionAttack{}
+	state := PubSubDisruptionState{
+		RedisURL:          redisURL,
+		ExecutionID:       uuid.New().String(),
+		Mode:              pubSubModeFlood,
+		Channel:           "events",
+		MessagesPerSecond: 100,
+		MessageSizeBytes:  16,
+		EndTime:           time.Now().Add(30 * time.Second).Unix(),
+	}
+
+	// When
+	_, err = action.Start(context.Background(), &state)
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		_, err := action.Status(context.Background(), &state)
+		return err == nil && state.MessagesReceived > 0 && received.Load() > 0
+	}, 5*time.Second, 50*time.Millisecond)
+
+	result, err := action.Status(context.Background(), &state)
+	require.NoError(t, err)
+	assert.False(t, result.Completed)
+	require.NotNil(t, result.Messages)
+	assert.Contains(t, (*result.Messages)[0].Message, "pubsub_channels: 1")
+
+	_, err = action.Stop(context.Background(), &state)
+
+	// Then
+	require.NoError(t, err)
+	assert.Positive(t, state.MessagesPublished)
+	assert.Equal(t, int64(16), payloadSize.Load())
+
+	activePubSubDisruptionsMutex.Lock()
+	_, stillActive := activePubSubDisruptions[state.ExecutionID]
+	activePubSubDisruptionsMutex.Unlock()
+	assert.False(t, stillActive)
+}
+
+func TestPubSubDisruptionAttack_Start_PatternWithoutActiveChannels(t *testing.T) {
+	// Given
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	action := &pubSubDisruptionAttack{}
+	state := PubSubDisruptionState{
+		RedisURL:          fmt.Sprintf("redis://%s", mr.Addr()),
+		ExecutionID:       uuid.New().String(),
+		Mode:              pubSubModeFlood,
+		Channel:           "events.*",
+		MessagesPerSecond: 10,
+		MessageSizeBytes:  16,
+		EndTime:           time.Now().Add(30 * time.Second).Unix(),
+	}
+
+	// When
+	_, err = action.Start(context.Background(), &state)
+
+	// Then
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no active channels found")
+}
+
+func TestResolvePubSubChannels_ExpandsPattern(t *testing.T) {
+	// Given
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	client, err := createSingleConnectionClient(fmt.Sprintf("redis://%s", mr.Addr()), 0)
+	require.NoError(t, err)
+	defer client.Close()
+
+	subscriber, err := createSingleConnectionClient(fmt.Sprintf("redis://%s", mr.Addr()), 0)
+	require.NoError(t, err)
+	defer subscriber.Close()
+
+	sub := subscriber.Subscribe(context.Background(), "events.a", "events.b", "other")
+	defer sub.Close()
+	for range 3 {
+		_, err = sub.Receive(context.Background())
+		require.NoError(t, err)
+	}
+
+	// When
+	channels, err := resolvePubSubChannels(context.Background(), client, &PubSubDisruptionState{Channel: "events.*"})
+
+	// Then
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"events.a", "events.b"}, channels)
+
+	plain, err := resolvePubSubChannels(context.Background(), client, &PubSubDisruptionState{Channel: "plain"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"plain"}, plain)
+}
+
+// usePubSubCluster configures a cluster endpoint of two masters, each with a subscriber to its own events channel,
+// to "other" and to a pattern. It returns the endpoint URL.
+func usePubSubCluster(t *testing.T) string {
+	t.Helper()
+	seed := miniredis.RunT(t)
+	other := miniredis.RunT(t)
+	seed.Server().SetPreHook(func(c *server.Peer, cmd string, args ...string) bool {
+		if cmd != "CLUSTER" || len(args) == 0 || !strings.EqualFold(args[0], "NODES") {
+			return false
+		}
+		c.WriteBulk(fmt.Sprintf("m1 %s@16379 myself,master - 0 0 1 connected 0-8191\n"+
+			"m2 %s@16379 master - 0 0 2 connected 8192-16383\n", seed.Addr(), other.Addr()))
+		return true
+	})
+	redisURL := fmt.Sprintf("redis://%s", seed.Addr())
+	origEndpoints := config.Config.Endpoints
+	t.Cleanup(func() { config.Config.Endpoints = origEndpoints })
+	config.Config.Endpoints = []config.RedisEndpoint{{URL: redisURL, ClusterMode: "cluster"}}
+	t.Cleanup(func() { clients.EvictClients(redisURL) })
+
+	for i, mr := range []*miniredis.Miniredis{seed, other} {
+		subscriber, err := createSingleConnectionClient(fmt.Sprintf("redis://%s", mr.Addr()), 0)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = subscriber.Close() })
+		sub := subscriber.Subscribe(context.Background(), fmt.Sprintf("events.%d", i), "other")
+		t.Cleanup(func() { _ = sub.Close() })
+		require.NoError(t, sub.PSubscribe(context.Background(), "jobs.*"))
+		for range 3 {
+			_, err = sub.Receive(context.Background())
+			require.NoError(t, err)
+		}
+	}
+	return redisURL
+}
+
+func TestResolvePubSubChannels_ClusterCollectsChannelsOfAllMasters(t *testing.T) {
+	// Given
+	redisURL := usePubSubCluster(t)
+	client, err := createSingleConnectionClient(redisURL, 0)
+	require.NoError(t, err)
+	defer client.Close()
+
+	// When
+	channels, err := resolvePubSubChannels(context.Background(), client, &PubSubDisruptionState{
+		RedisURL:    redisURL,
+		Channel:     "events.*",
+		ClusterMode: true,
+	})
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, []string{"events.0", "events.1"}, channels)
+}
+
+func TestPubSubDisruptionAttack_Status_ClusterCountsAllMasters(t *testing.T) {
+	// Given
+	redisURL := usePubSubCluster(t)
+	action := &pubSubDisruptionAttack{}
+	state := PubSubDisruptionState{
+		RedisURL:    redisURL,
+		ExecutionID: uuid.New().String(),
+		Mode:        pubSubModeKillSubscribers,
+		EndTime:     time.Now().Add(30 * time.Second).Unix(),
+		ClusterMode: true,
+	}
+
+	// When
+	result, err := action.Status(context.Background(), &state)
+
+	// Then
+	require.NoError(t, err)
+	require.NotEmpty(t, *result.Messages)
+	assert.Contains(t, (*result.Messages)[0].Message, "pubsub_channels: 3, pubsub_patterns: 2")
+}
+
+func TestPubSubDisruptionAttack_Start_ConnectionError(t *testing.T) {
+	// Given
+	action := &pubSubDisruptionAttack{}
+	state := PubSubDisruptionState{
+		RedisURL:    "redis://localhost:59999",
+		ExecutionID: uuid.New().String(),
+		Mode:        pubSubModeKillSubscribers,
+		EndTime:     time.Now().Add(30 * time.Second).Unix(),
+	}
+
+	// When
+	_, err := action.Start(context.Background(), &state)
+
+	// Then
+	require.Error(t, err)
+}
+
+func TestPubSubDisruptionAttack_Stop_WithoutStart(t *testing.T) {
+	// Given
+	action := &pubSubDisruptionAttack{}
+	state := PubSubDisruptionState{
+		ExecutionID:   uuid.New().String(),
+		Mode:          pubSubModeKillSubscribers,
+		ClientsKilled: 3,
+	}
+
+	// When
+	result, err := action.Stop(context.Background(), &state)
+
+	// Then
+	require.NoError(t, err)
+	require.NotNil(t, result.Messages)
+	assert.Contains(t, (*result.Messages)[0].Message, "3 disconnect(s)")
+}
@@ -15,13 +15,16 @@ import (
 	"github.com/steadybit/extension-kit/extbuild"
 	"github.com/steadybit/extension-kit/extutil"
 	"github.com/steadybit/extension-redis/clients"
+	"github.com/steadybit/extension-redis/config"
 )
 
+const sentinelStopActionID = "com.steadybit.extension_redis.instance.sentinel-stop"
+
 type sentinelStopAttack struct{}
 
 type SentinelStopState struct {
 	RedisURL string `json:"redisUrl"`
-	Password string `json:"password"`
+	DryRun   bool   `json:"dryRun"`
 	DB       int    `json:"db"`
 	EndTime  int64  `json:"endTime"`
 }
@@ -39,7 +42,7 @@ func (a *sentinelStopAttack) NewEmptyState() SentinelStopState {
 
 func (a *sentinelStopAttack) Describe() action_kit_api.ActionDescription {
 	return action_kit_api.ActionDescription{
-		Id:          "com.steadybit.extension_redis.instance.sentinel-stop",
+		Id:          sentinelStopActionID,
 		Label:       "Stop Sentinel",
 		Description: "Stops the Redis Sentinel server for a specific duration using DEBUG SLEEP, making it unresponsive to all clients and other Sentinels",
 		Version:     extbuild.GetSemverVersionStringOrUnknown(),
@@ -67,6 +70,7 @@ func (a *sentinelStopAttack) Describe() action_kit_api.ActionDescription {
 				DefaultValue: new("30s"),
 				Required:     new(true),
 			},
+			dryRunParameter(),
 		},
 	}
 }
@@ -76,10 +80,16 @@ func (a *sentinelStopAttack) Prepare(ctx context.Context, state *SentinelStopSta
 	if len(redisURL) == 0 {
 		return nil, fmt.Errorf("redis URL not found in target attributes")
 	}
+	// DEBUG SLEEP blocks the server like CLIENT PAUSE, it is refused on read-only endpoints as well
+	dryRun := dryRunRequested(request)
+	if _, err := checkGuardrails(request.Target.Attributes, sentinelStopActionID, !dryRun); err != nil {
+		return nil, err
+	}
 
 	duration := extutil.ToInt64(request.Config["duration"]) / 1000
 
-	state.RedisURL = redisURL[0]
+	state.RedisURL = config.RedactURL(redisURL[0])
+	state.DryRun = dryRun
 	state.DB = 0
 	state.EndTime = time.Now().Add(time.Duration(duration) * time.Second).Unix()
 
@@ -96,7 +106,7 @@ func (a *sentinelStopAttack) Prepare(ctx context.Context, state *SentinelStopSta
 }
 
 func (a *sentinelStopAttack) Start(ctx context.Context, state *SentinelStopState) (*action_kit_api.StartResult, error) {
-	client, err := clients.GetRedisClient(state.RedisURL, state.Password, state.DB)
+	client, err := clients.GetRedisClient(state.RedisURL, "", state.DB)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Redis client: %w", err)
 	}
@@ -134,6 +144,12 @@ func (a *sentinelStopAttack) Start(ctx context.Context, state *SentinelStopState
 		return nil, fmt.Errorf("sleep duration must be positive")
 	}
 
+	if state.DryRun {
+		plan := &dryRunPlan{messages: messages}
+		plan.info("would stop %s via DEBUG SLEEP for %d seconds", client.Options().Addr, sleepSeconds)
+		return plan.startResult(), nil
+	}
+
 	// DEBUG SLEEP blocks the entire Redis event loop, making the Sentinel completely unresponsive
 	// The Sentinel will automatically recover after the sleep duration
 	err = client.Do(ctx, "DEBUG", "SLEEP", sleepSeconds).Err()
@@ -152,11 +168,14 @@ func (a *sentinelStopAttack) Start(ctx context.Context, state *SentinelStopState
 }
 
 func (a *sentinelStopAttack) Status(ctx context.Context, state *SentinelStopState) (*action_kit_api.StatusResult, error) {
+	if state.DryRun {
+		return dryRunStatusResult(), nil
+	}
 	now := time.Now().Unix()
 	remaining := state.EndTime - now
 
 	// Try to ping — if it succeeds, the DEBUG SLEEP is over
-	client, err := clients.GetRedisClient(state.RedisURL, state.Password, state.DB)
+	client, err := clients.GetRedisClient(state.RedisURL, "", state.DB)
 	if err == nil {
 		if clients.PingRedis(ctx, client) == nil {
 			return &action_kit_api.StatusResult{
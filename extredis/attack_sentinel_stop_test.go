@@ -34,8 +34,9 @@ func TestSentinelStopAttack_Describe(t *testing.T) {
 
 	// Check parameters
 	require.NotNil(t, desc.Parameters)
-	require.Len(t, desc.Parameters, 1)
+	require.Len(t, desc.Parameters, 2)
 	assert.Equal(t, "duration", desc.Parameters[0].Name)
+	assert.Equal(t, "dryRun", desc.Parameters[1].Name)
 }
 
 func TestSentinelStopAttack_Prepare_MissingURL(t *testing.T) {
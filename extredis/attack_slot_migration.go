@@ -0,0 +1,666 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package extredis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+	"github.com/steadybit/action-kit/go/action_kit_api/v2"
+	"github.com/steadybit/action-kit/go/action_kit_sdk"
+	"github.com/steadybit/extension-kit/extbuild"
+	"github.com/steadybit/extension-kit/extutil"
+	"github.com/steadybit/extension-redis/clients"
+	"github.com/steadybit/extension-redis/config"
+	"github.com/steadybit/extension-redis/journal"
+)
+
+const (
+	slotMigrationActionID = "com.steadybit.extension_redis.instance.slot-migration"
+
+	slotMigrationComplete = "complete"
+	slotMigrationHalf     = "half"
+
+	// A slot is MIGRATING on the source and IMPORTING on the destination, its keys may be split between both
+	slotPhaseMigrating = "migrating"
+	// The destination owns the slot
+	slotPhaseMoved = "moved"
+
+	slotMigrationBatchSize = 100
+	slotMigrationTimeout   = 2 * time.Second
+)
+
+type slotMigrationAttack struct{}
+
+type SlotMigrationState struct {
+	RedisURL    string         `json:"redisUrl"`
+	DryRun      bool           `json:"dryRun"`
+	ExecutionID string         `json:"executionId"`
+	Mode        string         `json:"mode"`
+	KeyPercent  int            `json:"keyPercent"`
+	MaxKeys     int            `json:"maxKeys"`
+	MaxBytes    int64          `json:"maxBytes"`
+	SourceID    string         `json:"sourceId"`
+	SourceAddr  string         `json:"sourceAddr"`
+	DestID      string         `json:"destId"`
+	DestAddr    string         `json:"destAddr"`
+	Slots       []MigratedSlot `json:"slots"`
+	EndTime     int64          `json:"endTime"`
+	// Keys and bytes Prepare expects to move
+	PlannedKeys  int   `json:"plannedKeys"`
+	PlannedBytes int64 `json:"plannedBytes"`
+	// Keys and bytes moved to the destination so far, bounded by MaxKeys and MaxBytes
+	MigratedKeys  int   `json:"migratedKeys"`
+	MigratedBytes int64 `json:"migratedBytes"`
+}
+
+// MigratedSlot tracks a single slot, so that Stop can move it back from whatever phase it reached.
+type MigratedSlot struct {
+	Slot  int    `json:"slot"`
+	Keys  int    `json:"keys"`
+	Phase string `json:"phase,omitempty"`
+}
+
+var _ action_kit_sdk.Action[SlotMigrationState] = (*slotMigrationAttack)(nil)
+var _ action_kit_sdk.ActionWithStop[SlotMigrationState] = (*slotMigrationAttack)(nil)
+var _ action_kit_sdk.ActionWithStatus[SlotMigrationState] = (*slotMigrationAttack)(nil)
+
+func NewSlotMigrationAttack() action_kit_sdk.Action[SlotMigrationState] {
+	return &slotMigrationAttack{}
+}
+
+func (a *slotMigrationAttack) NewEmptyState() SlotMigrationState {
+	return SlotMigrationState{}
+}
+
+func (a *slotMigrationAttack) Describe() action_kit_api.ActionDescription {
+	return action_kit_api.ActionDescription{
+		Id:          slotMigrationActionID,
+		Label:       "Migrate Cluster Slots",
+		Description: "Migrates hash slots of the targeted cluster master to another master with CLUSTER SETSLOT and MIGRATE, or leaves them half-migrated, to cause MOVED and ASK redirects like a resharding does. The slots are moved back when the attack ends. Refuses to start if more keys or bytes than the limits would be moved.",
+		Version:     extbuild.GetSemverVersionStringOrUnknown(),
+		Icon:        new(redisIcon),
+		TargetSelection: new(action_kit_api.TargetSelection{
+			TargetType: TargetTypeInstance,
+			SelectionTemplates: new([]action_kit_api.TargetSelectionTemplate{
+				{
+					Label:       "by host and port",
+					Description: new("Find Redis instance by host and port"),
+					Query:       "redis.host=\"\" AND redis.port=\"\"",
+				},
+				{
+					Label:       "by cluster node",
+					Description: new("Find Redis cluster master by node ID"),
+					Query:       "redis.cluster.node_id=\"\"",
+				},
+			}),
+		}),
+		Technology:  new("Redis"),
+		Category:    new("state"),
+		Kind:        action_kit_api.Attack,
+		TimeControl: action_kit_api.TimeControlExternal,
+		Parameters: []action_kit_api.ActionParameter{
+			{
+				Name:         "duration",
+				Label:        "Duration",
+				Description:  new("How long the slots stay migrated"),
+				Type:         action_kit_api.ActionParameterTypeDuration,
+				DefaultValue: new("60s"),
+				Required:     new(true),
+			},
+			{
+				Name:         "migrationMode",
+				Label:        "Migration Mode",
+				Description:  new("Move the slots to the other master, or leave them MIGRATING/IMPORTING with a part of their keys moved"),
+				Type:         action_kit_api.ActionParameterTypeString,
+				DefaultValue: new(slotMigrationComplete),
+				Required:     new(true),
+				Options: new([]action_kit_api.ParameterOption{
+					action_kit_api.ExplicitParameterOption{
+						Label: "Complete (MOVED redirects)",
+						Value: slotMigrationComplete,
+					},
+					action_kit_api.ExplicitParameterOption{
+						Label: "Half-migrated (ASK redirects)",
+						Value: slotMigrationHalf,
+					},
+				}),
+			},
+			{
+				Name:         "slotCount",
+				Label:        "Slot Count",
+				Description:  new("Number of slots of the target master to migrate, starting with its lowest slot. Ignored if Slots is set."),
+				Type:         action_kit_api.ActionParameterTypeInteger,
+				DefaultValue: new("1"),
+				Required:     new(false),
+				MinValue:     new(1),
+				MaxValue:     new(100),
+			},
+			{
+				Name:         "slots",
+				Label:        "Slots",
+				Description:  new("Slots to migrate, e.g. '100,200-205'. They must be served by the same master."),
+				Type:         action_kit_api.ActionParameterTypeString,
+				DefaultValue: new(""),
+				Required:     new(false),
+				Advanced:     new(true),
+			},
+			{
+				Name:         "keyPercent",
+				Label:        "Key Percentage",
+				Description:  new("Percentage of the keys of each slot moved in half-migrated mode"),
+				Type:         action_kit_api.ActionParameterTypePercentage,
+				DefaultValue: new("50"),
+				Required:     new(false),
+				MinValue:     new(0),
+				MaxValue:     new(100),
+			},
+			{
+				Name:         "maxKeys",
+				Label:        "Max Keys",
+				Description:  new("Maximum number of keys moved to the other master"),
+				Type:         action_kit_api.ActionParameterTypeInteger,
+				DefaultValue: new("1000"),
+				Required:     new(true),
+				MinValue:     new(1),
+				MaxValue:     new(100000),
+			},
+			{
+				Name:         "maxSizeMb",
+				Label:        "Max Size (MB)",
+				Description:  new("Maximum memory of the keys moved to the other master, measured with MEMORY USAGE"),
+				Type:         action_kit_api.ActionParameterTypeInteger,
+				DefaultValue: new("10"),
+				Required:     new(true),
+				MinValue:     new(1),
+				MaxValue:     new(1024),
+			},
+			dryRunParameter(),
+		},
+	}
+}
+
+func (a *slotMigrationAttack) Prepare(ctx context.Context, state *SlotMigrationState, request action_kit_api.PrepareActionRequestBody) (*action_kit_api.PrepareResult, error) {
+	redisURL := request.Target.Attributes[AttrRedisURL]
+	if len(redisURL) == 0 {
+		return nil, fmt.Errorf("redis URL not found in target attributes")
+	}
+	dryRun := dryRunRequested(request)
+	guardrails, err := checkGuardrails(request.Target.Attributes, slotMigrationActionID, !dryRun)
+	if err != nil {
+		return nil, err
+	}
+	// Node targets carry the URL of the node, the attack works on the cluster of their endpoint
+	endpoint := targetEndpoint(request.Target.Attributes)
+	if endpoint == nil {
+		return nil, fmt.Errorf("no endpoint configured for %s", redisURL[0])
+	}
+
+	duration := extutil.ToInt64(request.Config["duration"]) / 1000 // Convert ms to seconds
+	mode := extutil.ToString(request.Config["migrationMode"])
+	slotCount := int(extutil.ToInt64(request.Config["slotCount"]))
+	slotList := extutil.ToString(request.Config["slots"])
+	keyPercent := int(extutil.ToInt64(request.Config["keyPercent"]))
+	maxKeys := int(extutil.ToInt64(request.Config["maxKeys"]))
+	maxSizeMb := extutil.ToInt64(request.Config["maxSizeMb"])
+
+	if mode == "" {
+		mode = slotMigrationComplete
+	}
+	if mode != slotMigrationComplete && mode != slotMigrationHalf {
+		return nil, fmt.Errorf("unsupported migrationMode %q (expected %q or %q)", mode, slotMigrationComplete, slotMigrationHalf)
+	}
+	if keyPercent < 0 || keyPercent > 100 {
+		return nil, fmt.Errorf("keyPercent must be between 0 and 100")
+	}
+	if maxKeys <= 0 || maxSizeMb <= 0 {
+		return nil, fmt.Errorf("maxKeys and maxSizeMb must be positive")
+	}
+	if slotCount <= 0 {
+		slotCount = 1
+	}
+
+	topology, err := clients.GetTopology(ctx, endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cluster topology: %w", err)
+	}
+	if !topology.Cluster {
+		return nil, fmt.Errorf("slot migration needs a Redis Cluster, %s is not in cluster mode", endpoint.URL)
+	}
+
+	var targetNodeID string
+	if ids := request.Target.Attributes[AttrRedisClusterNodeID]; len(ids) > 0 {
+		targetNodeID = ids[0]
+	}
+	source, slots, err := selectMigrationSlots(topology, targetNodeID, slotList, slotCount)
+	if err != nil {
+		return nil, err
+	}
+	dest, ok := selectMigrationDestination(topology, source.ID)
+	if !ok {
+		return nil, fmt.Errorf("slot migration needs at least two masters")
+	}
+
+	state.RedisURL = config.RedactURL(endpoint.URL)
+	state.DryRun = dryRun
+	state.ExecutionID = request.ExecutionId.String()
+	state.Mode = mode
+	state.KeyPercent = keyPercent
+	state.MaxKeys = maxKeys
+	state.MaxBytes = maxSizeMb * 1024 * 1024
+	state.SourceID = source.ID
+	state.SourceAddr = source.Addr
+	state.DestID = dest.ID
+	state.DestAddr = dest.Addr
+	state.EndTime = time.Now().Add(time.Duration(duration) * time.Second).Unix()
+	state.Slots = make([]MigratedSlot, 0, len(slots))
+	for _, slot := range slots {
+		state.Slots = append(state.Slots, MigratedSlot{Slot: slot})
+	}
+
+	// Refuse before anything is changed if the limits would be exceeded, Start checks them again while moving keys
+	sourceClient, err := clients.CreateDirectClient(endpoint, source.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Redis client for %s: %w", source.Addr, err)
+	}
+	defer sourceClient.Close()
+
+	var plannedKeys, slotKeys int
+	var plannedBytes int64
+	for _, slot := range slots {
+		count, err := sourceClient.ClusterCountKeysInSlot(ctx, slot).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to count keys in slot %d: %w", slot, err)
+		}
+		// Every key of a migrated slot is affected, whether it is moved or left behind
+		slotKeys += int(count)
+		if err := guardrails.CheckAffectedKeys(slotKeys); err != nil {
+			return nil, err
+		}
+		if guardrails.HasProtectedKeys() && count > 0 {
+			keys, err := sourceClient.ClusterGetKeysInSlot(ctx, slot, int(count)).Result()
+			if err != nil {
+				return nil, fmt.Errorf("failed to get keys in slot %d: %w", slot, err)
+			}
+			if err := guardrails.CheckProtectedKeys(keys); err != nil {
+				return nil, err
+			}
+		}
+		n := state.keysToMove(int(count))
+		plannedKeys += n
+		if plannedKeys > state.MaxKeys {
+			return nil, fmt.Errorf("migration would move more than %d keys (maxKeys), select fewer slots or raise the limit", state.MaxKeys)
+		}
+		keys, err := sourceClient.ClusterGetKeysInSlot(ctx, slot, n).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get keys in slot %d: %w", slot, err)
+		}
+		bytes, err := keysMemoryUsage(ctx, sourceClient, keys)
+		if err != nil {
+			return nil, err
+		}
+		plannedBytes += bytes
+		if plannedBytes > state.MaxBytes {
+			return nil, fmt.Errorf("migration would move more than %d MB (maxSizeMb), select fewer slots or raise the limit", maxSizeMb)
+		}
+	}
+	state.PlannedKeys = plannedKeys
+	state.PlannedBytes = plannedBytes
+
+	return nil, nil
+}
+
+func (a *slotMigrationAttack) Start(ctx context.Context, state *SlotMigrationState) (*action_kit_api.StartResult, error) {
+	if state.DryRun {
+		return a.dryRun(ctx, state)
+	}
+	endpoint := config.GetEndpointByURL(state.RedisURL)
+	if endpoint == nil {
+		return nil, fmt.Errorf("no endpoint configured for %s", state.RedisURL)
+	}
+	source, dest, closeClients, err := slotMigrationClients(endpoint, state)
+	if err != nil {
+		return nil, err
+	}
+	defer closeClients()
+	defer clients.InvalidateTopology(endpoint.URL)
+
+	for i := range state.Slots {
+		if err := migrateSlot(ctx, source, dest, state, &state.Slots[i]); err != nil {
+			log.Error().Err(err).Int("slot", state.Slots[i].Slot).Msg("Slot migration failed, moving slots back")
+			if revertErr := revertSlots(ctx, source, dest, state); revertErr != nil {
+				return nil, fmt.Errorf("slot migration failed: %w; moving the slots back failed: %w", err, revertErr)
+			}
+			journal.Remove(state.ExecutionID)
+			return nil, fmt.Errorf("slot migration failed, all slots were moved back: %w", err)
+		}
+	}
+
+	return &action_kit_api.StartResult{
+		Messages: new([]action_kit_api.Message{
+			{
+				Level:   extutil.Ptr(action_kit_api.Info),
+				Message: state.summary(),
+			},
+		}),
+	}, nil
+}
+
+// dryRun reports the slots Start would migrate and the keys it would move, as planned by Prepare.
+func (a *slotMigrationAttack) dryRun(_ context.Context, state *SlotMigrationState) (*action_kit_api.StartResult, error) {
+	slots := make([]string, 0, len(state.Slots))
+	for _, s := range state.Slots {
+		slots = append(slots, strconv.Itoa(s.Slot))
+	}
+
+	plan := &dryRunPlan{}
+	plan.info("would migrate %d slot(s) from %s (%s) to %s (%s) in %s mode: %s", len(state.Slots), state.SourceAddr, state.SourceID, state.DestAddr, state.DestID, state.Mode, formatList(slots))
+	plan.info("would move %d keys (%d bytes) to the destination and move them back on stop", state.PlannedKeys, state.PlannedBytes)
+	if state.Mode == slotMigrationHalf {
+		plan.info("the slots would stay MIGRATING on the source and IMPORTING on the destination, clients get ASK redirects for moved keys")
+	}
+	return plan.startResult(), nil
+}
+
+func (a *slotMigrationAttack) Status(_ context.Context, state *SlotMigrationState) (*action_kit_api.StatusResult, error) {
+	if state.DryRun {
+		return dryRunStatusResult(), nil
+	}
+	now := time.Now().Unix()
+	completed := now >= state.EndTime
+
+	return &action_kit_api.StatusResult{
+		Completed: completed,
+		Messages: new(append([]action_kit_api.Message{
+			{
+				Level:   extutil.Ptr(action_kit_api.Info),
+				Message: state.summary(),
+			},
+		}, journalRecoveryMessages(state.ExecutionID)...)),
+	}, nil
+}
+
+func (a *slotMigrationAttack) Stop(ctx context.Context, state *SlotMigrationState) (*action_kit_api.StopResult, error) {
+	if state.DryRun {
+		return dryRunStopResult(), nil
+	}
+	if recoveredByJournal(state.ExecutionID) {
+		return journalRecoveredStopResult(state.ExecutionID), nil
+	}
+	endpoint := config.GetEndpointByURL(state.RedisURL)
+	if endpoint == nil {
+		return nil, fmt.Errorf("no endpoint configured for %s", state.RedisURL)
+	}
+	source, dest, closeClients, err := slotMigrationClients(endpoint, state)
+	if err != nil {
+		return nil, err
+	}
+	defer closeClients()
+	defer clients.InvalidateTopology(endpoint.URL)
+
+	if err := revertSlots(ctx, source, dest, state); err != nil {
+		log.Error().Err(err).Msg("Failed to move slots back")
+		return nil, fmt.Errorf("failed to move slots back to %s: %w", state.SourceAddr, err)
+	}
+	journal.Remove(state.ExecutionID)
+
+	return &action_kit_api.StopResult{
+		Messages: new([]action_kit_api.Message{
+			{
+				Level:   extutil.Ptr(action_kit_api.Info),
+				Message: fmt.Sprintf("Moved %d slot(s) back to %s", len(state.Slots), state.SourceAddr),
+			},
+		}),
+	}, nil
+}
+
+// selectMigrationSlots picks the master whose slots are migrated and the slots. Explicit slots must share a master.
+// Otherwise the targeted master, or the master of a targeted replica, gives up its lowest slotCount slots.
+func selectMigrationSlots(topology *clients.Topology, targetNodeID, slotList string, slotCount int) (clients.ClusterNodeInfo, []int, error) {
+	if strings.TrimSpace(slotList) != "" {
+		slots, err := clients.ParseSlotList(slotList)
+		if err != nil {
+			return clients.ClusterNodeInfo{}, nil, err
+		}
+		if len(slots) == 0 {
+			return clients.ClusterNodeInfo{}, nil, fmt.Errorf("no slots given")
+		}
+		var source clients.ClusterNodeInfo
+		for _, slot := range slots {
+			owner, ok := topology.SlotOwner(slot)
+			if !ok {
+				return clients.ClusterNodeInfo{}, nil, fmt.Errorf("slot %d is not served by any master", slot)
+			}
+			if source.ID != "" && owner.ID != source.ID {
+				return clients.ClusterNodeInfo{}, nil, fmt.Errorf("slots are served by different masters (%s and %s), migrate them in separate attacks", source.Addr, owner.Addr)
+			}
+			source = owner
+		}
+		return source, slots, nil
+	}
+
+	source, ok := topology.NodeByID(targetNodeID)
+	if ok && source.Role != "master" {
+		source, ok = topology.NodeByID(source.MasterID)
+	}
+	if !ok {
+		return clients.ClusterNodeInfo{}, nil, fmt.Errorf("target is not a known cluster node, select a cluster master or set the slots")
+	}
+	if len(source.Slots) == 0 {
+		return clients.ClusterNodeInfo{}, nil, fmt.Errorf("master %s serves no slots", source.Addr)
+	}
+
+	var slots []int
+	for _, r := range source.Slots {
+		for slot := r.Start; slot <= r.End && len(slots) < slotCount; slot++ {
+			slots = append(slots, slot)
+		}
+	}
+	return source, slots, nil
+}
+
+// selectMigrationDestination picks the master serving the fewest slots, other than the source.
+func selectMigrationDestination(topology *clients.Topology, sourceID string) (clients.ClusterNodeInfo, bool) {
+	var dest clients.ClusterNodeInfo
+	found := false
+	for _, node := range topology.Masters() {
+		if node.ID == sourceID {
+			continue
+		}
+		if !found || clients.SlotCount(node.Slots) < clients.SlotCount(dest.Slots) {
+			dest = node
+			found = true
+		}
+	}
+	return dest, found
+}
+
+// keysToMove returns how many of the count keys of a slot are moved. Half-migrated slots move keyPercent of them,
+// rounded up.
+func (s *SlotMigrationState) keysToMove(count int) int {
+	if s.Mode != slotMigrationHalf {
+		return count
+	}
+	return (count*s.KeyPercent + 99) / 100
+}
+
+// reserve accounts keys that are about to be moved and fails if they exceed maxKeys or maxBytes.
+func (s *SlotMigrationState) reserve(ctx context.Context, client *redis.Client, keys []string) error {
+	if s.MigratedKeys+len(keys) > s.MaxKeys {
+		return fmt.Errorf("more than %d keys (maxKeys) would be moved", s.MaxKeys)
+	}
+	bytes, err := keysMemoryUsage(ctx, client, keys)
+	if err != nil {
+		return err
+	}
+	if s.MigratedBytes+bytes > s.MaxBytes {
+		return fmt.Errorf("more than %d bytes (maxSizeMb) would be moved", s.MaxBytes)
+	}
+	s.MigratedKeys += len(keys)
+	s.MigratedBytes += bytes
+	return nil
+}
+
+func (s *SlotMigrationState) summary() string {
+	slots := make([]string, 0, len(s.Slots))
+	for _, slot := range s.Slots {
+		slots = append(slots, fmt.Sprintf("%d", slot.Slot))
+	}
+	if s.Mode == slotMigrationHalf {
+		return fmt.Sprintf("Slot(s) %s half-migrated from %s to %s, %d key(s) moved", strings.Join(slots, ","), s.SourceAddr, s.DestAddr, s.MigratedKeys)
+	}
+	return fmt.Sprintf("Slot(s) %s migrated from %s to %s with %d key(s)", strings.Join(slots, ","), s.SourceAddr, s.DestAddr, s.MigratedKeys)
+}
+
+func slotMigrationClients(endpoint *config.RedisEndpoint, state *SlotMigrationState) (*redis.Client, *redis.Client, func(), error) {
+	source, err := clients.CreateDirectClient(endpoint, state.SourceAddr)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to create Redis client for %s: %w", state.SourceAddr, err)
+	}
+	dest, err := clients.CreateDirectClient(endpoint, state.DestAddr)
+	if err != nil {
+		_ = source.Close()
+		return nil, nil, nil, fmt.Errorf("failed to create Redis client for %s: %w", state.DestAddr, err)
+	}
+	return source, dest, func() {
+		_ = source.Close()
+		_ = dest.Close()
+	}, nil
+}
+
+// migrateSlot follows the resharding steps of the Redis Cluster specification. Half-migrated slots stop after
+// moving their share of keys, complete migrations move all keys and assign the slot to the destination.
+func migrateSlot(ctx context.Context, source, dest *redis.Client, state *SlotMigrationState, slot *MigratedSlot) error {
+	slot.Phase = slotPhaseMigrating
+	if err := recordJournal(slotMigrationActionID, state.ExecutionID, state.RedisURL, state); err != nil {
+		return err
+	}
+	if err := clients.ClusterSetSlot(ctx, dest, slot.Slot, clients.SlotImporting, state.SourceID); err != nil {
+		return err
+	}
+	if err := clients.ClusterSetSlot(ctx, source, slot.Slot, clients.SlotMigrating, state.DestID); err != nil {
+		return err
+	}
+
+	limit := -1
+	if state.Mode == slotMigrationHalf {
+		count, err := source.ClusterCountKeysInSlot(ctx, slot.Slot).Result()
+		if err != nil {
+			return fmt.Errorf("failed to count keys in slot %d: %w", slot.Slot, err)
+		}
+		limit = state.keysToMove(int(count))
+	}
+	moved, err := moveSlotKeys(ctx, source, state.DestAddr, slot.Slot, limit, state.reserve)
+	slot.Keys += moved
+	if err != nil || state.Mode == slotMigrationHalf {
+		return err
+	}
+
+	if err := clients.ClusterSetSlot(ctx, dest, slot.Slot, clients.SlotNode, state.DestID); err != nil {
+		return err
+	}
+	slot.Phase = slotPhaseMoved
+	if err := recordJournal(slotMigrationActionID, state.ExecutionID, state.RedisURL, state); err != nil {
+		return err
+	}
+	return clients.ClusterSetSlot(ctx, source, slot.Slot, clients.SlotNode, state.DestID)
+}
+
+// revertSlots moves all slots back to the source, the last one first. Slots that could not be moved back keep
+// their phase, so that another Stop retries them.
+func revertSlots(ctx context.Context, source, dest *redis.Client, state *SlotMigrationState) error {
+	var errs []error
+	for i := len(state.Slots) - 1; i >= 0; i-- {
+		slot := &state.Slots[i]
+		if slot.Phase == "" {
+			continue
+		}
+		if err := revertSlot(ctx, source, dest, state, slot); err != nil {
+			errs = append(errs, fmt.Errorf("slot %d: %w", slot.Slot, err))
+			continue
+		}
+		slot.Phase = ""
+		slot.Keys = 0
+	}
+	return errors.Join(errs...)
+}
+
+func revertSlot(ctx context.Context, source, dest *redis.Client, state *SlotMigrationState, slot *MigratedSlot) error {
+	if slot.Phase == slotPhaseMigrating {
+		if _, err := moveSlotKeys(ctx, dest, state.SourceAddr, slot.Slot, -1, nil); err != nil {
+			return err
+		}
+		if err := clients.ClusterSetSlot(ctx, dest, slot.Slot, clients.SlotStable, ""); err != nil {
+			return err
+		}
+		return clients.ClusterSetSlot(ctx, source, slot.Slot, clients.SlotStable, "")
+	}
+
+	// The source refuses IMPORTING if it never gave up the slot, because Start failed before assigning it there.
+	// It still accepts the keys as owner, so the migration back continues.
+	if err := clients.ClusterSetSlot(ctx, source, slot.Slot, clients.SlotImporting, state.DestID); err != nil {
+		log.Warn().Err(err).Int("slot", slot.Slot).Msg("Failed to set slot importing on source, continuing")
+	}
+	if err := clients.ClusterSetSlot(ctx, dest, slot.Slot, clients.SlotMigrating, state.SourceID); err != nil {
+		return err
+	}
+	if _, err := moveSlotKeys(ctx, dest, state.SourceAddr, slot.Slot, -1, nil); err != nil {
+		return err
+	}
+	if err := clients.ClusterSetSlot(ctx, source, slot.Slot, clients.SlotNode, state.SourceID); err != nil {
+		return err
+	}
+	return clients.ClusterSetSlot(ctx, dest, slot.Slot, clients.SlotNode, state.SourceID)
+}
+
+// moveSlotKeys migrates up to limit keys of a slot in batches, all keys if limit is negative. reserve is called
+// before each batch and may stop the migration.
+func moveSlotKeys(ctx context.Context, from *redis.Client, toAddr string, slot, limit int, reserve func(context.Context, *redis.Client, []string) error) (int, error) {
+	moved := 0
+	for limit < 0 || moved < limit {
+		batch := slotMigrationBatchSize
+		if limit >= 0 {
+			batch = min(batch, limit-moved)
+		}
+		keys, err := from.ClusterGetKeysInSlot(ctx, slot, batch).Result()
+		if err != nil {
+			return moved, fmt.Errorf("failed to get keys in slot %d: %w", slot, err)
+		}
+		if len(keys) == 0 {
+			return moved, nil
+		}
+		if reserve != nil {
+			if err := reserve(ctx, from, keys); err != nil {
+				return moved, err
+			}
+		}
+		if err := clients.MigrateKeys(ctx, from, toAddr, keys, slotMigrationTimeout); err != nil {
+			return moved, err
+		}
+		moved += len(keys)
+	}
+	return moved, nil
+}
+
+func keysMemoryUsage(ctx context.Context, client *redis.Client, keys []string) (int64, error) {
+	var total int64
+	for _, key := range keys {
+		usage, err := client.MemoryUsage(ctx, key).Result()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to get memory usage of %s: %w", key, err)
+		}
+		total += usage
+	}
+	return total, nil
+}
@@ -0,0 +1,404 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2024 Steadybit GmbH
+
+package extredis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/alicebob/miniredis/v2/server"
+	"github.com/go-redis/redismock/v9"
+	"github.com/google/uuid"
+	"github.com/steadybit/action-kit/go/action_kit_api/v2"
+	"github.com/steadybit/extension-kit/extutil"
+	"github.com/steadybit/extension-redis/clients"
+	"github.com/steadybit/extension-redis/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlotMigrationAttack_Describe(t *testing.T) {
+	// Given
+	action := &slotMigrationAttack{}
+
+	// When
+	desc := action.Describe()
+
+	// Then
+	assert.Equal(t, "com.steadybit.extension_redis.instance.slot-migration", desc.Id)
+	assert.Equal(t, "Migrate Cluster Slots", desc.Label)
+	assert.Contains(t, desc.Description, "CLUSTER SETSLOT")
+	assert.Equal(t, TargetTypeInstance, desc.TargetSelection.TargetType)
+	assert.Equal(t, action_kit_api.Attack, desc.Kind)
+	assert.Equal(t, action_kit_api.TimeControlExternal, desc.TimeControl)
+
+	paramNames := make([]string, len(desc.Parameters))
+	for i, p := range desc.Parameters {
+		paramNames[i] = p.Name
+	}
+	assert.Equal(t, []string{"duration", "migrationMode", "slotCount", "slots", "keyPercent", "maxKeys", "maxSizeMb", "dryRun"}, paramNames)
+}
+
+func TestSlotMigrationAttack_Prepare_Validation(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	redisURL := fmt.Sprintf("redis://%s", mr.Addr())
+	origEndpoints := config.Config.Endpoints
+	defer func() { config.Config.Endpoints = origEndpoints }()
+	config.Config.Endpoints = []config.RedisEndpoint{{URL: redisURL, ClusterMode: "standalone"}}
+
+	valid := map[string]any{"migrationMode": slotMigrationComplete, "keyPercent": float64(50), "maxKeys": float64(100), "maxSizeMb": float64(1)}
+	tests := []struct {
+		name    string
+		url     string
+		cfg     map[string]any
+		wantErr string
+	}{
+		{"unknown endpoint", "redis://unknown:6379", valid, "no endpoint configured"},
+		{"invalid mode", redisURL, map[string]any{"migrationMode": "partial", "maxKeys": float64(100), "maxSizeMb": float64(1)}, "unsupported migrationMode"},
+		{"key percent out of range", redisURL, map[string]any{"migrationMode": slotMigrationHalf, "keyPercent": float64(120), "maxKeys": float64(100), "maxSizeMb": float64(1)}, "keyPercent must be between 0 and 100"},
+		{"no key limit", redisURL, map[string]any{"migrationMode": slotMigrationComplete, "maxKeys": float64(0), "maxSizeMb": float64(1)}, "maxKeys and maxSizeMb must be positive"},
+		{"not a cluster", redisURL, valid, "needs a Redis Cluster"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			action := &slotMigrationAttack{}
+			state := SlotMigrationState{}
+			cfg := map[string]any{"duration": float64(60000)}
+			for k, v := range tc.cfg {
+				cfg[k] = v
+			}
+			req := extutil.JsonMangle(action_kit_api.PrepareActionRequestBody{
+				Target: &action_kit_api.Target{
+					Attributes: map[string][]string{AttrRedisURL: {tc.url}},
+				},
+				Config:      cfg,
+				ExecutionId: uuid.New(),
+			})
+
+			_, err := action.Prepare(context.Background(), &state, req)
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.wantErr)
+		})
+	}
+}
+
+func TestSlotMigrationAttack_Prepare_NodeTarget(t *testing.T) {
+	// Given - a cluster of two masters, the target is the node served by miniredis
+	mr := miniredis.RunT(t)
+	// The built-in CLUSTER of miniredis reports a single shard
+	mr.Server().SetPreHook(func(c *server.Peer, cmd string, args ...string) bool {
+		if cmd != "CLUSTER" || len(args) == 0 {
+			return false
+		}
+		switch strings.ToUpper(args[0]) {
+		case "NODES":
+			c.WriteBulk(fmt.Sprintf("m1 %s@16379 myself,master - 0 0 1 connected 0-100\n"+
+				"m2 127.0.0.1:1@16379 master - 0 0 2 connected 101-16383\n", mr.Addr()))
+		case "COUNTKEYSINSLOT":
+			c.WriteInt(0)
+		case "GETKEYSINSLOT":
+			c.WriteLen(0)
+		default:
+			return false
+		}
+		return true
+	})
+	// The endpoint is configured by host name, the node target carries the node address
+	endpointURL := fmt.Sprintf("redis://localhost:%d", mr.Server().Addr().Port)
+	origEndpoints := config.Config.Endpoints
+	defer func() { config.Config.Endpoints = origEndpoints }()
+	config.Config.Endpoints = []config.RedisEndpoint{{URL: endpointURL, ClusterMode: "cluster"}}
+	defer clients.InvalidateTopology(endpointURL)
+
+	action := &slotMigrationAttack{}
+	state := SlotMigrationState{}
+	req := extutil.JsonMangle(action_kit_api.PrepareActionRequestBody{
+		Target: &action_kit_api.Target{
+			Attributes: map[string][]string{
+				AttrRedisURL:           {clients.NodeURL("redis", mr.Addr())},
+				AttrRedisEndpointURL:   {endpointURL},
+				AttrRedisClusterNodeID: {"m1"},
+			},
+		},
+		Config:      map[string]any{"duration": float64(60000), "migrationMode": slotMigrationComplete, "maxKeys": float64(100), "maxSizeMb": float64(1)},
+		ExecutionId: uuid.New(),
+	})
+
+	// When
+	_, err := action.Prepare(context.Background(), &state, req)
+
+	// Then - the state references the endpoint, which Start and Stop resolve
+	require.NoError(t, err)
+	assert.Equal(t, endpointURL, state.RedisURL)
+	assert.Equal(t, "m1", state.SourceID)
+	assert.Equal(t, "m2", state.DestID)
+	require.Len(t, state.Slots, 1)
+	assert.Equal(t, 0, state.Slots[0].Slot)
+}
+
+func slotMigrationTopology() *clients.Topology {
+	return &clients.Topology{Cluster: true, Nodes: []clients.ClusterNodeInfo{
+		{ID: "m1", Addr: "10.0.0.1:6379", Role: "master", Slots: []clients.SlotRange{{Start: 0, End: 1}, {Start: 10, End: 5460}}},
+		{ID: "m2", Addr: "10.0.0.2:6379", Role: "master", Slots: []clients.SlotRange{{Start: 5461, End: 10922}}},
+		{ID: "m3", Addr: "10.0.0.3:6379", Role: "master", Slots: []clients.SlotRange{{Start: 10923, End: 16383}, {Start: 2, End: 9}}},
+		{ID: "r1", Addr: "10.0.0.4:6379", Role: "slave", MasterID: "m1"},
+		{ID: "m4", Addr: "10.0.0.5:6379", Role: "master"},
+	}}
+}
+
+func TestSelectMigrationSlots(t *testing.T) {
+	tests := []struct {
+		name       string
+		target     string
+		slotList   string
+		slotCount  int
+		wantSource string
+		wantSlots  []int
+		wantErr    string
+	}{
+		{name: "lowest slots of target master across ranges", target: "m1", slotCount: 3, wantSource: "m1", wantSlots: []int{0, 1, 10}},
+		{name: "replica target uses its master", target: "r1", slotCount: 1, wantSource: "m1", wantSlots: []int{0}},
+		{name: "explicit slots", target: "m1", slotList: "5461,5463-5464", wantSource: "m2", wantSlots: []int{5461, 5463, 5464}},
+		{name: "explicit slots of different masters", slotList: "0,5461", wantErr: "different masters"},
+		{name: "invalid slot list", slotList: "abc", wantErr: "invalid slot"},
+		{name: "unknown target", target: "unknown", slotCount: 1, wantErr: "not a known cluster node"},
+		{name: "master without slots", target: "m4", slotCount: 1, wantErr: "serves no slots"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// When
+			source, slots, err := selectMigrationSlots(slotMigrationTopology(), tt.target, tt.slotList, tt.slotCount)
+
+			// Then
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantSource, source.ID)
+			assert.Equal(t, tt.wantSlots, slots)
+		})
+	}
+}
+
+func TestSelectMigrationDestination(t *testing.T) {
+	// When
+	dest, ok := selectMigrationDestination(slotMigrationTopology(), "m1")
+	_, single := selectMigrationDestination(&clients.Topology{Cluster: true, Nodes: []clients.ClusterNodeInfo{{ID: "m1", Role: "master"}}}, "m1")
+
+	// Then
+	require.True(t, ok)
+	assert.Equal(t, "m4", dest.ID, "master with the fewest slots")
+	assert.False(t, single)
+}
+
+func TestSlotMigrationState_KeysToMove(t *testing.T) {
+	complete := &SlotMigrationState{Mode: slotMigrationComplete, KeyPercent: 10}
+	half := &SlotMigrationState{Mode: slotMigrationHalf, KeyPercent: 30}
+	none := &SlotMigrationState{Mode: slotMigrationHalf, KeyPercent: 0}
+
+	assert.Equal(t, 10, complete.keysToMove(10))
+	assert.Equal(t, 3, half.keysToMove(10))
+	assert.Equal(t, 1, half.keysToMove(1), "rounded up")
+	assert.Equal(t, 0, none.keysToMove(10))
+}
+
+func newSlotMigrationState(mode string) *SlotMigrationState {
+	return &SlotMigrationState{
+		Mode:       mode,
+		KeyPercent: 30,
+		MaxKeys:    100,
+		MaxBytes:   1024,
+		SourceID:   "src",
+		SourceAddr: "10.0.0.1:6379",
+		DestID:     "dst",
+		DestAddr:   "10.0.0.2:6379",
+		Slots:      []MigratedSlot{{Slot: 5}},
+	}
+}
+
+func TestMigrateSlot_Complete(t *testing.T) {
+	// Given
+	source, sourceMock := redismock.NewClientMock()
+	defer source.Close()
+	dest, destMock := redismock.NewClientMock()
+	defer dest.Close()
+	state := newSlotMigrationState(slotMigrationComplete)
+
+	destMock.ExpectDo("CLUSTER", "SETSLOT", 5, "IMPORTING", "src").SetVal("OK")
+	sourceMock.ExpectDo("CLUSTER", "SETSLOT", 5, "MIGRATING", "dst").SetVal("OK")
+	sourceMock.ExpectClusterGetKeysInSlot(5, slotMigrationBatchSize).SetVal([]string{"a", "b"})
+	sourceMock.ExpectMemoryUsage("a").SetVal(100)
+	sourceMock.ExpectMemoryUsage("b").SetVal(200)
+	sourceMock.ExpectDo("MIGRATE", "10.0.0.2", "6379", "", 0, int64(2000), "KEYS", "a", "b").SetVal("OK")
+	sourceMock.ExpectClusterGetKeysInSlot(5, slotMigrationBatchSize).SetVal([]string{})
+	destMock.ExpectDo("CLUSTER", "SETSLOT", 5, "NODE", "dst").SetVal("OK")
+	sourceMock.ExpectDo("CLUSTER", "SETSLOT", 5, "NODE", "dst").SetVal("OK")
+
+	// When
+	err := migrateSlot(context.Background(), source, dest, state, &state.Slots[0])
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, slotPhaseMoved, state.Slots[0].Phase)
+	assert.Equal(t, 2, state.Slots[0].Keys)
+	assert.Equal(t, 2, state.MigratedKeys)
+	assert.Equal(t, int64(300), state.MigratedBytes)
+	require.NoError(t, sourceMock.ExpectationsWereMet())
+	require.NoError(t, destMock.ExpectationsWereMet())
+}
+
+func TestMigrateSlot_HalfMovesKeyPercentage(t *testing.T) {
+	// Given
+	source, sourceMock := redismock.NewClientMock()
+	defer source.Close()
+	dest, destMock := redismock.NewClientMock()
+	defer dest.Close()
+	state := newSlotMigrationState(slotMigrationHalf)
+
+	destMock.ExpectDo("CLUSTER", "SETSLOT", 5, "IMPORTING", "src").SetVal("OK")
+	sourceMock.ExpectDo("CLUSTER", "SETSLOT", 5, "MIGRATING", "dst").SetVal("OK")
+	sourceMock.ExpectClusterCountKeysInSlot(5).SetVal(10)
+	sourceMock.ExpectClusterGetKeysInSlot(5, 3).SetVal([]string{"a", "b", "c"})
+	sourceMock.ExpectMemoryUsage("a").SetVal(10)
+	sourceMock.ExpectMemoryUsage("b").SetVal(10)
+	sourceMock.ExpectMemoryUsage("c").SetVal(10)
+	sourceMock.ExpectDo("MIGRATE", "10.0.0.2", "6379", "", 0, int64(2000), "KEYS", "a", "b", "c").SetVal("OK")
+
+	// When
+	err := migrateSlot(context.Background(), source, dest, state, &state.Slots[0])
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, slotPhaseMigrating, state.Slots[0].Phase)
+	assert.Equal(t, 3, state.Slots[0].Keys)
+	require.NoError(t, sourceMock.ExpectationsWereMet())
+	require.NoError(t, destMock.ExpectationsWereMet())
+}
+
+func TestMigrateSlot_StopsAtLimits(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxKeys  int
+		maxBytes int64
+		wantErr  string
+	}{
+		{name: "key limit", maxKeys: 1, maxBytes: 1024, wantErr: "maxKeys"},
+		{name: "size limit", maxKeys: 100, maxBytes: 150, wantErr: "maxSizeMb"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			source, sourceMock := redismock.NewClientMock()
+			defer source.Close()
+			dest, destMock := redismock.NewClientMock()
+			defer dest.Close()
+			state := newSlotMigrationState(slotMigrationComplete)
+			state.MaxKeys = tt.maxKeys
+			state.MaxBytes = tt.maxBytes
+
+			destMock.ExpectDo("CLUSTER", "SETSLOT", 5, "IMPORTING", "src").SetVal("OK")
+			sourceMock.ExpectDo("CLUSTER", "SETSLOT", 5, "MIGRATING", "dst").SetVal("OK")
+			sourceMock.ExpectClusterGetKeysInSlot(5, slotMigrationBatchSize).SetVal([]string{"a", "b"})
+			if tt.maxKeys > 1 {
+				sourceMock.ExpectMemoryUsage("a").SetVal(100)
+				sourceMock.ExpectMemoryUsage("b").SetVal(100)
+			}
+
+			// When
+			err := migrateSlot(context.Background(), source, dest, state, &state.Slots[0])
+
+			// Then
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+			assert.Equal(t, slotPhaseMigrating, state.Slots[0].Phase, "left for revert")
+			assert.Equal(t, 0, state.MigratedKeys)
+			require.NoError(t, sourceMock.ExpectationsWereMet())
+			require.NoError(t, destMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRevertSlots_Migrating(t *testing.T) {
+	// Given
+	source, sourceMock := redismock.NewClientMock()
+	defer source.Close()
+	dest, destMock := redismock.NewClientMock()
+	defer dest.Close()
+	state := newSlotMigrationState(slotMigrationHalf)
+	state.Slots[0].Phase = slotPhaseMigrating
+	state.Slots[0].Keys = 1
+
+	destMock.ExpectClusterGetKeysInSlot(5, slotMigrationBatchSize).SetVal([]string{"a"})
+	destMock.ExpectDo("MIGRATE", "10.0.0.1", "6379", "", 0, int64(2000), "KEYS", "a").SetVal("OK")
+	destMock.ExpectClusterGetKeysInSlot(5, slotMigrationBatchSize).SetVal([]string{})
+	destMock.ExpectDo("CLUSTER", "SETSLOT", 5, "STABLE").SetVal("OK")
+	sourceMock.ExpectDo("CLUSTER", "SETSLOT", 5, "STABLE").SetVal("OK")
+
+	// When
+	err := revertSlots(context.Background(), source, dest, state)
+
+	// Then
+	require.NoError(t, err)
+	assert.Empty(t, state.Slots[0].Phase)
+	require.NoError(t, sourceMock.ExpectationsWereMet())
+	require.NoError(t, destMock.ExpectationsWereMet())
+}
+
+func TestRevertSlots_Moved(t *testing.T) {
+	// Given
+	source, sourceMock := redismock.NewClientMock()
+	defer source.Close()
+	dest, destMock := redismock.NewClientMock()
+	defer dest.Close()
+	state := newSlotMigrationState(slotMigrationComplete)
+	state.Slots = []MigratedSlot{{Slot: 5, Phase: slotPhaseMoved}, {Slot: 6}}
+
+	sourceMock.ExpectDo("CLUSTER", "SETSLOT", 5, "IMPORTING", "dst").SetVal("OK")
+	destMock.ExpectDo("CLUSTER", "SETSLOT", 5, "MIGRATING", "src").SetVal("OK")
+	destMock.ExpectClusterGetKeysInSlot(5, slotMigrationBatchSize).SetVal([]string{})
+	sourceMock.ExpectDo("CLUSTER", "SETSLOT", 5, "NODE", "src").SetVal("OK")
+	destMock.ExpectDo("CLUSTER", "SETSLOT", 5, "NODE", "src").SetVal("OK")
+
+	// When
+	err := revertSlots(context.Background(), source, dest, state)
+
+	// Then
+	require.NoError(t, err)
+	assert.Empty(t, state.Slots[0].Phase)
+	require.NoError(t, sourceMock.ExpectationsWereMet())
+	require.NoError(t, destMock.ExpectationsWereMet())
+}
+
+func TestRevertSlots_KeepsPhaseOnFailure(t *testing.T) {
+	// Given
+	source, sourceMock := redismock.NewClientMock()
+	defer source.Close()
+	dest, destMock := redismock.NewClientMock()
+	defer dest.Close()
+	state := newSlotMigrationState(slotMigrationHalf)
+	state.Slots[0].Phase = slotPhaseMigrating
+
+	destMock.ExpectClusterGetKeysInSlot(5, slotMigrationBatchSize).SetErr(fmt.Errorf("connection refused"))
+
+	// When
+	err := revertSlots(context.Background(), source, dest, state)
+
+	// Then
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "slot 5")
+	assert.Equal(t, slotPhaseMigrating, state.Slots[0].Phase)
+	require.NoError(t, sourceMock.ExpectationsWereMet())
+}
@@ -0,0 +1,763 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package extredis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+	"github.com/steadybit/action-kit/go/action_kit_api/v2"
+	"github.com/steadybit/action-kit/go/action_kit_sdk"
+	"github.com/steadybit/extension-kit/extbuild"
+	"github.com/steadybit/extension-kit/extutil"
+	"github.com/steadybit/extension-redis/clients"
+	"github.com/steadybit/extension-redis/config"
+	"github.com/steadybit/extension-redis/journal"
+)
+
+const (
+	streamConsumerGroupActionID = "com.steadybit.extension_redis.database.stream-consumer-group"
+
+	streamModeStallGroup     = "stall-group"
+	streamModeDeleteConsumer = "delete-consumer"
+	streamModeTrim           = "trim"
+
+	// streamPendingPageSize is the number of pending entries fetched or claimed per round trip.
+	streamPendingPageSize = 1000
+
+	// streamStallBlock is how long a read of the phantom consumer waits for new entries. It bounds how long stopping
+	// a stall waits for the reader.
+	streamStallBlock = time.Second
+)
+
+type streamConsumerGroupAttack struct{}
+
+type StreamConsumerGroupState struct {
+	RedisURL          string            `json:"redisUrl"`
+	DryRun            bool              `json:"dryRun"`
+	DB                int               `json:"db"`
+	ExecutionID       string            `json:"executionId"`
+	StreamKey         string            `json:"streamKey"`
+	Group             string            `json:"group"`
+	Mode              string            `json:"mode"`
+	Consumer          string            `json:"consumer"`
+	PhantomConsumer   string            `json:"phantomConsumer"`
+	MaxLen            int64             `json:"maxLen"`
+	RestoreOnStop     bool              `json:"restoreOnStop"`
+	EndTime           int64             `json:"endTime"`
+	ClusterMode       bool              `json:"clusterMode"`
+	PendingOwners     map[string]string `json:"pendingOwners"` // entry ID -> consumer that owned it before the attack
+	TotalBackupBytes  int64             `json:"totalBackupBytes"`
+	MaxBackupBytes    int64             `json:"maxBackupBytes"`
+	ClaimedEntries    int64             `json:"claimedEntries"`
+	HandedOverEntries int64             `json:"handedOverEntries"`
+	TrimmedEntries    int64             `json:"trimmedEntries"`
+}
+
+// streamStall holds the background phantom reader of a running stall-group attack.
+type streamStall struct {
+	cancel  context.CancelFunc
+	done    chan struct{}
+	hoarded atomic.Int64
+}
+
+// Track running stalls by execution ID for status and cleanup
+var (
+	activeStreamStalls      = make(map[string]*streamStall)
+	activeStreamStallsMutex sync.Mutex
+)
+
+var _ action_kit_sdk.Action[StreamConsumerGroupState] = (*streamConsumerGroupAttack)(nil)
+var _ action_kit_sdk.ActionWithStatus[StreamConsumerGroupState] = (*streamConsumerGroupAttack)(nil)
+var _ action_kit_sdk.ActionWithStop[StreamConsumerGroupState] = (*streamConsumerGroupAttack)(nil)
+
+func NewStreamConsumerGroupAttack() action_kit_sdk.Action[StreamConsumerGroupState] {
+	return &streamConsumerGroupAttack{}
+}
+
+func (a *streamConsumerGroupAttack) NewEmptyState() StreamConsumerGroupState {
+	return StreamConsumerGroupState{}
+}
+
+func (a *streamConsumerGroupAttack) Describe() action_kit_api.ActionDescription {
+	return action_kit_api.ActionDescription{
+		Id:          streamConsumerGroupActionID,
+		Label:       "Disrupt Stream Consumer Group",
+		Description: "Injects faults into a Redis Stream consumer group. Stall Group claims all pending entries (XAUTOCLAIM) and reads all new entries into a phantom consumer so real consumers starve. Delete Consumer removes a consumer with XGROUP DELCONSUMER. Trim Stream aggressively trims the stream with XTRIM. Pending entry ownership is restored on stop where possible, the entries read by the phantom consumer are handed over to the consumers of the group.",
+		Version:     extbuild.GetSemverVersionStringOrUnknown(),
+		Icon:        new(redisIcon),
+		TargetSelection: new(action_kit_api.TargetSelection{
+			TargetType: TargetTypeDatabase,
+			SelectionTemplates: new([]action_kit_api.TargetSelectionTemplate{
+				{
+					Label:       "by host and database",
+					Description: new("Find Redis database by host and index"),
+					Query:       "redis.host=\"\" AND redis.database.index=\"\"",
+				},
+			}),
+		}),
+		Technology:  new("Redis"),
+		Category:    new("state"),
+		Kind:        action_kit_api.Attack,
+		TimeControl: action_kit_api.TimeControlExternal,
+		Parameters: []action_kit_api.ActionParameter{
+			{
+				Name:         "duration",
+				Label:        "Duration",
+				Description:  new("How long the attack should last"),
+				Type:         action_kit_api.ActionParameterTypeDuration,
+				DefaultValue: new("60s"),
+				Required:     new(true),
+			},
+			{
+				Name:         "streamKey",
+				Label:        "Stream Key",
+				Description:  new("Key of the Redis Stream (e.g., 'jobs:queue')"),
+				Type:         action_kit_api.ActionParameterTypeString,
+				DefaultValue: new(""),
+				Required:     new(true),
+			},
+			{
+				Name:         "group",
+				Label:        "Consumer Group",
+				Description:  new("Name of the consumer group to disrupt"),
+				Type:         action_kit_api.ActionParameterTypeString,
+				DefaultValue: new(""),
+				Required:     new(true),
+			},
+			{
+				Name:         "mode",
+				Label:        "Mode",
+				Description:  new("Fault to inject into the consumer group"),
+				Type:         action_kit_api.ActionParameterTypeString,
+				DefaultValue: new(streamModeStallGroup),
+				Required:     new(true),
+				Options: new([]action_kit_api.ParameterOption{
+					action_kit_api.ExplicitParameterOption{
+						Label: "Stall Group (claim entries into a phantom consumer)",
+						Value: streamModeStallGroup,
+					},
+					action_kit_api.ExplicitParameterOption{
+						Label: "Delete Consumer (XGROUP DELCONSUMER)",
+						Value: streamModeDeleteConsumer,
+					},
+					action_kit_api.ExplicitParameterOption{
+						Label: "Trim Stream (XTRIM, not reversible)",
+						Value: streamModeTrim,
+					},
+				}),
+			},
+			{
+				Name:         "consumer",
+				Label:        "Consumer",
+				Description:  new("Consumer to delete in Delete Consumer mode"),
+				Type:         action_kit_api.ActionParameterTypeString,
+				DefaultValue: new(""),
+				Required:     new(false),
+			},
+			{
+				Name:         "maxLen",
+				Label:        "Max Length",
+				Description:  new("Number of entries to keep when trimming the stream in Trim Stream mode, at least 1. Trimmed entries cannot be restored."),
+				Type:         action_kit_api.ActionParameterTypeInteger,
+				DefaultValue: new("1000"),
+				Required:     new(false),
+				MinValue:     new(1),
+			},
+			{
+				Name:         "restoreOnStop",
+				Label:        "Restore on Stop",
+				Description:  new("Hand pending entries back to their original consumers and the entries read during the stall to the consumers of the group when the attack stops"),
+				Type:         action_kit_api.ActionParameterTypeBoolean,
+				DefaultValue: new("true"),
+				Required:     new(false),
+				Advanced:     new(true),
+			},
+			dryRunParameter(),
+		},
+	}
+}
+
+func (a *streamConsumerGroupAttack) Prepare(ctx context.Context, state *StreamConsumerGroupState, request action_kit_api.PrepareActionRequestBody) (*action_kit_api.PrepareResult, error) {
+	redisURL := request.Target.Attributes[AttrRedisURL]
+	if len(redisURL) == 0 {
+		return nil, fmt.Errorf("redis URL not found in target attributes")
+	}
+	dryRun := dryRunRequested(request)
+	guardrails, err := checkGuardrails(request.Target.Attributes, streamConsumerGroupActionID, !dryRun)
+	if err != nil {
+		return nil, err
+	}
+
+	dbIndex := request.Target.Attributes[AttrDatabaseIndex]
+	db := 0
+	if len(dbIndex) > 0 {
+		db, _ = strconv.Atoi(dbIndex[0])
+	}
+
+	duration := extutil.ToInt64(request.Config["duration"]) / 1000 // Convert ms to seconds
+	streamKey := extutil.ToString(request.Config["streamKey"])
+	group := extutil.ToString(request.Config["group"])
+	mode := extutil.ToString(request.Config["mode"])
+	consumer := extutil.ToString(request.Config["consumer"])
+	maxLen := extutil.ToInt64(request.Config["maxLen"])
+	restoreOnStop := extutil.ToBool(request.Config["restoreOnStop"])
+
+	if streamKey == "" {
+		return nil, fmt.Errorf("streamKey is required")
+	}
+	if err := guardrails.CheckProtectedKeys([]string{streamKey}); err != nil {
+		return nil, err
+	}
+	if group == "" {
+		return nil, fmt.Errorf("group is required")
+	}
+	switch mode {
+	case streamModeStallGroup:
+	case streamModeDeleteConsumer:
+		if consumer == "" {
+			return nil, fmt.Errorf("consumer is required in %s mode", streamModeDeleteConsumer)
+		}
+	case streamModeTrim:
+		// XTRIM MAXLEN 0 deletes the whole stream
+		if maxLen < 1 {
+			return nil, fmt.Errorf("maxLen must be at least 1 in %s mode", streamModeTrim)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported mode %q (expected %q, %q or %q)", mode, streamModeStallGroup, streamModeDeleteConsumer, streamModeTrim)
+	}
+
+	state.RedisURL = config.RedactURL(redisURL[0])
+	state.DryRun = dryRun
+	state.DB = db
+	state.ExecutionID = request.ExecutionId.String()
+	state.StreamKey = streamKey
+	state.Group = group
+	state.Mode = mode
+	state.Consumer = consumer
+	state.PhantomConsumer = phantomConsumerName(state.ExecutionID)
+	state.MaxLen = maxLen
+	state.RestoreOnStop = restoreOnStop
+	state.EndTime = time.Now().Add(time.Duration(duration) * time.Second).Unix()
+	state.PendingOwners = make(map[string]string)
+
+	endpoint := config.GetEndpointByURL(state.RedisURL)
+	if endpoint != nil {
+		isCluster, err := clients.DetectClusterMode(ctx, endpoint)
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed to detect cluster mode, assuming standalone")
+		} else {
+			state.ClusterMode = isCluster
+		}
+		state.MaxBackupBytes = endpoint.GetMaxBackupSizeBytes()
+	} else {
+		state.MaxBackupBytes = config.DefaultMaxBackupSizeBytes
+	}
+
+	client, err := clients.GetUniversalClient(state.RedisURL, "", state.DB, state.ClusterMode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Redis client: %w", err)
+	}
+	if err := clients.PingRedis(ctx, client); err != nil {
+		return nil, fmt.Errorf("failed to ping Redis: %w", err)
+	}
+
+	// Validate that the stream and group exist — fail fast in Prepare
+	keyType, err := client.Type(ctx, state.StreamKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get type of key '%s': %w", state.StreamKey, err)
+	}
+	if keyType != "stream" {
+		return nil, fmt.Errorf("key '%s' is not a stream (type: %s)", state.StreamKey, keyType)
+	}
+
+	if _, err := findStreamGroup(ctx, client, state.StreamKey, state.Group); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func phantomConsumerName(executionID string) string {
+	if len(executionID) > 8 {
+		executionID = executionID[:8]
+	}
+	return "steadybit-phantom-" + executionID
+}
+
+// findStreamGroup returns the XINFO GROUPS entry of the given group.
+func findStreamGroup(ctx context.Context, client redis.Cmdable, streamKey, group string) (*redis.XInfoGroup, error) {
+	groups, err := client.XInfoGroups(ctx, streamKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get consumer groups of stream '%s': %w", streamKey, err)
+	}
+	for _, g := range groups {
+		if g.Name == group {
+			return &g, nil
+		}
+	}
+	return nil, fmt.Errorf("consumer group '%s' not found on stream '%s'", group, streamKey)
+}
+
+// backupPendingOwners records which consumer owns each pending entry, optionally limited to one consumer.
+func backupPendingOwners(ctx context.Context, client redis.Cmdable, state *StreamConsumerGroupState, consumer string) error {
+	start := "-"
+	for {
+		pending, err := client.XPendingExt(ctx, &redis.XPendingExtArgs{
+			Stream:   state.StreamKey,
+			Group:    state.Group,
+			Start:    start,
+			End:      "+",
+			Count:    streamPendingPageSize,
+			Consumer: consumer,
+		}).Result()
+		if err != nil {
+			return fmt.Errorf("failed to list pending entries: %w", err)
+		}
+
+		for _, p := range pending {
+			entrySize := int64(len(p.ID) + len(p.Consumer))
+			if state.MaxBackupBytes > 0 && state.TotalBackupBytes+entrySize > state.MaxBackupBytes {
+				return fmt.Errorf(
+					"backup size would exceed limit: pending entries of group '%s' require more than %d MB of backup storage. "+
+						"No entries were modified. Increase 'maxBackupSizeBytes' in the endpoint configuration or disable 'restoreOnStop'",
+					state.Group, state.MaxBackupBytes/1024/1024)
+			}
+			state.TotalBackupBytes += entrySize
+			state.PendingOwners[p.ID] = p.Consumer
+		}
+
+		if len(pending) < streamPendingPageSize {
+			return nil
+		}
+		start = "(" + pending[len(pending)-1].ID
+	}
+}
+
+// claimEntries moves the given pending entries to consumer without changing their delivery count.
+func claimEntries(ctx context.Context, client redis.Cmdable, streamKey, group, consumer string, ids []string) (int64, error) {
+	var claimed int64
+	for i := 0; i < len(ids); i += streamPendingPageSize {
+		end := min(i+streamPendingPageSize, len(ids))
+		result, err := client.XClaimJustID(ctx, &redis.XClaimArgs{
+			Stream:   streamKey,
+			Group:    group,
+			Consumer: consumer,
+			MinIdle:  0,
+			Messages: ids[i:end],
+		}).Result()
+		if err != nil {
+			return claimed, err
+		}
+		claimed += int64(len(result))
+	}
+	return claimed, nil
+}
+
+// claimAllPending moves every pending entry of the group to consumer via XAUTOCLAIM.
+func claimAllPending(ctx context.Context, client redis.Cmdable, streamKey, group, consumer string) (int64, error) {
+	var claimed int64
+	start := "0-0"
+	for {
+		ids, next, err := client.XAutoClaimJustID(ctx, &redis.XAutoClaimArgs{
+			Stream:   streamKey,
+			Group:    group,
+			MinIdle:  0,
+			Start:    start,
+			Count:    streamPendingPageSize,
+			Consumer: consumer,
+		}).Result()
+		if err != nil {
+			return claimed, fmt.Errorf("failed to execute XAUTOCLAIM: %w", err)
+		}
+		claimed += int64(len(ids))
+		if next == "0-0" || next == "" {
+			return claimed, nil
+		}
+		start = next
+	}
+}
+
+func (a *streamConsumerGroupAttack) Start(ctx context.Context, state *StreamConsumerGroupState) (*action_kit_api.StartResult, error) {
+	if state.DryRun {
+		return a.dryRun(ctx, state)
+	}
+	client, err := clients.GetUniversalClient(state.RedisURL, "", state.DB, state.ClusterMode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Redis client: %w", err)
+	}
+	if err := clients.PingRedis(ctx, client); err != nil {
+		return nil, fmt.Errorf("failed to ping Redis: %w", err)
+	}
+
+	var message string
+	switch state.Mode {
+	case streamModeStallGroup:
+		// Phase 1: Backup pending entry ownership before claiming anything
+		if state.RestoreOnStop {
+			if err := backupPendingOwners(ctx, client, state, ""); err != nil {
+				return nil, err
+			}
+		}
+		if err := recordJournal(streamConsumerGroupActionID, state.ExecutionID, state.RedisURL, state); err != nil {
+			return nil, err
+		}
+
+		// Phase 2: Claim everything pending and keep reading new entries into the phantom consumer
+		claimed, err := claimAllPending(ctx, client, state.StreamKey, state.Group, state.PhantomConsumer)
+		state.ClaimedEntries = claimed
+		if err != nil {
+			return nil, err
+		}
+
+		workerCtx, cancel := context.WithDeadline(context.Background(), time.Unix(state.EndTime, 0))
+		stall := &streamStall{cancel: cancel, done: make(chan struct{})}
+		go func() {
+			defer close(stall.done)
+			hoardStreamEntries(workerCtx, client, state.StreamKey, state.Group, state.PhantomConsumer, stall)
+		}()
+
+		activeStreamStallsMutex.Lock()
+		activeStreamStalls[state.ExecutionID] = stall
+		activeStreamStallsMutex.Unlock()
+
+		message = fmt.Sprintf("Stalled consumer group '%s': claimed %d pending entries into phantom consumer '%s' which now reads all new entries", state.Group, claimed, state.PhantomConsumer)
+
+	case streamModeDeleteConsumer:
+		if state.RestoreOnStop {
+			if err := backupPendingOwners(ctx, client, state, state.Consumer); err != nil {
+				return nil, err
+			}
+			if err := recordJournal(streamConsumerGroupActionID, state.ExecutionID, state.RedisURL, state); err != nil {
+				return nil, err
+			}
+			// Park the pending entries in the phantom consumer so deleting the consumer does not drop them
+			ids := make([]string, 0, len(state.PendingOwners))
+			for id := range state.PendingOwners {
+				ids = append(ids, id)
+			}
+			claimed, err := claimEntries(ctx, client, state.StreamKey, state.Group, state.PhantomConsumer, ids)
+			state.ClaimedEntries = claimed
+			if err != nil {
+				return nil, fmt.Errorf("failed to park pending entries of consumer '%s': %w", state.Consumer, err)
+			}
+		}
+
+		dropped, err := client.XGroupDelConsumer(ctx, state.StreamKey, state.Group, state.Consumer).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to execute XGROUP DELCONSUMER: %w", err)
+		}
+		message = fmt.Sprintf("Deleted consumer '%s' from group '%s'", state.Consumer, state.Group)
+		if state.RestoreOnStop {
+			message += fmt.Sprintf(" (%d pending entries parked for restore)", state.ClaimedEntries)
+		} else {
+			message += fmt.Sprintf(" (%d pending entries dropped)", dropped)
+		}
+
+	case streamModeTrim:
+		trimmed, err := client.XTrimMaxLen(ctx, state.StreamKey, state.MaxLen).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to execute XTRIM: %w", err)
+		}
+		state.TrimmedEntries = trimmed
+		message = fmt.Sprintf("Trimmed stream '%s' to %d entries (%d entries removed permanently)", state.StreamKey, state.MaxLen, trimmed)
+
+	default:
+		return nil, fmt.Errorf("unsupported mode %q", state.Mode)
+	}
+
+	return &action_kit_api.StartResult{
+		Messages: new([]action_kit_api.Message{
+			{
+				Level:   extutil.Ptr(action_kit_api.Info),
+				Message: message,
+			},
+		}),
+	}, nil
+}
+
+// dryRun reports the entries Start would take away from the group or its consumer, or the entries it would trim.
+func (a *streamConsumerGroupAttack) dryRun(ctx context.Context, state *StreamConsumerGroupState) (*action_kit_api.StartResult, error) {
+	client, err := clients.GetUniversalClient(state.RedisURL, "", state.DB, state.ClusterMode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Redis client: %w", err)
+	}
+	if err := clients.PingRedis(ctx, client); err != nil {
+		return nil, fmt.Errorf("failed to ping Redis: %w", err)
+	}
+
+	plan := &dryRunPlan{}
+	switch state.Mode {
+	case streamModeStallGroup, streamModeDeleteConsumer:
+		pending, err := client.XPending(ctx, state.StreamKey, state.Group).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get pending entries of group '%s': %w", state.Group, err)
+		}
+		if state.Mode == streamModeStallGroup {
+			plan.info("would claim %d pending entries of group '%s' into phantom consumer '%s', which would read all new entries of stream '%s'", pending.Count, state.Group, state.PhantomConsumer, state.StreamKey)
+			if state.RestoreOnStop {
+				plan.info("would hand the pending entries back to their consumers and the newly read entries over to the consumers of the group on stop")
+			} else {
+				plan.warn("restoreOnStop is disabled, the claimed and newly read entries are dropped with the phantom consumer on stop")
+			}
+		} else {
+			consumerPending := pending.Consumers[state.Consumer]
+			plan.info("would delete consumer '%s' from group '%s' with %d pending entries", state.Consumer, state.Group, consumerPending)
+			if state.RestoreOnStop {
+				plan.info("would park the pending entries and hand them back to the recreated consumer on stop")
+			} else {
+				plan.warn("restoreOnStop is disabled, the %d pending entries of the consumer are dropped", consumerPending)
+			}
+		}
+
+	case streamModeTrim:
+		length, err := client.XLen(ctx, state.StreamKey).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get length of stream '%s': %w", state.StreamKey, err)
+		}
+		plan.warn("would trim stream '%s' from %d to %d entries, removing %d entries permanently", state.StreamKey, length, min(length, state.MaxLen), max(length-state.MaxLen, 0))
+
+	default:
+		return nil, fmt.Errorf("unsupported mode %q", state.Mode)
+	}
+	return plan.startResult(), nil
+}
+
+// hoardStreamEntries reads every new entry of the group into the phantom consumer until ctx is done. The reads block
+// on the server, so new entries are delivered to the phantom consumer as soon as they arrive.
+func hoardStreamEntries(ctx context.Context, client redis.UniversalClient, streamKey, group, consumer string, stall *streamStall) {
+	for ctx.Err() == nil {
+		streams, err := client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    group,
+			Consumer: consumer,
+			Streams:  []string{streamKey, ">"},
+			Count:    streamPendingPageSize,
+			Block:    streamStallBlock,
+		}).Result()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Debug().Err(err).Str("stream", streamKey).Msg("Phantom consumer failed to read new entries")
+			// Do not retry a failing read in a tight loop
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(streamStallBlock):
+			}
+			continue
+		}
+		for _, s := range streams {
+			stall.hoarded.Add(int64(len(s.Messages)))
+		}
+	}
+}
+
+func (a *streamConsumerGroupAttack) Status(ctx context.Context, state *StreamConsumerGroupState) (*action_kit_api.StatusResult, error) {
+	if state.DryRun {
+		return dryRunStatusResult(), nil
+	}
+	now := time.Now().Unix()
+	completed := now >= state.EndTime
+
+	msg := fmt.Sprintf("Consumer group '%s' on stream '%s'", state.Group, state.StreamKey)
+
+	activeStreamStallsMutex.Lock()
+	stall := activeStreamStalls[state.ExecutionID]
+	activeStreamStallsMutex.Unlock()
+	if stall != nil {
+		msg += fmt.Sprintf(": phantom consumer holds %d claimed and %d newly read entries", state.ClaimedEntries, stall.hoarded.Load())
+	}
+
+	client, err := clients.GetUniversalClient(state.RedisURL, "", state.DB, state.ClusterMode)
+	if err == nil {
+		if groupInfo, err := findStreamGroup(ctx, client, state.StreamKey, state.Group); err == nil {
+			msg += fmt.Sprintf(", pending: %d, lag: %d, consumers: %d", groupInfo.Pending, groupInfo.Lag, groupInfo.Consumers)
+		} else {
+			log.Debug().Err(err).Msg("Failed to get consumer group info during stream attack")
+		}
+	}
+
+	return &action_kit_api.StatusResult{
+		Completed: completed,
+		Messages: new(append([]action_kit_api.Message{
+			{
+				Level:   extutil.Ptr(action_kit_api.Info),
+				Message: msg,
+			},
+		}, journalRecoveryMessages(state.ExecutionID)...)),
+	}, nil
+}
+
+func (a *streamConsumerGroupAttack) Stop(ctx context.Context, state *StreamConsumerGroupState) (*action_kit_api.StopResult, error) {
+	if state.DryRun {
+		return dryRunStopResult(), nil
+	}
+	if recoveredByJournal(state.ExecutionID) {
+		return journalRecoveredStopResult(state.ExecutionID), nil
+	}
+	activeStreamStallsMutex.Lock()
+	stall := activeStreamStalls[state.ExecutionID]
+	delete(activeStreamStalls, state.ExecutionID)
+	activeStreamStallsMutex.Unlock()
+
+	if stall != nil {
+		stall.cancel()
+		<-stall.done
+	}
+
+	if state.Mode == streamModeTrim {
+		return &action_kit_api.StopResult{
+			Messages: new([]action_kit_api.Message{
+				{
+					Level:   extutil.Ptr(action_kit_api.Warn),
+					Message: fmt.Sprintf("Stream trim is not reversible: %d entries removed from '%s' cannot be restored", state.TrimmedEntries, state.StreamKey),
+				},
+			}),
+		}, nil
+	}
+
+	client, err := clients.GetUniversalClient(state.RedisURL, "", state.DB, state.ClusterMode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Redis client for restore: %w", err)
+	}
+
+	restored, err := restoreStreamConsumerGroup(ctx, client, state)
+	if err != nil {
+		log.Error().Err(err).Str("stream", state.StreamKey).Str("group", state.Group).Msg("Failed to restore consumer group")
+		return nil, err
+	}
+	journal.Remove(state.ExecutionID)
+
+	msg := fmt.Sprintf("Removed phantom consumer from group '%s'", state.Group)
+	if state.RestoreOnStop {
+		msg = fmt.Sprintf("Restored consumer group '%s': %d/%d pending entries handed back to their original consumers", state.Group, restored, len(state.PendingOwners))
+		if state.Mode == streamModeStallGroup {
+			msg += fmt.Sprintf(", %d newly read entries handed over to the consumers of the group", state.HandedOverEntries)
+		}
+	}
+
+	return &action_kit_api.StopResult{
+		Messages: new([]action_kit_api.Message{
+			{
+				Level:   extutil.Ptr(action_kit_api.Info),
+				Message: msg,
+			},
+		}),
+	}, nil
+}
+
+// restoreStreamConsumerGroup hands pending entries back to their original owners and, after a stall, the entries
+// read by the phantom consumer over to the consumers of the group. Then the phantom consumer is removed, unless it
+// still holds entries that could not be handed back.
+func restoreStreamConsumerGroup(ctx context.Context, client redis.Cmdable, state *StreamConsumerGroupState) (int64, error) {
+	var restored int64
+	var restoreErrors []string
+
+	if state.RestoreOnStop {
+		if state.Mode == streamModeDeleteConsumer {
+			if err := client.XGroupCreateConsumer(ctx, state.StreamKey, state.Group, state.Consumer).Err(); err != nil {
+				restoreErrors = append(restoreErrors, fmt.Sprintf("recreate consumer '%s': %v", state.Consumer, err))
+			}
+		}
+
+		byOwner := make(map[string][]string)
+		for id, owner := range state.PendingOwners {
+			byOwner[owner] = append(byOwner[owner], id)
+		}
+		for owner, ids := range byOwner {
+			n, err := claimEntries(ctx, client, state.StreamKey, state.Group, owner, ids)
+			restored += n
+			if err != nil {
+				restoreErrors = append(restoreErrors, fmt.Sprintf("hand back entries to '%s': %v", owner, err))
+			}
+		}
+
+		if state.Mode == streamModeStallGroup {
+			n, err := handOverHoardedEntries(ctx, client, state)
+			state.HandedOverEntries = n
+			if err != nil {
+				restoreErrors = append(restoreErrors, err.Error())
+			}
+		}
+
+		// Deleting the phantom consumer drops its pending entries, keep them for the next attempt
+		if len(restoreErrors) > 0 {
+			return restored, fmt.Errorf("restore failed, phantom consumer '%s' is kept: %v", state.PhantomConsumer, restoreErrors)
+		}
+	}
+
+	// Without restore the entries of the phantom consumer are dropped on purpose
+	if err := client.XGroupDelConsumer(ctx, state.StreamKey, state.Group, state.PhantomConsumer).Err(); err != nil {
+		return restored, fmt.Errorf("restore failed: delete phantom consumer: %w", err)
+	}
+	return restored, nil
+}
+
+// handOverHoardedEntries spreads the entries the phantom consumer read during a stall over the other consumers of the
+// group with XCLAIM. Unlike resetting the group offset, this leaves the entries alone that real consumers read and
+// acknowledged meanwhile, so none of them is delivered twice.
+func handOverHoardedEntries(ctx context.Context, client redis.Cmdable, state *StreamConsumerGroupState) (int64, error) {
+	var hoarded []string
+	start := "-"
+	for {
+		pending, err := client.XPendingExt(ctx, &redis.XPendingExtArgs{
+			Stream:   state.StreamKey,
+			Group:    state.Group,
+			Start:    start,
+			End:      "+",
+			Count:    streamPendingPageSize,
+			Consumer: state.PhantomConsumer,
+		}).Result()
+		if err != nil {
+			return 0, fmt.Errorf("list entries of phantom consumer: %w", err)
+		}
+		for _, p := range pending {
+			if _, claimed := state.PendingOwners[p.ID]; !claimed {
+				hoarded = append(hoarded, p.ID)
+			}
+		}
+		if len(pending) < streamPendingPageSize {
+			break
+		}
+		start = "(" + pending[len(pending)-1].ID
+	}
+	if len(hoarded) == 0 {
+		return 0, nil
+	}
+
+	consumers, err := client.XInfoConsumers(ctx, state.StreamKey, state.Group).Result()
+	if err != nil {
+		return 0, fmt.Errorf("list consumers: %w", err)
+	}
+	var names []string
+	for _, c := range consumers {
+		if c.Name != state.PhantomConsumer {
+			names = append(names, c.Name)
+		}
+	}
+	if len(names) == 0 {
+		return 0, fmt.Errorf("no consumer to hand over the %d entries read by the phantom consumer to", len(hoarded))
+	}
+
+	var handedOver int64
+	share := (len(hoarded) + len(names) - 1) / len(names)
+	for i := 0; i*share < len(hoarded); i++ {
+		ids := hoarded[i*share : min((i+1)*share, len(hoarded))]
+		n, err := claimEntries(ctx, client, state.StreamKey, state.Group, names[i], ids)
+		handedOver += n
+		if err != nil {
+			return handedOver, fmt.Errorf("hand over entries to '%s': %w", names[i], err)
+		}
+	}
+	return handedOver, nil
+}
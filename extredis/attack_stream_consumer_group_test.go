@@ -0,0 +1,431 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2024 Steadybit GmbH
+
+package extredis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redismock/v9"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/steadybit/action-kit/go/action_kit_api/v2"
+	"github.com/steadybit/extension-kit/extutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupStreamWithPending creates a stream with a consumer group where consumer "worker-1"
+// holds the first two entries as pending and the remaining entries are undelivered.
+func setupStreamWithPending(t *testing.T, mr *miniredis.Miniredis, entries int) *redis.Client {
+	t.Helper()
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	ctx := context.Background()
+
+	for i := range entries {
+		require.NoError(t, client.XAdd(ctx, &redis.XAddArgs{
+			Stream: "jobs",
+			ID:     fmt.Sprintf("%d-0", i+1),
+			Values: map[string]any{"job": i},
+		}).Err())
+	}
+	require.NoError(t, client.XGroupCreate(ctx, "jobs", "workers", "0").Err())
+	require.NoError(t, client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    "workers",
+		Consumer: "worker-1",
+		Streams:  []string{"jobs", ">"},
+		Count:    2,
+		Block:    -1,
+	}).Err())
+	return client
+}
+
+func streamPrepareRequest(redisURL string, cfg map[string]any) action_kit_api.PrepareActionRequestBody {
+	return extutil.JsonMangle(action_kit_api.PrepareActionRequestBody{
+		Target: &action_kit_api.Target{
+			Attributes: map[string][]string{
+				AttrRedisURL:      {redisURL},
+				AttrDatabaseIndex: {"0"},
+			},
+		},
+		Config:      cfg,
+		ExecutionId: uuid.New(),
+	})
+}
+
+func TestStreamConsumerGroupAttack_Describe(t *testing.T) {
+	// Given
+	action := &streamConsumerGroupAttack{}
+
+	// When
+	desc := action.Describe()
+
+	// Then
+	assert.Equal(t, "com.steadybit.extension_redis.database.stream-consumer-group", desc.Id)
+	assert.Equal(t, TargetTypeDatabase, desc.TargetSelection.TargetType)
+	assert.Equal(t, "state", *desc.Category)
+	assert.Equal(t, action_kit_api.Attack, desc.Kind)
+	assert.Equal(t, action_kit_api.TimeControlExternal, desc.TimeControl)
+
+	paramNames := make([]string, len(desc.Parameters))
+	for i, p := range desc.Parameters {
+		paramNames[i] = p.Name
+	}
+	assert.Contains(t, paramNames, "streamKey")
+	assert.Contains(t, paramNames, "group")
+	assert.Contains(t, paramNames, "mode")
+	assert.Contains(t, paramNames, "consumer")
+	assert.Contains(t, paramNames, "maxLen")
+	assert.Contains(t, paramNames, "restoreOnStop")
+}
+
+func TestStreamConsumerGroupAttack_Prepare_Validation(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	client := setupStreamWithPending(t, mr, 3)
+	defer client.Close()
+	mr.Set("plain", "value")
+
+	redisURL := fmt.Sprintf("redis://%s", mr.Addr())
+	tests := []struct {
+		name    string
+		cfg     map[string]any
+		wantErr string
+	}{
+		{"missing stream", map[string]any{"group": "workers", "mode": streamModeStallGroup}, "streamKey is required"},
+		{"missing group", map[string]any{"streamKey": "jobs", "mode": streamModeStallGroup}, "group is required"},
+		{"invalid mode", map[string]any{"streamKey": "jobs", "group": "workers", "mode": "nope"}, "unsupported mode"},
+		{"delete without consumer", map[string]any{"streamKey": "jobs", "group": "workers", "mode": streamModeDeleteConsumer}, "consumer is required"},
+		{"trim to zero", map[string]any{"streamKey": "jobs", "group": "workers", "mode": streamModeTrim, "maxLen": float64(0)}, "maxLen must be at least 1"},
+		{"trim without maxLen", map[string]any{"streamKey": "jobs", "group": "workers", "mode": streamModeTrim}, "maxLen must be at least 1"},
+		{"not a stream", map[string]any{"streamKey": "plain", "group": "workers", "mode": streamModeStallGroup}, "is not a stream"},
+		{"unknown group", map[string]any{"streamKey": "jobs", "group": "nobody", "mode": streamModeStallGroup}, "consumer group 'nobody' not found"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			action := &streamConsumerGroupAttack{}
+			state := StreamConsumerGroupState{}
+			tc.cfg["duration"] = float64(30000)
+
+			_, err := action.Prepare(context.Background(), &state, streamPrepareRequest(redisURL, tc.cfg))
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.wantErr)
+		})
+	}
+}
+
+func TestStreamConsumerGroupAttack_Prepare_SetsState(t *testing.T) {
+	// Given
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	client := setupStreamWithPending(t, mr, 3)
+	defer client.Close()
+
+	action := &streamConsumerGroupAttack{}
+	state := StreamConsumerGroupState{}
+
+	// When
+	_, err = action.Prepare(context.Background(), &state, streamPrepareRequest(fmt.Sprintf("redis://%s", mr.Addr()), map[string]any{
+		"duration":      float64(30000),
+		"streamKey":     "jobs",
+		"group":         "workers",
+		"mode":          streamModeStallGroup,
+		"restoreOnStop": true,
+	}))
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, "jobs", state.StreamKey)
+	assert.Equal(t, "workers", state.Group)
+	assert.Contains(t, state.PhantomConsumer, "steadybit-phantom-")
+	assert.True(t, state.RestoreOnStop)
+}
+
+func TestStreamConsumerGroupAttack_StallGroup_ClaimsAndHoardsEntries(t *testing.T) {
+	// Given
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	client := setupStreamWithPending(t, mr, 5)
+	defer client.Close()
+
+	action := &streamConsumerGroupAttack{}
+	state := StreamConsumerGroupState{
+		RedisURL:        fmt.Sprintf("redis://%s", mr.Addr()),
+		ExecutionID:     uuid.New().String(),
+		StreamKey:       "jobs",
+		Group:           "workers",
+		Mode:            streamModeStallGroup,
+		PhantomConsumer: "steadybit-phantom-test",
+		RestoreOnStop:   true,
+		EndTime:         time.Now().Add(30 * time.Second).Unix(),
+		PendingOwners:   make(map[string]string),
+		MaxBackupBytes:  1024 * 1024,
+	}
+	defer func() {
+		activeStreamStallsMutex.Lock()
+		stall := activeStreamStalls[state.ExecutionID]
+		delete(activeStreamStalls, state.ExecutionID)
+		activeStreamStallsMutex.Unlock()
+		if stall != nil {
+			stall.cancel()
+			<-stall.done
+		}
+	}()
+
+	// When
+	_, err = action.Start(context.Background(), &state)
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), state.ClaimedEntries)
+	assert.Equal(t, map[string]string{"1-0": "worker-1", "2-0": "worker-1"}, state.PendingOwners)
+
+	require.Eventually(t, func() bool {
+		consumers, err := client.XInfoConsumers(context.Background(), "jobs", "workers").Result()
+		if err != nil {
+			return false
+		}
+		for _, c := range consumers {
+			if c.Name == "steadybit-phantom-test" {
+				return c.Pending == 5
+			}
+		}
+		return false
+	}, 5*time.Second, 50*time.Millisecond)
+
+	require.Eventually(t, func() bool {
+		result, err := action.Status(context.Background(), &state)
+		return err == nil && strings.Contains((*result.Messages)[0].Message, "phantom consumer holds 2 claimed and 3 newly read entries")
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
+func TestStreamConsumerGroupAttack_DeleteConsumer_RestoresPendingEntries(t *testing.T) {
+	// Given
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	client := setupStreamWithPending(t, mr, 3)
+	defer client.Close()
+
+	action := &streamConsumerGroupAttack{}
+	state := StreamConsumerGroupState{
+		RedisURL:        fmt.Sprintf("redis://%s", mr.Addr()),
+		ExecutionID:     uuid.New().String(),
+		StreamKey:       "jobs",
+		Group:           "workers",
+		Mode:            streamModeDeleteConsumer,
+		Consumer:        "worker-1",
+		PhantomConsumer: "steadybit-phantom-test",
+		RestoreOnStop:   true,
+		EndTime:         time.Now().Add(30 * time.Second).Unix(),
+		PendingOwners:   make(map[string]string),
+		MaxBackupBytes:  1024 * 1024,
+	}
+	ctx := context.Background()
+
+	// When
+	_, err = action.Start(ctx, &state)
+	require.NoError(t, err)
+
+	consumers, err := client.XInfoConsumers(ctx, "jobs", "workers").Result()
+	require.NoError(t, err)
+	require.Len(t, consumers, 1)
+	assert.Equal(t, "steadybit-phantom-test", consumers[0].Name)
+
+	_, err = action.Stop(ctx, &state)
+
+	// Then
+	require.NoError(t, err)
+	consumers, err = client.XInfoConsumers(ctx, "jobs", "workers").Result()
+	require.NoError(t, err)
+	require.Len(t, consumers, 1)
+	assert.Equal(t, "worker-1", consumers[0].Name)
+	assert.Equal(t, int64(2), consumers[0].Pending)
+}
+
+func TestStreamConsumerGroupAttack_Trim(t *testing.T) {
+	// Given
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	client := setupStreamWithPending(t, mr, 10)
+	defer client.Close()
+
+	action := &streamConsumerGroupAttack{}
+	state := StreamConsumerGroupState{
+		RedisURL:    fmt.Sprintf("redis://%s", mr.Addr()),
+		ExecutionID: uuid.New().String(),
+		StreamKey:   "jobs",
+		Group:       "workers",
+		Mode:        streamModeTrim,
+		MaxLen:      3,
+		EndTime:     time.Now().Add(30 * time.Second).Unix(),
+	}
+
+	// When
+	_, err = action.Start(context.Background(), &state)
+	require.NoError(t, err)
+	result, err := action.Stop(context.Background(), &state)
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), state.TrimmedEntries)
+	assert.Equal(t, int64(3), client.XLen(context.Background(), "jobs").Val())
+	assert.Equal(t, action_kit_api.Warn, *(*result.Messages)[0].Level)
+	assert.Contains(t, (*result.Messages)[0].Message, "not reversible")
+}
+
+func TestStreamConsumerGroupAttack_StallGroup_DoesNotRedeliverAcknowledgedEntries(t *testing.T) {
+	// Given
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	client := setupStreamWithPending(t, mr, 5)
+	defer client.Close()
+	ctx := context.Background()
+
+	action := &streamConsumerGroupAttack{}
+	state := StreamConsumerGroupState{
+		RedisURL:        fmt.Sprintf("redis://%s", mr.Addr()),
+		ExecutionID:     uuid.New().String(),
+		StreamKey:       "jobs",
+		Group:           "workers",
+		Mode:            streamModeStallGroup,
+		PhantomConsumer: "steadybit-phantom-test",
+		RestoreOnStop:   true,
+		EndTime:         time.Now().Add(30 * time.Second).Unix(),
+		PendingOwners:   make(map[string]string),
+		MaxBackupBytes:  1024 * 1024,
+	}
+	_, err = action.Start(ctx, &state)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		return client.XPending(ctx, "jobs", "workers").Val().Consumers["steadybit-phantom-test"] == 5
+	}, 5*time.Second, 50*time.Millisecond)
+
+	// A real consumer reads and acknowledges a new entry during the stall
+	_, err = client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.XAdd(ctx, &redis.XAddArgs{Stream: "jobs", ID: "6-0", Values: map[string]any{"job": 6}})
+		pipe.XReadGroup(ctx, &redis.XReadGroupArgs{Group: "workers", Consumer: "worker-2", Streams: []string{"jobs", ">"}, Block: -1})
+		return nil
+	})
+	require.NoError(t, err)
+	require.NoError(t, client.XAck(ctx, "jobs", "workers", "6-0").Err())
+
+	// When
+	_, err = action.Stop(ctx, &state)
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), state.HandedOverEntries)
+	pending := client.XPending(ctx, "jobs", "workers").Val()
+	assert.Equal(t, int64(5), pending.Count)
+	assert.Equal(t, "1-0", pending.Lower)
+	assert.Equal(t, "5-0", pending.Higher)
+	assert.NotContains(t, pending.Consumers, "steadybit-phantom-test")
+	redelivered, err := client.XReadGroup(ctx, &redis.XReadGroupArgs{Group: "workers", Consumer: "worker-2", Streams: []string{"jobs", ">"}, Block: -1}).Result()
+	assert.ErrorIs(t, err, redis.Nil)
+	assert.Empty(t, redelivered)
+}
+
+func TestRestoreStreamConsumerGroup_StallHandsOverHoardedEntries(t *testing.T) {
+	// Given
+	client, mock := redismock.NewClientMock()
+	defer client.Close()
+
+	state := &StreamConsumerGroupState{
+		StreamKey:       "jobs",
+		Group:           "workers",
+		Mode:            streamModeStallGroup,
+		PhantomConsumer: "steadybit-phantom-test",
+		RestoreOnStop:   true,
+		PendingOwners:   map[string]string{"1-0": "worker-1"},
+	}
+
+	mock.ExpectXClaimJustID(&redis.XClaimArgs{
+		Stream:   "jobs",
+		Group:    "workers",
+		Consumer: "worker-1",
+		Messages: []string{"1-0"},
+	}).SetVal([]string{"1-0"})
+	mock.ExpectXPendingExt(&redis.XPendingExtArgs{
+		Stream:   "jobs",
+		Group:    "workers",
+		Start:    "-",
+		End:      "+",
+		Count:    streamPendingPageSize,
+		Consumer: "steadybit-phantom-test",
+	}).SetVal([]redis.XPendingExt{{ID: "3-0"}, {ID: "4-0"}, {ID: "5-0"}})
+	mock.ExpectXInfoConsumers("jobs", "workers").SetVal([]redis.XInfoConsumer{{Name: "steadybit-phantom-test"}, {Name: "worker-1"}, {Name: "worker-2"}})
+	mock.ExpectXClaimJustID(&redis.XClaimArgs{
+		Stream:   "jobs",
+		Group:    "workers",
+		Consumer: "worker-1",
+		Messages: []string{"3-0", "4-0"},
+	}).SetVal([]string{"3-0", "4-0"})
+	mock.ExpectXClaimJustID(&redis.XClaimArgs{
+		Stream:   "jobs",
+		Group:    "workers",
+		Consumer: "worker-2",
+		Messages: []string{"5-0"},
+	}).SetVal([]string{"5-0"})
+	mock.ExpectXGroupDelConsumer("jobs", "workers", "steadybit-phantom-test").SetVal(0)
+
+	// When
+	restored, err := restoreStreamConsumerGroup(context.Background(), client, state)
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), restored)
+	assert.Equal(t, int64(3), state.HandedOverEntries)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRestoreStreamConsumerGroup_KeepsPhantomWithoutConsumers(t *testing.T) {
+	// Given
+	client, mock := redismock.NewClientMock()
+	defer client.Close()
+
+	state := &StreamConsumerGroupState{
+		StreamKey:       "jobs",
+		Group:           "workers",
+		Mode:            streamModeStallGroup,
+		PhantomConsumer: "steadybit-phantom-test",
+		RestoreOnStop:   true,
+		PendingOwners:   map[string]string{},
+	}
+
+	mock.ExpectXPendingExt(&redis.XPendingExtArgs{
+		Stream:   "jobs",
+		Group:    "workers",
+		Start:    "-",
+		End:      "+",
+		Count:    streamPendingPageSize,
+		Consumer: "steadybit-phantom-test",
+	}).SetVal([]redis.XPendingExt{{ID: "3-0"}})
+	mock.ExpectXInfoConsumers("jobs", "workers").SetVal([]redis.XInfoConsumer{{Name: "steadybit-phantom-test"}})
+
+	// When
+	_, err := restoreStreamConsumerGroup(context.Background(), client, state)
+
+	// Then
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "phantom consumer 'steadybit-phantom-test' is kept")
+	assert.Contains(t, err.Error(), "no consumer to hand over the 1 entries")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPhantomConsumerName(t *testing.T) {
+	assert.Equal(t, "steadybit-phantom-12345678", phantomConsumerName("12345678-aaaa-bbbb"))
+	assert.Equal(t, "steadybit-phantom-abc", phantomConsumerName("abc"))
+}
@@ -0,0 +1,68 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package extredis
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/steadybit/extension-redis/config"
+)
+
+// storedBackup is the serialized form of KeyBackup and FieldBackup. With STEADYBIT_EXTENSION_STATE_ENCRYPTION_KEY set
+// the value is encrypted, so backed up data leaves the extension in action state and the journal only as ciphertext.
+type storedBackup struct {
+	Value      string `json:"value"`
+	Encrypted  bool   `json:"encrypted,omitempty"`
+	TTLSeconds int64  `json:"ttlSeconds"`
+}
+
+func (b KeyBackup) MarshalJSON() ([]byte, error) {
+	return marshalBackup(b.Value, b.TTLSeconds)
+}
+
+func (b *KeyBackup) UnmarshalJSON(data []byte) (err error) {
+	b.Value, b.TTLSeconds, err = unmarshalBackup(data)
+	return err
+}
+
+func (b FieldBackup) MarshalJSON() ([]byte, error) {
+	return marshalBackup(b.Value, b.TTLSeconds)
+}
+
+func (b *FieldBackup) UnmarshalJSON(data []byte) (err error) {
+	b.Value, b.TTLSeconds, err = unmarshalBackup(data)
+	return err
+}
+
+func marshalBackup(value string, ttlSeconds int64) ([]byte, error) {
+	stored := storedBackup{Value: value, TTLSeconds: ttlSeconds}
+	if config.StateEncryptionEnabled() {
+		encrypted, err := config.EncryptStateValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt backup: %w", err)
+		}
+		stored.Value = encrypted
+		stored.Encrypted = true
+	}
+	return json.Marshal(stored)
+}
+
+// unmarshalBackup decrypts encrypted values. Plain values are read as they are, so states written before the key was
+// configured can still be restored.
+func unmarshalBackup(data []byte) (string, int64, error) {
+	var stored storedBackup
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return "", 0, err
+	}
+	if !stored.Encrypted {
+		return stored.Value, stored.TTLSeconds, nil
+	}
+	value, err := config.DecryptStateValue(stored.Value)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to decrypt backup: %w", err)
+	}
+	return value, stored.TTLSeconds, nil
+}
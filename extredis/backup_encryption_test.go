@@ -0,0 +1,90 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2024 Steadybit GmbH
+
+package extredis
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/steadybit/extension-kit/extconversion"
+	"github.com/steadybit/extension-redis/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func useStateEncryptionKey(t *testing.T, key string) {
+	t.Helper()
+	orig := config.Config.StateEncryptionKey
+	t.Cleanup(func() { config.Config.StateEncryptionKey = orig })
+	config.Config.StateEncryptionKey = key
+}
+
+func TestBackupEncryption_Disabled(t *testing.T) {
+	// Given
+	useStateEncryptionKey(t, "")
+
+	// When
+	data, err := json.Marshal(KeyBackup{Value: "plain", TTLSeconds: -1})
+
+	// Then
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"value":"plain","ttlSeconds":-1}`, string(data))
+}
+
+func TestBackupEncryption_StateRoundTrip(t *testing.T) {
+	// Given
+	useStateEncryptionKey(t, base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32))))
+	state := CacheExpirationState{
+		RedisURL:   "redis://localhost:6379",
+		BackupData: map[string]KeyBackup{"session:1": {Value: "token-of-alice", TTLSeconds: 300}},
+		HashBackupData: map[string]map[string]FieldBackup{
+			"user:1": {"email": {Value: "alice@example.com", TTLSeconds: -1}},
+		},
+	}
+
+	// When
+	var serialized map[string]any
+	require.NoError(t, extconversion.Convert(state, &serialized))
+	var restored CacheExpirationState
+	require.NoError(t, extconversion.Convert(serialized, &restored))
+
+	// Then
+	data, err := json.Marshal(serialized)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "token-of-alice")
+	assert.NotContains(t, string(data), "alice@example.com")
+	assert.Contains(t, string(data), `"encrypted":true`)
+	assert.Equal(t, state.BackupData, restored.BackupData)
+	assert.Equal(t, state.HashBackupData, restored.HashBackupData)
+}
+
+func TestBackupEncryption_PlainStateWithKey(t *testing.T) {
+	// Given - state written before the key was configured
+	useStateEncryptionKey(t, base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 16))))
+
+	// When
+	var backup KeyBackup
+	err := json.Unmarshal([]byte(`{"value":"plain","ttlSeconds":60}`), &backup)
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, KeyBackup{Value: "plain", TTLSeconds: 60}, backup)
+}
+
+func TestBackupEncryption_KeyRemoved(t *testing.T) {
+	// Given
+	useStateEncryptionKey(t, base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 16))))
+	data, err := json.Marshal(FieldBackup{Value: "secret", TTLSeconds: -1})
+	require.NoError(t, err)
+
+	// When
+	config.Config.StateEncryptionKey = ""
+	var backup FieldBackup
+	err = json.Unmarshal(data, &backup)
+
+	// Then
+	assert.ErrorContains(t, err, "failed to decrypt backup")
+}
@@ -15,13 +15,13 @@ import (
 	"github.com/steadybit/extension-kit/extbuild"
 	"github.com/steadybit/extension-kit/extutil"
 	"github.com/steadybit/extension-redis/clients"
+	"github.com/steadybit/extension-redis/config"
 )
 
 type connectionCountCheck struct{}
 
 type ConnectionCountCheckState struct {
 	RedisURL          string  `json:"redisUrl"`
-	Password          string  `json:"password"`
 	DB                int     `json:"db"`
 	MaxConnections    int     `json:"maxConnections"`
 	MaxConnectionsPct float64 `json:"maxConnectionsPct"`
@@ -122,7 +122,7 @@ func (a *connectionCountCheck) Prepare(ctx context.Context, state *ConnectionCou
 	maxConnections := int(extutil.ToInt64(request.Config["maxConnections"]))
 	maxConnectionsPct := float64(extutil.ToInt64(request.Config["maxConnectionsPct"]))
 
-	state.RedisURL = redisURL[0]
+	state.RedisURL = config.RedactURL(redisURL[0])
 	state.DB = 0
 	state.MaxConnections = maxConnections
 	state.MaxConnectionsPct = maxConnectionsPct
@@ -134,7 +134,7 @@ func (a *connectionCountCheck) Prepare(ctx context.Context, state *ConnectionCou
 }
 
 func (a *connectionCountCheck) Start(ctx context.Context, state *ConnectionCountCheckState) (*action_kit_api.StartResult, error) {
-	client, err := clients.GetRedisClient(state.RedisURL, state.Password, state.DB)
+	client, err := clients.GetRedisClient(state.RedisURL, "", state.DB)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Redis client: %w", err)
 	}
@@ -157,7 +157,7 @@ func (a *connectionCountCheck) Status(ctx context.Context, state *ConnectionCoun
 	now := time.Now()
 	completed := now.Unix() >= state.EndTime
 
-	client, err := clients.GetRedisClient(state.RedisURL, state.Password, state.DB)
+	client, err := clients.GetRedisClient(state.RedisURL, "", state.DB)
 	if err != nil {
 		return &action_kit_api.StatusResult{
 			Completed: completed,
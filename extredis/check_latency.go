@@ -12,14 +12,16 @@ import (
 	"github.com/steadybit/extension-kit/extbuild"
 	"github.com/steadybit/extension-kit/extutil"
 	"github.com/steadybit/extension-redis/clients"
+	"github.com/steadybit/extension-redis/config"
 	"time"
 )
 
+const latencyCheckActionID = "com.steadybit.extension_redis.instance.check-latency"
+
 type latencyCheck struct{}
 
 type LatencyCheckState struct {
 	RedisURL          string  `json:"redisUrl"`
-	Password          string  `json:"password"`
 	DB                int     `json:"db"`
 	MaxLatencyMs      float64 `json:"maxLatencyMs"`
 	EndTime           int64   `json:"endTime"`
@@ -42,7 +44,7 @@ func (a *latencyCheck) NewEmptyState() LatencyCheckState {
 
 func (a *latencyCheck) Describe() action_kit_api.ActionDescription {
 	return action_kit_api.ActionDescription{
-		Id:          "com.steadybit.extension_redis.instance.check-latency",
+		Id:          latencyCheckActionID,
 		Label:       "Latency Check",
 		Description: "Monitors Redis response latency and fails if threshold is exceeded",
 		Version:     extbuild.GetSemverVersionStringOrUnknown(),
@@ -132,7 +134,7 @@ func (a *latencyCheck) Prepare(ctx context.Context, state *LatencyCheckState, re
 	duration := extutil.ToInt64(request.Config["duration"]) / 1000 // Convert ms to seconds
 	maxLatencyMs := float64(extutil.ToInt64(request.Config["maxLatencyMs"]))
 
-	state.RedisURL = redisURL[0]
+	state.RedisURL = config.RedactURL(redisURL[0])
 	state.DB = 0
 	state.MaxLatencyMs = maxLatencyMs
 	state.EndTime = time.Now().Add(time.Duration(duration) * time.Second).Unix()
@@ -146,7 +148,7 @@ func (a *latencyCheck) Prepare(ctx context.Context, state *LatencyCheckState, re
 
 func (a *latencyCheck) Start(ctx context.Context, state *LatencyCheckState) (*action_kit_api.StartResult, error) {
 	// Verify connection
-	client, err := clients.GetRedisClient(state.RedisURL, state.Password, state.DB)
+	client, err := clients.GetRedisClient(state.RedisURL, "", state.DB)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Redis client: %w", err)
 	}
@@ -170,7 +172,7 @@ func (a *latencyCheck) Status(ctx context.Context, state *LatencyCheckState) (*a
 	completed := now.Unix() >= state.EndTime
 
 	// Measure latency
-	client, err := clients.GetRedisClient(state.RedisURL, state.Password, state.DB)
+	client, err := clients.GetRedisClient(state.RedisURL, "", state.DB)
 	if err != nil {
 		state.FailedPings++
 		return &action_kit_api.StatusResult{
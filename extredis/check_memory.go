@@ -12,6 +12,7 @@ import (
 	"github.com/steadybit/extension-kit/extbuild"
 	"github.com/steadybit/extension-kit/extutil"
 	"github.com/steadybit/extension-redis/clients"
+	"github.com/steadybit/extension-redis/config"
 	"strconv"
 	"time"
 )
@@ -20,7 +21,6 @@ type memoryCheck struct{}
 
 type MemoryCheckState struct {
 	RedisURL          string  `json:"redisUrl"`
-	Password          string  `json:"password"`
 	DB                int     `json:"db"`
 	MaxMemoryPercent  float64 `json:"maxMemoryPercent"`
 	MaxMemoryBytes    int64   `json:"maxMemoryBytes"`
@@ -121,7 +121,7 @@ func (a *memoryCheck) Prepare(ctx context.Context, state *MemoryCheckState, requ
 	maxMemoryPercent := float64(extutil.ToInt64(request.Config["maxMemoryPercent"]))
 	maxMemoryBytes := extutil.ToInt64(request.Config["maxMemoryBytes"]) * 1024 * 1024 // Convert MB to bytes
 
-	state.RedisURL = redisURL[0]
+	state.RedisURL = config.RedactURL(redisURL[0])
 	state.DB = 0
 	state.MaxMemoryPercent = maxMemoryPercent
 	state.MaxMemoryBytes = maxMemoryBytes
@@ -134,7 +134,7 @@ func (a *memoryCheck) Prepare(ctx context.Context, state *MemoryCheckState, requ
 
 func (a *memoryCheck) Start(ctx context.Context, state *MemoryCheckState) (*action_kit_api.StartResult, error) {
 	// Verify connection
-	client, err := clients.GetRedisClient(state.RedisURL, state.Password, state.DB)
+	client, err := clients.GetRedisClient(state.RedisURL, "", state.DB)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Redis client: %w", err)
 	}
@@ -157,7 +157,7 @@ func (a *memoryCheck) Status(ctx context.Context, state *MemoryCheckState) (*act
 	now := time.Now()
 	completed := now.Unix() >= state.EndTime
 
-	client, err := clients.GetRedisClient(state.RedisURL, state.Password, state.DB)
+	client, err := clients.GetRedisClient(state.RedisURL, "", state.DB)
 	if err != nil {
 		return &action_kit_api.StatusResult{
 			Completed: completed,
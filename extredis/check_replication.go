@@ -15,13 +15,15 @@ import (
 	"github.com/steadybit/extension-kit/extbuild"
 	"github.com/steadybit/extension-kit/extutil"
 	"github.com/steadybit/extension-redis/clients"
+	"github.com/steadybit/extension-redis/config"
 )
 
+const replicationCheckActionID = "com.steadybit.extension_redis.instance.check-replication"
+
 type replicationLagCheck struct{}
 
 type ReplicationLagCheckState struct {
 	RedisURL          string `json:"redisUrl"`
-	Password          string `json:"password"`
 	DB                int    `json:"db"`
 	MaxLagSeconds     int    `json:"maxLagSeconds"`
 	RequireLinkUp     bool   `json:"requireLinkUp"`
@@ -44,7 +46,7 @@ func (a *replicationLagCheck) NewEmptyState() ReplicationLagCheckState {
 
 func (a *replicationLagCheck) Describe() action_kit_api.ActionDescription {
 	return action_kit_api.ActionDescription{
-		Id:          "com.steadybit.extension_redis.instance.check-replication",
+		Id:          replicationCheckActionID,
 		Label:       "Replication Lag Check",
 		Description: "Monitors Redis replication status and lag for replicas. Fails if replication lag exceeds the threshold or if the master link goes down (configurable). Use to verify replication resilience during fault injection.",
 		Version:     extbuild.GetSemverVersionStringOrUnknown(),
@@ -144,7 +146,7 @@ func (a *replicationLagCheck) Prepare(ctx context.Context, state *ReplicationLag
 	maxLagSeconds := int(extutil.ToInt64(request.Config["maxLagSeconds"]))
 	requireLinkUp := extutil.ToBool(request.Config["requireLinkUp"])
 
-	state.RedisURL = redisURL[0]
+	state.RedisURL = config.RedactURL(redisURL[0])
 	state.DB = 0
 	state.MaxLagSeconds = maxLagSeconds
 	state.RequireLinkUp = requireLinkUp
@@ -157,7 +159,7 @@ func (a *replicationLagCheck) Prepare(ctx context.Context, state *ReplicationLag
 }
 
 func (a *replicationLagCheck) Start(ctx context.Context, state *ReplicationLagCheckState) (*action_kit_api.StartResult, error) {
-	client, err := clients.GetRedisClient(state.RedisURL, state.Password, state.DB)
+	client, err := clients.GetRedisClient(state.RedisURL, "", state.DB)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Redis client: %w", err)
 	}
@@ -198,7 +200,7 @@ func (a *replicationLagCheck) Status(ctx context.Context, state *ReplicationLagC
 	now := time.Now()
 	completed := now.Unix() >= state.EndTime
 
-	client, err := clients.GetRedisClient(state.RedisURL, state.Password, state.DB)
+	client, err := clients.GetRedisClient(state.RedisURL, "", state.DB)
 	if err != nil {
 		return &action_kit_api.StatusResult{
 			Completed: completed,
@@ -0,0 +1,495 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package extredis
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+	"github.com/steadybit/action-kit/go/action_kit_api/v2"
+	"github.com/steadybit/action-kit/go/action_kit_sdk"
+	"github.com/steadybit/extension-kit/extbuild"
+	"github.com/steadybit/extension-kit/extutil"
+	"github.com/steadybit/extension-redis/clients"
+	"github.com/steadybit/extension-redis/config"
+)
+
+type streamBacklogCheck struct{}
+
+type StreamBacklogCheckState struct {
+	RedisURL              string   `json:"redisUrl"`
+	DB                    int      `json:"db"`
+	StreamKeys            []string `json:"streamKeys"`
+	Group                 string   `json:"group"`
+	MaxBacklog            int64    `json:"maxBacklog"`
+	RecoveryWindowSeconds int64    `json:"recoveryWindowSeconds"`
+	EndTime               int64    `json:"endTime"`
+	ClusterMode           bool     `json:"clusterMode"`
+	// BacklogAboveSince tracks, per "stream/group", since when the backlog is above MaxBacklog (unix seconds).
+	BacklogAboveSince  map[string]int64 `json:"backlogAboveSince"`
+	RecoveryExceeded   bool             `json:"recoveryExceeded"`
+	MaxObservedBacklog int64            `json:"maxObservedBacklog"`
+	Violation          string           `json:"violation"`
+}
+
+// streamGroupBacklog is the backlog of a single consumer group as observed by one status call.
+type streamGroupBacklog struct {
+	Stream            string
+	Group             string
+	Pending           int64
+	Lag               int64
+	LastDeliveredAge  time.Duration
+	ConsumerIdleTimes map[string]time.Duration
+}
+
+// Backlog returns pending plus lag, falling back to pending alone when Redis cannot determine the lag.
+func (b streamGroupBacklog) Backlog() int64 {
+	if b.Lag < 0 {
+		return b.Pending
+	}
+	return b.Pending + b.Lag
+}
+
+var _ action_kit_sdk.Action[StreamBacklogCheckState] = (*streamBacklogCheck)(nil)
+var _ action_kit_sdk.ActionWithStatus[StreamBacklogCheckState] = (*streamBacklogCheck)(nil)
+
+func NewStreamBacklogCheck() action_kit_sdk.Action[StreamBacklogCheckState] {
+	return &streamBacklogCheck{}
+}
+
+func (a *streamBacklogCheck) NewEmptyState() StreamBacklogCheckState {
+	return StreamBacklogCheckState{}
+}
+
+func (a *streamBacklogCheck) Describe() action_kit_api.ActionDescription {
+	return action_kit_api.ActionDescription{
+		Id:          "com.steadybit.extension_redis.database.check-stream-backlog",
+		Label:       "Stream Backlog Check",
+		Description: "Monitors Redis Stream consumer groups using XINFO STREAM, XINFO GROUPS and XINFO CONSUMERS. Reports pending entries, lag, last-delivered id age and consumer idle times. Fails if the backlog of a group stays above the threshold for longer than the recovery window.",
+		Version:     extbuild.GetSemverVersionStringOrUnknown(),
+		Icon:        new(redisIcon),
+		TargetSelection: new(action_kit_api.TargetSelection{
+			TargetType: TargetTypeDatabase,
+			SelectionTemplates: new([]action_kit_api.TargetSelectionTemplate{
+				{
+					Label:       "by host and database",
+					Description: new("Find Redis database by host and index"),
+					Query:       "redis.host=\"\" AND redis.database.index=\"\"",
+				},
+			}),
+		}),
+		Technology:  new("Redis"),
+		Category:    new("monitoring"),
+		Kind:        action_kit_api.Check,
+		TimeControl: action_kit_api.TimeControlExternal,
+		Parameters: []action_kit_api.ActionParameter{
+			{
+				Name:         "duration",
+				Label:        "Duration",
+				Description:  new("How long to monitor the streams"),
+				Type:         action_kit_api.ActionParameterTypeDuration,
+				DefaultValue: new("60s"),
+				Required:     new(true),
+			},
+			{
+				Name:         "streamKeys",
+				Label:        "Stream Keys",
+				Description:  new("Comma-separated list of stream keys to monitor"),
+				Type:         action_kit_api.ActionParameterTypeString,
+				DefaultValue: new(""),
+				Required:     new(true),
+			},
+			{
+				Name:         "group",
+				Label:        "Consumer Group",
+				Description:  new("Consumer group to monitor (empty monitors all groups of the streams)"),
+				Type:         action_kit_api.ActionParameterTypeString,
+				DefaultValue: new(""),
+				Required:     new(false),
+			},
+			{
+				Name:         "maxBacklog",
+				Label:        "Max Backlog",
+				Description:  new("Backlog (pending + lag) a group must drain to"),
+				Type:         action_kit_api.ActionParameterTypeInteger,
+				DefaultValue: new("100"),
+				Required:     new(true),
+			},
+			{
+				Name:         "recoveryWindowSeconds",
+				Label:        "Recovery Window (seconds)",
+				Description:  new("How long the backlog may stay above the threshold before the check fails"),
+				Type:         action_kit_api.ActionParameterTypeInteger,
+				DefaultValue: new("30"),
+				Required:     new(true),
+			},
+		},
+		Status: new(action_kit_api.MutatingEndpointReferenceWithCallInterval{
+			CallInterval: new("2s"),
+		}),
+		Widgets: new([]action_kit_api.Widget{
+			action_kit_api.LineChartWidget{
+				Type:  action_kit_api.ComSteadybitWidgetLineChart,
+				Title: "Redis Stream Backlog",
+				Identity: action_kit_api.LineChartWidgetIdentityConfig{
+					MetricName: "redis_stream_backlog",
+					From:       "redis.stream.group",
+					Mode:       action_kit_api.ComSteadybitWidgetLineChartIdentityModeSelect,
+				},
+				Grouping: new(action_kit_api.LineChartWidgetGroupingConfig{
+					ShowSummary: new(true),
+					Groups: []action_kit_api.LineChartWidgetGroup{
+						{
+							Title: "Draining",
+							Color: "success",
+							Matcher: action_kit_api.LineChartWidgetGroupMatcherKeyEqualsValue{
+								Type:  action_kit_api.ComSteadybitWidgetLineChartGroupMatcherKeyEqualsValue,
+								Key:   "backlog_status",
+								Value: "ok",
+							},
+						},
+						{
+							Title: "Above Threshold",
+							Color: "warn",
+							Matcher: action_kit_api.LineChartWidgetGroupMatcherKeyEqualsValue{
+								Type:  action_kit_api.ComSteadybitWidgetLineChartGroupMatcherKeyEqualsValue,
+								Key:   "backlog_status",
+								Value: "recovering",
+							},
+						},
+						{
+							Title: "Recovery Window Exceeded",
+							Color: "danger",
+							Matcher: action_kit_api.LineChartWidgetGroupMatcherFallback{
+								Type: action_kit_api.ComSteadybitWidgetLineChartGroupMatcherFallback,
+							},
+						},
+					},
+				}),
+				Tooltip: new(action_kit_api.LineChartWidgetTooltipConfig{
+					MetricValueTitle: new("Backlog (entries)"),
+					AdditionalContent: []action_kit_api.LineChartWidgetTooltipContent{
+						{From: "redis.stream", Title: "Stream"},
+						{From: "redis.stream.group", Title: "Group"},
+						{From: "backlog_status", Title: "Status"},
+					},
+				}),
+			},
+		}),
+	}
+}
+
+func (a *streamBacklogCheck) Prepare(ctx context.Context, state *StreamBacklogCheckState, request action_kit_api.PrepareActionRequestBody) (*action_kit_api.PrepareResult, error) {
+	redisURL := request.Target.Attributes[AttrRedisURL]
+	if len(redisURL) == 0 {
+		return nil, fmt.Errorf("redis URL not found in target attributes")
+	}
+
+	dbIndex := request.Target.Attributes[AttrDatabaseIndex]
+	db := 0
+	if len(dbIndex) > 0 {
+		db, _ = strconv.Atoi(dbIndex[0])
+	}
+
+	duration := extutil.ToInt64(request.Config["duration"]) / 1000
+	streamKeys := parseStreamKeys(extutil.ToString(request.Config["streamKeys"]))
+	maxBacklog := extutil.ToInt64(request.Config["maxBacklog"])
+	recoveryWindowSeconds := extutil.ToInt64(request.Config["recoveryWindowSeconds"])
+
+	if len(streamKeys) == 0 {
+		return nil, fmt.Errorf("at least one stream key is required")
+	}
+	if maxBacklog < 0 {
+		return nil, fmt.Errorf("maxBacklog must not be negative")
+	}
+	if recoveryWindowSeconds < 0 {
+		return nil, fmt.Errorf("recoveryWindowSeconds must not be negative")
+	}
+
+	state.RedisURL = config.RedactURL(redisURL[0])
+	state.DB = db
+	state.StreamKeys = streamKeys
+	state.Group = extutil.ToString(request.Config["group"])
+	state.MaxBacklog = maxBacklog
+	state.RecoveryWindowSeconds = recoveryWindowSeconds
+	state.EndTime = time.Now().Add(time.Duration(duration) * time.Second).Unix()
+	state.BacklogAboveSince = make(map[string]int64)
+	state.RecoveryExceeded = false
+	state.MaxObservedBacklog = 0
+	state.Violation = ""
+
+	// XINFO and XPENDING must reach the node of the stream key
+	if endpoint := config.GetEndpointByURL(state.RedisURL); endpoint != nil {
+		isCluster, err := clients.DetectClusterMode(ctx, endpoint)
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed to detect cluster mode, assuming standalone")
+		} else {
+			state.ClusterMode = isCluster
+		}
+	}
+
+	return nil, nil
+}
+
+func (a *streamBacklogCheck) Start(ctx context.Context, state *StreamBacklogCheckState) (*action_kit_api.StartResult, error) {
+	client, err := clients.GetUniversalClient(state.RedisURL, "", state.DB, state.ClusterMode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Redis client: %w", err)
+	}
+
+	if err := clients.PingRedis(ctx, client); err != nil {
+		return nil, fmt.Errorf("failed to ping Redis: %w", err)
+	}
+
+	// Fail fast on typos so the check does not silently report an empty chart
+	for _, streamKey := range state.StreamKeys {
+		if _, err := collectStreamBacklogs(ctx, client, streamKey, state.Group); err != nil {
+			return nil, err
+		}
+	}
+
+	return &action_kit_api.StartResult{
+		Messages: new([]action_kit_api.Message{
+			{
+				Level:   extutil.Ptr(action_kit_api.Info),
+				Message: fmt.Sprintf("Started monitoring stream backlog of %s (max backlog: %d, recovery window: %ds)", strings.Join(state.StreamKeys, ", "), state.MaxBacklog, state.RecoveryWindowSeconds),
+			},
+		}),
+	}, nil
+}
+
+func (a *streamBacklogCheck) Status(ctx context.Context, state *StreamBacklogCheckState) (*action_kit_api.StatusResult, error) {
+	now := time.Now()
+	completed := now.Unix() >= state.EndTime
+
+	client, err := clients.GetUniversalClient(state.RedisURL, "", state.DB, state.ClusterMode)
+	if err != nil {
+		return &action_kit_api.StatusResult{
+			Completed: completed,
+			Error: &action_kit_api.ActionKitError{
+				Title:  "Failed to connect to Redis",
+				Detail: new(err.Error()),
+				Status: extutil.Ptr(action_kit_api.Failed),
+			},
+		}, nil
+	}
+
+	if state.BacklogAboveSince == nil {
+		state.BacklogAboveSince = make(map[string]int64)
+	}
+
+	var metrics []action_kit_api.Metric
+	var warnings []string
+	for _, streamKey := range state.StreamKeys {
+		backlogs, err := collectStreamBacklogs(ctx, client, streamKey, state.Group)
+		if err != nil {
+			log.Debug().Err(err).Str("stream", streamKey).Msg("Failed to collect stream backlog")
+			warnings = append(warnings, err.Error())
+			continue
+		}
+
+		for _, b := range backlogs {
+			backlogStatus := evaluateStreamBacklog(state, b, now)
+			if backlogStatus != "ok" {
+				warnings = append(warnings, fmt.Sprintf("Backlog of group '%s' on stream '%s' is %d (threshold: %d)", b.Group, b.Stream, b.Backlog(), state.MaxBacklog))
+			}
+			metrics = append(metrics, streamBacklogMetrics(state.RedisURL, b, backlogStatus, now)...)
+		}
+	}
+
+	result := &action_kit_api.StatusResult{
+		Completed: completed,
+		Metrics:   new(metrics),
+	}
+
+	if completed && state.RecoveryExceeded {
+		result.Error = &action_kit_api.ActionKitError{
+			Title:  "Stream backlog check failed",
+			Detail: new(state.Violation),
+			Status: extutil.Ptr(action_kit_api.Failed),
+		}
+	} else if len(warnings) > 0 {
+		messages := make([]action_kit_api.Message, 0, len(warnings))
+		for _, w := range warnings {
+			messages = append(messages, action_kit_api.Message{
+				Level:   extutil.Ptr(action_kit_api.Warn),
+				Message: w,
+			})
+		}
+		result.Messages = new(messages)
+	}
+
+	return result, nil
+}
+
+// evaluateStreamBacklog updates the recovery tracking of the group and returns its backlog status
+// ("ok", "recovering" or "exceeded").
+func evaluateStreamBacklog(state *StreamBacklogCheckState, b streamGroupBacklog, now time.Time) string {
+	key := b.Stream + "/" + b.Group
+	backlog := b.Backlog()
+	if backlog > state.MaxObservedBacklog {
+		state.MaxObservedBacklog = backlog
+	}
+
+	if backlog <= state.MaxBacklog {
+		delete(state.BacklogAboveSince, key)
+		return "ok"
+	}
+
+	since, ok := state.BacklogAboveSince[key]
+	if !ok {
+		since = now.Unix()
+		state.BacklogAboveSince[key] = since
+	}
+
+	if now.Unix()-since > state.RecoveryWindowSeconds {
+		if !state.RecoveryExceeded {
+			state.RecoveryExceeded = true
+			state.Violation = fmt.Sprintf("Backlog of group '%s' on stream '%s' stayed above %d for more than %ds (current: %d)", b.Group, b.Stream, state.MaxBacklog, state.RecoveryWindowSeconds, backlog)
+		}
+		return "exceeded"
+	}
+	return "recovering"
+}
+
+// collectStreamBacklogs reads XINFO STREAM, XINFO GROUPS and XINFO CONSUMERS for a stream, optionally limited to one group.
+func collectStreamBacklogs(ctx context.Context, client redis.Cmdable, streamKey, group string) ([]streamGroupBacklog, error) {
+	info, err := client.XInfoStream(ctx, streamKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get info of stream '%s': %w", streamKey, err)
+	}
+
+	groups, err := client.XInfoGroups(ctx, streamKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get consumer groups of stream '%s': %w", streamKey, err)
+	}
+
+	var backlogs []streamGroupBacklog
+	for _, g := range groups {
+		if group != "" && g.Name != group {
+			continue
+		}
+
+		consumers, err := client.XInfoConsumers(ctx, streamKey, g.Name).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get consumers of group '%s' on stream '%s': %w", g.Name, streamKey, err)
+		}
+		idleTimes := make(map[string]time.Duration, len(consumers))
+		for _, c := range consumers {
+			idleTimes[c.Name] = c.Idle
+		}
+
+		backlogs = append(backlogs, streamGroupBacklog{
+			Stream:            streamKey,
+			Group:             g.Name,
+			Pending:           g.Pending,
+			Lag:               g.Lag,
+			LastDeliveredAge:  streamIDAge(info.LastGeneratedID, g.LastDeliveredID),
+			ConsumerIdleTimes: idleTimes,
+		})
+	}
+
+	if group != "" && len(backlogs) == 0 {
+		return nil, fmt.Errorf("consumer group '%s' not found on stream '%s'", group, streamKey)
+	}
+	return backlogs, nil
+}
+
+// streamIDAge returns how far the last delivered entry is behind the newest entry of the stream,
+// based on the millisecond part of the stream ids. It is zero when the group is caught up.
+func streamIDAge(lastGeneratedID, lastDeliveredID string) time.Duration {
+	generated, ok := streamIDMillis(lastGeneratedID)
+	if !ok {
+		return 0
+	}
+	delivered, ok := streamIDMillis(lastDeliveredID)
+	if !ok || delivered >= generated {
+		return 0
+	}
+	return time.Duration(generated-delivered) * time.Millisecond
+}
+
+func streamIDMillis(id string) (int64, bool) {
+	ms, _, _ := strings.Cut(id, "-")
+	value, err := strconv.ParseInt(ms, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return value, true
+}
+
+func streamBacklogMetrics(redisURL string, b streamGroupBacklog, backlogStatus string, now time.Time) []action_kit_api.Metric {
+	labels := map[string]string{
+		"redis.host":         redisURL,
+		"redis.stream":       b.Stream,
+		"redis.stream.group": b.Group,
+		"backlog_status":     backlogStatus,
+	}
+
+	metrics := []action_kit_api.Metric{
+		{
+			Name:      new("redis_stream_backlog"),
+			Metric:    labels,
+			Value:     float64(b.Backlog()),
+			Timestamp: now,
+		},
+		{
+			Name:      new("redis_stream_pending"),
+			Metric:    labels,
+			Value:     float64(b.Pending),
+			Timestamp: now,
+		},
+		{
+			Name:      new("redis_stream_last_delivered_age_seconds"),
+			Metric:    labels,
+			Value:     b.LastDeliveredAge.Seconds(),
+			Timestamp: now,
+		},
+	}
+	if b.Lag >= 0 {
+		metrics = append(metrics, action_kit_api.Metric{
+			Name:      new("redis_stream_lag"),
+			Metric:    labels,
+			Value:     float64(b.Lag),
+			Timestamp: now,
+		})
+	}
+
+	consumerNames := make([]string, 0, len(b.ConsumerIdleTimes))
+	for name := range b.ConsumerIdleTimes {
+		consumerNames = append(consumerNames, name)
+	}
+	sort.Strings(consumerNames)
+	for _, name := range consumerNames {
+		metrics = append(metrics, action_kit_api.Metric{
+			Name: new("redis_stream_consumer_idle_seconds"),
+			Metric: map[string]string{
+				"redis.host":            redisURL,
+				"redis.stream":          b.Stream,
+				"redis.stream.group":    b.Group,
+				"redis.stream.consumer": name,
+			},
+			Value:     b.ConsumerIdleTimes[name].Seconds(),
+			Timestamp: now,
+		})
+	}
+	return metrics
+}
+
+func parseStreamKeys(value string) []string {
+	var keys []string
+	for key := range strings.SplitSeq(value, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,290 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2024 Steadybit GmbH
+
+package extredis
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/steadybit/action-kit/go/action_kit_api/v2"
+	"github.com/steadybit/extension-kit/extutil"
+	"github.com/steadybit/extension-redis/clients"
+	"github.com/steadybit/extension-redis/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamBacklogCheck_Describe(t *testing.T) {
+	// Given
+	action := &streamBacklogCheck{}
+
+	// When
+	desc := action.Describe()
+
+	// Then
+	assert.Equal(t, "com.steadybit.extension_redis.database.check-stream-backlog", desc.Id)
+	assert.Equal(t, "Stream Backlog Check", desc.Label)
+	assert.Equal(t, TargetTypeDatabase, desc.TargetSelection.TargetType)
+	assert.Equal(t, action_kit_api.Check, desc.Kind)
+	assert.Equal(t, action_kit_api.TimeControlExternal, desc.TimeControl)
+	require.NotNil(t, desc.Status)
+	assert.Equal(t, "2s", *desc.Status.CallInterval)
+	require.NotNil(t, desc.Widgets)
+
+	paramNames := make([]string, len(desc.Parameters))
+	for i, p := range desc.Parameters {
+		paramNames[i] = p.Name
+	}
+	assert.Contains(t, paramNames, "duration")
+	assert.Contains(t, paramNames, "streamKeys")
+	assert.Contains(t, paramNames, "group")
+	assert.Contains(t, paramNames, "maxBacklog")
+	assert.Contains(t, paramNames, "recoveryWindowSeconds")
+}
+
+func TestStreamBacklogCheck_Prepare(t *testing.T) {
+	// Given
+	action := &streamBacklogCheck{}
+	state := StreamBacklogCheckState{}
+	req := extutil.JsonMangle(action_kit_api.PrepareActionRequestBody{
+		Target: &action_kit_api.Target{
+			Attributes: map[string][]string{
+				AttrRedisURL:      {"redis://localhost:6379"},
+				AttrDatabaseIndex: {"2"},
+			},
+		},
+		Config: map[string]any{
+			"duration":              float64(60000),
+			"streamKeys":            " orders, payments ,,",
+			"group":                 "workers",
+			"maxBacklog":            float64(50),
+			"recoveryWindowSeconds": float64(20),
+		},
+		ExecutionId: uuid.New(),
+	})
+
+	// When
+	_, err := action.Prepare(context.Background(), &state, req)
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, 2, state.DB)
+	assert.Equal(t, []string{"orders", "payments"}, state.StreamKeys)
+	assert.Equal(t, "workers", state.Group)
+	assert.Equal(t, int64(50), state.MaxBacklog)
+	assert.Equal(t, int64(20), state.RecoveryWindowSeconds)
+	assert.NotNil(t, state.BacklogAboveSince)
+}
+
+func TestStreamBacklogCheck_Prepare_Validation(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     map[string]any
+		wantErr string
+	}{
+		{"no stream keys", map[string]any{"streamKeys": " , "}, "at least one stream key is required"},
+		{"negative backlog", map[string]any{"streamKeys": "orders", "maxBacklog": float64(-1)}, "maxBacklog must not be negative"},
+		{"negative window", map[string]any{"streamKeys": "orders", "recoveryWindowSeconds": float64(-1)}, "recoveryWindowSeconds must not be negative"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			action := &streamBacklogCheck{}
+			state := StreamBacklogCheckState{}
+			tc.cfg["duration"] = float64(60000)
+			req := extutil.JsonMangle(action_kit_api.PrepareActionRequestBody{
+				Target: &action_kit_api.Target{
+					Attributes: map[string][]string{AttrRedisURL: {"redis://localhost:6379"}},
+				},
+				Config:      tc.cfg,
+				ExecutionId: uuid.New(),
+			})
+
+			_, err := action.Prepare(context.Background(), &state, req)
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.wantErr)
+		})
+	}
+}
+
+func TestStreamBacklogCheck_Start_UnknownGroup(t *testing.T) {
+	// Given
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	client := setupStreamWithPending(t, mr, 3)
+	defer client.Close()
+
+	action := &streamBacklogCheck{}
+	state := StreamBacklogCheckState{
+		RedisURL:   fmt.Sprintf("redis://%s", mr.Addr()),
+		StreamKeys: []string{"jobs"},
+		Group:      "nobody",
+	}
+
+	// When
+	_, err = action.Start(context.Background(), &state)
+
+	// Then
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "consumer group 'nobody' not found")
+}
+
+func TestStreamBacklogCheck_Status_ReportsMetrics(t *testing.T) {
+	// Given
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	client := setupStreamWithPending(t, mr, 5)
+	defer client.Close()
+
+	action := &streamBacklogCheck{}
+	state := StreamBacklogCheckState{
+		RedisURL:              fmt.Sprintf("redis://%s", mr.Addr()),
+		StreamKeys:            []string{"jobs"},
+		MaxBacklog:            100,
+		RecoveryWindowSeconds: 30,
+		EndTime:               time.Now().Add(60 * time.Second).Unix(),
+		BacklogAboveSince:     make(map[string]int64),
+	}
+
+	// When
+	_, err = action.Start(context.Background(), &state)
+	require.NoError(t, err)
+	result, err := action.Status(context.Background(), &state)
+
+	// Then
+	require.NoError(t, err)
+	assert.False(t, result.Completed)
+	assert.Nil(t, result.Error)
+	require.NotNil(t, result.Metrics)
+
+	values := map[string]float64{}
+	for _, m := range *result.Metrics {
+		assert.Equal(t, "jobs", m.Metric["redis.stream"])
+		assert.Equal(t, "workers", m.Metric["redis.stream.group"])
+		values[*m.Name] = m.Value
+	}
+	assert.Equal(t, float64(2), values["redis_stream_pending"])
+	assert.Contains(t, values, "redis_stream_backlog")
+	assert.Contains(t, values, "redis_stream_last_delivered_age_seconds")
+	assert.Contains(t, values, "redis_stream_consumer_idle_seconds")
+}
+
+func TestStreamBacklogCheck_ClusterMode_UsesClusterClient(t *testing.T) {
+	// Given - miniredis answers CLUSTER NODES and CLUSTER SLOTS as a single-shard cluster
+	mr := miniredis.RunT(t)
+	client := setupStreamWithPending(t, mr, 5)
+	defer client.Close()
+	redisURL := fmt.Sprintf("redis://%s", mr.Addr())
+
+	origEndpoints := config.Config.Endpoints
+	defer func() { config.Config.Endpoints = origEndpoints }()
+	config.Config.Endpoints = []config.RedisEndpoint{{URL: redisURL, ClusterMode: "cluster"}}
+	defer clients.EvictClients(redisURL)
+
+	action := &streamBacklogCheck{}
+	state := StreamBacklogCheckState{}
+	ctx := context.Background()
+	_, err := action.Prepare(ctx, &state, extutil.JsonMangle(action_kit_api.PrepareActionRequestBody{
+		Target: &action_kit_api.Target{
+			Attributes: map[string][]string{AttrRedisURL: {redisURL}},
+		},
+		Config: map[string]any{
+			"duration":   float64(30000),
+			"streamKeys": "jobs",
+			"group":      "workers",
+		},
+		ExecutionId: uuid.New(),
+	}))
+	require.NoError(t, err)
+	require.True(t, state.ClusterMode)
+
+	universal, err := clients.GetUniversalClient(state.RedisURL, "", state.DB, state.ClusterMode)
+	require.NoError(t, err)
+	require.IsType(t, &redis.ClusterClient{}, universal)
+
+	// When
+	_, err = action.Start(ctx, &state)
+	require.NoError(t, err)
+	result, err := action.Status(ctx, &state)
+
+	// Then
+	require.NoError(t, err)
+	assert.Nil(t, result.Error)
+	assert.NotEmpty(t, *result.Metrics)
+}
+
+func TestStreamBacklogCheck_Status_FailsWhenRecoveryWindowExceeded(t *testing.T) {
+	// Given
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	client := setupStreamWithPending(t, mr, 5)
+	defer client.Close()
+
+	action := &streamBacklogCheck{}
+	state := StreamBacklogCheckState{
+		RedisURL:              fmt.Sprintf("redis://%s", mr.Addr()),
+		StreamKeys:            []string{"jobs"},
+		Group:                 "workers",
+		MaxBacklog:            1,
+		RecoveryWindowSeconds: 10,
+		EndTime:               time.Now().Add(-1 * time.Second).Unix(),
+		BacklogAboveSince:     map[string]int64{"jobs/workers": time.Now().Add(-20 * time.Second).Unix()},
+	}
+
+	// When
+	result, err := action.Status(context.Background(), &state)
+
+	// Then
+	require.NoError(t, err)
+	assert.True(t, result.Completed)
+	require.NotNil(t, result.Error)
+	assert.Equal(t, "Stream backlog check failed", result.Error.Title)
+	assert.Contains(t, *result.Error.Detail, "stayed above 1 for more than 10s")
+	assert.True(t, state.RecoveryExceeded)
+}
+
+func TestEvaluateStreamBacklog_RecoveryTracking(t *testing.T) {
+	// Given
+	state := &StreamBacklogCheckState{
+		MaxBacklog:            10,
+		RecoveryWindowSeconds: 5,
+		BacklogAboveSince:     make(map[string]int64),
+	}
+	start := time.Now()
+	high := streamGroupBacklog{Stream: "orders", Group: "workers", Pending: 8, Lag: 7}
+	low := streamGroupBacklog{Stream: "orders", Group: "workers", Pending: 2, Lag: -1}
+
+	// When / Then
+	assert.Equal(t, "recovering", evaluateStreamBacklog(state, high, start))
+	assert.Equal(t, "recovering", evaluateStreamBacklog(state, high, start.Add(4*time.Second)))
+	assert.Equal(t, "ok", evaluateStreamBacklog(state, low, start.Add(5*time.Second)))
+	assert.Empty(t, state.BacklogAboveSince)
+	assert.False(t, state.RecoveryExceeded)
+
+	assert.Equal(t, "recovering", evaluateStreamBacklog(state, high, start.Add(10*time.Second)))
+	assert.Equal(t, "exceeded", evaluateStreamBacklog(state, high, start.Add(16*time.Second)))
+	assert.True(t, state.RecoveryExceeded)
+	assert.Equal(t, int64(15), state.MaxObservedBacklog)
+}
+
+func TestStreamIDAge(t *testing.T) {
+	assert.Equal(t, 1500*time.Millisecond, streamIDAge("3000-0", "1500-4"))
+	assert.Equal(t, time.Duration(0), streamIDAge("3000-0", "3000-0"))
+	assert.Equal(t, time.Duration(0), streamIDAge("3000-0", "4000-0"))
+	assert.Equal(t, time.Duration(0), streamIDAge("", "1-0"))
+}
+
+func TestStreamGroupBacklog_Backlog(t *testing.T) {
+	assert.Equal(t, int64(7), streamGroupBacklog{Pending: 3, Lag: 4}.Backlog())
+	assert.Equal(t, int64(3), streamGroupBacklog{Pending: 3, Lag: -1}.Backlog())
+}
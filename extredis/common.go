@@ -5,9 +5,22 @@
 package extredis
 
 import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
 	"github.com/rs/zerolog/log"
+	"github.com/steadybit/action-kit/go/action_kit_api/v2"
 	"github.com/steadybit/discovery-kit/go/discovery_kit_api"
+	"github.com/steadybit/extension-kit/extutil"
+	"github.com/steadybit/extension-redis/clients"
 	"github.com/steadybit/extension-redis/config"
+	"github.com/steadybit/extension-redis/metrics"
+	"github.com/steadybit/extension-redis/tracing"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -16,7 +29,9 @@ const (
 	TargetTypeDatabase = "com.steadybit.extension_redis.database"
 
 	// Common attribute names
-	AttrRedisURL         = "redis.url"
+	AttrRedisURL = "redis.url"
+	// AttrRedisEndpointURL is the URL of the configured endpoint, cluster node targets carry a node URL in redis.url
+	AttrRedisEndpointURL = "redis.endpoint.url"
 	AttrRedisHost        = "redis.host"
 	AttrRedisPort        = "redis.port"
 	AttrRedisVersion     = "redis.version"
@@ -25,23 +40,61 @@ const (
 	AttrRedisMemoryMax   = "redis.memory.max_bytes"
 	AttrRedisName        = "redis.name"
 
+	// Resilience attributes, evaluated by the advice
+	AttrRedisMemoryPolicy               = "redis.memory.policy"
+	AttrRedisPersistence                = "redis.persistence"
+	AttrRedisUsage                      = "redis.usage"
+	AttrRedisConnectedReplicas          = "redis.replication.connected_replicas"
+	AttrRedisMinReplicasToWrite         = "redis.replication.min_replicas_to_write"
+	AttrRedisClusterRequireFullCoverage = "redis.cluster.require_full_coverage"
+
+	// Instance details for filtering and pre-flight checks
+	AttrRedisMaxClients    = "redis.clients.max"
+	AttrRedisUptime        = "redis.uptime_seconds"
+	AttrRedisMode          = "redis.mode"
+	AttrRedisOS            = "redis.os"
+	AttrRedisArch          = "redis.arch"
+	AttrRedisModules       = "redis.modules"
+	AttrRedisTLSEnabled    = "redis.tls.enabled"
+	AttrRedisConfigAllowed = "redis.commands.config_allowed"
+	AttrRedisDebugAllowed  = "redis.commands.debug_allowed"
+
 	AttrDatabaseIndex = "redis.database.index"
 	AttrDatabaseName  = "redis.database.name"
 
 	AttrRedisClusterNodeID = "redis.cluster.node_id"
+
+	// Cluster shard attributes, replicas carry the slots of their master
+	AttrRedisClusterSlotRanges  = "redis.cluster.slot_ranges"
+	AttrRedisClusterSlotCount   = "redis.cluster.slot_count"
+	AttrRedisClusterMasterID    = "redis.cluster.master_id"
+	AttrRedisClusterShardID     = "redis.cluster.shard_id"
+	AttrRedisClusterConfigEpoch = "redis.cluster.config_epoch"
+
+	// Sentinel attributes
+	AttrRedisSentinelMasterName = "redis.sentinel.master_name"
+	AttrRedisSentinelMasterAddr = "redis.sentinel.master_address"
+	AttrRedisSentinelCount      = "redis.sentinel.count"
 )
 
 var redisIcon = "data:image/svg+xml;base64,PHN2ZyB2aWV3Qm94PSIwIDAgMjQgMjQiIGZpbGw9Im5vbmUiIHhtbG5zPSJodHRwOi8vd3d3LnczLm9yZy8yMDAwL3N2ZyI+PHBhdGggZD0iTTIxLjk5NDQgMTMuNTIxM0MyMS45ODgxIDEzLjcxMzEgMjEuNzMzOCAxMy45MjUgMjEuMjE2MyAxNC4xOTVDMjAuMTQ4OCAxNC43NTE5IDE0LjYyMTIgMTcuMDI2OSAxMy40NDI1IDE3LjYzODhDMTIuMjY0NCAxOC4yNTM4IDExLjYxMzEgMTguMjQ3NSAxMC42ODE5IDE3LjgwMTNDOS43NTA2MyAxNy4zNTg4IDMuODY4NzUgMTQuOTc4OCAyLjgwNzUgMTQuNDc0NEMyLjI4IDE0LjIyMDYgMi4wMSAxNC4wMDg4IDIgMTMuODA2OVYxNS44MjgxQzIgMTYuMDMgMi4yOCAxNi4yNDEzIDIuODA3NSAxNi40OTU2QzMuODY4NzUgMTcuMDAzOCA5Ljc1NDM4IDE5LjM4IDEwLjY4MTkgMTkuODIyNUMxMS42MTMxIDIwLjI2ODggMTIuMjYzNyAyMC4yNzUgMTMuNDQyNSAxOS42NkMxNC42MjA2IDE5LjA0ODEgMjAuMTQ4MSAxNi43NzI1IDIxLjIxNjMgMTYuMjE2M0MyMS43NiAxNS45MzYzIDIyLjAwMDYgMTUuNzE1IDIyLjAwMDYgMTUuNTE2M0MyMi4wMDA2IDE1LjMyNzUgMjIuMDAwNiAxMy41MjM4IDIyLjAwMDYgMTMuNTIzOEMyMi4wMDA2IDEzLjUyMDYgMjEuOTk3NSAxMy41MjA2IDIxLjk5NDQgMTMuNTIwNlYxMy41MjEzWk0yMS45OTQ0IDEwLjIyNjlDMjEuOTg0NCAxMC40MTU2IDIxLjczMzggMTAuNjI3NSAyMS4yMTYzIDEwLjkwMDZDMjAuMTQ4OCAxMS40NTM4IDE0LjYyMTIgMTMuNzI5NCAxMy40NDI1IDE0LjM0MTNDMTIuMjY0NCAxNC45NTYzIDExLjYxMzEgMTQuOTUgMTAuNjgxOSAxNC41MDc1QzkuNzUwNjMgMTQuMDYxMyAzLjg2ODc1IDExLjY4NSAyLjgwNzUgMTEuMTc3NUMyLjI4IDEwLjkyNjkgMi4wMSAxMC43MTE5IDIgMTAuNTFWMTIuNTMxM0MyIDEyLjczMzEgMi4yOCAxMi45NDgxIDIuODA3NSAxMy4xOTg4QzMuODY4NzUgMTMuNzA2OSA5Ljc1MDYzIDE2LjA4MzEgMTAuNjgxOSAxNi41Mjg4QzExLjYxMzEgMTYuOTcxMyAxMi4yNjM3IDE2Ljk3ODEgMTMuNDQyNSAxNi4zNjYzQzE0LjYyMDYgMTUuNzUxMyAyMC4xNDgxIDEzLjQ3ODggMjEuMjE2MyAxMi45MjI1QzIxLjc2IDEyLjYzOTQgMjIuMDAwNiAxMi40MTgxIDIyLjAwMDYgMTIuMjE5NEMyMi4wMDA2IDEyLjAzMDYgMjIuMDAwNiAxMC4yMjY5IDIyLjAwMDYgMTAuMjI2OUMyMi4wMDA2IDEwLjIyNjkgMjEuOTk3NSAxMC4yMjY5IDIxLjk5NDQgMTAuMjI2OVpNMjEuOTk0NCA2LjgwNTYzQzIyLjAwNDQgNi42MDM3NiAyMS43NDA2IDYuNDI1MDEgMjEuMjAzMSA2LjIyOTM4QzIwLjE2NSA1Ljg0ODc2IDE0LjY2NjkgMy42NjEyNiAxMy42MTUgMy4yNzM3NkMxMi41NjM3IDIuODg5MzggMTIuMTMzOCAyLjkwNTYzIDEwLjg5NjkgMy4zNDg3NkM5LjY2IDMuNzk1MDEgMy44MSA2LjA4OTM4IDIuNzY4NzUgNi40OTYyNkMyLjI0ODEzIDYuNzAxMjYgMS45OTM3NSA2Ljg5MDAxIDIuMDAzNzUgNy4wOTE4OFY5LjExMzEzQzIuMDAzNzUgOS4zMTUwMSAyLjI4MDYzIDkuNTI2MjYgMi44MTEyNSA5Ljc4MDYzQzMuODY5MzggMTAuMjg4OCA5Ljc1NDM4IDEyLjY2NSAxMC42ODU2IDEzLjExMDZDMTEuNjEzMSAxMy41NTMxIDEyLjI2NzUgMTMuNTYgMTMuNDQ2MiAxMi45NDQ0QzE0LjYyMTIgMTIuMzMyNSAyMC4xNTE5IDEwLjA1NjkgMjEuMjIgOS41MDM3NkMyMS43NjA2IDkuMjIwNjMgMjIuMDAxMiA4Ljk5OTM4IDIyLjAwMTIgOC44MDA2M0MyMi4wMDEyIDguNjExODggMjIuMDAxMiA2LjgwNTAxIDIyLjAwMTIgNi44MDUwMUwyMS45OTQ0IDYuODA1NjNaTTkuMTYxODggOC43MjAwMUwxMy43OTc1IDguMDEwNjNMMTIuMzk3NSAxMC4wNjEzTDkuMTYxODggOC43MjAwMVpNMTkuNDEyNSA2Ljg3MDYzTDE2LjM3NTYgOC4wNzE4OEwxMy42MzUgNi45ODgxM0wxNi42Njg3IDUuNzkwMDFMMTkuNDEyNSA2Ljg3MDYzWk0xMS4zNjU2IDQuODg1MDFMMTAuOTE2MyA0LjA1ODEzTDEyLjMxNjIgNC42MDUwMUwxMy42MzQ0IDQuMTc1MDFMMTMuMjc2MiA1LjAyODEzTDE0LjYyMDYgNS41MzI1MUwxMi44ODg3IDUuNzExMjZMMTIuNDk4MSA2LjY0NTYzTDExLjg3MzEgNS42MDM3Nkw5Ljg3MTI1IDUuNDI1MDFMMTEuMzY1NiA0Ljg4NTAxWk03LjkxMTg4IDYuMDUzNzZDOS4yODI1IDYuMDUzNzYgMTAuMzg5NCA2LjQ4Mzc2IDEwLjM4OTQgNy4wMTA2M0MxMC4zODk0IDcuNTQxMjYgOS4yNzkzOCA3Ljk3MDYzIDcuOTExODggNy45NzA2M0M2LjU0NDM4IDcuOTcwNjMgNS40MzQzNyA3LjU0MDYzIDUuNDM0MzcgNy4wMTA2M0M1LjQzNDM3IDYuNDgzMTMgNi41NDQzOCA2LjA1Mzc2IDcuOTExODggNi4wNTM3NloiIGZpbGw9ImN1cnJlbnRDb2xvciIvPjwvc3ZnPg=="
 
-// FetchTargetsPerEndpoint iterates through all configured endpoints and collects targets
-func FetchTargetsPerEndpoint(handler func(endpoint *config.RedisEndpoint) ([]discovery_kit_api.Target, error)) ([]discovery_kit_api.Target, error) {
+// FetchTargetsPerEndpoint iterates through all configured endpoints and collects targets. Duration and errors are
+// recorded per endpoint, labeled with targetType, and every endpoint gets a span.
+func FetchTargetsPerEndpoint(ctx context.Context, targetType string, handler func(ctx context.Context, endpoint *config.RedisEndpoint) ([]discovery_kit_api.Target, error)) ([]discovery_kit_api.Target, error) {
 	allTargets := make([]discovery_kit_api.Target, 0)
 
-	for _, endpoint := range config.Config.Endpoints {
-		targets, err := handler(&endpoint)
+	for _, endpoint := range config.GetEndpoints() {
+		redactedURL := config.RedactURL(endpoint.URL)
+		spanCtx, span := tracing.Tracer().Start(ctx, "discover "+targetType, trace.WithAttributes(tracing.AttrRedisURL.String(redactedURL)))
+		started := time.Now()
+		targets, err := handler(spanCtx, &endpoint)
+		metrics.DiscoveryDuration.WithLabelValues(targetType, redactedURL).Observe(time.Since(started).Seconds())
+		tracing.End(span, err)
 		if err != nil {
+			metrics.DiscoveryErrors.WithLabelValues(targetType, redactedURL).Inc()
 			// Log error but continue with other endpoints
-			log.Warn().Err(err).Msgf("Error discovering targets for endpoint %s", endpoint.URL)
+			log.Warn().Err(err).Msgf("Error discovering targets for endpoint %s", redactedURL)
 			continue
 		}
 		allTargets = append(allTargets, targets...)
@@ -49,3 +102,140 @@ func FetchTargetsPerEndpoint(handler func(endpoint *config.RedisEndpoint) ([]dis
 
 	return allTargets, nil
 }
+
+// excludeAttributes removes the attributes matching one of the patterns from the targets. Patterns use path.Match
+// syntax, e.g. "redis.cluster.*".
+func excludeAttributes(targets []discovery_kit_api.Target, patterns []string) []discovery_kit_api.Target {
+	if len(patterns) == 0 {
+		return targets
+	}
+	for _, target := range targets {
+		for name := range target.Attributes {
+			for _, pattern := range patterns {
+				if matched, _ := path.Match(pattern, name); matched {
+					delete(target.Attributes, name)
+					break
+				}
+			}
+		}
+	}
+	return targets
+}
+
+// addSentinelAttributes adds the master name, the current master address and the number of sentinels of a Sentinel
+// endpoint. Host and port stay the configured ones, so the target is stable across failovers.
+func addSentinelAttributes(ctx context.Context, endpoint *config.RedisEndpoint, targets []discovery_kit_api.Target) {
+	if !endpoint.IsSentinel() {
+		return
+	}
+	masterAddr, err := clients.ResolveMasterAddr(ctx, endpoint)
+	if err != nil {
+		log.Warn().Err(err).Str("url", config.RedactURL(endpoint.URL)).Msg("Failed to resolve current Sentinel master")
+	}
+	sentinelCount, err := clients.CountSentinels(ctx, endpoint)
+	if err != nil {
+		log.Warn().Err(err).Str("url", config.RedactURL(endpoint.URL)).Msg("Failed to count sentinels")
+	}
+	for _, target := range targets {
+		target.Attributes[AttrRedisSentinelMasterName] = []string{endpoint.Sentinel.MasterName}
+		if masterAddr != "" {
+			target.Attributes[AttrRedisSentinelMasterAddr] = []string{masterAddr}
+		}
+		if sentinelCount > 0 {
+			target.Attributes[AttrRedisSentinelCount] = []string{strconv.Itoa(sentinelCount)}
+		}
+	}
+}
+
+// nodeClient returns a client for node-level changes (CONFIG SET, CLIENT PAUSE) on a non-cluster endpoint and the
+// address of that node. Sentinel endpoints follow the master, so the master is resolved once: pass the returned
+// address as pinnedAddr when reverting, and the revert reaches the modified node even after a failover.
+// The release func must be called when the client is no longer needed.
+func nodeClient(ctx context.Context, redisURL string, db int, pinnedAddr string) (*redis.Client, string, func(), error) {
+	endpoint := config.GetEndpointByURL(redisURL)
+	if endpoint == nil || !endpoint.IsSentinel() {
+		client, err := clients.GetRedisClient(redisURL, "", db)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("failed to create Redis client: %w", err)
+		}
+		return client, client.Options().Addr, func() {}, nil
+	}
+
+	addr := pinnedAddr
+	if addr == "" {
+		var err error
+		addr, err = clients.ResolveMasterAddr(ctx, endpoint)
+		if err != nil {
+			return nil, "", nil, err
+		}
+	}
+	client, err := clients.CreateDirectClient(endpoint, addr)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("failed to create Redis client for %s: %w", addr, err)
+	}
+	return client, addr, func() { _ = client.Close() }, nil
+}
+
+// partialFailureParameter lets cluster-wide attacks choose what happens when the change fails on some of the masters.
+func partialFailureParameter() action_kit_api.ActionParameter {
+	return action_kit_api.ActionParameter{
+		Name:         "partialFailurePolicy",
+		Label:        "On Partial Failure",
+		Description:  new("In cluster mode, whether a failure on some masters rolls back the others and fails the attack, or the attack continues on the masters that succeeded"),
+		Type:         action_kit_api.ActionParameterTypeString,
+		DefaultValue: new(string(clients.PartialFailureAbort)),
+		Required:     new(false),
+		Advanced:     new(true),
+		Options: new([]action_kit_api.ParameterOption{
+			action_kit_api.ExplicitParameterOption{
+				Label: "Abort and roll back",
+				Value: string(clients.PartialFailureAbort),
+			},
+			action_kit_api.ExplicitParameterOption{
+				Label: "Continue on succeeded masters",
+				Value: string(clients.PartialFailureContinue),
+			},
+		}),
+	}
+}
+
+func parsePartialFailurePolicy(value string) (clients.PartialFailurePolicy, error) {
+	switch policy := clients.PartialFailurePolicy(value); policy {
+	case "":
+		return clients.PartialFailureAbort, nil
+	case clients.PartialFailureAbort, clients.PartialFailureContinue:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unsupported partialFailurePolicy %q (expected %q or %q)", value, clients.PartialFailureAbort, clients.PartialFailureContinue)
+	}
+}
+
+// applyOnMasters runs apply on all masters of a cluster endpoint. With the abort policy a partial failure rolls back
+// the succeeded masters and fails. With the continue policy it returns a warning naming the failed masters, unless
+// no master succeeded at all. It returns the number of changed masters.
+func applyOnMasters(ctx context.Context, endpoint *config.RedisEndpoint, policy clients.PartialFailurePolicy, apply, rollback clients.NodeFunc) (int, []action_kit_api.Message, error) {
+	result, err := clients.ForEachMasterWithOptions(ctx, endpoint, apply, clients.FanOutOptions{Policy: policy, Rollback: rollback})
+	if result == nil {
+		return 0, nil, err
+	}
+	succeeded := len(result.Succeeded())
+	if err == nil {
+		return succeeded, nil, nil
+	}
+
+	if policy == clients.PartialFailureAbort {
+		if rollbackErr := result.RollbackErr(); rollbackErr != nil {
+			return 0, nil, fmt.Errorf("%w; %w: %w", err, errRollbackFailed, rollbackErr)
+		}
+		return 0, nil, fmt.Errorf("%w; rolled back %d master(s)", err, succeeded)
+	}
+	if succeeded == 0 {
+		return 0, nil, err
+	}
+	return succeeded, []action_kit_api.Message{
+		{
+			Level:   extutil.Ptr(action_kit_api.Warn),
+			Message: fmt.Sprintf("Continuing on %d/%d masters: %v", succeeded, len(result.Nodes), err),
+		},
+	}, nil
+}
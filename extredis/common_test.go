@@ -4,12 +4,27 @@
 package extredis
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
 	"testing"
 
+	"github.com/alicebob/miniredis/v2"
+	"github.com/alicebob/miniredis/v2/server"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/expfmt"
+	"github.com/redis/go-redis/v9"
 	"github.com/steadybit/discovery-kit/go/discovery_kit_api"
+	"github.com/steadybit/extension-redis/clients"
 	"github.com/steadybit/extension-redis/config"
+	"github.com/steadybit/extension-redis/metrics"
+	"github.com/steadybit/extension-redis/tracing"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func TestConstants(t *testing.T) {
@@ -33,7 +48,7 @@ func TestFetchTargetsPerEndpoint_EmptyEndpoints(t *testing.T) {
 	config.Config.Endpoints = []config.RedisEndpoint{}
 
 	// When
-	targets, err := FetchTargetsPerEndpoint(func(endpoint *config.RedisEndpoint) ([]discovery_kit_api.Target, error) {
+	targets, err := FetchTargetsPerEndpoint(context.Background(), TargetTypeInstance, func(_ context.Context, endpoint *config.RedisEndpoint) ([]discovery_kit_api.Target, error) {
 		return []discovery_kit_api.Target{{Id: "test"}}, nil
 	})
 
@@ -52,7 +67,7 @@ func TestFetchTargetsPerEndpoint_MultipleEndpoints(t *testing.T) {
 	}
 
 	// When
-	targets, err := FetchTargetsPerEndpoint(func(endpoint *config.RedisEndpoint) ([]discovery_kit_api.Target, error) {
+	targets, err := FetchTargetsPerEndpoint(context.Background(), TargetTypeInstance, func(_ context.Context, endpoint *config.RedisEndpoint) ([]discovery_kit_api.Target, error) {
 		return []discovery_kit_api.Target{
 			{Id: endpoint.Name, Label: endpoint.Name},
 		}, nil
@@ -77,7 +92,7 @@ func TestFetchTargetsPerEndpoint_ContinuesOnError(t *testing.T) {
 
 	callCount := 0
 	// When
-	targets, err := FetchTargetsPerEndpoint(func(endpoint *config.RedisEndpoint) ([]discovery_kit_api.Target, error) {
+	targets, err := FetchTargetsPerEndpoint(context.Background(), TargetTypeInstance, func(_ context.Context, endpoint *config.RedisEndpoint) ([]discovery_kit_api.Target, error) {
 		callCount++
 		if endpoint.Name == "redis2" {
 			return nil, assert.AnError
@@ -110,7 +125,7 @@ func TestFetchTargetsPerEndpoint_SequentialProcessing(t *testing.T) {
 
 	callCount := 0
 	// When
-	targets, err := FetchTargetsPerEndpoint(func(endpoint *config.RedisEndpoint) ([]discovery_kit_api.Target, error) {
+	targets, err := FetchTargetsPerEndpoint(context.Background(), TargetTypeInstance, func(_ context.Context, endpoint *config.RedisEndpoint) ([]discovery_kit_api.Target, error) {
 		callCount++
 		return []discovery_kit_api.Target{
 			{Id: endpoint.Name, Label: endpoint.Name},
@@ -128,3 +143,229 @@ func TestAttrConstants_Values(t *testing.T) {
 	assert.Equal(t, "redis.name", AttrRedisName)
 	assert.Equal(t, "redis.database.name", AttrDatabaseName)
 }
+
+// runStubSentinel starts a miniredis that reports the address in master for every SENTINEL GET-MASTER-ADDR-BY-NAME.
+func runStubSentinel(t *testing.T, master *atomic.Value) *miniredis.Miniredis {
+	t.Helper()
+	sentinel := miniredis.RunT(t)
+	require.NoError(t, sentinel.Server().Register("SENTINEL", func(c *server.Peer, cmd string, args []string) {
+		if len(args) == 2 && strings.EqualFold(args[0], "get-master-addr-by-name") {
+			host, port, _ := strings.Cut(master.Load().(string), ":")
+			c.WriteLen(2)
+			c.WriteBulk(host)
+			c.WriteBulk(port)
+			return
+		}
+		c.WriteLen(0)
+	}))
+	return sentinel
+}
+
+func TestNodeClient_SentinelPinsResolvedMaster(t *testing.T) {
+	// Given
+	oldMaster := miniredis.RunT(t)
+	oldMaster.Set("node", "old")
+	newMaster := miniredis.RunT(t)
+	newMaster.Set("node", "new")
+	var master atomic.Value
+	master.Store(oldMaster.Addr())
+	sentinel := runStubSentinel(t, &master)
+
+	origEndpoints := config.Config.Endpoints
+	defer func() { config.Config.Endpoints = origEndpoints }()
+	config.Config.Endpoints = []config.RedisEndpoint{{
+		URL:      "redis://mymaster",
+		Sentinel: &config.SentinelConfig{MasterName: "mymaster", Addresses: []string{sentinel.Addr()}},
+	}}
+	ctx := context.Background()
+
+	client, addr, release, err := nodeClient(ctx, "redis://mymaster", 0, "")
+	require.NoError(t, err)
+	assert.Equal(t, oldMaster.Addr(), addr)
+	assert.Equal(t, "old", client.Get(ctx, "node").Val())
+	release()
+
+	// When - a failover happens between Start and Stop
+	master.Store(newMaster.Addr())
+	pinned, _, releasePinned, err := nodeClient(ctx, "redis://mymaster", 0, addr)
+	require.NoError(t, err)
+	defer releasePinned()
+	current, currentAddr, releaseCurrent, err := nodeClient(ctx, "redis://mymaster", 0, "")
+	require.NoError(t, err)
+	defer releaseCurrent()
+
+	// Then
+	assert.Equal(t, "old", pinned.Get(ctx, "node").Val())
+	assert.Equal(t, newMaster.Addr(), currentAddr)
+	assert.Equal(t, "new", current.Get(ctx, "node").Val())
+}
+
+func TestNodeClient_Standalone(t *testing.T) {
+	// Given
+	mr := miniredis.RunT(t)
+	redisURL := fmt.Sprintf("redis://%s", mr.Addr())
+
+	// When
+	client, addr, release, err := nodeClient(context.Background(), redisURL, 0, "")
+
+	// Then
+	require.NoError(t, err)
+	defer release()
+	assert.Equal(t, mr.Addr(), addr)
+	assert.NoError(t, client.Ping(context.Background()).Err())
+}
+
+func TestAddSentinelAttributes(t *testing.T) {
+	// Given
+	var master atomic.Value
+	master.Store("10.0.0.7:6379")
+	sentinel := runStubSentinel(t, &master)
+	endpoint := &config.RedisEndpoint{
+		URL:      "redis://mymaster",
+		Sentinel: &config.SentinelConfig{MasterName: "mymaster", Addresses: []string{sentinel.Addr()}},
+	}
+	targets := []discovery_kit_api.Target{{Id: "mymaster:6379", Attributes: map[string][]string{}}}
+
+	// When
+	addSentinelAttributes(context.Background(), endpoint, targets)
+	addSentinelAttributes(context.Background(), &config.RedisEndpoint{URL: "redis://other"}, targets)
+
+	// Then
+	assert.Equal(t, []string{"mymaster"}, targets[0].Attributes[AttrRedisSentinelMasterName])
+	assert.Equal(t, []string{"10.0.0.7:6379"}, targets[0].Attributes[AttrRedisSentinelMasterAddr])
+	assert.Equal(t, []string{"1"}, targets[0].Attributes[AttrRedisSentinelCount])
+	assert.Equal(t, "mymaster:6379", targets[0].Id)
+}
+
+func TestExcludeAttributes(t *testing.T) {
+	// Given
+	targets := []discovery_kit_api.Target{{Attributes: map[string][]string{
+		AttrRedisURL:                {"redis://a"},
+		AttrRedisClusterSlotRanges:  {"0-100"},
+		AttrRedisClusterSlotCount:   {"101"},
+		AttrRedisSentinelMasterName: {"mymaster"},
+	}}}
+
+	// When
+	targets = excludeAttributes(targets, []string{"redis.cluster.*", AttrRedisSentinelMasterName})
+
+	// Then
+	assert.Equal(t, map[string][]string{AttrRedisURL: {"redis://a"}}, targets[0].Attributes)
+}
+
+func TestParsePartialFailurePolicy(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected clients.PartialFailurePolicy
+		wantErr  bool
+	}{
+		{value: "", expected: clients.PartialFailureAbort},
+		{value: "abort", expected: clients.PartialFailureAbort},
+		{value: "continue", expected: clients.PartialFailureContinue},
+		{value: "ignore", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			policy, err := parsePartialFailurePolicy(tt.value)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, policy)
+		})
+	}
+}
+
+func TestApplyOnMasters(t *testing.T) {
+	// Given - miniredis answers CLUSTER NODES as a single-master cluster
+	mr := miniredis.RunT(t)
+	endpoint := &config.RedisEndpoint{URL: "redis://" + mr.Addr(), ClusterMode: "cluster"}
+	failing := func(ctx context.Context, client *redis.Client, addr string) error { return errors.New("boom") }
+	var rollbacks atomic.Int32
+	rollback := func(ctx context.Context, client *redis.Client, addr string) error {
+		rollbacks.Add(1)
+		return nil
+	}
+
+	t.Run("success", func(t *testing.T) {
+		changed, warnings, err := applyOnMasters(context.Background(), endpoint, clients.PartialFailureAbort, func(ctx context.Context, client *redis.Client, addr string) error {
+			return client.Set(ctx, "marker", addr, 0).Err()
+		}, rollback)
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, changed)
+		assert.Empty(t, warnings)
+		mr.CheckGet(t, "marker", mr.Addr())
+	})
+
+	t.Run("abort", func(t *testing.T) {
+		_, _, err := applyOnMasters(context.Background(), endpoint, clients.PartialFailureAbort, failing, rollback)
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "boom")
+		assert.Contains(t, err.Error(), "rolled back 0 master(s)")
+	})
+
+	t.Run("continue without any succeeded master", func(t *testing.T) {
+		_, _, err := applyOnMasters(context.Background(), endpoint, clients.PartialFailureContinue, failing, rollback)
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "errors on 1/1 nodes")
+	})
+
+	assert.Equal(t, int32(0), rollbacks.Load())
+}
+
+func TestFetchTargetsPerEndpoint_RecordsMetrics(t *testing.T) {
+	// Given
+	origEndpoints := config.Config.Endpoints
+	defer func() { config.Config.Endpoints = origEndpoints }()
+	config.Config.Endpoints = []config.RedisEndpoint{
+		{URL: "redis://:secret@metrics-ok:6379", Name: "ok"},
+		{URL: "redis://:secret@metrics-failing:6379", Name: "failing"},
+	}
+	failures := metrics.DiscoveryErrors.WithLabelValues(TargetTypeDatabase, "redis://metrics-failing:6379")
+	failuresBefore := testutil.ToFloat64(failures)
+
+	// When
+	_, err := FetchTargetsPerEndpoint(context.Background(), TargetTypeDatabase, func(_ context.Context, endpoint *config.RedisEndpoint) ([]discovery_kit_api.Target, error) {
+		if endpoint.Name == "failing" {
+			return nil, assert.AnError
+		}
+		return nil, nil
+	})
+
+	// Then - endpoints are labeled without the password
+	require.NoError(t, err)
+	assert.Equal(t, failuresBefore+1, testutil.ToFloat64(failures))
+	assert.Equal(t, float64(0), testutil.ToFloat64(metrics.DiscoveryErrors.WithLabelValues(TargetTypeDatabase, "redis://metrics-ok:6379")))
+	durations, err := testutil.CollectAndFormat(metrics.DiscoveryDuration, expfmt.TypeTextPlain, "steadybit_redis_discovery_duration_seconds")
+	require.NoError(t, err)
+	assert.Contains(t, string(durations), `discovery_duration_seconds_count{discovery="com.steadybit.extension_redis.database",endpoint="redis://metrics-ok:6379"}`)
+	assert.NotContains(t, string(durations), "secret")
+}
+
+func TestFetchTargetsPerEndpoint_SpanPerEndpoint(t *testing.T) {
+	// Given
+	recorder := useSpanRecorder(t)
+	origEndpoints := config.Config.Endpoints
+	defer func() { config.Config.Endpoints = origEndpoints }()
+	config.Config.Endpoints = []config.RedisEndpoint{{URL: "redis://:secret@traced:6379"}}
+
+	// When
+	var handlerSpan trace.SpanContext
+	_, err := FetchTargetsPerEndpoint(context.Background(), TargetTypeInstance, func(ctx context.Context, _ *config.RedisEndpoint) ([]discovery_kit_api.Target, error) {
+		handlerSpan = trace.SpanContextFromContext(ctx)
+		return nil, assert.AnError
+	})
+
+	// Then - the handler runs in the span, Redis commands become its children
+	require.NoError(t, err)
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "discover "+TargetTypeInstance, spans[0].Name())
+	assert.Equal(t, spans[0].SpanContext(), handlerSpan)
+	assert.Contains(t, spans[0].Attributes(), tracing.AttrRedisURL.String("redis://traced:6379"))
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+}
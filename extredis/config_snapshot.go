@@ -0,0 +1,197 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package extredis
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/steadybit/extension-redis/clients"
+	"github.com/steadybit/extension-redis/config"
+)
+
+// snapshotSettings are the settings compared before and after an experiment, the ones attacks change and the ones
+// that decide how Redis behaves under load.
+var snapshotSettings = []string{
+	"maxmemory",
+	"maxmemory-policy",
+	"maxclients",
+	"timeout",
+	"client-output-buffer-limit",
+	"appendonly",
+	"save",
+	"min-replicas-to-write",
+	"min-replicas-max-lag",
+}
+
+const (
+	driftItemACL  = "acl"
+	driftItemRole = "role"
+	driftItemNode = "node"
+)
+
+// nodeSnapshot is the state of a single node.
+type nodeSnapshot struct {
+	Config map[string]string
+	// ACL holds the rules per user, nil if ACL LIST is not available
+	ACL map[string]string
+	// Role is "master" or "slave of <host>:<port>", empty if INFO replication is not available
+	Role string
+}
+
+// endpointSnapshot holds a nodeSnapshot per node of an endpoint, masters and replicas, keyed by address.
+type endpointSnapshot struct {
+	Nodes map[string]nodeSnapshot
+	// Failed holds the addresses of the nodes that could not be snapshotted
+	Failed map[string]bool
+}
+
+// drift is a difference between a snapshot and the current state of a node.
+type drift struct {
+	Node   string
+	Item   string // Setting name, driftItemACL, driftItemRole or driftItemNode
+	Detail string // What changed, without ACL rules which contain password hashes
+	Before string // Snapshot value, set back on repair
+}
+
+// Repairable reports whether the drift is a setting that CONFIG SET can set back.
+func (d drift) Repairable() bool {
+	return d.Item != driftItemACL && d.Item != driftItemRole && d.Item != driftItemNode
+}
+
+// takeSnapshot captures the settings, ACL users and replication role of every node of the endpoint. When some of the
+// nodes fail, the snapshot holds the other nodes and the error reports the failed ones. The snapshot is nil only if
+// the topology could not be fetched.
+func takeSnapshot(ctx context.Context, endpoint *config.RedisEndpoint) (*endpointSnapshot, error) {
+	snapshot := &endpointSnapshot{Nodes: make(map[string]nodeSnapshot), Failed: make(map[string]bool)}
+	var mu sync.Mutex
+	result, err := clients.ForEachNode(ctx, endpoint, func(ctx context.Context, client *redis.Client, addr string) error {
+		node, err := snapshotNode(ctx, client)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		snapshot.Nodes[addr] = node
+		return nil
+	})
+	if result == nil {
+		return nil, err
+	}
+	for _, node := range result.Failed() {
+		snapshot.Failed[node.Addr] = true
+	}
+	return snapshot, err
+}
+
+func snapshotNode(ctx context.Context, client *redis.Client) (nodeSnapshot, error) {
+	node := nodeSnapshot{Config: make(map[string]string, len(snapshotSettings))}
+	for _, setting := range snapshotSettings {
+		values, err := client.ConfigGet(ctx, setting).Result()
+		if err != nil {
+			return nodeSnapshot{}, fmt.Errorf("CONFIG GET %s: %w", setting, err)
+		}
+		// Settings unknown to the Redis version are left out
+		if value, ok := values[setting]; ok {
+			node.Config[setting] = value
+		}
+	}
+
+	// ACL and INFO may be renamed or not permitted, the settings are still compared
+	if rules, err := client.ACLList(ctx).Result(); err == nil {
+		node.ACL = make(map[string]string, len(rules))
+		for _, rule := range rules {
+			fields := strings.Fields(rule)
+			if len(fields) >= 2 && fields[0] == "user" {
+				node.ACL[fields[1]] = rule
+			}
+		}
+	}
+	if info, err := clients.GetRedisInfo(ctx, client, "replication"); err == nil {
+		node.Role = info["role"]
+		if node.Role == "slave" {
+			node.Role = fmt.Sprintf("slave of %s:%s", info["master_host"], info["master_port"])
+		}
+	}
+	return node, nil
+}
+
+// diffSnapshots returns the drift from before to after. A node that left the endpoint, e.g. after a failover, is
+// reported as drift. Nodes whose snapshot failed are not compared, they are reported by the failed snapshot instead.
+func diffSnapshots(before, after *endpointSnapshot) []drift {
+	var drifts []drift
+	for _, addr := range slices.Sorted(maps.Keys(before.Nodes)) {
+		old := before.Nodes[addr]
+		current, ok := after.Nodes[addr]
+		if !ok {
+			if !after.Failed[addr] {
+				drifts = append(drifts, drift{Node: addr, Item: driftItemNode, Detail: "node is no longer part of the endpoint"})
+			}
+			continue
+		}
+		for _, setting := range slices.Sorted(maps.Keys(old.Config)) {
+			if value, ok := current.Config[setting]; ok && value != old.Config[setting] {
+				drifts = append(drifts, drift{
+					Node:   addr,
+					Item:   setting,
+					Detail: fmt.Sprintf("%s changed from %q to %q", setting, old.Config[setting], value),
+					Before: old.Config[setting],
+				})
+			}
+		}
+		if old.ACL != nil && current.ACL != nil {
+			users := make(map[string]bool, len(old.ACL))
+			for name := range old.ACL {
+				users[name] = true
+			}
+			for name := range current.ACL {
+				users[name] = true
+			}
+			for _, user := range slices.Sorted(maps.Keys(users)) {
+				oldRule, existed := old.ACL[user]
+				rule, exists := current.ACL[user]
+				var detail string
+				switch {
+				case !existed:
+					detail = fmt.Sprintf("ACL user %s was added", user)
+				case !exists:
+					detail = fmt.Sprintf("ACL user %s was removed", user)
+				case oldRule != rule:
+					detail = fmt.Sprintf("ACL rules of user %s changed", user)
+				default:
+					continue
+				}
+				drifts = append(drifts, drift{Node: addr, Item: driftItemACL, Detail: detail})
+			}
+		}
+		if old.Role != "" && current.Role != "" && old.Role != current.Role {
+			drifts = append(drifts, drift{
+				Node:   addr,
+				Item:   driftItemRole,
+				Detail: fmt.Sprintf("replication role changed from %q to %q", old.Role, current.Role),
+				Before: old.Role,
+			})
+		}
+	}
+	return drifts
+}
+
+// repairDrift sets drifted settings of a node back to their snapshot value. ACL and role drift is only reported.
+func repairDrift(ctx context.Context, client *redis.Client, drifts []drift) error {
+	for _, d := range drifts {
+		if !d.Repairable() {
+			continue
+		}
+		if err := client.ConfigSet(ctx, d.Item, d.Before).Err(); err != nil {
+			return fmt.Errorf("CONFIG SET %s: %w", d.Item, err)
+		}
+	}
+	return nil
+}
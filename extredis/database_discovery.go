@@ -7,7 +7,6 @@ package extredis
 import (
 	"context"
 	"fmt"
-	"net/url"
 	"regexp"
 	"strconv"
 	"time"
@@ -32,6 +31,7 @@ func NewRedisDatabaseDiscovery(ctx context.Context) discovery_kit_sdk.TargetDisc
 	return discovery_kit_sdk.NewCachedTargetDiscovery(discovery,
 		discovery_kit_sdk.WithRefreshTargetsNow(),
 		discovery_kit_sdk.WithRefreshTargetsInterval(ctx, time.Duration(config.Config.DiscoveryIntervalDatabaseSeconds)*time.Second),
+		discovery_kit_sdk.WithRefreshTargetsTrigger(ctx, config.SubscribeEndpointsChanged(), 5*time.Second),
 	)
 }
 
@@ -78,21 +78,16 @@ func (d *redisDatabaseDiscovery) DescribeAttributes() []discovery_kit_api.Attrib
 }
 
 func (d *redisDatabaseDiscovery) DiscoverTargets(ctx context.Context) ([]discovery_kit_api.Target, error) {
-	return FetchTargetsPerEndpoint(func(endpoint *config.RedisEndpoint) ([]discovery_kit_api.Target, error) {
+	targets, err := FetchTargetsPerEndpoint(ctx, TargetTypeDatabase, func(ctx context.Context, endpoint *config.RedisEndpoint) ([]discovery_kit_api.Target, error) {
 		return discoverDatabases(ctx, endpoint)
 	})
+	return excludeAttributes(targets, config.Config.DiscoveryAttributesExcludesDatabases), err
 }
 
 func discoverDatabases(ctx context.Context, endpoint *config.RedisEndpoint) ([]discovery_kit_api.Target, error) {
-	parsedURL, err := url.Parse(endpoint.URL)
+	host, port, err := clients.EndpointHostPort(endpoint.URL)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse Redis URL: %w", err)
-	}
-
-	host := parsedURL.Hostname()
-	port := parsedURL.Port()
-	if port == "" {
-		port = "6379"
+		return nil, err
 	}
 
 	client, err := clients.CreateRedisClient(endpoint)
@@ -107,14 +102,14 @@ func discoverDatabases(ctx context.Context, endpoint *config.RedisEndpoint) ([]d
 
 	instanceName := endpoint.Name
 	if instanceName == "" {
-		instanceName = fmt.Sprintf("%s:%s", host, port)
+		instanceName = clients.JoinHostPort(host, port)
 	}
 
 	// Cluster mode: only db0 is supported
 	serverInfo, err := clients.GetRedisInfo(ctx, client, "server")
 	if err == nil && serverInfo["redis_mode"] == "cluster" {
 		return []discovery_kit_api.Target{
-			buildDatabaseTarget(host, port, instanceName, endpoint.URL, "0", "db0"),
+			buildDatabaseTarget(host, port, instanceName, config.RedactURL(endpoint.URL), "0", "db0"),
 		}, nil
 	}
 
@@ -138,30 +133,36 @@ func discoverDatabases(ctx context.Context, endpoint *config.RedisEndpoint) ([]d
 		dbIndexInt, _ := strconv.Atoi(dbIndex)
 		dbName := fmt.Sprintf("db%s", dbIndex)
 
-		target := buildDatabaseTarget(host, port, instanceName, endpoint.URL, dbIndex, dbName)
-		target.Id = fmt.Sprintf("%s:%s/db%d", host, port, dbIndexInt)
+		target := buildDatabaseTarget(host, port, instanceName, config.RedactURL(endpoint.URL), dbIndex, dbName)
+		target.Id = fmt.Sprintf("%s/db%d", clients.JoinHostPort(host, port), dbIndexInt)
 		targets = append(targets, target)
 	}
 
 	if len(targets) == 0 {
-		targets = append(targets, buildDatabaseTarget(host, port, instanceName, endpoint.URL, "0", "db0"))
+		targets = append(targets, buildDatabaseTarget(host, port, instanceName, config.RedactURL(endpoint.URL), "0", "db0"))
 	}
 
+	addSentinelAttributes(ctx, endpoint, targets)
 	return targets, nil
 }
 
 func buildDatabaseTarget(host, port, instanceName, redisURL, dbIndex, dbName string) discovery_kit_api.Target {
+	attributes := map[string][]string{
+		AttrRedisURL:      {redisURL},
+		AttrRedisHost:     {host},
+		AttrRedisName:     {instanceName},
+		AttrDatabaseIndex: {dbIndex},
+		AttrDatabaseName:  {dbName},
+	}
+	// Unix sockets have no port
+	if port != "" {
+		attributes[AttrRedisPort] = []string{port}
+	}
+
 	return discovery_kit_api.Target{
-		Id:         fmt.Sprintf("%s:%s/%s", host, port, dbName),
+		Id:         fmt.Sprintf("%s/%s", clients.JoinHostPort(host, port), dbName),
 		TargetType: TargetTypeDatabase,
 		Label:      fmt.Sprintf("%s/%s", instanceName, dbName),
-		Attributes: map[string][]string{
-			AttrRedisURL:      {redisURL},
-			AttrRedisHost:     {host},
-			AttrRedisPort:     {port},
-			AttrRedisName:     {instanceName},
-			AttrDatabaseIndex: {dbIndex},
-			AttrDatabaseName:  {dbName},
-		},
+		Attributes: attributes,
 	}
 }
@@ -246,3 +246,28 @@ func TestDatabaseDiscovery_DiscoverTargets_ViaEndpoints(t *testing.T) {
 	require.NoError(t, err)
 	require.NotEmpty(t, targets)
 }
+
+func TestDiscoverDatabases_UnixSocket(t *testing.T) {
+	// Given
+	mr := miniredis.RunT(t)
+	path := serveUnixSocket(t, mr.Addr())
+	endpoint := &config.RedisEndpoint{URL: "unix://" + path}
+
+	// When
+	targets, err := discoverDatabases(context.Background(), endpoint)
+
+	// Then
+	require.NoError(t, err)
+	require.Len(t, targets, 1)
+	assert.Equal(t, path+"/db0", targets[0].Id)
+	assert.Equal(t, path+"/db0", targets[0].Label)
+	assert.NotContains(t, targets[0].Attributes, AttrRedisPort)
+}
+
+func TestBuildDatabaseTarget_IPv6(t *testing.T) {
+	target := buildDatabaseTarget("2001:db8::1", "6379", "[2001:db8::1]:6379", "redis://[2001:db8::1]:6379", "0", "db0")
+
+	assert.Equal(t, "[2001:db8::1]:6379/db0", target.Id)
+	assert.Equal(t, []string{"2001:db8::1"}, target.Attributes[AttrRedisHost])
+	assert.Equal(t, []string{"6379"}, target.Attributes[AttrRedisPort])
+}
@@ -0,0 +1,138 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package extredis
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/steadybit/action-kit/go/action_kit_api/v2"
+	"github.com/steadybit/extension-kit/extutil"
+	"github.com/steadybit/extension-redis/clients"
+	"github.com/steadybit/extension-redis/config"
+)
+
+// dryRunListLimit is the number of keys, channels or slots a dry run lists before it only reports how many more there are.
+const dryRunListLimit = 20
+
+// dryRunParameter lets mutating attacks report what they would change instead of changing it.
+func dryRunParameter() action_kit_api.ActionParameter {
+	return action_kit_api.ActionParameter{
+		Name:         "dryRun",
+		Label:        "Dry Run",
+		Description:  new("Only report what the attack would change, e.g. the affected keys, nodes and configuration values, without changing anything"),
+		Type:         action_kit_api.ActionParameterTypeBoolean,
+		DefaultValue: new("false"),
+		Required:     new(false),
+		Advanced:     new(true),
+	}
+}
+
+// dryRunRequested reports whether the attack only reports what it would change, requested by the dryRun parameter
+// or forced by the guardrails of the endpoint.
+func dryRunRequested(request action_kit_api.PrepareActionRequestBody) bool {
+	return extutil.ToBool(request.Config["dryRun"]) || targetEndpoint(request.Target.Attributes).GetGuardrails().IsDryRun()
+}
+
+// dryRunPlan collects what a dry run found the attack would do.
+type dryRunPlan struct {
+	messages []action_kit_api.Message
+}
+
+func (p *dryRunPlan) info(format string, args ...any) {
+	p.add(action_kit_api.Info, format, args...)
+}
+
+// warn reports consequences that cannot be undone or that would make the attack fail.
+func (p *dryRunPlan) warn(format string, args ...any) {
+	p.add(action_kit_api.Warn, format, args...)
+}
+
+func (p *dryRunPlan) add(level action_kit_api.MessageLevel, format string, args ...any) {
+	p.messages = append(p.messages, action_kit_api.Message{
+		Level:   extutil.Ptr(level),
+		Message: "Dry run: " + fmt.Sprintf(format, args...),
+	})
+}
+
+func (p *dryRunPlan) startResult() *action_kit_api.StartResult {
+	messages := append(p.messages, action_kit_api.Message{
+		Level:   extutil.Ptr(action_kit_api.Info),
+		Message: "Dry run: nothing was changed",
+	})
+	return &action_kit_api.StartResult{Messages: new(messages)}
+}
+
+// dryRunStatusResult completes a dry run right away, there is nothing to wait for.
+func dryRunStatusResult() *action_kit_api.StatusResult {
+	return &action_kit_api.StatusResult{
+		Completed: true,
+		Messages: new([]action_kit_api.Message{{
+			Level:   extutil.Ptr(action_kit_api.Info),
+			Message: "Dry run completed, nothing was changed",
+		}}),
+	}
+}
+
+func dryRunStopResult() *action_kit_api.StopResult {
+	return &action_kit_api.StopResult{
+		Messages: new([]action_kit_api.Message{{
+			Level:   extutil.Ptr(action_kit_api.Info),
+			Message: "Dry run: nothing to roll back",
+		}}),
+	}
+}
+
+// dryRunOnNodes runs the read-only describe on every node a node-level attack would change: all masters in cluster
+// mode, otherwise the node of the endpoint (the current master for Sentinel endpoints). It returns one line per node,
+// sorted by address.
+func dryRunOnNodes(ctx context.Context, redisURL string, db int, clusterMode bool, describe func(ctx context.Context, client *redis.Client, addr string) (string, error)) ([]string, error) {
+	endpoint := config.GetEndpointByURL(redisURL)
+	if clusterMode && endpoint != nil {
+		var mu sync.Mutex
+		var lines []string
+		result, err := clients.ForEachMaster(ctx, endpoint, func(ctx context.Context, client *redis.Client, addr string) error {
+			line, err := describe(ctx, client, addr)
+			if err != nil {
+				return err
+			}
+			mu.Lock()
+			lines = append(lines, line)
+			mu.Unlock()
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := result.Err(); err != nil {
+			return nil, err
+		}
+		slices.Sort(lines)
+		return lines, nil
+	}
+
+	client, addr, release, err := nodeClient(ctx, redisURL, db, "")
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	line, err := describe(ctx, client, addr)
+	if err != nil {
+		return nil, err
+	}
+	return []string{line}, nil
+}
+
+// formatList joins the first dryRunListLimit items and appends how many more there are.
+func formatList(items []string) string {
+	if len(items) <= dryRunListLimit {
+		return strings.Join(items, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(items[:dryRunListLimit], ", "), len(items)-dryRunListLimit)
+}
@@ -0,0 +1,345 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2024 Steadybit GmbH
+
+package extredis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/steadybit/action-kit/go/action_kit_api/v2"
+	"github.com/steadybit/extension-redis/config"
+	"github.com/steadybit/extension-redis/journal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func messageTexts(messages *[]action_kit_api.Message) string {
+	var texts []string
+	for _, m := range *messages {
+		texts = append(texts, m.Message)
+	}
+	return strings.Join(texts, "\n")
+}
+
+func hasDryRunParameter(desc action_kit_api.ActionDescription) bool {
+	for _, p := range desc.Parameters {
+		if p.Name == "dryRun" {
+			return true
+		}
+	}
+	return false
+}
+
+func TestDryRun_Parameter(t *testing.T) {
+	mutating := []action_kit_api.ActionDescription{
+		(&cacheExpirationAttack{}).Describe(),
+		(&clientPauseAttack{}).Describe(),
+		(&maxmemoryLimitAttack{}).Describe(),
+		(&outputBufferLimitAttack{}).Describe(),
+		(&pubSubDisruptionAttack{}).Describe(),
+		(&slotMigrationAttack{}).Describe(),
+		(&streamConsumerGroupAttack{}).Describe(),
+		(&connectionExhaustionAttack{}).Describe(),
+		(&sentinelStopAttack{}).Describe(),
+		(&clusterNodeStopAttack{}).Describe(),
+	}
+	for _, desc := range mutating {
+		assert.True(t, hasDryRunParameter(desc), desc.Id)
+	}
+}
+
+func TestDryRun_CacheExpiration(t *testing.T) {
+	// Given
+	orig := config.Config.JournalDir
+	defer func() { config.Config.JournalDir = orig }()
+	config.Config.JournalDir = t.TempDir()
+
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	mr.Set("cache:1", "hello")
+	mr.Set("cache:2", "world!")
+
+	url := fmt.Sprintf("redis://%s", mr.Addr())
+	useGuardedEndpoint(t, url, config.Guardrails{ReadOnly: true})
+	action := &cacheExpirationAttack{}
+	state := CacheExpirationState{}
+
+	// When
+	_, err = action.Prepare(context.Background(), &state, guardrailRequest(url, map[string]any{
+		"duration": float64(60000), "pattern": "cache:*", "ttl": float64(1), "restoreOnStop": true, "dryRun": true,
+	}))
+	require.NoError(t, err)
+	startResult, err := action.Start(context.Background(), &state)
+	require.NoError(t, err)
+
+	// Then - nothing changed, the plan lists keys and backup size
+	assert.True(t, state.DryRun)
+	assert.Zero(t, mr.TTL("cache:1"))
+	assert.Zero(t, mr.TTL("cache:2"))
+	assert.Empty(t, state.AffectedKeys)
+	text := messageTexts(startResult.Messages)
+	assert.Contains(t, text, "Dry run: would set a TTL of 1 seconds on 2 string keys")
+	assert.Contains(t, text, "cache:1")
+	assert.Contains(t, text, "would back up 11 bytes")
+	assert.Contains(t, text, "nothing was changed")
+	entries, err := journal.Pending()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	// When
+	status, err := action.Status(context.Background(), &state)
+	require.NoError(t, err)
+	stopResult, err := action.Stop(context.Background(), &state)
+	require.NoError(t, err)
+
+	// Then
+	assert.True(t, status.Completed)
+	assert.Contains(t, messageTexts(stopResult.Messages), "nothing to roll back")
+	assert.Zero(t, mr.TTL("cache:1"))
+}
+
+func TestDryRun_CacheExpirationBackupLimit(t *testing.T) {
+	// Given
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	mr.Set("big", strings.Repeat("x", 2048))
+
+	action := &cacheExpirationAttack{}
+	state := CacheExpirationState{
+		RedisURL:       fmt.Sprintf("redis://%s", mr.Addr()),
+		DryRun:         true,
+		Pattern:        "big",
+		TTLSeconds:     1,
+		MatchedKeys:    []string{"big"},
+		RestoreOnStop:  true,
+		EndTime:        time.Now().Add(time.Minute).Unix(),
+		MaxBackupBytes: 1024,
+	}
+
+	// When
+	result, err := action.Start(context.Background(), &state)
+
+	// Then
+	require.NoError(t, err)
+	var warned bool
+	for _, m := range *result.Messages {
+		if *m.Level == action_kit_api.Warn && strings.Contains(m.Message, "would exceed the limit") {
+			warned = true
+		}
+	}
+	assert.True(t, warned)
+	assert.Zero(t, mr.TTL("big"))
+}
+
+func TestDryRun_ForcedByGuardrails(t *testing.T) {
+	// Given
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	mr.Set("cache:1", "v")
+
+	url := fmt.Sprintf("redis://%s", mr.Addr())
+	useGuardedEndpoint(t, url, config.Guardrails{DryRun: true})
+	state := CacheExpirationState{}
+
+	// When
+	_, err = (&cacheExpirationAttack{}).Prepare(context.Background(), &state, guardrailRequest(url, map[string]any{
+		"duration": float64(60000), "pattern": "cache:*", "ttl": float64(1), "dryRun": false,
+	}))
+
+	// Then
+	require.NoError(t, err)
+	assert.True(t, state.DryRun)
+}
+
+func TestDryRun_StreamConsumerGroup(t *testing.T) {
+	// Given
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	ctx := context.Background()
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	for i := range 5 {
+		require.NoError(t, client.XAdd(ctx, &redis.XAddArgs{Stream: "orders", Values: map[string]any{"n": i}}).Err())
+	}
+	require.NoError(t, client.XGroupCreate(ctx, "orders", "workers", "0").Err())
+	require.NoError(t, client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group: "workers", Consumer: "worker-1", Streams: []string{"orders", ">"}, Count: 2,
+	}).Err())
+
+	action := &streamConsumerGroupAttack{}
+	base := StreamConsumerGroupState{
+		RedisURL:        fmt.Sprintf("redis://%s", mr.Addr()),
+		DryRun:          true,
+		StreamKey:       "orders",
+		Group:           "workers",
+		Consumer:        "worker-1",
+		PhantomConsumer: "steadybit-phantom-test",
+		MaxLen:          1,
+		EndTime:         time.Now().Add(time.Minute).Unix(),
+	}
+
+	tests := []struct {
+		mode string
+		want string
+	}{
+		{streamModeStallGroup, "would claim 2 pending entries of group 'workers'"},
+		{streamModeDeleteConsumer, "would delete consumer 'worker-1' from group 'workers' with 2 pending entries"},
+		{streamModeTrim, "would trim stream 'orders' from 5 to 1 entries, removing 4 entries permanently"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			state := base
+			state.Mode = tt.mode
+
+			// When
+			result, err := action.Start(context.Background(), &state)
+
+			// Then
+			require.NoError(t, err)
+			assert.Contains(t, messageTexts(result.Messages), tt.want)
+			assert.Equal(t, int64(5), client.XLen(ctx, "orders").Val())
+		})
+	}
+}
+
+func TestDryRun_PubSubFlood(t *testing.T) {
+	// Given
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	sub := mr.NewSubscriber()
+	defer sub.Close()
+	sub.Subscribe("events")
+
+	action := &pubSubDisruptionAttack{}
+	state := PubSubDisruptionState{
+		RedisURL:          fmt.Sprintf("redis://%s", mr.Addr()),
+		DryRun:            true,
+		Mode:              pubSubModeFlood,
+		Channel:           "events",
+		MessagesPerSecond: 100,
+		MessageSizeBytes:  10,
+		EndTime:           time.Now().Add(time.Minute).Unix(),
+	}
+
+	// When
+	result, err := action.Start(context.Background(), &state)
+
+	// Then
+	require.NoError(t, err)
+	assert.Contains(t, messageTexts(result.Messages), "would flood 1 channel(s) with 1 subscriber(s) with 100 messages/s of 10 bytes: events")
+	activePubSubDisruptionsMutex.Lock()
+	_, running := activePubSubDisruptions[state.ExecutionID]
+	activePubSubDisruptionsMutex.Unlock()
+	assert.False(t, running)
+}
+
+func TestDryRun_SlotMigration(t *testing.T) {
+	// Given
+	state := SlotMigrationState{
+		DryRun:       true,
+		Mode:         slotMigrationHalf,
+		SourceID:     "src",
+		SourceAddr:   "10.0.0.1:6379",
+		DestID:       "dst",
+		DestAddr:     "10.0.0.2:6379",
+		Slots:        []MigratedSlot{{Slot: 100}, {Slot: 101}},
+		PlannedKeys:  12,
+		PlannedBytes: 3400,
+	}
+
+	// When
+	result, err := (&slotMigrationAttack{}).Start(context.Background(), &state)
+
+	// Then
+	require.NoError(t, err)
+	text := messageTexts(result.Messages)
+	assert.Contains(t, text, "would migrate 2 slot(s) from 10.0.0.1:6379 (src) to 10.0.0.2:6379 (dst) in half mode: 100, 101")
+	assert.Contains(t, text, "would move 12 keys (3400 bytes)")
+	assert.Contains(t, text, "MIGRATING")
+}
+
+func TestDryRun_SentinelStopForcedByGuardrails(t *testing.T) {
+	// Given - DEBUG SLEEP is refused by miniredis, a real stop would fail
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	url := fmt.Sprintf("redis://%s", mr.Addr())
+	useGuardedEndpoint(t, url, config.Guardrails{DryRun: true, ReadOnly: true})
+	action := &sentinelStopAttack{}
+	state := SentinelStopState{}
+
+	// When
+	_, err = action.Prepare(context.Background(), &state, guardrailRequest(url, map[string]any{"duration": float64(30000)}))
+	require.NoError(t, err)
+	startResult, err := action.Start(context.Background(), &state)
+	require.NoError(t, err)
+	status, err := action.Status(context.Background(), &state)
+	require.NoError(t, err)
+
+	// Then
+	assert.True(t, state.DryRun)
+	text := messageTexts(startResult.Messages)
+	assert.Contains(t, text, fmt.Sprintf("Dry run: would stop %s via DEBUG SLEEP for", mr.Addr()))
+	assert.Contains(t, text, "nothing was changed")
+	assert.True(t, status.Completed)
+}
+
+func TestDryRun_ConnectionExhaustionForcedByGuardrails(t *testing.T) {
+	// Given
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	url := fmt.Sprintf("redis://%s", mr.Addr())
+	useGuardedEndpoint(t, url, config.Guardrails{DryRun: true})
+	action := &connectionExhaustionAttack{}
+	state := ConnectionExhaustionState{}
+
+	// When
+	_, err = action.Prepare(context.Background(), &state, guardrailRequest(url, map[string]any{
+		"duration": float64(30000), "numConnections": float64(50),
+	}))
+	require.NoError(t, err)
+	connectedBefore := mr.CurrentConnectionCount()
+	startResult, err := action.Start(context.Background(), &state)
+	require.NoError(t, err)
+	stopResult, err := action.Stop(context.Background(), &state)
+	require.NoError(t, err)
+
+	// Then - no connections are held
+	assert.True(t, state.DryRun)
+	assert.Zero(t, state.ConnectionCount)
+	assert.LessOrEqual(t, mr.CurrentConnectionCount(), connectedBefore+1)
+	assert.Contains(t, messageTexts(startResult.Messages), fmt.Sprintf("Dry run: would open 50 connections to %s", url))
+	assert.Contains(t, messageTexts(stopResult.Messages), "nothing to roll back")
+}
+
+func TestFormatList(t *testing.T) {
+	items := make([]string, dryRunListLimit+3)
+	for i := range items {
+		items[i] = fmt.Sprint(i)
+	}
+
+	assert.Equal(t, "a, b", formatList([]string{"a", "b"}))
+	assert.True(t, strings.HasSuffix(formatList(items), "19 and 3 more"))
+}
+
+func TestEvictionPolicyWarning(t *testing.T) {
+	_, ok := evictionPolicyWarning("noeviction")
+	assert.False(t, ok)
+	warning, ok := evictionPolicyWarning("allkeys-lru")
+	assert.True(t, ok)
+	assert.Contains(t, warning, "PERMANENTLY DELETE")
+	_, ok = evictionPolicyWarning("volatile-ttl")
+	assert.True(t, ok)
+}
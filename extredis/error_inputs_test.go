@@ -13,6 +13,8 @@ import (
 	"github.com/google/uuid"
 	"github.com/steadybit/action-kit/go/action_kit_api/v2"
 	"github.com/steadybit/extension-kit/extutil"
+	"github.com/steadybit/extension-redis/clients"
+	"github.com/steadybit/extension-redis/config"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -100,6 +102,18 @@ func TestMaxmemoryLimitAttack_Start_MalformedURL(t *testing.T) {
 // miniredis supports RequireAuth to simulate this.
 // ============================================================
 
+// useWrongPasswordEndpoint configures an endpoint for mr with a wrong password and returns its URL, which holds no
+// credentials like the URL in the action state.
+func useWrongPasswordEndpoint(t *testing.T, mr *miniredis.Miniredis) string {
+	t.Helper()
+	redisURL := fmt.Sprintf("redis://%s", mr.Addr())
+	orig := config.GetEndpoints()
+	t.Cleanup(func() { config.SetEndpoints(orig) })
+	config.SetEndpoints([]config.RedisEndpoint{{URL: redisURL, Password: "wrong-password", ClusterMode: "standalone"}})
+	t.Cleanup(func() { clients.EvictClients(redisURL) })
+	return redisURL
+}
+
 func TestCacheExpirationAttack_Start_WrongPassword(t *testing.T) {
 	mr, err := miniredis.Run()
 	require.NoError(t, err)
@@ -110,8 +124,7 @@ func TestCacheExpirationAttack_Start_WrongPassword(t *testing.T) {
 
 	action := &cacheExpirationAttack{}
 	state := CacheExpirationState{
-		RedisURL:       fmt.Sprintf("redis://%s", mr.Addr()),
-		Password:       "wrong-password",
+		RedisURL:       useWrongPasswordEndpoint(t, mr),
 		DB:             0,
 		Pattern:        "test:*",
 		TTLSeconds:     60,
@@ -125,6 +138,7 @@ func TestCacheExpirationAttack_Start_WrongPassword(t *testing.T) {
 
 	_, err = action.Start(context.Background(), &state)
 	require.Error(t, err, "Should fail with wrong password")
+	assert.Contains(t, err.Error(), "WRONGPASS")
 }
 
 func TestClientPauseAttack_Start_WrongPassword(t *testing.T) {
@@ -136,8 +150,7 @@ func TestClientPauseAttack_Start_WrongPassword(t *testing.T) {
 
 	action := &clientPauseAttack{}
 	state := ClientPauseState{
-		RedisURL:  fmt.Sprintf("redis://%s", mr.Addr()),
-		Password:  "wrong-password",
+		RedisURL:  useWrongPasswordEndpoint(t, mr),
 		DB:        0,
 		PauseMode: "ALL",
 		EndTime:   time.Now().Add(5 * time.Second).Unix(),
@@ -145,6 +158,7 @@ func TestClientPauseAttack_Start_WrongPassword(t *testing.T) {
 
 	_, err = action.Start(context.Background(), &state)
 	require.Error(t, err, "Should fail with wrong password")
+	assert.Contains(t, err.Error(), "WRONGPASS")
 }
 
 func TestMaxmemoryLimitAttack_Start_WrongPassword(t *testing.T) {
@@ -156,8 +170,7 @@ func TestMaxmemoryLimitAttack_Start_WrongPassword(t *testing.T) {
 
 	action := &maxmemoryLimitAttack{}
 	state := MaxmemoryLimitState{
-		RedisURL:     fmt.Sprintf("redis://%s", mr.Addr()),
-		Password:     "wrong-password",
+		RedisURL:     useWrongPasswordEndpoint(t, mr),
 		DB:           0,
 		NewMaxmemory: "100mb",
 		NewPolicy:    "noeviction",
@@ -166,6 +179,7 @@ func TestMaxmemoryLimitAttack_Start_WrongPassword(t *testing.T) {
 
 	_, err = action.Start(context.Background(), &state)
 	require.Error(t, err, "Should fail with wrong password")
+	assert.Contains(t, err.Error(), "WRONGPASS")
 }
 
 // ============================================================
@@ -305,8 +319,7 @@ func TestLatencyCheck_Start_WrongPassword(t *testing.T) {
 
 	action := &latencyCheck{}
 	state := LatencyCheckState{
-		RedisURL:     fmt.Sprintf("redis://%s", mr.Addr()),
-		Password:     "wrong-password",
+		RedisURL:     useWrongPasswordEndpoint(t, mr),
 		MaxLatencyMs: 1000,
 		EndTime:      time.Now().Add(5 * time.Second).Unix(),
 	}
@@ -336,8 +349,7 @@ func TestMemoryCheck_Start_WrongPassword(t *testing.T) {
 
 	action := &memoryCheck{}
 	state := MemoryCheckState{
-		RedisURL:         fmt.Sprintf("redis://%s", mr.Addr()),
-		Password:         "wrong-password",
+		RedisURL:         useWrongPasswordEndpoint(t, mr),
 		MaxMemoryPercent: 90,
 		EndTime:          time.Now().Add(5 * time.Second).Unix(),
 	}
@@ -368,8 +380,7 @@ func TestConnectionCountCheck_Start_WrongPassword(t *testing.T) {
 
 	action := &connectionCountCheck{}
 	state := ConnectionCountCheckState{
-		RedisURL:          fmt.Sprintf("redis://%s", mr.Addr()),
-		Password:          "wrong-password",
+		RedisURL:          useWrongPasswordEndpoint(t, mr),
 		MaxConnectionsPct: 90,
 		MaxConnections:    10000,
 		EndTime:           time.Now().Add(5 * time.Second).Unix(),
@@ -400,8 +411,7 @@ func TestReplicationLagCheck_Start_WrongPassword(t *testing.T) {
 
 	action := &replicationLagCheck{}
 	state := ReplicationLagCheckState{
-		RedisURL:      fmt.Sprintf("redis://%s", mr.Addr()),
-		Password:      "wrong-password",
+		RedisURL:      useWrongPasswordEndpoint(t, mr),
 		MaxLagSeconds: 60,
 		EndTime:       time.Now().Add(5 * time.Second).Unix(),
 	}
@@ -0,0 +1,325 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package extredis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+	"github.com/steadybit/event-kit/go/event_kit_api"
+	extension_kit "github.com/steadybit/extension-kit"
+	"github.com/steadybit/extension-kit/exthttp"
+	"github.com/steadybit/extension-redis/clients"
+	"github.com/steadybit/extension-redis/config"
+	"github.com/steadybit/extension-redis/metrics"
+)
+
+const (
+	eventPathExperimentStarted = "/events/experiment-started"
+	eventPathTargetStarted     = "/events/experiment-target-started"
+	eventPathExperimentEnded   = "/events/experiment-ended"
+
+	// Snapshots of executions whose end event never arrived are dropped after this time
+	maxSnapshotAge = 24 * time.Hour
+	// The endpoints are snapshotted in the background, endpoints that do not answer in time are not checked for drift
+	snapshotTimeout = 30 * time.Second
+)
+
+// experimentSnapshot holds the snapshots taken when an experiment started and the endpoints it targeted since.
+// All fields are guarded by experimentSnapshotsMutex while the snapshot is registered.
+type experimentSnapshot struct {
+	takenAt time.Time
+	// endpoints is keyed by the redacted endpoint URL, endpoints that could not be snapshotted are missing.
+	// It is set once all endpoints were snapshotted, complete reports whether that happened.
+	endpoints map[string]*endpointSnapshot
+	complete  bool
+	// ended is set when the snapshot was taken out for the drift check, a later result of the snapshotting is dropped
+	ended bool
+	// targetURLs are the URLs of the started Redis targets, they are matched with the endpoints once complete
+	targetURLs []string
+	targeted   map[string]bool
+}
+
+var (
+	experimentSnapshotsMutex sync.Mutex
+	experimentSnapshots      = make(map[float32]*experimentSnapshot)
+)
+
+// GetEventListenerList returns the experiment event listeners that snapshot the endpoints and check them for drift.
+func GetEventListenerList() event_kit_api.EventListenerList {
+	return event_kit_api.EventListenerList{
+		EventListeners: []event_kit_api.EventListener{
+			{
+				Method:   "POST",
+				Path:     eventPathExperimentStarted,
+				ListenTo: []string{"experiment.execution.created"},
+			},
+			{
+				Method:   "POST",
+				Path:     eventPathTargetStarted,
+				ListenTo: []string{"experiment.execution.target-started"},
+			},
+			{
+				Method: "POST",
+				Path:   eventPathExperimentEnded,
+				ListenTo: []string{
+					"experiment.execution.completed",
+					"experiment.execution.failed",
+					"experiment.execution.canceled",
+					"experiment.execution.errored",
+				},
+			},
+		},
+	}
+}
+
+// RegisterEventListenerHandlers registers the HTTP handlers of GetEventListenerList.
+func RegisterEventListenerHandlers(ctx context.Context) {
+	exthttp.RegisterHttpHandler(eventPathExperimentStarted, eventHandler(func(event event_kit_api.EventRequestBody) {
+		snapshot := onExperimentStarted(event)
+		if snapshot == nil {
+			return
+		}
+		// Snapshotting slow or unreachable endpoints must not hold up the start of the experiment
+		go func() {
+			snapshotCtx, cancel := context.WithTimeout(ctx, snapshotTimeout)
+			defer cancel()
+			snapshotEndpoints(snapshotCtx, snapshot)
+		}()
+	}))
+	exthttp.RegisterHttpHandler(eventPathTargetStarted, eventHandler(onTargetStarted))
+	exthttp.RegisterHttpHandler(eventPathExperimentEnded, eventHandler(func(event event_kit_api.EventRequestBody) {
+		snapshot := takeExperimentSnapshot(event)
+		if snapshot == nil {
+			return
+		}
+		// Give the Stop of the actions time to roll back before the leftovers are reported
+		go func() {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Duration(config.Config.DriftCheckDelaySeconds) * time.Second):
+			}
+			checkDrift(ctx, snapshot)
+		}()
+	}))
+}
+
+func eventHandler(handle func(event event_kit_api.EventRequestBody)) exthttp.Handler {
+	return func(w http.ResponseWriter, _ *http.Request, body []byte) {
+		var event event_kit_api.EventRequestBody
+		if err := json.Unmarshal(body, &event); err != nil {
+			exthttp.WriteError(w, extension_kit.ToError("Failed to decode event request body", err))
+			return
+		}
+		handle(event)
+		exthttp.WriteBody(w, event_kit_api.ListenResult{})
+	}
+}
+
+// onExperimentStarted registers the snapshot of the started experiment, snapshotEndpoints takes it.
+func onExperimentStarted(event event_kit_api.EventRequestBody) *experimentSnapshot {
+	if event.ExperimentExecution == nil {
+		return nil
+	}
+	snapshot := &experimentSnapshot{
+		takenAt:   time.Now(),
+		endpoints: make(map[string]*endpointSnapshot),
+		targeted:  make(map[string]bool),
+	}
+
+	experimentSnapshotsMutex.Lock()
+	defer experimentSnapshotsMutex.Unlock()
+	for id, s := range experimentSnapshots {
+		if time.Since(s.takenAt) > maxSnapshotAge {
+			delete(experimentSnapshots, id)
+		}
+	}
+	experimentSnapshots[event.ExperimentExecution.ExecutionId] = snapshot
+	return snapshot
+}
+
+// snapshotEndpoints snapshots all configured endpoints, the targets of the experiment are not known yet.
+func snapshotEndpoints(ctx context.Context, snapshot *experimentSnapshot) {
+	endpoints := make(map[string]*endpointSnapshot)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, endpoint := range config.GetEndpoints() {
+		wg.Go(func() {
+			redactedURL := config.RedactURL(endpoint.URL)
+			endpointSnapshot, err := takeSnapshot(ctx, &endpoint)
+			if endpointSnapshot == nil {
+				log.Warn().Err(err).Str("url", redactedURL).Msg("Failed to snapshot endpoint, it is not checked for drift")
+				return
+			}
+			if err != nil {
+				log.Warn().Err(err).Str("url", redactedURL).Msg("Failed to snapshot some nodes of the endpoint, they are not checked for drift")
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			endpoints[redactedURL] = endpointSnapshot
+		})
+	}
+	wg.Wait()
+
+	experimentSnapshotsMutex.Lock()
+	defer experimentSnapshotsMutex.Unlock()
+	if snapshot.ended {
+		log.Warn().Msg("Experiment ended before its endpoints were snapshotted, it is not checked for drift")
+		return
+	}
+	snapshot.endpoints = endpoints
+	snapshot.complete = true
+	for _, redisURL := range snapshot.targetURLs {
+		snapshot.markTargeted(redisURL)
+	}
+}
+
+// onTargetStarted marks the endpoint of a Redis target as targeted by the experiment. Targets started while the
+// endpoints are still snapshotted are marked once the snapshot is complete.
+func onTargetStarted(event event_kit_api.EventRequestBody) {
+	target := event.ExperimentStepTargetExecution
+	if target == nil || (target.TargetType != TargetTypeInstance && target.TargetType != TargetTypeDatabase) {
+		return
+	}
+
+	experimentSnapshotsMutex.Lock()
+	defer experimentSnapshotsMutex.Unlock()
+	snapshot, ok := experimentSnapshots[target.ExecutionId]
+	if !ok {
+		return
+	}
+	for _, redisURL := range target.TargetAttributes[AttrRedisURL] {
+		snapshot.targetURLs = append(snapshot.targetURLs, redisURL)
+		if snapshot.complete {
+			snapshot.markTargeted(redisURL)
+		}
+	}
+}
+
+// markTargeted marks the endpoint of the target URL as targeted. Cluster node targets carry the URL of the node,
+// they are matched by the node addresses of the snapshots.
+func (s *experimentSnapshot) markTargeted(redisURL string) {
+	redactedURL := config.RedactURL(redisURL)
+	if _, ok := s.endpoints[redactedURL]; ok {
+		s.targeted[redactedURL] = true
+		return
+	}
+	host, port, err := clients.EndpointHostPort(redisURL)
+	if err != nil {
+		return
+	}
+	addr := clients.JoinHostPort(host, port)
+	for endpointURL, endpointSnapshot := range s.endpoints {
+		if _, ok := endpointSnapshot.Nodes[addr]; ok {
+			s.targeted[endpointURL] = true
+		}
+	}
+}
+
+// takeExperimentSnapshot removes and returns the snapshot of the ended execution.
+func takeExperimentSnapshot(event event_kit_api.EventRequestBody) *experimentSnapshot {
+	if event.ExperimentExecution == nil {
+		return nil
+	}
+	experimentSnapshotsMutex.Lock()
+	defer experimentSnapshotsMutex.Unlock()
+	snapshot, ok := experimentSnapshots[event.ExperimentExecution.ExecutionId]
+	if !ok {
+		return nil
+	}
+	delete(experimentSnapshots, event.ExperimentExecution.ExecutionId)
+	snapshot.ended = true
+	return snapshot
+}
+
+// endpointInUse reports whether a running experiment holds a snapshot of the endpoint. Experiments whose endpoints
+// are still snapshotted may hold any endpoint.
+func endpointInUse(redactedURL string) bool {
+	experimentSnapshotsMutex.Lock()
+	defer experimentSnapshotsMutex.Unlock()
+	for _, s := range experimentSnapshots {
+		if !s.complete {
+			return true
+		}
+		if _, ok := s.endpoints[redactedURL]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// checkDrift compares the targeted endpoints with their snapshot, reports every drift and, with DriftRepair, sets
+// drifted settings back. It returns the drift found per endpoint.
+func checkDrift(ctx context.Context, snapshot *experimentSnapshot) map[string][]drift {
+	drifts := make(map[string][]drift)
+	for redactedURL := range snapshot.targeted {
+		endpoint := config.GetEndpointByURL(redactedURL)
+		if endpoint == nil {
+			continue
+		}
+		current, err := takeSnapshot(ctx, endpoint)
+		if current == nil {
+			log.Warn().Err(err).Str("url", redactedURL).Msg("Failed to snapshot endpoint after experiment, drift is not checked")
+			continue
+		}
+		if err != nil {
+			log.Warn().Err(err).Str("url", redactedURL).Msg("Failed to snapshot some nodes of the endpoint after experiment, their drift is not checked")
+		}
+		endpointDrifts := diffSnapshots(snapshot.endpoints[redactedURL], current)
+		if len(endpointDrifts) == 0 {
+			continue
+		}
+		drifts[redactedURL] = endpointDrifts
+		for _, d := range endpointDrifts {
+			metrics.ConfigDrift.WithLabelValues(redactedURL, d.Item).Inc()
+			log.Warn().Str("url", redactedURL).Str("node", d.Node).Msgf("Drift after experiment: %s", d.Detail)
+		}
+		if !config.Config.DriftRepair {
+			continue
+		}
+		// The drift may come from an attack of an overlapping experiment that is still running, its Stop rolls it back
+		if endpointInUse(redactedURL) {
+			log.Warn().Str("url", redactedURL).Msg("Drift is not repaired, another running experiment holds a snapshot of the endpoint")
+			continue
+		}
+		repairEndpoint(ctx, endpoint, endpointDrifts)
+	}
+	return drifts
+}
+
+func repairEndpoint(ctx context.Context, endpoint *config.RedisEndpoint, drifts []drift) {
+	redactedURL := config.RedactURL(endpoint.URL)
+	byNode := make(map[string][]drift)
+	for _, d := range drifts {
+		if d.Repairable() {
+			byNode[d.Node] = append(byNode[d.Node], d)
+		}
+	}
+	if len(byNode) == 0 {
+		return
+	}
+	_, err := clients.ForEachNode(ctx, endpoint, func(ctx context.Context, client *redis.Client, addr string) error {
+		nodeDrifts, ok := byNode[addr]
+		if !ok {
+			return nil
+		}
+		if err := repairDrift(ctx, client, nodeDrifts); err != nil {
+			return fmt.Errorf("repair drift: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Error().Err(err).Str("url", redactedURL).Msg("Failed to repair drift after experiment")
+		return
+	}
+	log.Info().Str("url", redactedURL).Msg("Set drifted settings back to their snapshot")
+}
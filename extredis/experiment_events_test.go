@@ -0,0 +1,281 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2024 Steadybit GmbH
+
+package extredis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/alicebob/miniredis/v2/server"
+	"github.com/steadybit/event-kit/go/event_kit_api"
+	"github.com/steadybit/extension-redis/clients"
+	"github.com/steadybit/extension-redis/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// registerConfigStub adds CONFIG GET and CONFIG SET, which miniredis lacks, backed by settings.
+func registerConfigStub(t *testing.T, mr *miniredis.Miniredis, settings map[string]string) *sync.Mutex {
+	t.Helper()
+	var mu sync.Mutex
+	require.NoError(t, mr.Server().Register("CONFIG", func(c *server.Peer, cmd string, args []string) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case len(args) == 2 && strings.EqualFold(args[0], "GET"):
+			value, ok := settings[args[1]]
+			if !ok {
+				c.WriteMapLen(0)
+				return
+			}
+			c.WriteMapLen(1)
+			c.WriteBulk(args[1])
+			c.WriteBulk(value)
+		case len(args) == 3 && strings.EqualFold(args[0], "SET"):
+			settings[args[1]] = args[2]
+			c.WriteOK()
+		default:
+			c.WriteError("ERR unsupported CONFIG subcommand")
+		}
+	}))
+	return &mu
+}
+
+func experimentEvent(executionID float32) event_kit_api.EventRequestBody {
+	return event_kit_api.EventRequestBody{
+		ExperimentExecution: &event_kit_api.ExperimentExecution{ExecutionId: executionID},
+	}
+}
+
+func targetStartedEvent(executionID float32, targetType, redisURL string) event_kit_api.EventRequestBody {
+	return event_kit_api.EventRequestBody{
+		ExperimentStepTargetExecution: &event_kit_api.ExperimentStepTargetExecution{
+			ExecutionId:      executionID,
+			TargetType:       targetType,
+			TargetAttributes: map[string][]string{AttrRedisURL: {redisURL}},
+		},
+	}
+}
+
+func useSnapshotEndpoint(t *testing.T, settings map[string]string) (*miniredis.Miniredis, string, *sync.Mutex) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	mu := registerConfigStub(t, mr, settings)
+	redisURL := fmt.Sprintf("redis://%s", mr.Addr())
+
+	origEndpoints := config.Config.Endpoints
+	t.Cleanup(func() { config.Config.Endpoints = origEndpoints })
+	config.Config.Endpoints = []config.RedisEndpoint{{URL: redisURL, ClusterMode: "standalone"}}
+	t.Cleanup(func() { clients.EvictClients(redisURL) })
+	return mr, redisURL, mu
+}
+
+func TestGetEventListenerList(t *testing.T) {
+	// When
+	list := GetEventListenerList()
+
+	// Then
+	require.Len(t, list.EventListeners, 3)
+	paths := make([]string, len(list.EventListeners))
+	for i, l := range list.EventListeners {
+		paths[i] = l.Path
+		assert.NotEmpty(t, l.ListenTo)
+	}
+	assert.Equal(t, []string{eventPathExperimentStarted, eventPathTargetStarted, eventPathExperimentEnded}, paths)
+}
+
+func TestExperimentEvents_ReportsDriftOfTargetedEndpoint(t *testing.T) {
+	// Given
+	settings := map[string]string{"maxmemory": "0", "maxmemory-policy": "noeviction"}
+	_, redisURL, mu := useSnapshotEndpoint(t, settings)
+	ctx := context.Background()
+
+	snapshotEndpoints(ctx, onExperimentStarted(experimentEvent(1)))
+	onTargetStarted(targetStartedEvent(1, TargetTypeInstance, redisURL))
+	mu.Lock()
+	settings["maxmemory"] = "1048576"
+	mu.Unlock()
+
+	// When
+	snapshot := takeExperimentSnapshot(experimentEvent(1))
+	require.NotNil(t, snapshot)
+	drifts := checkDrift(ctx, snapshot)
+
+	// Then
+	require.Len(t, drifts[redisURL], 1)
+	assert.Equal(t, "maxmemory", drifts[redisURL][0].Item)
+	assert.Equal(t, "0", drifts[redisURL][0].Before)
+	assert.Equal(t, "1048576", settings["maxmemory"], "drift is only reported without DriftRepair")
+	assert.Nil(t, takeExperimentSnapshot(experimentEvent(1)), "snapshot is removed at the end")
+}
+
+func TestExperimentEvents_RepairsDrift(t *testing.T) {
+	// Given
+	settings := map[string]string{"maxmemory": "0", "maxmemory-policy": "noeviction"}
+	_, redisURL, mu := useSnapshotEndpoint(t, settings)
+	origRepair := config.Config.DriftRepair
+	t.Cleanup(func() { config.Config.DriftRepair = origRepair })
+	config.Config.DriftRepair = true
+	ctx := context.Background()
+
+	snapshotEndpoints(ctx, onExperimentStarted(experimentEvent(2)))
+	onTargetStarted(targetStartedEvent(2, TargetTypeDatabase, redisURL))
+	mu.Lock()
+	settings["maxmemory-policy"] = "allkeys-lru"
+	mu.Unlock()
+
+	// When
+	drifts := checkDrift(ctx, takeExperimentSnapshot(experimentEvent(2)))
+
+	// Then
+	require.Len(t, drifts[redisURL], 1)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, "noeviction", settings["maxmemory-policy"])
+}
+
+func TestExperimentEvents_DoesNotRepairWhileAnotherExperimentRuns(t *testing.T) {
+	// Given - experiment 5 overlaps experiment 4 and is still running
+	settings := map[string]string{"maxmemory": "0", "maxmemory-policy": "noeviction"}
+	_, redisURL, mu := useSnapshotEndpoint(t, settings)
+	origRepair := config.Config.DriftRepair
+	t.Cleanup(func() { config.Config.DriftRepair = origRepair })
+	config.Config.DriftRepair = true
+	ctx := context.Background()
+
+	snapshotEndpoints(ctx, onExperimentStarted(experimentEvent(4)))
+	snapshotEndpoints(ctx, onExperimentStarted(experimentEvent(5)))
+	t.Cleanup(func() { takeExperimentSnapshot(experimentEvent(5)) })
+	onTargetStarted(targetStartedEvent(4, TargetTypeInstance, redisURL))
+	mu.Lock()
+	settings["maxmemory-policy"] = "allkeys-lru"
+	mu.Unlock()
+
+	// When
+	drifts := checkDrift(ctx, takeExperimentSnapshot(experimentEvent(4)))
+
+	// Then
+	require.Len(t, drifts[redisURL], 1)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, "allkeys-lru", settings["maxmemory-policy"], "the attack of the running experiment is left to its Stop")
+}
+
+func TestExperimentEvents_MarksTargetsStartedWhileSnapshotting(t *testing.T) {
+	// Given - the target starts before the endpoints were snapshotted
+	settings := map[string]string{"maxmemory": "0"}
+	_, redisURL, mu := useSnapshotEndpoint(t, settings)
+	ctx := context.Background()
+
+	snapshot := onExperimentStarted(experimentEvent(6))
+	onTargetStarted(targetStartedEvent(6, TargetTypeInstance, redisURL))
+	snapshotEndpoints(ctx, snapshot)
+	mu.Lock()
+	settings["maxmemory"] = "1048576"
+	mu.Unlock()
+
+	// When
+	drifts := checkDrift(ctx, takeExperimentSnapshot(experimentEvent(6)))
+
+	// Then
+	require.Len(t, drifts[redisURL], 1)
+	assert.Equal(t, "maxmemory", drifts[redisURL][0].Item)
+}
+
+func TestExperimentEvents_IgnoresUntargetedEndpoints(t *testing.T) {
+	// Given
+	settings := map[string]string{"maxmemory": "0"}
+	_, redisURL, mu := useSnapshotEndpoint(t, settings)
+	ctx := context.Background()
+
+	snapshotEndpoints(ctx, onExperimentStarted(experimentEvent(3)))
+	onTargetStarted(targetStartedEvent(3, "com.steadybit.extension_host.host", redisURL))
+	mu.Lock()
+	settings["maxmemory"] = "1048576"
+	mu.Unlock()
+
+	// When
+	drifts := checkDrift(ctx, takeExperimentSnapshot(experimentEvent(3)))
+
+	// Then
+	assert.Empty(t, drifts)
+}
+
+func TestTakeSnapshot_ClusterKeepsSucceededNodes(t *testing.T) {
+	// Given
+	seed := miniredis.RunT(t)
+	replica := miniredis.RunT(t)
+	registerConfigStub(t, seed, map[string]string{"maxmemory": "0"})
+	registerConfigStub(t, replica, map[string]string{"maxmemory": "100"})
+	unreachable := "127.0.0.1:1"
+	seed.Server().SetPreHook(func(c *server.Peer, cmd string, args ...string) bool {
+		if cmd != "CLUSTER" || len(args) == 0 || !strings.EqualFold(args[0], "NODES") {
+			return false
+		}
+		c.WriteBulk(fmt.Sprintf("m1 %s@16379 myself,master - 0 0 1 connected 0-8191\n"+
+			"r1 %s@16379 slave m1 0 0 1 connected\n"+
+			"m2 %s@16379 master - 0 0 2 connected 8192-16383\n", seed.Addr(), replica.Addr(), unreachable))
+		return true
+	})
+	endpoint := &config.RedisEndpoint{URL: fmt.Sprintf("redis://%s", seed.Addr()), ClusterMode: "cluster"}
+	t.Cleanup(func() { clients.EvictClients(endpoint.URL) })
+
+	// When
+	snapshot, err := takeSnapshot(context.Background(), endpoint)
+
+	// Then
+	require.Error(t, err)
+	require.NotNil(t, snapshot)
+	assert.Equal(t, map[string]string{"maxmemory": "0"}, snapshot.Nodes[seed.Addr()].Config)
+	assert.Equal(t, map[string]string{"maxmemory": "100"}, snapshot.Nodes[replica.Addr()].Config)
+	assert.NotContains(t, snapshot.Nodes, unreachable)
+	assert.Equal(t, map[string]bool{unreachable: true}, snapshot.Failed)
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	// Given
+	before := &endpointSnapshot{Nodes: map[string]nodeSnapshot{
+		"10.0.0.1:6379": {
+			Config: map[string]string{"maxmemory": "0", "timeout": "0"},
+			ACL:    map[string]string{"default": "user default on", "app": "user app on"},
+			Role:   "master",
+		},
+		"10.0.0.2:6379": {Config: map[string]string{"maxmemory": "0"}},
+		"10.0.0.3:6379": {Config: map[string]string{"maxmemory": "0"}},
+	}}
+	after := &endpointSnapshot{
+		Nodes: map[string]nodeSnapshot{
+			"10.0.0.1:6379": {
+				Config: map[string]string{"maxmemory": "100", "timeout": "0"},
+				ACL:    map[string]string{"default": "user default on", "chaos": "user chaos on"},
+				Role:   "slave of 10.0.0.4:6379",
+			},
+		},
+		Failed: map[string]bool{"10.0.0.3:6379": true},
+	}
+
+	// When
+	drifts := diffSnapshots(before, after)
+
+	// Then
+	details := make([]string, len(drifts))
+	for i, d := range drifts {
+		details[i] = d.Node + ": " + d.Detail
+	}
+	assert.Equal(t, []string{
+		`10.0.0.1:6379: maxmemory changed from "0" to "100"`,
+		"10.0.0.1:6379: ACL user app was removed",
+		"10.0.0.1:6379: ACL user chaos was added",
+		`10.0.0.1:6379: replication role changed from "master" to "slave of 10.0.0.4:6379"`,
+		"10.0.0.2:6379: node is no longer part of the endpoint",
+	}, details)
+	assert.True(t, drifts[0].Repairable())
+	assert.False(t, drifts[1].Repairable())
+	assert.False(t, drifts[3].Repairable())
+	assert.False(t, drifts[4].Repairable())
+}
@@ -0,0 +1,39 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package extredis
+
+import (
+	"fmt"
+
+	"github.com/steadybit/extension-redis/config"
+)
+
+// targetEndpoint returns the configured endpoint of a target. Cluster node targets carry the URL of their node in
+// redis.url, they are resolved by redis.endpoint.url.
+func targetEndpoint(attributes map[string][]string) *config.RedisEndpoint {
+	if urls := attributes[AttrRedisEndpointURL]; len(urls) > 0 {
+		return config.GetEndpointByURL(urls[0])
+	}
+	if urls := attributes[AttrRedisURL]; len(urls) > 0 {
+		return config.GetEndpointByURL(urls[0])
+	}
+	return nil
+}
+
+// checkGuardrails refuses the attack if the guardrails of the endpoint forbid it, and returns the guardrails for the
+// checks that depend on the attack. Mutating attacks change configuration, data or cluster state.
+// Targets of an unknown endpoint are refused when the target names an endpoint or any endpoint has guardrails, as
+// they might belong to a guarded one.
+func checkGuardrails(attributes map[string][]string, actionID string, mutating bool) (*config.Guardrails, error) {
+	endpoint := targetEndpoint(attributes)
+	if endpoint == nil && (len(attributes[AttrRedisEndpointURL]) > 0 || config.HasGuardrails()) {
+		return nil, fmt.Errorf("%w: the target does not belong to a configured endpoint, its guardrails are unknown", config.ErrGuardrail)
+	}
+	guardrails := endpoint.GetGuardrails()
+	if err := guardrails.CheckAction(actionID, mutating); err != nil {
+		return nil, err
+	}
+	return guardrails, nil
+}
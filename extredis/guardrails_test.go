@@ -0,0 +1,291 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2024 Steadybit GmbH
+
+package extredis
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/steadybit/action-kit/go/action_kit_api/v2"
+	"github.com/steadybit/extension-kit/extutil"
+	"github.com/steadybit/extension-redis/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func useGuardedEndpoint(t *testing.T, url string, guardrails config.Guardrails) {
+	t.Helper()
+	orig := config.Config.Endpoints
+	t.Cleanup(func() { config.Config.Endpoints = orig })
+	config.Config.Endpoints = []config.RedisEndpoint{{URL: url, Guardrails: &guardrails}}
+}
+
+func guardrailRequest(url string, cfg map[string]any) action_kit_api.PrepareActionRequestBody {
+	return extutil.JsonMangle(action_kit_api.PrepareActionRequestBody{
+		Target: &action_kit_api.Target{
+			Attributes: map[string][]string{
+				AttrRedisURL:      {url},
+				AttrDatabaseIndex: {"0"},
+			},
+		},
+		Config:      cfg,
+		ExecutionId: uuid.New(),
+	})
+}
+
+func TestGuardrails_ReadOnly(t *testing.T) {
+	// Given
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	url := fmt.Sprintf("redis://%s", mr.Addr())
+	useGuardedEndpoint(t, url, config.Guardrails{ReadOnly: true})
+	req := guardrailRequest(url, map[string]any{"duration": float64(10000)})
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		mutating bool
+		prepare  func() error
+	}{
+		{"cache expiration", true, func() error {
+			_, err := (&cacheExpirationAttack{}).Prepare(ctx, &CacheExpirationState{}, req)
+			return err
+		}},
+		{"client pause", true, func() error {
+			_, err := (&clientPauseAttack{}).Prepare(ctx, &ClientPauseState{}, req)
+			return err
+		}},
+		{"maxmemory limit", true, func() error {
+			_, err := (&maxmemoryLimitAttack{}).Prepare(ctx, &MaxmemoryLimitState{}, req)
+			return err
+		}},
+		{"output buffer limit", true, func() error {
+			_, err := (&outputBufferLimitAttack{}).Prepare(ctx, &OutputBufferLimitState{}, req)
+			return err
+		}},
+		{"pubsub disruption", true, func() error {
+			_, err := (&pubSubDisruptionAttack{}).Prepare(ctx, &PubSubDisruptionState{}, req)
+			return err
+		}},
+		{"slot migration", true, func() error {
+			_, err := (&slotMigrationAttack{}).Prepare(ctx, &SlotMigrationState{}, req)
+			return err
+		}},
+		{"stream consumer group", true, func() error {
+			_, err := (&streamConsumerGroupAttack{}).Prepare(ctx, &StreamConsumerGroupState{}, req)
+			return err
+		}},
+		{"connection exhaustion", false, func() error {
+			_, err := (&connectionExhaustionAttack{}).Prepare(ctx, &ConnectionExhaustionState{}, req)
+			return err
+		}},
+		{"sentinel stop", true, func() error {
+			_, err := (&sentinelStopAttack{}).Prepare(ctx, &SentinelStopState{}, req)
+			return err
+		}},
+		{"cluster node stop", true, func() error {
+			_, err := (&clusterNodeStopAttack{}).Prepare(ctx, &ClusterNodeStopState{}, req)
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// When
+			err := tt.prepare()
+
+			// Then
+			if tt.mutating {
+				assert.ErrorIs(t, err, config.ErrGuardrail)
+				assert.Contains(t, err.Error(), "read-only")
+			} else {
+				assert.NotErrorIs(t, err, config.ErrGuardrail)
+			}
+		})
+	}
+}
+
+func TestGuardrails_AllowedActions(t *testing.T) {
+	// Given
+	url := "redis://127.0.0.1:1"
+	useGuardedEndpoint(t, url, config.Guardrails{AllowedActions: []string{clientPauseActionID}})
+	req := guardrailRequest(url, map[string]any{"duration": float64(10000), "maxmemory": "1mb"})
+
+	// When
+	_, err := (&maxmemoryLimitAttack{}).Prepare(context.Background(), &MaxmemoryLimitState{}, req)
+
+	// Then
+	require.ErrorIs(t, err, config.ErrGuardrail)
+	assert.Contains(t, err.Error(), maxmemoryLimitActionID)
+}
+
+func TestGuardrails_ForbiddenEvictionPolicy(t *testing.T) {
+	// Given
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	url := fmt.Sprintf("redis://%s", mr.Addr())
+	useGuardedEndpoint(t, url, config.Guardrails{ForbiddenEvictionPolicies: []string{"allkeys-random"}})
+	action := &maxmemoryLimitAttack{}
+
+	// When
+	_, err = action.Prepare(context.Background(), &MaxmemoryLimitState{}, guardrailRequest(url, map[string]any{
+		"duration": float64(10000), "maxmemory": "1mb", "evictionPolicy": "allkeys-random",
+	}))
+
+	// Then
+	require.ErrorIs(t, err, config.ErrGuardrail)
+	assert.Contains(t, err.Error(), "allkeys-random")
+
+	// When - keeping the policy passes the guardrails, miniredis then refuses CONFIG GET
+	_, err = action.Prepare(context.Background(), &MaxmemoryLimitState{}, guardrailRequest(url, map[string]any{
+		"duration": float64(10000), "maxmemory": "1mb", "evictionPolicy": "keep",
+	}))
+
+	// Then
+	assert.NotErrorIs(t, err, config.ErrGuardrail)
+}
+
+func TestGuardrails_StreamKeyProtected(t *testing.T) {
+	// Given
+	url := "redis://127.0.0.1:1"
+	useGuardedEndpoint(t, url, config.Guardrails{ProtectedKeyPatterns: []string{"orders:*"}})
+
+	// When
+	_, err := (&streamConsumerGroupAttack{}).Prepare(context.Background(), &StreamConsumerGroupState{}, guardrailRequest(url, map[string]any{
+		"duration": float64(10000), "streamKey": "orders:stream", "group": "workers", "mode": streamModeStallGroup,
+	}))
+
+	// Then
+	require.ErrorIs(t, err, config.ErrGuardrail)
+	assert.Contains(t, err.Error(), "orders:stream")
+}
+
+func TestGuardrails_CacheExpirationSkipsProtectedKeys(t *testing.T) {
+	// Given
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	mr.Set("user:1", "alice")
+	mr.Set("user:2", "bob")
+	mr.Set("user:admin", "root")
+
+	url := fmt.Sprintf("redis://%s", mr.Addr())
+	useGuardedEndpoint(t, url, config.Guardrails{ProtectedKeyPatterns: []string{"user:admin"}})
+	action := &cacheExpirationAttack{}
+	state := CacheExpirationState{}
+
+	// When
+	_, err = action.Prepare(context.Background(), &state, guardrailRequest(url, map[string]any{
+		"duration": float64(10000), "pattern": "user:*", "ttl": float64(5), "restoreOnStop": false,
+	}))
+	require.NoError(t, err)
+	result, err := action.Start(context.Background(), &state)
+	require.NoError(t, err)
+	defer func() { _, _ = action.Stop(context.Background(), &state) }()
+
+	// Then
+	assert.ElementsMatch(t, []string{"user:1", "user:2"}, state.MatchedKeys)
+	assert.Equal(t, 1, state.SkippedProtected)
+	assert.Contains(t, (*result.Messages)[0].Message, "skipped 1 protected keys")
+	assert.Zero(t, mr.TTL("user:admin"))
+	assert.Positive(t, mr.TTL("user:1"))
+}
+
+func TestGuardrails_CacheExpirationAllKeysProtected(t *testing.T) {
+	// Given
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	mr.Set("session:1", "a")
+
+	url := fmt.Sprintf("redis://%s", mr.Addr())
+	useGuardedEndpoint(t, url, config.Guardrails{ProtectedKeyPatterns: []string{"session:*"}})
+
+	// When
+	_, err = (&cacheExpirationAttack{}).Prepare(context.Background(), &CacheExpirationState{}, guardrailRequest(url, map[string]any{
+		"duration": float64(10000), "pattern": "*", "ttl": float64(5),
+	}))
+
+	// Then
+	require.ErrorIs(t, err, config.ErrGuardrail)
+	assert.Contains(t, err.Error(), "all 1 keys")
+}
+
+func TestGuardrails_CacheExpirationMaxAffectedKeys(t *testing.T) {
+	// Given
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	for i := range 5 {
+		mr.Set(fmt.Sprintf("cache:%d", i), "v")
+	}
+
+	url := fmt.Sprintf("redis://%s", mr.Addr())
+	useGuardedEndpoint(t, url, config.Guardrails{MaxAffectedKeys: 3})
+	action := &cacheExpirationAttack{}
+
+	// When
+	_, err = action.Prepare(context.Background(), &CacheExpirationState{}, guardrailRequest(url, map[string]any{
+		"duration": float64(10000), "pattern": "cache:*", "ttl": float64(5), "maxKeys": float64(0),
+	}))
+
+	// Then
+	require.ErrorIs(t, err, config.ErrGuardrail)
+	assert.Contains(t, err.Error(), "would affect 5 keys, at most 3")
+
+	// When - maxKeys keeps the attack within the limit
+	_, err = action.Prepare(context.Background(), &CacheExpirationState{}, guardrailRequest(url, map[string]any{
+		"duration": float64(10000), "pattern": "cache:*", "ttl": float64(5), "maxKeys": float64(3),
+	}))
+
+	// Then
+	assert.NoError(t, err)
+}
+
+func TestGuardrails_ClusterNodeTarget(t *testing.T) {
+	// Given - a node target carries the node URL and the URL of its endpoint
+	endpointURL := "redis://seed:6379"
+	useGuardedEndpoint(t, endpointURL, config.Guardrails{ReadOnly: true})
+	attributes := map[string][]string{
+		AttrRedisURL:         {"redis://10.0.0.5:6379"},
+		AttrRedisEndpointURL: {endpointURL},
+	}
+
+	// When
+	_, err := checkGuardrails(attributes, maxmemoryLimitActionID, true)
+
+	// Then
+	require.ErrorIs(t, err, config.ErrGuardrail)
+	assert.Contains(t, err.Error(), "read-only")
+}
+
+func TestGuardrails_UnknownEndpoint(t *testing.T) {
+	tests := []struct {
+		name       string
+		attributes map[string][]string
+	}{
+		{"node target of a removed endpoint", map[string][]string{
+			AttrRedisURL:         {"redis://10.0.0.5:6379"},
+			AttrRedisEndpointURL: {"redis://removed:6379"},
+		}},
+		{"node target discovered before redis.endpoint.url", map[string][]string{
+			AttrRedisURL: {"redis://10.0.0.5:6379"},
+		}},
+	}
+	useGuardedEndpoint(t, "redis://seed:6379", config.Guardrails{MaxAffectedKeys: 10})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// When
+			_, err := checkGuardrails(tt.attributes, connectionExhaustionActionID, false)
+
+			// Then
+			require.ErrorIs(t, err, config.ErrGuardrail)
+			assert.Contains(t, err.Error(), "configured endpoint")
+		})
+	}
+}
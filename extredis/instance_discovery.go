@@ -7,7 +7,7 @@ package extredis
 import (
 	"context"
 	"fmt"
-	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
@@ -32,6 +32,7 @@ func NewRedisInstanceDiscovery(ctx context.Context) discovery_kit_sdk.TargetDisc
 	return discovery_kit_sdk.NewCachedTargetDiscovery(discovery,
 		discovery_kit_sdk.WithRefreshTargetsNow(),
 		discovery_kit_sdk.WithRefreshTargetsInterval(ctx, time.Duration(config.Config.DiscoveryIntervalInstanceSeconds)*time.Second),
+		discovery_kit_sdk.WithRefreshTargetsTrigger(ctx, config.SubscribeEndpointsChanged(), 5*time.Second),
 	)
 }
 
@@ -99,29 +100,116 @@ func (d *redisInstanceDiscovery) DescribeAttributes() []discovery_kit_api.Attrib
 			Attribute: AttrRedisName,
 			Label:     discovery_kit_api.PluralLabel{One: "Instance name", Other: "Instance names"},
 		},
+		{
+			Attribute: AttrRedisMemoryPolicy,
+			Label:     discovery_kit_api.PluralLabel{One: "Eviction policy", Other: "Eviction policies"},
+		},
+		{
+			Attribute: AttrRedisPersistence,
+			Label:     discovery_kit_api.PluralLabel{One: "Persistence", Other: "Persistence"},
+		},
+		{
+			Attribute: AttrRedisUsage,
+			Label:     discovery_kit_api.PluralLabel{One: "Usage", Other: "Usages"},
+		},
+		{
+			Attribute: AttrRedisConnectedReplicas,
+			Label:     discovery_kit_api.PluralLabel{One: "Connected replicas", Other: "Connected replicas"},
+		},
+		{
+			Attribute: AttrRedisMinReplicasToWrite,
+			Label:     discovery_kit_api.PluralLabel{One: "Min replicas to write", Other: "Min replicas to write"},
+		},
+		{
+			Attribute: AttrRedisClusterRequireFullCoverage,
+			Label:     discovery_kit_api.PluralLabel{One: "Cluster requires full coverage", Other: "Cluster requires full coverage"},
+		},
+		{
+			Attribute: AttrRedisMaxClients,
+			Label:     discovery_kit_api.PluralLabel{One: "Max clients", Other: "Max clients"},
+		},
+		{
+			Attribute: AttrRedisUptime,
+			Label:     discovery_kit_api.PluralLabel{One: "Uptime (seconds)", Other: "Uptimes (seconds)"},
+		},
+		{
+			Attribute: AttrRedisMode,
+			Label:     discovery_kit_api.PluralLabel{One: "Redis mode", Other: "Redis modes"},
+		},
+		{
+			Attribute: AttrRedisOS,
+			Label:     discovery_kit_api.PluralLabel{One: "Operating system", Other: "Operating systems"},
+		},
+		{
+			Attribute: AttrRedisArch,
+			Label:     discovery_kit_api.PluralLabel{One: "Architecture", Other: "Architectures"},
+		},
+		{
+			Attribute: AttrRedisModules,
+			Label:     discovery_kit_api.PluralLabel{One: "Module", Other: "Modules"},
+		},
+		{
+			Attribute: AttrRedisTLSEnabled,
+			Label:     discovery_kit_api.PluralLabel{One: "TLS enabled", Other: "TLS enabled"},
+		},
+		{
+			Attribute: AttrRedisConfigAllowed,
+			Label:     discovery_kit_api.PluralLabel{One: "CONFIG allowed", Other: "CONFIG allowed"},
+		},
+		{
+			Attribute: AttrRedisDebugAllowed,
+			Label:     discovery_kit_api.PluralLabel{One: "DEBUG allowed", Other: "DEBUG allowed"},
+		},
 		{
 			Attribute: AttrRedisClusterNodeID,
 			Label:     discovery_kit_api.PluralLabel{One: "Cluster node ID", Other: "Cluster node IDs"},
 		},
+		{
+			Attribute: AttrRedisClusterSlotRanges,
+			Label:     discovery_kit_api.PluralLabel{One: "Cluster slot range", Other: "Cluster slot ranges"},
+		},
+		{
+			Attribute: AttrRedisClusterSlotCount,
+			Label:     discovery_kit_api.PluralLabel{One: "Cluster slot count", Other: "Cluster slot counts"},
+		},
+		{
+			Attribute: AttrRedisClusterMasterID,
+			Label:     discovery_kit_api.PluralLabel{One: "Cluster master ID", Other: "Cluster master IDs"},
+		},
+		{
+			Attribute: AttrRedisClusterShardID,
+			Label:     discovery_kit_api.PluralLabel{One: "Cluster shard ID", Other: "Cluster shard IDs"},
+		},
+		{
+			Attribute: AttrRedisClusterConfigEpoch,
+			Label:     discovery_kit_api.PluralLabel{One: "Cluster config epoch", Other: "Cluster config epochs"},
+		},
+		{
+			Attribute: AttrRedisSentinelMasterName,
+			Label:     discovery_kit_api.PluralLabel{One: "Sentinel master name", Other: "Sentinel master names"},
+		},
+		{
+			Attribute: AttrRedisSentinelMasterAddr,
+			Label:     discovery_kit_api.PluralLabel{One: "Sentinel master address", Other: "Sentinel master addresses"},
+		},
+		{
+			Attribute: AttrRedisSentinelCount,
+			Label:     discovery_kit_api.PluralLabel{One: "Sentinel count", Other: "Sentinel counts"},
+		},
 	}
 }
 
 func (d *redisInstanceDiscovery) DiscoverTargets(ctx context.Context) ([]discovery_kit_api.Target, error) {
-	return FetchTargetsPerEndpoint(func(endpoint *config.RedisEndpoint) ([]discovery_kit_api.Target, error) {
+	targets, err := FetchTargetsPerEndpoint(ctx, TargetTypeInstance, func(ctx context.Context, endpoint *config.RedisEndpoint) ([]discovery_kit_api.Target, error) {
 		return discoverInstance(ctx, endpoint)
 	})
+	return excludeAttributes(targets, config.Config.DiscoveryAttributesExcludesInstances), err
 }
 
 func discoverInstance(ctx context.Context, endpoint *config.RedisEndpoint) ([]discovery_kit_api.Target, error) {
-	parsedURL, err := url.Parse(endpoint.URL)
+	host, port, err := clients.EndpointHostPort(endpoint.URL)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse Redis URL: %w", err)
-	}
-
-	host := parsedURL.Hostname()
-	port := parsedURL.Port()
-	if port == "" {
-		port = "6379"
+		return nil, err
 	}
 
 	client, err := clients.CreateRedisClient(endpoint)
@@ -146,16 +234,31 @@ func discoverInstance(ctx context.Context, endpoint *config.RedisEndpoint) ([]di
 	}
 
 	// Standalone: return the single configured instance
-	return []discovery_kit_api.Target{buildInstanceTarget(endpoint, host, port, allInfo, "")}, nil
+	targets := []discovery_kit_api.Target{buildInstanceTarget(endpoint, host, port, allInfo, getInstanceDetails(ctx, client), "")}
+	addSentinelAttributes(ctx, endpoint, targets)
+	return targets, nil
 }
 
 func discoverClusterNodes(ctx context.Context, endpoint *config.RedisEndpoint, seedClient *redis.Client) ([]discovery_kit_api.Target, error) {
-	nodes, err := clients.ParseClusterNodes(ctx, seedClient)
+	// Actions see the same nodes, unless the endpoint is forced to standalone mode
+	var nodes []clients.ClusterNodeInfo
+	topology, err := clients.GetTopology(ctx, endpoint)
+	if err == nil && topology.Cluster {
+		nodes = topology.Nodes
+	} else {
+		nodes, err = clients.ParseClusterNodes(ctx, seedClient)
+	}
 	if err != nil {
 		log.Warn().Err(err).Msg("Failed to get cluster nodes, falling back to single instance")
 		return nil, fmt.Errorf("failed to get cluster nodes: %w", err)
 	}
 
+	nodesByID := make(map[string]clients.ClusterNodeInfo, len(nodes))
+	for _, node := range nodes {
+		nodesByID[node.ID] = node
+	}
+	shardIDs := clusterShardIDs(ctx, seedClient)
+
 	var targets []discovery_kit_api.Target
 	for _, node := range nodes {
 		nodeClient, err := clients.CreateDirectClient(endpoint, node.Addr)
@@ -165,25 +268,25 @@ func discoverClusterNodes(ctx context.Context, endpoint *config.RedisEndpoint, s
 		}
 
 		nodeInfo, err := clients.GetRedisInfo(ctx, nodeClient, "")
-		nodeClient.Close()
 		if err != nil {
+			clients.InvalidateTopologyOnError(endpoint.URL, err)
 			log.Warn().Err(err).Str("addr", node.Addr).Msg("Failed to get info for cluster node")
 			nodeInfo = make(map[string]string)
 		}
+		details := getInstanceDetails(ctx, nodeClient)
+		nodeClient.Close()
 
 		// Parse host:port from the node address
-		nodeHost, nodePort := parseHostPort(node.Addr)
+		nodeHost, nodePort := clients.SplitHostPort(node.Addr)
 
-		target := buildInstanceTarget(endpoint, nodeHost, nodePort, nodeInfo, node.ID)
+		target := buildInstanceTarget(endpoint, nodeHost, nodePort, nodeInfo, details, node.ID)
 		// Override the URL to point to this specific node
 		scheme := "redis"
-		if endpoint.InsecureSkipVerify || len(endpoint.URL) > 8 && endpoint.URL[:8] == "rediss://" {
-			scheme = "rediss"
-		}
 		if strings.HasPrefix(endpoint.URL, "rediss://") {
 			scheme = "rediss"
 		}
-		target.Attributes[AttrRedisURL] = []string{fmt.Sprintf("%s://%s:%s", scheme, nodeHost, nodePort)}
+		target.Attributes[AttrRedisURL] = []string{clients.NodeURL(scheme, node.Addr)}
+		addShardAttributes(target.Attributes, node, nodesByID, shardIDs[node.ID])
 
 		targets = append(targets, target)
 	}
@@ -191,19 +294,137 @@ func discoverClusterNodes(ctx context.Context, endpoint *config.RedisEndpoint, s
 	return targets, nil
 }
 
-func buildInstanceTarget(endpoint *config.RedisEndpoint, host, port string, info map[string]string, clusterNodeID string) discovery_kit_api.Target {
+// clusterShardIDs maps the node IDs to the ID of their shard, taken from CLUSTER SHARDS (Redis 7.0+). Its reply has no
+// shard ID, so a shard is identified by the node ID of its master, which is the same for the master and its replicas.
+func clusterShardIDs(ctx context.Context, client *redis.Client) map[string]string {
+	shards, err := client.ClusterShards(ctx).Result()
+	if err != nil {
+		log.Debug().Err(err).Msg("Failed to get cluster shards, the nodes are discovered without shard ID")
+		return nil
+	}
+
+	shardIDs := make(map[string]string)
+	for _, shard := range shards {
+		masterID := ""
+		for _, node := range shard.Nodes {
+			if node.Role == "master" {
+				masterID = node.ID
+			}
+		}
+		if masterID == "" {
+			continue
+		}
+		for _, node := range shard.Nodes {
+			shardIDs[node.ID] = masterID
+		}
+	}
+	return shardIDs
+}
+
+// instanceSettings are read with CONFIG GET for the instance attributes.
+var instanceSettings = []string{"save", "min-replicas-to-write", "cluster-require-full-coverage", "maxclients", "enable-debug-command"}
+
+// instanceDetails are the details of a node besides INFO. Managed Redis often denies or renames CONFIG, DEBUG and
+// MODULE, the attributes based on them are left out then.
+type instanceDetails struct {
+	// settings holds the instanceSettings known to the node
+	settings map[string]string
+	// configAllowed and debugAllowed are nil when they could not be determined
+	configAllowed *bool
+	debugAllowed  *bool
+	// modules is nil when MODULE LIST failed
+	modules []string
+}
+
+func getInstanceDetails(ctx context.Context, client *redis.Client) instanceDetails {
+	details := instanceDetails{settings: make(map[string]string, len(instanceSettings))}
+	for _, setting := range instanceSettings {
+		values, err := client.ConfigGet(ctx, setting).Result()
+		if err != nil {
+			log.Debug().Err(err).Str("addr", client.Options().Addr).Msg("Failed to get settings for instance attributes")
+			break
+		}
+		if value, ok := values[setting]; ok {
+			details.settings[setting] = value
+		}
+	}
+
+	// The attacks run CONFIG SET and DEBUG SLEEP, whether the user of the extension may do so is asked with ACL DRYRUN
+	if user, err := client.Do(ctx, "ACL", "WHOAMI").Text(); err == nil {
+		details.configAllowed = commandAllowed(ctx, client, user, "CONFIG", "SET", "maxmemory", "0")
+		details.debugAllowed = commandAllowed(ctx, client, user, "DEBUG", "SLEEP", "0")
+	} else {
+		log.Debug().Err(err).Str("addr", client.Options().Addr).Msg("Failed to get the ACL user for instance attributes")
+	}
+	// Redis 7 only allows DEBUG for local connections with "local", the extension connects remotely
+	if setting, ok := details.settings["enable-debug-command"]; ok && setting != "yes" {
+		details.debugAllowed = new(false)
+	}
+
+	if modules, err := client.Do(ctx, "MODULE", "LIST").Slice(); err == nil {
+		details.modules = parseModuleList(modules)
+	}
+	return details
+}
+
+// commandAllowed asks with ACL DRYRUN (Redis 7+) whether user may run the command. It returns nil when this cannot be
+// determined, e.g. before Redis 7 or when ACL is denied itself.
+func commandAllowed(ctx context.Context, client *redis.Client, user string, command ...any) *bool {
+	reply, err := client.Do(ctx, append([]any{"ACL", "DRYRUN", user}, command...)...).Text()
+	if err != nil {
+		// A renamed or disabled command is unknown to ACL DRYRUN
+		if strings.Contains(err.Error(), "not found") {
+			return new(false)
+		}
+		log.Debug().Err(err).Str("addr", client.Options().Addr).Msgf("Failed to check whether %v is allowed", command[0])
+		return nil
+	}
+	// Denied commands are answered with the reason instead of OK
+	return new(reply == "OK")
+}
+
+// parseModuleList returns the module names of a MODULE LIST reply, a map per module with RESP3 and a flat list of
+// field names and values with RESP2.
+func parseModuleList(reply []any) []string {
+	names := make([]string, 0, len(reply))
+	for _, module := range reply {
+		switch fields := module.(type) {
+		case map[any]any:
+			if name, ok := fields["name"].(string); ok {
+				names = append(names, name)
+			}
+		case []any:
+			for i := 0; i+1 < len(fields); i += 2 {
+				if fields[i] == "name" {
+					if name, ok := fields[i+1].(string); ok {
+						names = append(names, name)
+					}
+				}
+			}
+		}
+	}
+	return names
+}
+
+func buildInstanceTarget(endpoint *config.RedisEndpoint, host, port string, info map[string]string, details instanceDetails, clusterNodeID string) discovery_kit_api.Target {
+	settings := details.settings
+	addr := clients.JoinHostPort(host, port)
 	name := endpoint.Name
 	if name == "" {
-		name = fmt.Sprintf("%s:%s", host, port)
+		name = addr
 	} else if clusterNodeID != "" {
-		name = fmt.Sprintf("%s/%s:%s", endpoint.Name, host, port)
+		name = fmt.Sprintf("%s/%s", endpoint.Name, addr)
 	}
 
 	attributes := map[string][]string{
-		AttrRedisURL:  {endpoint.URL},
-		AttrRedisHost: {host},
-		AttrRedisPort: {port},
-		AttrRedisName: {name},
+		AttrRedisURL:         {config.RedactURL(endpoint.URL)},
+		AttrRedisEndpointURL: {config.RedactURL(endpoint.URL)},
+		AttrRedisHost:        {host},
+		AttrRedisName:        {name},
+	}
+	// Unix sockets have no port
+	if port != "" {
+		attributes[AttrRedisPort] = []string{port}
 	}
 
 	if version, ok := info["redis_version"]; ok {
@@ -221,19 +442,133 @@ func buildInstanceTarget(endpoint *config.RedisEndpoint, host, port string, info
 	if clusterNodeID != "" {
 		attributes[AttrRedisClusterNodeID] = []string{clusterNodeID}
 	}
+	if policy, ok := info["maxmemory_policy"]; ok {
+		attributes[AttrRedisMemoryPolicy] = []string{policy}
+	}
+	if replicas, ok := info["connected_slaves"]; ok {
+		attributes[AttrRedisConnectedReplicas] = []string{replicas}
+	}
+	if persistence := persistenceMode(info, settings); persistence != "" {
+		attributes[AttrRedisPersistence] = []string{persistence}
+	}
+	if usage := keyspaceUsage(info); usage != "" {
+		attributes[AttrRedisUsage] = []string{usage}
+	}
+	if minReplicas, ok := settings["min-replicas-to-write"]; ok {
+		attributes[AttrRedisMinReplicasToWrite] = []string{minReplicas}
+	}
+	if fullCoverage, ok := settings["cluster-require-full-coverage"]; ok && clusterNodeID != "" {
+		attributes[AttrRedisClusterRequireFullCoverage] = []string{fullCoverage}
+	}
+	if maxClients, ok := settings["maxclients"]; ok {
+		attributes[AttrRedisMaxClients] = []string{maxClients}
+	} else if maxClients, ok := info["maxclients"]; ok {
+		attributes[AttrRedisMaxClients] = []string{maxClients}
+	}
+	if uptime, ok := info["uptime_in_seconds"]; ok {
+		attributes[AttrRedisUptime] = []string{uptime}
+	}
+	if mode, ok := info["redis_mode"]; ok {
+		attributes[AttrRedisMode] = []string{mode}
+	}
+	// e.g. "Linux 6.1.0-18-amd64 x86_64", the machine is the last field
+	if os, ok := info["os"]; ok && os != "" {
+		attributes[AttrRedisOS] = []string{os}
+		fields := strings.Fields(os)
+		attributes[AttrRedisArch] = []string{fields[len(fields)-1]}
+	}
+	if len(details.modules) > 0 {
+		attributes[AttrRedisModules] = details.modules
+	}
+	attributes[AttrRedisTLSEnabled] = []string{strconv.FormatBool(strings.HasPrefix(endpoint.URL, "rediss://"))}
+	if details.configAllowed != nil {
+		attributes[AttrRedisConfigAllowed] = []string{strconv.FormatBool(*details.configAllowed)}
+	}
+	if details.debugAllowed != nil {
+		attributes[AttrRedisDebugAllowed] = []string{strconv.FormatBool(*details.debugAllowed)}
+	}
 
 	return discovery_kit_api.Target{
-		Id:         fmt.Sprintf("%s:%s", host, port),
+		Id:         addr,
 		TargetType: TargetTypeInstance,
 		Label:      name,
 		Attributes: attributes,
 	}
 }
 
-func parseHostPort(addr string) (string, string) {
-	idx := strings.LastIndex(addr, ":")
-	if idx == -1 {
-		return addr, "6379"
+// persistenceMode returns "none", "rdb", "aof" or "rdb+aof". RDB snapshots are only known from the save setting, so
+// it is empty without CONFIG GET.
+func persistenceMode(info, settings map[string]string) string {
+	save, ok := settings["save"]
+	aofEnabled, aofKnown := info["aof_enabled"]
+	if !ok || !aofKnown {
+		return ""
+	}
+	rdb := strings.TrimSpace(save) != ""
+	aof := aofEnabled == "1"
+	switch {
+	case rdb && aof:
+		return "rdb+aof"
+	case rdb:
+		return "rdb"
+	case aof:
+		return "aof"
+	default:
+		return "none"
+	}
+}
+
+// keyspaceUsage tells from the keyspace section whether an instance is used as a cache, when at least half of its keys
+// expire, or as a store. It is empty for an empty instance.
+func keyspaceUsage(info map[string]string) string {
+	var keys, expires int
+	for name, value := range info {
+		if _, err := strconv.Atoi(strings.TrimPrefix(name, "db")); err != nil || !strings.HasPrefix(name, "db") {
+			continue
+		}
+		for field := range strings.SplitSeq(value, ",") {
+			k, v, _ := strings.Cut(field, "=")
+			n, _ := strconv.Atoi(v)
+			switch k {
+			case "keys":
+				keys += n
+			case "expires":
+				expires += n
+			}
+		}
+	}
+	switch {
+	case keys == 0:
+		return ""
+	case expires*2 >= keys:
+		return "cache"
+	default:
+		return "store"
+	}
+}
+
+// addShardAttributes describes the shard of a cluster node. Replicas get the slots of their master, so e.g. the
+// replicas of the shard owning a slot range can be selected.
+func addShardAttributes(attributes map[string][]string, node clients.ClusterNodeInfo, nodesByID map[string]clients.ClusterNodeInfo, shardID string) {
+	slots := node.Slots
+	if node.MasterID != "" {
+		attributes[AttrRedisClusterMasterID] = []string{node.MasterID}
+		slots = nodesByID[node.MasterID].Slots
+	}
+
+	slotRanges := make([]string, 0, len(slots))
+	for _, r := range slots {
+		slotRanges = append(slotRanges, r.String())
+	}
+	if len(slotRanges) > 0 {
+		attributes[AttrRedisClusterSlotRanges] = slotRanges
+	}
+	attributes[AttrRedisClusterSlotCount] = []string{strconv.Itoa(clients.SlotCount(slots))}
+
+	if shardID != "" {
+		attributes[AttrRedisClusterShardID] = []string{shardID}
+	}
+	if node.ConfigEpoch != "" {
+		attributes[AttrRedisClusterConfigEpoch] = []string{node.ConfigEpoch}
 	}
-	return addr[:idx], addr[idx+1:]
 }
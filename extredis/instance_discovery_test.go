@@ -6,10 +6,16 @@ package extredis
 import (
 	"context"
 	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/alicebob/miniredis/v2"
+	"github.com/alicebob/miniredis/v2/server"
 	"github.com/steadybit/discovery-kit/go/discovery_kit_api"
+	"github.com/steadybit/extension-redis/clients"
 	"github.com/steadybit/extension-redis/config"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -233,3 +239,466 @@ func TestInstanceDiscovery_DiscoverTargets_ViaEndpoints(t *testing.T) {
 	require.Len(t, targets, 1)
 	assert.Equal(t, "via-endpoints", targets[0].Label)
 }
+
+func TestInstanceDiscovery_DiscoverTargets_ExcludesAttributes(t *testing.T) {
+	// Given
+	mr := miniredis.RunT(t)
+	origEndpoints := config.Config.Endpoints
+	origExcludes := config.Config.DiscoveryAttributesExcludesInstances
+	t.Cleanup(func() {
+		config.Config.Endpoints = origEndpoints
+		config.Config.DiscoveryAttributesExcludesInstances = origExcludes
+	})
+	config.Config.Endpoints = []config.RedisEndpoint{{URL: fmt.Sprintf("redis://%s", mr.Addr())}}
+	config.Config.DiscoveryAttributesExcludesInstances = []string{AttrRedisTLSEnabled, "redis.commands.*"}
+
+	// When
+	targets, err := (&redisInstanceDiscovery{}).DiscoverTargets(context.Background())
+
+	// Then
+	require.NoError(t, err)
+	require.Len(t, targets, 1)
+	assert.NotContains(t, targets[0].Attributes, AttrRedisTLSEnabled)
+	assert.NotContains(t, targets[0].Attributes, AttrRedisConfigAllowed)
+	assert.Contains(t, targets[0].Attributes, AttrRedisURL)
+}
+
+// serveUnixSocket forwards connections on a unix socket to addr and returns the socket path.
+func serveUnixSocket(t *testing.T, addr string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "redis.sock")
+	listener, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			upstream, err := net.Dial("tcp", addr)
+			if err != nil {
+				_ = conn.Close()
+				continue
+			}
+			go func() {
+				_, _ = io.Copy(upstream, conn)
+				_ = upstream.Close()
+			}()
+			go func() {
+				_, _ = io.Copy(conn, upstream)
+				_ = conn.Close()
+			}()
+		}
+	}()
+	return path
+}
+
+func TestBuildInstanceTarget_Addresses(t *testing.T) {
+	tests := []struct {
+		name          string
+		host          string
+		port          string
+		clusterNodeID string
+		wantID        string
+		wantLabel     string
+		wantPort      []string
+	}{
+		{name: "ipv4", host: "10.0.0.1", port: "6379", wantID: "10.0.0.1:6379", wantLabel: "10.0.0.1:6379", wantPort: []string{"6379"}},
+		{name: "ipv6", host: "2001:db8::1", port: "6379", wantID: "[2001:db8::1]:6379", wantLabel: "[2001:db8::1]:6379", wantPort: []string{"6379"}},
+		{name: "ipv6 cluster node", host: "::1", port: "7000", clusterNodeID: "abc", wantID: "[::1]:7000", wantLabel: "[::1]:7000", wantPort: []string{"7000"}},
+		{name: "unix socket", host: "/var/run/redis/redis.sock", port: "", wantID: "/var/run/redis/redis.sock", wantLabel: "/var/run/redis/redis.sock"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			target := buildInstanceTarget(&config.RedisEndpoint{URL: "redis://seed"}, tc.host, tc.port, map[string]string{}, instanceDetails{}, tc.clusterNodeID)
+
+			assert.Equal(t, tc.wantID, target.Id)
+			assert.Equal(t, tc.wantLabel, target.Label)
+			assert.Equal(t, []string{tc.host}, target.Attributes[AttrRedisHost])
+			assert.Equal(t, tc.wantPort, target.Attributes[AttrRedisPort])
+		})
+	}
+}
+
+func TestBuildInstanceTarget_ResilienceAttributes(t *testing.T) {
+	// Given
+	info := map[string]string{
+		"maxmemory_policy": "allkeys-lru",
+		"connected_slaves": "2",
+		"aof_enabled":      "1",
+		"db0":              "keys=10,expires=8,avg_ttl=1000",
+	}
+	details := instanceDetails{settings: map[string]string{"save": "3600 1", "min-replicas-to-write": "1", "cluster-require-full-coverage": "yes"}}
+
+	// When
+	standalone := buildInstanceTarget(&config.RedisEndpoint{URL: "redis://seed"}, "10.0.0.1", "6379", info, details, "")
+	clusterNode := buildInstanceTarget(&config.RedisEndpoint{URL: "redis://seed"}, "10.0.0.1", "6379", info, details, "abc")
+
+	// Then
+	assert.Equal(t, []string{"allkeys-lru"}, standalone.Attributes[AttrRedisMemoryPolicy])
+	assert.Equal(t, []string{"2"}, standalone.Attributes[AttrRedisConnectedReplicas])
+	assert.Equal(t, []string{"rdb+aof"}, standalone.Attributes[AttrRedisPersistence])
+	assert.Equal(t, []string{"cache"}, standalone.Attributes[AttrRedisUsage])
+	assert.Equal(t, []string{"1"}, standalone.Attributes[AttrRedisMinReplicasToWrite])
+	assert.NotContains(t, standalone.Attributes, AttrRedisClusterRequireFullCoverage)
+	assert.Equal(t, []string{"yes"}, clusterNode.Attributes[AttrRedisClusterRequireFullCoverage])
+}
+
+func TestBuildInstanceTarget_DetailAttributes(t *testing.T) {
+	// Given
+	info := map[string]string{
+		"uptime_in_seconds": "3600",
+		"redis_mode":        "standalone",
+		"os":                "Linux 6.1.0-18-amd64 x86_64",
+		"maxclients":        "1000",
+	}
+	details := instanceDetails{
+		settings:      map[string]string{"maxclients": "10000"},
+		configAllowed: new(true),
+		debugAllowed:  new(false),
+		modules:       []string{"search", "ReJSON"},
+	}
+
+	// When
+	target := buildInstanceTarget(&config.RedisEndpoint{URL: "rediss://seed:6380"}, "seed", "6380", info, details, "")
+
+	// Then
+	assert.Equal(t, []string{"10000"}, target.Attributes[AttrRedisMaxClients], "CONFIG GET takes precedence over INFO")
+	assert.Equal(t, []string{"3600"}, target.Attributes[AttrRedisUptime])
+	assert.Equal(t, []string{"standalone"}, target.Attributes[AttrRedisMode])
+	assert.Equal(t, []string{"Linux 6.1.0-18-amd64 x86_64"}, target.Attributes[AttrRedisOS])
+	assert.Equal(t, []string{"x86_64"}, target.Attributes[AttrRedisArch])
+	assert.Equal(t, []string{"search", "ReJSON"}, target.Attributes[AttrRedisModules])
+	assert.Equal(t, []string{"true"}, target.Attributes[AttrRedisTLSEnabled])
+	assert.Equal(t, []string{"true"}, target.Attributes[AttrRedisConfigAllowed])
+	assert.Equal(t, []string{"false"}, target.Attributes[AttrRedisDebugAllowed])
+}
+
+func TestBuildInstanceTarget_UnknownDetails(t *testing.T) {
+	// When
+	target := buildInstanceTarget(&config.RedisEndpoint{URL: "redis://seed"}, "seed", "6379", map[string]string{"maxclients": "1000"}, instanceDetails{}, "")
+
+	// Then
+	assert.Equal(t, []string{"1000"}, target.Attributes[AttrRedisMaxClients])
+	assert.Equal(t, []string{"false"}, target.Attributes[AttrRedisTLSEnabled])
+	assert.NotContains(t, target.Attributes, AttrRedisConfigAllowed)
+	assert.NotContains(t, target.Attributes, AttrRedisDebugAllowed)
+	assert.NotContains(t, target.Attributes, AttrRedisModules)
+}
+
+func TestParseModuleList(t *testing.T) {
+	resp3 := []any{map[any]any{"name": "search", "ver": int64(20809)}, map[any]any{"name": "ReJSON", "ver": int64(20607)}}
+	resp2 := []any{[]any{"name", "search", "ver", int64(20809)}}
+
+	assert.Equal(t, []string{"search", "ReJSON"}, parseModuleList(resp3))
+	assert.Equal(t, []string{"search"}, parseModuleList(resp2))
+	assert.Empty(t, parseModuleList(nil))
+}
+
+func TestPersistenceMode(t *testing.T) {
+	tests := []struct {
+		name     string
+		info     map[string]string
+		settings map[string]string
+		expected string
+	}{
+		{name: "none", info: map[string]string{"aof_enabled": "0"}, settings: map[string]string{"save": ""}, expected: "none"},
+		{name: "rdb", info: map[string]string{"aof_enabled": "0"}, settings: map[string]string{"save": "3600 1 300 100"}, expected: "rdb"},
+		{name: "aof", info: map[string]string{"aof_enabled": "1"}, settings: map[string]string{"save": ""}, expected: "aof"},
+		{name: "rdb and aof", info: map[string]string{"aof_enabled": "1"}, settings: map[string]string{"save": "60 1000"}, expected: "rdb+aof"},
+		{name: "config denied", info: map[string]string{"aof_enabled": "1"}, settings: map[string]string{}, expected: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, persistenceMode(tt.info, tt.settings))
+		})
+	}
+}
+
+func TestKeyspaceUsage(t *testing.T) {
+	tests := []struct {
+		name     string
+		info     map[string]string
+		expected string
+	}{
+		{name: "empty", info: map[string]string{}, expected: ""},
+		{name: "cache", info: map[string]string{"db0": "keys=10,expires=10,avg_ttl=5000"}, expected: "cache"},
+		{name: "store", info: map[string]string{"db0": "keys=10,expires=1,avg_ttl=5000"}, expected: "store"},
+		{name: "summed over databases", info: map[string]string{"db0": "keys=10,expires=0,avg_ttl=0", "db1": "keys=10,expires=10,avg_ttl=0"}, expected: "cache"},
+		{name: "other fields ignored", info: map[string]string{"dbfilename": "dump.rdb", "db0": "keys=4,expires=0,avg_ttl=0"}, expected: "store"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, keyspaceUsage(tt.info))
+		})
+	}
+}
+
+func TestDiscoverInstance_Settings(t *testing.T) {
+	// Given
+	mr := miniredis.RunT(t)
+	registerConfigStub(t, mr, map[string]string{"save": "", "min-replicas-to-write": "0"})
+	endpoint := &config.RedisEndpoint{URL: fmt.Sprintf("redis://%s", mr.Addr())}
+
+	// When
+	targets, err := discoverInstance(context.Background(), endpoint)
+
+	// Then
+	require.NoError(t, err)
+	require.Len(t, targets, 1)
+	assert.Equal(t, []string{"0"}, targets[0].Attributes[AttrRedisMinReplicasToWrite])
+	assert.NotContains(t, targets[0].Attributes, AttrRedisConfigAllowed, "CONFIG GET says nothing about CONFIG SET")
+}
+
+// registerACLStub adds ACL WHOAMI and ACL DRYRUN, which miniredis lacks, answering DRYRUN with the reply per command.
+func registerACLStub(t *testing.T, mr *miniredis.Miniredis, replies map[string]string) {
+	t.Helper()
+	require.NoError(t, mr.Server().Register("ACL", func(c *server.Peer, cmd string, args []string) {
+		switch {
+		case len(args) == 1 && strings.EqualFold(args[0], "WHOAMI"):
+			c.WriteBulk("chaos")
+		case len(args) >= 3 && strings.EqualFold(args[0], "DRYRUN") && args[1] == "chaos":
+			reply, ok := replies[strings.ToUpper(args[2])]
+			if !ok {
+				c.WriteError(fmt.Sprintf("ERR Command '%s' not found", strings.ToLower(args[2])))
+				return
+			}
+			c.WriteBulk(reply)
+		default:
+			c.WriteError("ERR unsupported ACL subcommand")
+		}
+	}))
+}
+
+func TestGetInstanceDetails_CommandsAllowedByACL(t *testing.T) {
+	tests := []struct {
+		name          string
+		replies       map[string]string
+		settings      map[string]string
+		configAllowed *bool
+		debugAllowed  *bool
+	}{
+		{
+			name:          "allowed",
+			replies:       map[string]string{"CONFIG": "OK", "DEBUG": "OK"},
+			settings:      map[string]string{"enable-debug-command": "yes"},
+			configAllowed: new(true),
+			debugAllowed:  new(true),
+		},
+		{
+			name:          "denied",
+			replies:       map[string]string{"CONFIG": "User chaos has no permissions to run the 'config|set' command", "DEBUG": "User chaos has no permissions to run the 'debug' command"},
+			configAllowed: new(false),
+			debugAllowed:  new(false),
+		},
+		{
+			name:          "renamed",
+			replies:       map[string]string{"CONFIG": "OK"},
+			configAllowed: new(true),
+			debugAllowed:  new(false),
+		},
+		{
+			name:          "debug only for local connections",
+			replies:       map[string]string{"CONFIG": "OK", "DEBUG": "OK"},
+			settings:      map[string]string{"enable-debug-command": "local"},
+			configAllowed: new(true),
+			debugAllowed:  new(false),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			mr := miniredis.RunT(t)
+			registerACLStub(t, mr, tt.replies)
+			if tt.settings != nil {
+				registerConfigStub(t, mr, tt.settings)
+			}
+			client, err := createSingleConnectionClient(fmt.Sprintf("redis://%s", mr.Addr()), 0)
+			require.NoError(t, err)
+			defer client.Close()
+
+			// When
+			details := getInstanceDetails(context.Background(), client)
+
+			// Then
+			assert.Equal(t, tt.configAllowed, details.configAllowed)
+			assert.Equal(t, tt.debugAllowed, details.debugAllowed)
+		})
+	}
+}
+
+func TestGetInstanceDetails_CommandsUnknownWithoutACLDryRun(t *testing.T) {
+	// Given
+	mr := miniredis.RunT(t)
+	client, err := createSingleConnectionClient(fmt.Sprintf("redis://%s", mr.Addr()), 0)
+	require.NoError(t, err)
+	defer client.Close()
+
+	// When
+	details := getInstanceDetails(context.Background(), client)
+
+	// Then
+	assert.Nil(t, details.configAllowed)
+	assert.Nil(t, details.debugAllowed)
+}
+
+func TestDiscoverInstance_UnixSocket(t *testing.T) {
+	// Given
+	mr := miniredis.RunT(t)
+	path := serveUnixSocket(t, mr.Addr())
+	endpoint := &config.RedisEndpoint{URL: "unix://" + path}
+
+	// When
+	targets, err := discoverInstance(context.Background(), endpoint)
+
+	// Then
+	require.NoError(t, err)
+	require.Len(t, targets, 1)
+	assert.Equal(t, path, targets[0].Id)
+	assert.Equal(t, []string{path}, targets[0].Attributes[AttrRedisHost])
+	assert.NotContains(t, targets[0].Attributes, AttrRedisPort)
+	assert.Equal(t, []string{"unix://" + path}, targets[0].Attributes[AttrRedisURL])
+}
+
+func TestDiscoverInstance_IPv6(t *testing.T) {
+	// Given
+	mr := miniredis.NewMiniRedis()
+	if err := mr.StartAddr("[::1]:0"); err != nil {
+		t.Skipf("IPv6 loopback not available: %v", err)
+	}
+	defer mr.Close()
+	endpoint := &config.RedisEndpoint{URL: fmt.Sprintf("redis://%s", mr.Addr())}
+
+	// When
+	targets, err := discoverInstance(context.Background(), endpoint)
+
+	// Then
+	require.NoError(t, err)
+	require.Len(t, targets, 1)
+	assert.Equal(t, mr.Addr(), targets[0].Id)
+	assert.Equal(t, []string{"::1"}, targets[0].Attributes[AttrRedisHost])
+	assert.Equal(t, []string{mr.Port()}, targets[0].Attributes[AttrRedisPort])
+}
+
+func TestAddShardAttributes(t *testing.T) {
+	master := clients.ClusterNodeInfo{
+		ID:          "m1",
+		Role:        "master",
+		Slots:       []clients.SlotRange{{Start: 0, End: 5460}, {Start: 5462, End: 5462}},
+		ConfigEpoch: "3",
+	}
+	replica := clients.ClusterNodeInfo{ID: "r1", Role: "slave", MasterID: "m1", ConfigEpoch: "3"}
+	emptyMaster := clients.ClusterNodeInfo{ID: "m2", Role: "master", ConfigEpoch: "0"}
+	nodesByID := map[string]clients.ClusterNodeInfo{"m1": master, "r1": replica, "m2": emptyMaster}
+
+	tests := []struct {
+		name     string
+		node     clients.ClusterNodeInfo
+		shardID  string
+		expected map[string][]string
+	}{
+		{
+			name:    "master",
+			node:    master,
+			shardID: "shard-a",
+			expected: map[string][]string{
+				AttrRedisClusterSlotRanges:  {"0-5460", "5462"},
+				AttrRedisClusterSlotCount:   {"5462"},
+				AttrRedisClusterShardID:     {"shard-a"},
+				AttrRedisClusterConfigEpoch: {"3"},
+			},
+		},
+		{
+			name:    "replica gets the slots of its master",
+			node:    replica,
+			shardID: "shard-a",
+			expected: map[string][]string{
+				AttrRedisClusterMasterID:    {"m1"},
+				AttrRedisClusterSlotRanges:  {"0-5460", "5462"},
+				AttrRedisClusterSlotCount:   {"5462"},
+				AttrRedisClusterShardID:     {"shard-a"},
+				AttrRedisClusterConfigEpoch: {"3"},
+			},
+		},
+		{
+			name: "master without slots before Redis 7.2",
+			node: emptyMaster,
+			expected: map[string][]string{
+				AttrRedisClusterSlotCount:   {"0"},
+				AttrRedisClusterConfigEpoch: {"0"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			attributes := map[string][]string{}
+
+			// When
+			addShardAttributes(attributes, tt.node, nodesByID, tt.shardID)
+
+			// Then
+			assert.Equal(t, tt.expected, attributes)
+		})
+	}
+}
+
+func TestDiscoverInstance_RedactsPassword(t *testing.T) {
+	// Given
+	mr := miniredis.RunT(t)
+	mr.RequireAuth("s3cret")
+	endpoint := &config.RedisEndpoint{URL: fmt.Sprintf("redis://:s3cret@%s", mr.Addr())}
+
+	// When
+	targets, err := discoverInstance(context.Background(), endpoint)
+
+	// Then
+	require.NoError(t, err)
+	require.Len(t, targets, 1)
+	assert.Equal(t, []string{fmt.Sprintf("redis://%s", mr.Addr())}, targets[0].Attributes[AttrRedisURL])
+}
+
+func TestClusterShardIDs_MapsNodesToTheirShard(t *testing.T) {
+	// Given
+	mr := miniredis.RunT(t)
+	mr.Server().SetPreHook(func(c *server.Peer, cmd string, args ...string) bool {
+		if cmd != "CLUSTER" || len(args) == 0 || !strings.EqualFold(args[0], "SHARDS") {
+			return false
+		}
+		writeShard := func(slots []int, nodes [][2]string) {
+			c.WriteMapLen(2)
+			c.WriteBulk("slots")
+			c.WriteLen(len(slots))
+			for _, slot := range slots {
+				c.WriteInt(slot)
+			}
+			c.WriteBulk("nodes")
+			c.WriteLen(len(nodes))
+			for _, node := range nodes {
+				c.WriteMapLen(2)
+				c.WriteBulk("id")
+				c.WriteBulk(node[0])
+				c.WriteBulk("role")
+				c.WriteBulk(node[1])
+			}
+		}
+		c.WriteLen(3)
+		writeShard([]int{0, 8191}, [][2]string{{"r1", "replica"}, {"m1", "master"}})
+		writeShard([]int{8192, 16383}, [][2]string{{"m2", "master"}})
+		// A shard without master, e.g. while a failover is pending
+		writeShard(nil, [][2]string{{"r3", "replica"}})
+		return true
+	})
+	client, err := createSingleConnectionClient(fmt.Sprintf("redis://%s", mr.Addr()), 0)
+	require.NoError(t, err)
+	defer client.Close()
+
+	// When
+	shardIDs := clusterShardIDs(context.Background(), client)
+
+	// Then
+	assert.Equal(t, map[string]string{"m1": "m1", "r1": "m1", "m2": "m2"}, shardIDs)
+}
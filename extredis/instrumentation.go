@@ -0,0 +1,234 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package extredis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/steadybit/action-kit/go/action_kit_api/v2"
+	"github.com/steadybit/action-kit/go/action_kit_sdk"
+	"github.com/steadybit/extension-redis/metrics"
+	"github.com/steadybit/extension-redis/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentedState is the state of an instrumented action, the state of the action itself plus the execution and
+// target it belongs to.
+type InstrumentedState[T any] struct {
+	ExecutionID uuid.UUID `json:"executionId"`
+	Target      string    `json:"target,omitempty"`
+	State       T         `json:"state"`
+}
+
+// UnmarshalJSON also accepts the flat state of executions prepared before the actions were instrumented, so that
+// they can still be stopped after an upgrade.
+func (s *InstrumentedState[T]) UnmarshalJSON(data []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	if _, ok := fields["state"]; !ok {
+		*s = InstrumentedState[T]{}
+		return json.Unmarshal(data, &s.State)
+	}
+	var wrapped struct {
+		ExecutionID uuid.UUID `json:"executionId"`
+		Target      string    `json:"target,omitempty"`
+		State       T         `json:"state"`
+	}
+	if err := json.Unmarshal(data, &wrapped); err != nil {
+		return err
+	}
+	*s = InstrumentedState[T](wrapped)
+	return nil
+}
+
+// activeExecutions stores the action ID per execution ID of the attacks counted in metrics.ActiveAttacks.
+var activeExecutions sync.Map
+
+// Instrument wraps an action to record its lifecycle in the metrics and traces. Attacks are active from a successful
+// Start until Stop, or until Status reports completion for attacks without Stop. Every call gets a span in the trace
+// of its execution, the Redis commands of the call are its children. The returned action implements
+// ActionWithStatus and ActionWithStop exactly if the wrapped one does, the SDK checks for them.
+func Instrument[T any](action action_kit_sdk.Action[T]) action_kit_sdk.Action[InstrumentedState[T]] {
+	description := action.Describe()
+	base := &instrumentedAction[T]{
+		action:   action,
+		actionID: description.Id,
+		attack:   description.Kind == action_kit_api.Attack,
+	}
+	status, hasStatus := action.(action_kit_sdk.ActionWithStatus[T])
+	stop, hasStop := action.(action_kit_sdk.ActionWithStop[T])
+	base.hasStop = hasStop
+	switch {
+	case hasStatus && hasStop:
+		return &instrumentedActionWithStatusAndStop[T]{
+			instrumentedActionWithStatus: &instrumentedActionWithStatus[T]{instrumentedAction: base, status: status},
+			instrumentedActionWithStop:   &instrumentedActionWithStop[T]{instrumentedAction: base, stop: stop},
+		}
+	case hasStatus:
+		return &instrumentedActionWithStatus[T]{instrumentedAction: base, status: status}
+	case hasStop:
+		return &instrumentedActionWithStop[T]{instrumentedAction: base, stop: stop}
+	default:
+		return base
+	}
+}
+
+type instrumentedAction[T any] struct {
+	action   action_kit_sdk.Action[T]
+	actionID string
+	attack   bool
+	hasStop  bool
+}
+
+type instrumentedActionWithStatus[T any] struct {
+	*instrumentedAction[T]
+	status action_kit_sdk.ActionWithStatus[T]
+}
+
+type instrumentedActionWithStop[T any] struct {
+	*instrumentedAction[T]
+	stop action_kit_sdk.ActionWithStop[T]
+}
+
+type instrumentedActionWithStatusAndStop[T any] struct {
+	*instrumentedActionWithStatus[T]
+	*instrumentedActionWithStop[T]
+}
+
+func (a *instrumentedAction[T]) NewEmptyState() InstrumentedState[T] {
+	return InstrumentedState[T]{State: a.action.NewEmptyState()}
+}
+
+func (a *instrumentedAction[T]) Describe() action_kit_api.ActionDescription {
+	return a.action.Describe()
+}
+
+func (a *instrumentedAction[T]) Prepare(ctx context.Context, state *InstrumentedState[T], request action_kit_api.PrepareActionRequestBody) (*action_kit_api.PrepareResult, error) {
+	state.ExecutionID = request.ExecutionId
+	if request.Target != nil {
+		state.Target = request.Target.Name
+	}
+	ctx, span := a.startSpan(ctx, "prepare", state)
+	result, err := a.action.Prepare(ctx, &state.State, request)
+	spanErr := err
+	if spanErr == nil && result != nil {
+		spanErr = actionKitError(result.Error)
+	}
+	tracing.End(span, spanErr)
+	return result, err
+}
+
+func (a *instrumentedAction[T]) Start(ctx context.Context, state *InstrumentedState[T]) (*action_kit_api.StartResult, error) {
+	ctx, span := a.startSpan(ctx, "start", state)
+	result, err := a.action.Start(ctx, &state.State)
+	if err != nil {
+		if errors.Is(err, errRollbackFailed) {
+			metrics.RollbackFailures.WithLabelValues(a.actionID).Inc()
+		}
+		tracing.End(span, err)
+		return result, err
+	}
+	a.started(state.ExecutionID)
+	var resultErr error
+	if result != nil {
+		resultErr = actionKitError(result.Error)
+	}
+	tracing.End(span, resultErr)
+	return result, nil
+}
+
+func (a *instrumentedActionWithStatus[T]) Status(ctx context.Context, state *InstrumentedState[T]) (*action_kit_api.StatusResult, error) {
+	ctx, span := a.startSpan(ctx, "status", state)
+	result, err := a.status.Status(ctx, &state.State)
+	if !a.hasStop && (err != nil || (result != nil && (result.Completed || result.Error != nil))) {
+		a.ended(state.ExecutionID)
+	}
+	spanErr := err
+	if spanErr == nil && result != nil {
+		span.SetAttributes(attribute.Bool("steadybit.status.completed", result.Completed))
+		spanErr = actionKitError(result.Error)
+	}
+	tracing.End(span, spanErr)
+	return result, err
+}
+
+func (a *instrumentedActionWithStop[T]) Stop(ctx context.Context, state *InstrumentedState[T]) (*action_kit_api.StopResult, error) {
+	ctx, span := a.startSpan(ctx, "stop", state)
+	result, err := a.stop.Stop(ctx, &state.State)
+	a.ended(state.ExecutionID)
+	spanErr := err
+	if spanErr == nil && result != nil {
+		spanErr = actionKitError(result.Error)
+	}
+	if a.attack && spanErr != nil {
+		metrics.RollbackFailures.WithLabelValues(a.actionID).Inc()
+	}
+	tracing.End(span, spanErr)
+	return result, err
+}
+
+// The embedded actions share instrumentedAction, the methods are promoted from it explicitly to resolve the
+// ambiguity.
+
+func (a *instrumentedActionWithStatusAndStop[T]) NewEmptyState() InstrumentedState[T] {
+	return a.instrumentedActionWithStatus.NewEmptyState()
+}
+
+func (a *instrumentedActionWithStatusAndStop[T]) Describe() action_kit_api.ActionDescription {
+	return a.instrumentedActionWithStatus.Describe()
+}
+
+func (a *instrumentedActionWithStatusAndStop[T]) Prepare(ctx context.Context, state *InstrumentedState[T], request action_kit_api.PrepareActionRequestBody) (*action_kit_api.PrepareResult, error) {
+	return a.instrumentedActionWithStatus.Prepare(ctx, state, request)
+}
+
+func (a *instrumentedActionWithStatusAndStop[T]) Start(ctx context.Context, state *InstrumentedState[T]) (*action_kit_api.StartResult, error) {
+	return a.instrumentedActionWithStatus.Start(ctx, state)
+}
+
+// startSpan starts the span of a call in the trace of the execution.
+func (a *instrumentedAction[T]) startSpan(ctx context.Context, call string, state *InstrumentedState[T]) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(tracing.ExecutionContext(ctx, state.ExecutionID), call+" "+a.actionID,
+		trace.WithAttributes(
+			tracing.AttrActionID.String(a.actionID),
+			tracing.AttrExecutionID.String(state.ExecutionID.String()),
+			tracing.AttrTarget.String(state.Target),
+		))
+}
+
+// actionKitError returns the error reported in a result as an error, nil if there is none.
+func actionKitError(err *action_kit_api.ActionKitError) error {
+	if err == nil {
+		return nil
+	}
+	if err.Detail != nil {
+		return fmt.Errorf("%s: %s", err.Title, *err.Detail)
+	}
+	return errors.New(err.Title)
+}
+
+func (a *instrumentedAction[T]) started(executionID uuid.UUID) {
+	if !a.attack {
+		return
+	}
+	if _, loaded := activeExecutions.LoadOrStore(executionID, a.actionID); !loaded {
+		metrics.ActiveAttacks.WithLabelValues(a.actionID).Inc()
+	}
+}
+
+// ended is a no-op for executions that were not counted, e.g. after a failed Start or a restart of the extension.
+func (a *instrumentedAction[T]) ended(executionID uuid.UUID) {
+	if _, loaded := activeExecutions.LoadAndDelete(executionID); loaded {
+		metrics.ActiveAttacks.WithLabelValues(a.actionID).Dec()
+	}
+}
@@ -0,0 +1,297 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2024 Steadybit GmbH
+
+package extredis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/steadybit/action-kit/go/action_kit_api/v2"
+	"github.com/steadybit/action-kit/go/action_kit_sdk"
+	"github.com/steadybit/extension-redis/metrics"
+	"github.com/steadybit/extension-redis/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type fakeState struct {
+	Value string `json:"value"`
+}
+
+type fakeAction struct {
+	id       string
+	kind     action_kit_api.ActionKind
+	startErr error
+	stopErr  error
+	stopped  int
+}
+
+func (a *fakeAction) NewEmptyState() fakeState { return fakeState{} }
+
+func (a *fakeAction) Describe() action_kit_api.ActionDescription {
+	return action_kit_api.ActionDescription{Id: a.id, Kind: a.kind}
+}
+
+func (a *fakeAction) Prepare(_ context.Context, state *fakeState, _ action_kit_api.PrepareActionRequestBody) (*action_kit_api.PrepareResult, error) {
+	state.Value = "prepared"
+	return nil, nil
+}
+
+func (a *fakeAction) Start(_ context.Context, _ *fakeState) (*action_kit_api.StartResult, error) {
+	return nil, a.startErr
+}
+
+type fakeActionWithStatus struct {
+	*fakeAction
+	completed bool
+}
+
+func (a *fakeActionWithStatus) Status(_ context.Context, _ *fakeState) (*action_kit_api.StatusResult, error) {
+	return &action_kit_api.StatusResult{Completed: a.completed}, nil
+}
+
+type fakeActionWithStatusAndStop struct {
+	*fakeActionWithStatus
+}
+
+func (a *fakeActionWithStatusAndStop) Stop(_ context.Context, state *fakeState) (*action_kit_api.StopResult, error) {
+	a.stopped++
+	if state.Value != "prepared" {
+		return nil, fmt.Errorf("unexpected state %q", state.Value)
+	}
+	return nil, a.stopErr
+}
+
+func fakeAttack(id string) *fakeActionWithStatusAndStop {
+	return &fakeActionWithStatusAndStop{&fakeActionWithStatus{fakeAction: &fakeAction{id: id, kind: action_kit_api.Attack}}}
+}
+
+func prepareInstrumented[T any](t *testing.T, action action_kit_sdk.Action[InstrumentedState[T]]) *InstrumentedState[T] {
+	t.Helper()
+	state := action.NewEmptyState()
+	_, err := action.Prepare(context.Background(), &state, action_kit_api.PrepareActionRequestBody{ExecutionId: uuid.New()})
+	require.NoError(t, err)
+	return &state
+}
+
+func TestInstrumentedState_Unmarshal(t *testing.T) {
+	executionID := uuid.New()
+	tests := []struct {
+		name            string
+		data            string
+		wantExecutionID uuid.UUID
+	}{
+		{"instrumented", fmt.Sprintf(`{"executionId":"%s","target":"redis","state":{"value":"prepared"}}`, executionID), executionID},
+		{"flat state of an execution prepared before the upgrade", `{"value":"prepared"}`, uuid.Nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			attack := fakeAttack("com.steadybit.test.legacy-state")
+			action := Instrument[fakeState](attack).(action_kit_sdk.ActionWithStop[InstrumentedState[fakeState]])
+			var state InstrumentedState[fakeState]
+
+			// When
+			require.NoError(t, json.Unmarshal([]byte(tt.data), &state))
+			_, err := action.Stop(context.Background(), &state)
+
+			// Then
+			require.NoError(t, err)
+			assert.Equal(t, "prepared", state.State.Value)
+			assert.Equal(t, tt.wantExecutionID, state.ExecutionID)
+			assert.Equal(t, 1, attack.stopped)
+		})
+	}
+}
+
+func TestInstrument_KeepsOptionalInterfaces(t *testing.T) {
+	base := &fakeAction{id: "instrument.base", kind: action_kit_api.Check}
+	withStatus := &fakeActionWithStatus{fakeAction: base}
+
+	tests := []struct {
+		name       string
+		action     action_kit_sdk.Action[InstrumentedState[fakeState]]
+		wantStatus bool
+		wantStop   bool
+	}{
+		{name: "base", action: Instrument[fakeState](base)},
+		{name: "status", action: Instrument[fakeState](withStatus), wantStatus: true},
+		{name: "status and stop", action: Instrument[fakeState](fakeAttack("instrument.attack")), wantStatus: true, wantStop: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, hasStatus := tt.action.(action_kit_sdk.ActionWithStatus[InstrumentedState[fakeState]])
+			_, hasStop := tt.action.(action_kit_sdk.ActionWithStop[InstrumentedState[fakeState]])
+			assert.Equal(t, tt.wantStatus, hasStatus)
+			assert.Equal(t, tt.wantStop, hasStop)
+		})
+	}
+}
+
+func TestInstrument_CountsActiveAttacks(t *testing.T) {
+	// Given
+	attack := fakeAttack("instrument.active")
+	action := Instrument[fakeState](attack).(action_kit_sdk.ActionWithStop[InstrumentedState[fakeState]])
+	state := prepareInstrumented(t, action)
+	gauge := metrics.ActiveAttacks.WithLabelValues("instrument.active")
+
+	// When
+	_, err := action.Start(context.Background(), state)
+	require.NoError(t, err)
+
+	// Then
+	assert.Equal(t, float64(1), testutil.ToFloat64(gauge))
+
+	// When - the state went through the SDK state persistence
+	raw, err := json.Marshal(state)
+	require.NoError(t, err)
+	var restored InstrumentedState[fakeState]
+	require.NoError(t, json.Unmarshal(raw, &restored))
+	_, err = action.Stop(context.Background(), &restored)
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, 1, attack.stopped)
+	assert.Equal(t, float64(0), testutil.ToFloat64(gauge))
+}
+
+func TestInstrument_StopWithoutStartIsNotCounted(t *testing.T) {
+	// Given - Start failed, or the extension restarted after Start
+	attack := fakeAttack("instrument.not-started")
+	attack.startErr = assert.AnError
+	action := Instrument[fakeState](attack).(action_kit_sdk.ActionWithStop[InstrumentedState[fakeState]])
+	state := prepareInstrumented(t, action)
+	_, err := action.Start(context.Background(), state)
+	require.Error(t, err)
+
+	// When
+	_, err = action.Stop(context.Background(), state)
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, float64(0), testutil.ToFloat64(metrics.ActiveAttacks.WithLabelValues("instrument.not-started")))
+	assert.Equal(t, float64(0), testutil.ToFloat64(metrics.RollbackFailures.WithLabelValues("instrument.not-started")))
+}
+
+func TestInstrument_CountsRollbackFailures(t *testing.T) {
+	// Given
+	attack := fakeAttack("instrument.rollback")
+	action := Instrument[fakeState](attack).(action_kit_sdk.ActionWithStop[InstrumentedState[fakeState]])
+	failures := metrics.RollbackFailures.WithLabelValues("instrument.rollback")
+
+	// When - Stop fails
+	attack.stopErr = assert.AnError
+	state := prepareInstrumented(t, action)
+	_, err := action.Start(context.Background(), state)
+	require.NoError(t, err)
+	_, err = action.Stop(context.Background(), state)
+
+	// Then
+	require.Error(t, err)
+	assert.Equal(t, float64(1), testutil.ToFloat64(failures))
+	assert.Equal(t, float64(0), testutil.ToFloat64(metrics.ActiveAttacks.WithLabelValues("instrument.rollback")))
+
+	// When - Start fails and cannot roll back its partial changes
+	attack.startErr = fmt.Errorf("set ttl: %w; %w", assert.AnError, errRollbackFailed)
+	_, err = action.Start(context.Background(), prepareInstrumented(t, action))
+
+	// Then
+	require.Error(t, err)
+	assert.Equal(t, float64(2), testutil.ToFloat64(failures))
+}
+
+func TestInstrument_AttackWithoutStopEndsWhenCompleted(t *testing.T) {
+	// Given
+	attack := &fakeActionWithStatus{fakeAction: &fakeAction{id: "instrument.status-only", kind: action_kit_api.Attack}}
+	action := Instrument[fakeState](attack).(action_kit_sdk.ActionWithStatus[InstrumentedState[fakeState]])
+	state := prepareInstrumented(t, action)
+	gauge := metrics.ActiveAttacks.WithLabelValues("instrument.status-only")
+	_, err := action.Start(context.Background(), state)
+	require.NoError(t, err)
+
+	// When
+	_, err = action.Status(context.Background(), state)
+	require.NoError(t, err)
+
+	// Then
+	assert.Equal(t, float64(1), testutil.ToFloat64(gauge))
+
+	// When
+	attack.completed = true
+	_, err = action.Status(context.Background(), state)
+	require.NoError(t, err)
+
+	// Then
+	assert.Equal(t, float64(0), testutil.ToFloat64(gauge))
+}
+
+func TestInstrument_ChecksAreNotCounted(t *testing.T) {
+	// Given
+	check := &fakeActionWithStatus{fakeAction: &fakeAction{id: "instrument.check", kind: action_kit_api.Check}}
+	action := Instrument[fakeState](check)
+	state := prepareInstrumented(t, action)
+
+	// When
+	_, err := action.Start(context.Background(), state)
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, float64(0), testutil.ToFloat64(metrics.ActiveAttacks.WithLabelValues("instrument.check")))
+}
+
+func useSpanRecorder(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	orig := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(orig) })
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	return recorder
+}
+
+func TestInstrument_SpansPerCall(t *testing.T) {
+	// Given
+	recorder := useSpanRecorder(t)
+	attack := fakeAttack("instrument.spans")
+	attack.stopErr = assert.AnError
+	action := Instrument[fakeState](attack).(action_kit_sdk.ActionWithStop[InstrumentedState[fakeState]])
+	executionID := uuid.New()
+	state := action.NewEmptyState()
+
+	// When
+	_, err := action.Prepare(context.Background(), &state, action_kit_api.PrepareActionRequestBody{
+		ExecutionId: executionID,
+		Target:      &action_kit_api.Target{Name: "redis-1:6379"},
+	})
+	require.NoError(t, err)
+	_, err = action.Start(context.Background(), &state)
+	require.NoError(t, err)
+	_, err = action.Stop(context.Background(), &state)
+	require.Error(t, err)
+
+	// Then
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+	for i, call := range []string{"prepare", "start", "stop"} {
+		span := spans[i]
+		assert.Equal(t, call+" instrument.spans", span.Name())
+		assert.Equal(t, trace.TraceID(executionID), span.SpanContext().TraceID())
+		assert.Subset(t, span.Attributes(), []attribute.KeyValue{
+			tracing.AttrActionID.String("instrument.spans"),
+			tracing.AttrExecutionID.String(executionID.String()),
+			tracing.AttrTarget.String("redis-1:6379"),
+		})
+	}
+	assert.Equal(t, codes.Unset, spans[1].Status().Code)
+	assert.Equal(t, codes.Error, spans[2].Status().Code)
+}
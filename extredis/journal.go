@@ -0,0 +1,110 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package extredis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/steadybit/action-kit/go/action_kit_api/v2"
+	"github.com/steadybit/action-kit/go/action_kit_sdk"
+	"github.com/steadybit/extension-kit/extutil"
+	"github.com/steadybit/extension-redis/journal"
+	"github.com/steadybit/extension-redis/metrics"
+)
+
+// errRollbackFailed marks Start errors after which changes may be left behind, their journal entry is kept.
+var errRollbackFailed = errors.New("rolling back the other masters failed")
+
+// RecoverFromJournal rolls back the attacks that were still active when the extension stopped, using the Stop of
+// the attack with the journaled state. It must run before actions are served.
+func RecoverFromJournal(ctx context.Context) {
+	if !journal.Enabled() {
+		return
+	}
+	recoveries := journal.Replay(ctx, journalRecoverers())
+	failed := 0
+	for _, r := range recoveries {
+		if r.Err != nil {
+			failed++
+			metrics.RollbackFailures.WithLabelValues(r.ActionID).Inc()
+		}
+	}
+	if len(recoveries) > 0 {
+		log.Info().Int("recovered", len(recoveries)-failed).Int("failed", failed).Msg("Replayed attack journal")
+	}
+}
+
+func journalRecoverers() map[string]journal.RecoverFunc {
+	recoverers := make(map[string]journal.RecoverFunc)
+	addStopRecoverer[MaxmemoryLimitState](recoverers, &maxmemoryLimitAttack{})
+	addStopRecoverer[ClientPauseState](recoverers, &clientPauseAttack{})
+	addStopRecoverer[CacheExpirationState](recoverers, &cacheExpirationAttack{})
+	addStopRecoverer[StreamConsumerGroupState](recoverers, &streamConsumerGroupAttack{})
+	addStopRecoverer[OutputBufferLimitState](recoverers, &outputBufferLimitAttack{})
+	addStopRecoverer[SlotMigrationState](recoverers, &slotMigrationAttack{})
+	return recoverers
+}
+
+func addStopRecoverer[T any](recoverers map[string]journal.RecoverFunc, action action_kit_sdk.ActionWithStop[T]) {
+	recoverers[action.Describe().Id] = func(ctx context.Context, entry journal.Entry) error {
+		var state T
+		if err := json.Unmarshal(entry.State, &state); err != nil {
+			return fmt.Errorf("unmarshal journaled state: %w", err)
+		}
+		_, err := action.Stop(ctx, &state)
+		return err
+	}
+}
+
+// recordJournal saves state before the attack changes anything it has to roll back.
+func recordJournal(actionID, executionID, redisURL string, state any) error {
+	if err := journal.Record(actionID, executionID, redisURL, state); err != nil {
+		return fmt.Errorf("failed to record attack in journal: %w", err)
+	}
+	return nil
+}
+
+// forgetFailedStart removes the journal entry of a failed Start, unless err reports changes that could not be
+// rolled back. Those are rolled back by the journal after a restart.
+func forgetFailedStart(executionID string, err error) {
+	if !errors.Is(err, errRollbackFailed) {
+		journal.Remove(executionID)
+	}
+}
+
+// recoveredByJournal reports whether the journal already rolled back the execution after a restart, so that Stop
+// must not roll it back again over newer changes.
+func recoveredByJournal(executionID string) bool {
+	recovery, ok := journal.GetRecovery(executionID)
+	return ok && recovery.Err == nil
+}
+
+// journalRecoveryMessages reports a rollback of the execution by the journal for Status and Stop.
+func journalRecoveryMessages(executionID string) []action_kit_api.Message {
+	recovery, ok := journal.GetRecovery(executionID)
+	if !ok {
+		return nil
+	}
+	if recovery.Err != nil {
+		return []action_kit_api.Message{{
+			Level:   extutil.Ptr(action_kit_api.Error),
+			Message: fmt.Sprintf("The extension restarted during the attack and failed to roll it back at %s: %v", recovery.RecoveredAt.Format(time.RFC3339), recovery.Err),
+		}}
+	}
+	return []action_kit_api.Message{{
+		Level:   extutil.Ptr(action_kit_api.Warn),
+		Message: fmt.Sprintf("The extension restarted during the attack, its changes were rolled back at %s", recovery.RecoveredAt.Format(time.RFC3339)),
+	}}
+}
+
+// journalRecoveredStopResult is returned by Stop for executions the journal already rolled back.
+func journalRecoveredStopResult(executionID string) *action_kit_api.StopResult {
+	return &action_kit_api.StopResult{Messages: new(journalRecoveryMessages(executionID))}
+}
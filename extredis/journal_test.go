@@ -0,0 +1,184 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2024 Steadybit GmbH
+
+package extredis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/steadybit/action-kit/go/action_kit_api/v2"
+	"github.com/steadybit/extension-redis/clients"
+	"github.com/steadybit/extension-redis/config"
+	"github.com/steadybit/extension-redis/journal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJournalRecoverers(t *testing.T) {
+	// When
+	recoverers := journalRecoverers()
+
+	// Then
+	assert.Len(t, recoverers, 6)
+	for _, id := range []string{
+		maxmemoryLimitActionID,
+		clientPauseActionID,
+		cacheExpirationActionID,
+		streamConsumerGroupActionID,
+		outputBufferLimitActionID,
+		slotMigrationActionID,
+	} {
+		assert.Contains(t, recoverers, id)
+	}
+}
+
+func TestForgetFailedStart(t *testing.T) {
+	orig := config.Config.JournalDir
+	defer func() { config.Config.JournalDir = orig }()
+	config.Config.JournalDir = t.TempDir()
+
+	tests := []struct {
+		name     string
+		err      error
+		wantKept bool
+	}{
+		{name: "rolled back", err: errors.New("CONFIG SET failed"), wantKept: false},
+		{name: "rollback failed", err: fmt.Errorf("CONFIG SET failed; %w: timeout", errRollbackFailed), wantKept: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			executionID := uuid.New().String()
+			require.NoError(t, journal.Record(maxmemoryLimitActionID, executionID, "redis://localhost:6379", MaxmemoryLimitState{}))
+
+			// When
+			forgetFailedStart(executionID, tt.err)
+
+			// Then
+			entries, err := journal.Pending()
+			require.NoError(t, err)
+			kept := false
+			for _, e := range entries {
+				kept = kept || e.ExecutionID == executionID
+			}
+			assert.Equal(t, tt.wantKept, kept)
+		})
+	}
+}
+
+func TestRecoverFromJournal_CacheExpiration(t *testing.T) {
+	// Given
+	orig := config.Config.JournalDir
+	defer func() { config.Config.JournalDir = orig }()
+	config.Config.JournalDir = t.TempDir()
+
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	mr.Set("session:1", "alice")
+	mr.Set("session:2", "bob")
+
+	action := &cacheExpirationAttack{}
+	state := CacheExpirationState{
+		RedisURL:       fmt.Sprintf("redis://%s", mr.Addr()),
+		ExecutionID:    uuid.New().String(),
+		Pattern:        "session:*",
+		TTLSeconds:     1,
+		AffectedKeys:   []string{},
+		MatchedKeys:    []string{"session:1", "session:2"},
+		BackupData:     make(map[string]KeyBackup),
+		RestoreOnStop:  true,
+		EndTime:        time.Now().Add(60 * time.Second).Unix(),
+		MaxBackupBytes: 1024 * 1024,
+	}
+	_, err = action.Start(context.Background(), &state)
+	require.NoError(t, err)
+	entries, err := journal.Pending()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, cacheExpirationActionID, entries[0].ActionID)
+
+	// The extension crashes, the keys expire
+	mr.FastForward(2 * time.Second)
+	require.False(t, mr.Exists("session:1"))
+	unlockKeys(state.MatchedKeys)
+
+	// When
+	RecoverFromJournal(context.Background())
+
+	// Then
+	val, err := mr.Get("session:1")
+	require.NoError(t, err)
+	assert.Equal(t, "alice", val)
+	val, err = mr.Get("session:2")
+	require.NoError(t, err)
+	assert.Equal(t, "bob", val)
+	entries, err = journal.Pending()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	status, err := action.Status(context.Background(), &state)
+	require.NoError(t, err)
+	require.NotNil(t, status.Messages)
+	last := (*status.Messages)[len(*status.Messages)-1]
+	assert.Equal(t, action_kit_api.Warn, *last.Level)
+	assert.Contains(t, last.Message, "rolled back")
+
+	// Stop must not restore a second time
+	mr.Set("session:1", "changed-after-recovery")
+	stopResult, err := action.Stop(context.Background(), &state)
+	require.NoError(t, err)
+	require.NotNil(t, stopResult.Messages)
+	assert.Contains(t, (*stopResult.Messages)[0].Message, "rolled back")
+	val, err = mr.Get("session:1")
+	require.NoError(t, err)
+	assert.Equal(t, "changed-after-recovery", val)
+}
+
+func TestRecoverFromJournal_CacheExpiration_RestoreFails(t *testing.T) {
+	// Given
+	orig := config.Config.JournalDir
+	defer func() { config.Config.JournalDir = orig }()
+	config.Config.JournalDir = t.TempDir()
+
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	mr.Set("session:1", "alice")
+
+	action := &cacheExpirationAttack{}
+	state := CacheExpirationState{
+		RedisURL:       fmt.Sprintf("redis://%s", mr.Addr()),
+		ExecutionID:    uuid.New().String(),
+		Pattern:        "session:*",
+		TTLSeconds:     1,
+		AffectedKeys:   []string{},
+		MatchedKeys:    []string{"session:1"},
+		BackupData:     make(map[string]KeyBackup),
+		RestoreOnStop:  true,
+		EndTime:        time.Now().Add(60 * time.Second).Unix(),
+		MaxBackupBytes: 1024 * 1024,
+	}
+	_, err = action.Start(context.Background(), &state)
+	require.NoError(t, err)
+	defer clients.EvictClients(state.RedisURL)
+
+	// The extension crashes and Redis is unreachable on restart
+	mr.Close()
+	unlockKeys(state.MatchedKeys)
+
+	// When
+	RecoverFromJournal(context.Background())
+
+	// Then - the execution is not reported as recovered and its entry is kept for the next start
+	assert.False(t, recoveredByJournal(state.ExecutionID))
+	entries, err := journal.Pending()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, state.ExecutionID, entries[0].ExecutionID)
+}
@@ -0,0 +1,209 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+// Package journal persists the rollback data of running attacks, so that attacks left behind by a crash or
+// restart of the extension can be rolled back when it starts again.
+package journal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/steadybit/extension-redis/config"
+)
+
+const (
+	entrySuffix     = ".json"
+	recoveryTimeout = 30 * time.Second
+)
+
+// Entry is a pending mutation of an attack execution. State is the action state needed to roll it back.
+type Entry struct {
+	ExecutionID string          `json:"executionId"`
+	ActionID    string          `json:"actionId"`
+	RedisURL    string          `json:"redisUrl"`
+	RecordedAt  time.Time       `json:"recordedAt"`
+	State       json.RawMessage `json:"state"`
+}
+
+// Recovery is the outcome of rolling back an entry that was left in the journal.
+type Recovery struct {
+	ExecutionID string
+	ActionID    string
+	RecoveredAt time.Time
+	// Err is set if the rollback failed, the entry then stays in the journal
+	Err error
+}
+
+// RecoverFunc rolls back the mutation recorded in entry.
+type RecoverFunc func(ctx context.Context, entry Entry) error
+
+// recoveries stores a Recovery per execution ID.
+var recoveries sync.Map
+
+// Enabled reports whether STEADYBIT_EXTENSION_JOURNAL_DIR is set.
+func Enabled() bool {
+	return config.Config.JournalDir != ""
+}
+
+// Record writes the state of an execution to the journal, replacing an earlier entry of the same execution.
+// It must be called before the mutation is applied. The entry is written atomically, a crash leaves either the
+// old or the new entry. Without a journal directory it does nothing.
+func Record(actionID, executionID, redisURL string, state any) error {
+	if !Enabled() {
+		return nil
+	}
+	path, err := entryPath(executionID)
+	if err != nil {
+		return err
+	}
+	raw, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("marshal journal state: %w", err)
+	}
+	data, err := json.Marshal(Entry{
+		ExecutionID: executionID,
+		ActionID:    actionID,
+		RedisURL:    redisURL,
+		RecordedAt:  time.Now(),
+		State:       raw,
+	})
+	if err != nil {
+		return fmt.Errorf("marshal journal entry: %w", err)
+	}
+
+	dir := config.Config.JournalDir
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("create journal directory: %w", err)
+	}
+	// The entry holds key backups, so it is only readable by the extension
+	tmp, err := os.CreateTemp(dir, ".entry-*")
+	if err != nil {
+		return fmt.Errorf("create journal entry: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("write journal entry: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("sync journal entry: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close journal entry: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("write journal entry: %w", err)
+	}
+	return nil
+}
+
+// Remove deletes the entry of an execution once its mutation was rolled back.
+func Remove(executionID string) {
+	if !Enabled() {
+		return
+	}
+	path, err := entryPath(executionID)
+	if err != nil {
+		return
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Warn().Err(err).Str("executionId", executionID).Msg("Failed to remove journal entry")
+	}
+}
+
+// Pending returns the entries in the journal, oldest first. Unreadable entries are skipped.
+func Pending() ([]Entry, error) {
+	if !Enabled() {
+		return nil, nil
+	}
+	files, err := os.ReadDir(config.Config.JournalDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read journal directory: %w", err)
+	}
+
+	var entries []Entry
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), entrySuffix) {
+			continue
+		}
+		path := filepath.Join(config.Config.JournalDir, file.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Warn().Err(err).Str("path", path).Msg("Failed to read journal entry")
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			log.Warn().Err(err).Str("path", path).Msg("Skipping corrupt journal entry")
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	slices.SortFunc(entries, func(a, b Entry) int { return a.RecordedAt.Compare(b.RecordedAt) })
+	return entries, nil
+}
+
+// Replay rolls back all pending entries with the recoverer registered for their action. Rolled back entries are
+// removed, failed ones stay for the next start. The outcome is kept for GetRecovery.
+func Replay(ctx context.Context, recoverers map[string]RecoverFunc) []Recovery {
+	entries, err := Pending()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to read attack journal")
+		return nil
+	}
+
+	var result []Recovery
+	for _, entry := range entries {
+		recoverEntry, ok := recoverers[entry.ActionID]
+		if !ok {
+			log.Warn().Str("executionId", entry.ExecutionID).Str("actionId", entry.ActionID).Msg("No recovery for journaled action, keeping the entry")
+			continue
+		}
+
+		log.Info().Str("executionId", entry.ExecutionID).Str("actionId", entry.ActionID).Time("recordedAt", entry.RecordedAt).Msg("Rolling back attack left behind by a previous run")
+		recoverCtx, cancel := context.WithTimeout(ctx, recoveryTimeout)
+		err := recoverEntry(recoverCtx, entry)
+		cancel()
+
+		recovery := Recovery{ExecutionID: entry.ExecutionID, ActionID: entry.ActionID, RecoveredAt: time.Now(), Err: err}
+		if err != nil {
+			log.Error().Err(err).Str("executionId", entry.ExecutionID).Str("actionId", entry.ActionID).Msg("Failed to roll back journaled attack")
+		} else {
+			Remove(entry.ExecutionID)
+		}
+		recoveries.Store(entry.ExecutionID, recovery)
+		result = append(result, recovery)
+	}
+	return result
+}
+
+// GetRecovery returns the recovery of an execution that was rolled back by Replay.
+func GetRecovery(executionID string) (Recovery, bool) {
+	v, ok := recoveries.Load(executionID)
+	if !ok {
+		return Recovery{}, false
+	}
+	return v.(Recovery), true
+}
+
+func entryPath(executionID string) (string, error) {
+	if executionID == "" || strings.ContainsAny(executionID, `/\`) || strings.HasPrefix(executionID, ".") {
+		return "", fmt.Errorf("invalid execution ID %q for the journal", executionID)
+	}
+	return filepath.Join(config.Config.JournalDir, executionID+entrySuffix), nil
+}
@@ -0,0 +1,151 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2024 Steadybit GmbH
+
+package journal
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/steadybit/extension-redis/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testState struct {
+	Original string `json:"original"`
+}
+
+func useJournalDir(t *testing.T, dir string) {
+	t.Helper()
+	orig := config.Config.JournalDir
+	t.Cleanup(func() { config.Config.JournalDir = orig })
+	config.Config.JournalDir = dir
+}
+
+func TestRecord_Disabled(t *testing.T) {
+	// Given
+	useJournalDir(t, "")
+
+	// When
+	err := Record("action", "exec-1", "redis://localhost:6379", testState{Original: "0"})
+	entries, pendingErr := Pending()
+
+	// Then
+	assert.NoError(t, err)
+	assert.NoError(t, pendingErr)
+	assert.Empty(t, entries)
+	assert.False(t, Enabled())
+}
+
+func TestRecord_PendingAndRemove(t *testing.T) {
+	// Given
+	dir := filepath.Join(t.TempDir(), "journal")
+	useJournalDir(t, dir)
+
+	// When
+	require.NoError(t, Record("action-a", "exec-1", "redis://a:6379", testState{Original: "first"}))
+	require.NoError(t, Record("action-a", "exec-1", "redis://a:6379", testState{Original: "updated"}))
+	require.NoError(t, Record("action-b", "exec-2", "redis://b:6379", testState{Original: "other"}))
+	entries, err := Pending()
+
+	// Then
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "exec-1", entries[0].ExecutionID)
+	assert.Equal(t, "action-a", entries[0].ActionID)
+	assert.Equal(t, "redis://a:6379", entries[0].RedisURL)
+	assert.JSONEq(t, `{"original":"updated"}`, string(entries[0].State))
+	assert.Equal(t, "exec-2", entries[1].ExecutionID)
+
+	info, err := os.Stat(filepath.Join(dir, "exec-1.json"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, files, 2, "no temporary files left")
+
+	// When
+	Remove("exec-1")
+	Remove("unknown")
+	entries, err = Pending()
+
+	// Then
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "exec-2", entries[0].ExecutionID)
+}
+
+func TestRecord_InvalidExecutionID(t *testing.T) {
+	useJournalDir(t, t.TempDir())
+
+	for _, id := range []string{"", "../escape", "a/b", ".hidden"} {
+		assert.Error(t, Record("action", id, "redis://localhost:6379", testState{}), id)
+	}
+}
+
+func TestPending_SkipsCorruptEntries(t *testing.T) {
+	// Given
+	dir := t.TempDir()
+	useJournalDir(t, dir)
+	require.NoError(t, Record("action", "exec-1", "redis://localhost:6379", testState{}))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "broken.json"), []byte("{not json"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".entry-123"), []byte("partial"), 0o600))
+
+	// When
+	entries, err := Pending()
+
+	// Then
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "exec-1", entries[0].ExecutionID)
+}
+
+func TestReplay(t *testing.T) {
+	// Given
+	useJournalDir(t, t.TempDir())
+	require.NoError(t, Record("restorable", "exec-ok", "redis://localhost:6379", testState{Original: "10mb"}))
+	require.NoError(t, Record("failing", "exec-failed", "redis://localhost:6379", testState{}))
+	require.NoError(t, Record("unknown", "exec-unknown", "redis://localhost:6379", testState{}))
+
+	var restored []string
+	recoverers := map[string]RecoverFunc{
+		"restorable": func(ctx context.Context, entry Entry) error {
+			restored = append(restored, string(entry.State))
+			return nil
+		},
+		"failing": func(ctx context.Context, entry Entry) error {
+			return errors.New("redis unreachable")
+		},
+	}
+
+	// When
+	recoveries := Replay(context.Background(), recoverers)
+
+	// Then
+	require.Len(t, recoveries, 2)
+	assert.Equal(t, []string{`{"original":"10mb"}`}, restored)
+
+	ok, found := GetRecovery("exec-ok")
+	require.True(t, found)
+	assert.NoError(t, ok.Err)
+	assert.Equal(t, "restorable", ok.ActionID)
+
+	failed, found := GetRecovery("exec-failed")
+	require.True(t, found)
+	assert.Error(t, failed.Err)
+
+	_, found = GetRecovery("exec-unknown")
+	assert.False(t, found)
+
+	entries, err := Pending()
+	require.NoError(t, err)
+	ids := make([]string, 0, len(entries))
+	for _, e := range entries {
+		ids = append(ids, e.ExecutionID)
+	}
+	assert.ElementsMatch(t, []string{"exec-failed", "exec-unknown"}, ids, "failed and unknown entries stay for the next start")
+}
@@ -6,11 +6,14 @@ package main
 
 import (
 	"context"
+	"net/http"
 	"os/signal"
 	"syscall"
 	"time"
 
 	_ "github.com/KimMachineGun/automemlimit"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
 	"github.com/steadybit/action-kit/go/action_kit_api/v2"
 	"github.com/steadybit/action-kit/go/action_kit_sdk"
 	"github.com/steadybit/discovery-kit/go/discovery_kit_api"
@@ -20,9 +23,12 @@ import (
 	"github.com/steadybit/extension-kit/exthealth"
 	"github.com/steadybit/extension-kit/exthttp"
 	"github.com/steadybit/extension-kit/extlogging"
+	"github.com/steadybit/extension-redis/backupstore"
 	"github.com/steadybit/extension-redis/clients"
 	"github.com/steadybit/extension-redis/config"
 	"github.com/steadybit/extension-redis/extredis"
+	"github.com/steadybit/extension-redis/metrics"
+	"github.com/steadybit/extension-redis/tracing"
 	_ "go.uber.org/automaxprocs"
 )
 
@@ -35,30 +41,69 @@ func main() {
 	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM, syscall.SIGUSR1)
 	defer cancel()
 	defer clients.CloseAllClients()
+	defer backupstore.Close()
 
 	config.ParseConfiguration()
 	config.ValidateConfiguration()
 
+	shutdownTracing, err := tracing.Init(ctx)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to set up tracing")
+	}
+	defer func() {
+		shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancelShutdown()
+		if err := shutdownTracing(shutdownCtx); err != nil {
+			log.Warn().Err(err).Msg("Failed to flush traces")
+		}
+	}()
+
+	config.OnEndpointsChanged(func(previous, current []config.RedisEndpoint) {
+		for _, url := range config.ChangedEndpointURLs(previous, current) {
+			clients.EvictClients(url)
+		}
+	})
+	if config.Config.EndpointsFile != "" {
+		go config.WatchEndpointsFile(ctx, config.Config.EndpointsFile, time.Duration(config.Config.EndpointsFileReloadIntervalSeconds)*time.Second)
+	}
+
 	exthealth.SetReady(false)
 	exthealth.StartProbes(8084)
 
 	discovery_kit_sdk.Register(extredis.NewRedisInstanceDiscovery(ctx))
 	discovery_kit_sdk.Register(extredis.NewRedisDatabaseDiscovery(ctx))
 
-	action_kit_sdk.RegisterAction(extredis.NewConnectionExhaustionAttack())
-	action_kit_sdk.RegisterAction(extredis.NewClientPauseAttack())
-	action_kit_sdk.RegisterAction(extredis.NewMaxmemoryLimitAttack())
-	action_kit_sdk.RegisterAction(extredis.NewCacheExpirationAttack())
-	action_kit_sdk.RegisterAction(extredis.NewSentinelStopAttack())
-	action_kit_sdk.RegisterAction(extredis.NewMemoryCheck())
-	action_kit_sdk.RegisterAction(extredis.NewLatencyCheck())
-	action_kit_sdk.RegisterAction(extredis.NewConnectionCountCheck())
-	action_kit_sdk.RegisterAction(extredis.NewReplicationLagCheck())
+	action_kit_sdk.RegisterAction(extredis.Instrument(extredis.NewConnectionExhaustionAttack()))
+	action_kit_sdk.RegisterAction(extredis.Instrument(extredis.NewClientPauseAttack()))
+	action_kit_sdk.RegisterAction(extredis.Instrument(extredis.NewMaxmemoryLimitAttack()))
+	action_kit_sdk.RegisterAction(extredis.Instrument(extredis.NewCacheExpirationAttack()))
+	action_kit_sdk.RegisterAction(extredis.Instrument(extredis.NewSentinelStopAttack()))
+	action_kit_sdk.RegisterAction(extredis.Instrument(extredis.NewClusterNodeStopAttack()))
+	action_kit_sdk.RegisterAction(extredis.Instrument(extredis.NewPubSubDisruptionAttack()))
+	action_kit_sdk.RegisterAction(extredis.Instrument(extredis.NewStreamConsumerGroupAttack()))
+	action_kit_sdk.RegisterAction(extredis.Instrument(extredis.NewOutputBufferLimitAttack()))
+	action_kit_sdk.RegisterAction(extredis.Instrument(extredis.NewSlotMigrationAttack()))
+	action_kit_sdk.RegisterAction(extredis.Instrument(extredis.NewMemoryCheck()))
+	action_kit_sdk.RegisterAction(extredis.Instrument(extredis.NewLatencyCheck()))
+	action_kit_sdk.RegisterAction(extredis.Instrument(extredis.NewConnectionCountCheck()))
+	action_kit_sdk.RegisterAction(extredis.Instrument(extredis.NewReplicationLagCheck()))
+	action_kit_sdk.RegisterAction(extredis.Instrument(extredis.NewStreamBacklogCheck()))
+
+	extredis.RegisterEventListenerHandlers(ctx)
+	extredis.RegisterAdviceHandlers()
 
 	exthttp.RegisterHttpHandler("/", exthttp.IfNoneMatchHandler(func() string { return startedAt }, exthttp.GetterAsHandler(getExtensionList)))
 
 	action_kit_sdk.RegisterCoverageEndpoints()
 
+	metrics.Registry.MustRegister(clients.NewPoolCollector())
+	exthttp.RegisterHttpHandlerWithLogLevel("/metrics", func(w http.ResponseWriter, r *http.Request, _ []byte) {
+		metrics.Handler().ServeHTTP(w, r)
+	}, zerolog.DebugLevel)
+
+	// Roll back attacks left behind by a previous run before new ones can start
+	extredis.RecoverFromJournal(ctx)
+
 	exthealth.SetReady(true)
 	exthttp.Listen(exthttp.ListenOpts{Port: 8083})
 }
@@ -67,11 +112,14 @@ type ExtensionListResponse struct {
 	action_kit_api.ActionList       `json:",inline"`
 	discovery_kit_api.DiscoveryList `json:",inline"`
 	event_kit_api.EventListenerList `json:",inline"`
+	extredis.AdviceList             `json:",inline"`
 }
 
 func getExtensionList() ExtensionListResponse {
 	return ExtensionListResponse{
-		ActionList:    action_kit_sdk.GetActionList(),
-		DiscoveryList: discovery_kit_sdk.GetDiscoveryList(),
+		ActionList:        action_kit_sdk.GetActionList(),
+		DiscoveryList:     discovery_kit_sdk.GetDiscoveryList(),
+		EventListenerList: extredis.GetEventListenerList(),
+		AdviceList:        extredis.GetAdviceList(),
 	}
 }
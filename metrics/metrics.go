@@ -0,0 +1,84 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+// Package metrics holds the Prometheus metrics of the extension, served on /metrics. Endpoint labels carry the
+// endpoint URL without the password.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Namespace prefixes the names of all metrics of the extension.
+const Namespace = "steadybit_redis"
+
+// Registry holds the metrics of the extension and the Go runtime and process metrics.
+var Registry = prometheus.NewRegistry()
+
+var (
+	// DiscoveryDuration is the time a discovery took per endpoint.
+	DiscoveryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Name:      "discovery_duration_seconds",
+		Help:      "Duration of the discovery of an endpoint.",
+		Buckets:   []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"discovery", "endpoint"})
+
+	// DiscoveryErrors counts failed discoveries per endpoint.
+	DiscoveryErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "discovery_errors_total",
+		Help:      "Number of failed discoveries of an endpoint.",
+	}, []string{"discovery", "endpoint"})
+
+	// ActiveAttacks is the number of started attacks that were not stopped yet.
+	ActiveAttacks = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Name:      "active_attacks",
+		Help:      "Number of attacks that are currently running.",
+	}, []string{"action"})
+
+	// RollbackFailures counts attacks whose changes could not be rolled back, by Stop, a failed Start or the journal.
+	RollbackFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "rollback_failures_total",
+		Help:      "Number of attacks whose changes could not be rolled back.",
+	}, []string{"action"})
+
+	// ConfigDrift counts differences between the snapshot taken when an experiment started and the state after it ended.
+	ConfigDrift = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "config_drift_total",
+		Help:      "Number of settings, ACL users and replication roles that differed from their snapshot after an experiment.",
+	}, []string{"endpoint", "item"})
+
+	// CommandErrors counts failed Redis commands per endpoint, redis.Nil replies are not errors.
+	CommandErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "command_errors_total",
+		Help:      "Number of Redis commands that failed.",
+	}, []string{"endpoint", "command"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		DiscoveryDuration,
+		DiscoveryErrors,
+		ActiveAttacks,
+		RollbackFailures,
+		ConfigDrift,
+		CommandErrors,
+	)
+}
+
+// Handler serves the metrics of Registry. Compression is left to the HTTP middleware of the extension.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{DisableCompression: true})
+}
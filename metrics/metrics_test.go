@@ -0,0 +1,33 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2024 Steadybit GmbH
+
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler_ServesRegisteredMetrics(t *testing.T) {
+	// Given
+	DiscoveryErrors.WithLabelValues("com.steadybit.extension_redis.instance", "redis://metrics-test:6379").Inc()
+	ActiveAttacks.WithLabelValues("com.steadybit.extension_redis.test").Set(0)
+
+	// When
+	recorder := httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	// Then
+	require.Equal(t, http.StatusOK, recorder.Code)
+	body, err := io.ReadAll(recorder.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `steadybit_redis_discovery_errors_total{discovery="com.steadybit.extension_redis.instance",endpoint="redis://metrics-test:6379"} 1`)
+	assert.Contains(t, string(body), `steadybit_redis_active_attacks{action="com.steadybit.extension_redis.test"} 0`)
+	assert.Contains(t, string(body), "go_goroutines")
+	assert.Empty(t, recorder.Header().Get("Content-Encoding"))
+}
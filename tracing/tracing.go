@@ -0,0 +1,131 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+// Package tracing exports OpenTelemetry traces of the extension with OTLP. The exporter is configured by the
+// standard OTEL_EXPORTER_OTLP_* environment variables, tracing stays off unless an OTLP endpoint is set.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/steadybit/extension-kit/extbuild"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	instrumentationName = "github.com/steadybit/extension-redis"
+	serviceName         = "steadybit-extension-redis"
+)
+
+// Span attributes of action calls.
+const (
+	AttrActionID    = attribute.Key("steadybit.action.id")
+	AttrExecutionID = attribute.Key("steadybit.execution.id")
+	AttrTarget      = attribute.Key("steadybit.target")
+	AttrRedisURL    = attribute.Key("redis.url")
+)
+
+// Enabled reports whether an OTLP endpoint is configured and the SDK is not disabled.
+func Enabled() bool {
+	if strings.EqualFold(os.Getenv("OTEL_SDK_DISABLED"), "true") {
+		return false
+	}
+	return os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != ""
+}
+
+// Init sets up the global tracer provider. The returned function flushes and stops the exporter, it must be called
+// on shutdown. Without an OTLP endpoint nothing is set up and spans are not recorded.
+func Init(ctx context.Context) (func(context.Context) error, error) {
+	if !Enabled() {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := newExporter(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES override the defaults
+	res, err := resource.New(ctx,
+		resource.WithAttributes(
+			semconv.ServiceName(serviceName),
+			semconv.ServiceVersion(extbuild.GetSemverVersionStringOrUnknown()),
+		),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("create trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		log.Warn().Err(err).Msg("OpenTelemetry error")
+	}))
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return provider.Shutdown, nil
+}
+
+// newExporter creates the exporter for OTEL_EXPORTER_OTLP_TRACES_PROTOCOL or OTEL_EXPORTER_OTLP_PROTOCOL, the
+// exporters read their endpoint, headers and TLS settings from the environment themselves.
+func newExporter(ctx context.Context) (sdktrace.SpanExporter, error) {
+	protocol := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_PROTOCOL")
+	if protocol == "" {
+		protocol = os.Getenv("OTEL_EXPORTER_OTLP_PROTOCOL")
+	}
+	switch protocol {
+	case "", "http/protobuf":
+		return otlptracehttp.New(ctx)
+	case "grpc":
+		return otlptracegrpc.New(ctx)
+	default:
+		return nil, fmt.Errorf("unsupported OTLP protocol %q, use grpc or http/protobuf", protocol)
+	}
+}
+
+// Tracer returns the tracer of the extension.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// ExecutionContext returns ctx with a remote parent derived from the execution ID, so the spans of all calls of an
+// execution share one trace whose ID is the execution ID without dashes.
+func ExecutionContext(ctx context.Context, executionID uuid.UUID) context.Context {
+	if executionID == uuid.Nil {
+		return ctx
+	}
+	var spanID trace.SpanID
+	copy(spanID[:], executionID[8:])
+	parent := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID(executionID),
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	})
+	return trace.ContextWithRemoteSpanContext(ctx, parent)
+}
+
+// End records err on the span and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
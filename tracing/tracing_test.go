@@ -0,0 +1,109 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2024 Steadybit GmbH
+
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestEnabled(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want bool
+	}{
+		{name: "no endpoint", env: map[string]string{}, want: false},
+		{name: "endpoint", env: map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "http://collector:4318"}, want: true},
+		{name: "traces endpoint", env: map[string]string{"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT": "http://collector:4318/v1/traces"}, want: true},
+		{name: "sdk disabled", env: map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "http://collector:4318", "OTEL_SDK_DISABLED": "true"}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"OTEL_EXPORTER_OTLP_ENDPOINT", "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "OTEL_SDK_DISABLED"} {
+				t.Setenv(key, tt.env[key])
+			}
+			assert.Equal(t, tt.want, Enabled())
+		})
+	}
+}
+
+func TestInit_Disabled(t *testing.T) {
+	// Given
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+
+	// When
+	shutdown, err := Init(context.Background())
+
+	// Then
+	require.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+}
+
+func TestInit_UnsupportedProtocol(t *testing.T) {
+	// Given
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://localhost:4318")
+	t.Setenv("OTEL_EXPORTER_OTLP_PROTOCOL", "http/json")
+
+	// When
+	_, err := Init(context.Background())
+
+	// Then
+	assert.ErrorContains(t, err, `unsupported OTLP protocol "http/json"`)
+}
+
+func TestNewExporter_Protocols(t *testing.T) {
+	for _, protocol := range []string{"", "http/protobuf", "grpc"} {
+		t.Run(protocol, func(t *testing.T) {
+			// Given
+			t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://localhost:4318")
+			t.Setenv("OTEL_EXPORTER_OTLP_PROTOCOL", protocol)
+
+			// When
+			exporter, err := newExporter(context.Background())
+
+			// Then
+			require.NoError(t, err)
+			assert.NoError(t, exporter.Shutdown(context.Background()))
+		})
+	}
+}
+
+func TestExecutionContext_SharesTraceOfExecution(t *testing.T) {
+	// Given
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	tracer := provider.Tracer("test")
+	executionID := uuid.New()
+
+	// When
+	_, prepare := tracer.Start(ExecutionContext(context.Background(), executionID), "prepare")
+	End(prepare, nil)
+	_, stop := tracer.Start(ExecutionContext(context.Background(), executionID), "stop")
+	End(stop, assert.AnError)
+
+	// Then
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, trace.TraceID(executionID), spans[0].SpanContext().TraceID())
+	assert.Equal(t, spans[0].SpanContext().TraceID(), spans[1].SpanContext().TraceID())
+	assert.True(t, spans[0].SpanContext().IsSampled())
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+	assert.Len(t, spans[1].Events(), 1)
+}
+
+func TestExecutionContext_NilExecutionID(t *testing.T) {
+	ctx := ExecutionContext(context.Background(), uuid.Nil)
+	assert.False(t, trace.SpanContextFromContext(ctx).IsValid())
+}